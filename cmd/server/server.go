@@ -12,6 +12,7 @@ import (
 	"github.com/supergiant/supergiant/pkg/provider/kubernetes"
 	"github.com/supergiant/supergiant/pkg/provider/openstack"
 	"github.com/supergiant/supergiant/pkg/provider/packet"
+	"github.com/supergiant/supergiant/pkg/provider/rfc2136"
 	"github.com/supergiant/supergiant/pkg/server"
 	"github.com/urfave/cli"
 )
@@ -71,6 +72,10 @@ func main() {
 
 		c.K8SProvider = &kubernetes.Provider{Core: c}
 
+		c.Route53Provider = aws.NewRoute53
+		c.DODNSProvider = digitalocean.NewDNS
+		c.RFC2136Provider = &rfc2136.Provider{}
+
 		// We do this here, and not in core, so that we can ensure the file closes on exit.
 		if c.LogPath != "" {
 			file, err := os.OpenFile(c.LogPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
@@ -166,6 +171,56 @@ func main() {
 			Destination: &c.LogLevel,
 			// Value:  <--- NOTE just cuz you always forget you can set defaults
 		},
+		cli.StringFlag{
+			Name:        "alert-webhook-url",
+			Usage:       "URL that alerts (e.g. on budgets) are posted to, in the format of Slack incoming webhooks",
+			Destination: &c.AlertWebhookURL,
+		},
+		cli.StringFlag{
+			Name:        "acme-directory-url",
+			Usage:       "ACME directory Ingress certificates are issued from (Let's Encrypt by default)",
+			Destination: &c.ACMEDirectoryURL,
+		},
+		cli.StringFlag{
+			Name:        "acme-email",
+			Usage:       "Contact email of the ACME account, for expiry notices",
+			Destination: &c.ACMEEmail,
+		},
+		cli.StringFlag{
+			Name:        "acme-account-key-file",
+			Usage:       "File the ACME account key is kept in (generated if missing)",
+			Destination: &c.ACMEAccountKeyFile,
+		},
+		cli.StringFlag{
+			Name:        "acme-ca-file",
+			Usage:       "CA certificates to trust the ACME directory with, e.g. for a test server",
+			Destination: &c.ACMECAFile,
+		},
+		cli.IntFlag{
+			Name:        "acme-propagation-seconds",
+			Usage:       "Seconds to wait for ACME challenge records to reach all name servers (60 by default)",
+			Destination: &c.ACMEPropagationSeconds,
+		},
+		cli.StringFlag{
+			Name:        "helm-worker-image",
+			Usage:       "Image of the pods Helm commands run in (supergiant/helm-worker:v2.8.2 by default)",
+			Destination: &c.HelmWorkerImage,
+		},
+		cli.StringFlag{
+			Name:        "helm-worker-namespace",
+			Usage:       "Namespace of the pods Helm commands run in (default by default)",
+			Destination: &c.HelmWorkerNamespace,
+		},
+		cli.StringFlag{
+			Name:        "helm-local-repo-dir",
+			Usage:       "Directory uploaded charts are stored in (a charts directory next to the SQLite file by default)",
+			Destination: &c.HelmLocalRepoDir,
+		},
+		cli.BoolFlag{
+			Name:        "helm-update-notifications",
+			Usage:       "Alert when newer versions of the charts of Helm Releases are found",
+			Destination: &c.HelmUpdateNotifications,
+		},
 		cli.StringFlag{
 			Name:        "config-file",
 			Usage:       "JSON config filepath (command line arguments will override the values set here)",
@@ -0,0 +1,474 @@
+// Package acme is a minimal ACME (RFC 8555) client, used to issue the TLS
+// certificates of Ingresses through DNS-01 challenges.
+package acme
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DNSSolver publishes the TXT records DNS-01 challenges are validated with.
+type DNSSolver interface {
+	Present(fqdn string, value string) error
+	CleanUp(fqdn string, value string) error
+}
+
+// Certificate is an issued certificate chain and its private key, both PEM
+// encoded.
+type Certificate struct {
+	CertPEM  []byte
+	KeyPEM   []byte
+	NotAfter time.Time
+}
+
+// Client talks to a single ACME server with a single account. It is safe for
+// concurrent use.
+type Client struct {
+	DirectoryURL string
+	// Key of the account, which is registered on first use.
+	Key   *ecdsa.PrivateKey
+	Email string
+
+	HTTPClient *http.Client
+
+	// PropagationWait is how long to wait after publishing challenge records
+	// before asking the server to validate them.
+	PropagationWait time.Duration
+	PollInterval    time.Duration
+	PollTimeout     time.Duration
+
+	mu    sync.Mutex
+	dir   *directory
+	kid   string
+	nonce string
+}
+
+// NewKey generates an account or certificate key.
+func NewKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// Obtain issues a certificate for domains, the first of which is its common
+// name.
+func (c *Client) Obtain(domains []string, solver DNSSolver) (*Certificate, error) {
+	if len(domains) == 0 {
+		return nil, errors.New("No domains to issue a certificate for")
+	}
+	if err := c.register(); err != nil {
+		return nil, err
+	}
+
+	var identifiers []identifier
+	for _, domain := range domains {
+		identifiers = append(identifiers, identifier{Type: "dns", Value: domain})
+	}
+	order := new(order)
+	resp, err := c.post(c.dir.NewOrder, map[string]interface{}{"identifiers": identifiers}, order)
+	if err != nil {
+		return nil, err
+	}
+	orderURL := resp.Header.Get("Location")
+
+	if err := c.authorize(order.Authorizations, solver); err != nil {
+		return nil, err
+	}
+
+	key, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.post(order.Finalize, map[string]string{"csr": encode(csr)}, order); err != nil {
+		return nil, err
+	}
+
+	err = c.poll(func() (bool, error) {
+		if _, err := c.post(orderURL, nil, order); err != nil {
+			return false, err
+		}
+		switch order.Status {
+		case "valid":
+			return true, nil
+		case "invalid":
+			return false, fmt.Errorf("ACME order for %v is invalid", domains)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = c.post(order.Certificate, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	certPEM, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("ACME server returned no PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{
+		CertPEM:  certPEM,
+		KeyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		NotAfter: cert.NotAfter,
+	}, nil
+}
+
+// Private
+
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type order struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate"`
+}
+
+type authorization struct {
+	Status     string      `json:"status"`
+	Identifier identifier  `json:"identifier"`
+	Challenges []challenge `json:"challenges"`
+}
+
+type challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Token  string   `json:"token"`
+	Status string   `json:"status"`
+	Error  *problem `json:"error"`
+}
+
+type problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+func (p *problem) Error() string {
+	return fmt.Sprintf("ACME %s: %s", p.Type, p.Detail)
+}
+
+// register loads the directory and registers the account, once.
+func (c *Client) register() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.kid != "" {
+		return nil
+	}
+
+	if c.dir == nil {
+		resp, err := c.httpClient().Get(c.DirectoryURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		dir := new(directory)
+		if err := json.NewDecoder(resp.Body).Decode(dir); err != nil {
+			return err
+		}
+		c.dir = dir
+	}
+
+	account := map[string]interface{}{
+		"termsOfServiceAgreed": true,
+	}
+	if c.Email != "" {
+		account["contact"] = []string{"mailto:" + c.Email}
+	}
+	resp, err := c.postLocked(c.dir.NewAccount, account, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	c.kid = resp.Header.Get("Location")
+	return nil
+}
+
+// authorize completes the DNS-01 challenges of the order's authorizations.
+func (c *Client) authorize(urls []string, solver DNSSolver) error {
+	type pending struct {
+		authzURL string
+		chal     challenge
+		fqdn     string
+		value    string
+	}
+	var challenges []*pending
+
+	defer func() {
+		for _, p := range challenges {
+			solver.CleanUp(p.fqdn, p.value)
+		}
+	}()
+
+	for _, authzURL := range urls {
+		authz := new(authorization)
+		if _, err := c.post(authzURL, nil, authz); err != nil {
+			return err
+		}
+		if authz.Status == "valid" {
+			continue
+		}
+
+		var dns01 *challenge
+		for i, chal := range authz.Challenges {
+			if chal.Type == "dns-01" {
+				dns01 = &authz.Challenges[i]
+			}
+		}
+		if dns01 == nil {
+			return fmt.Errorf("ACME server offers no dns-01 challenge for %s", authz.Identifier.Value)
+		}
+
+		digest := sha256.Sum256([]byte(dns01.Token + "." + c.thumbprint()))
+		p := &pending{
+			authzURL: authzURL,
+			chal:     *dns01,
+			fqdn:     "_acme-challenge." + authz.Identifier.Value,
+			value:    encode(digest[:]),
+		}
+		if err := solver.Present(p.fqdn, p.value); err != nil {
+			return err
+		}
+		challenges = append(challenges, p)
+	}
+
+	if len(challenges) == 0 {
+		return nil
+	}
+	time.Sleep(c.PropagationWait)
+
+	for _, p := range challenges {
+		resp, err := c.post(p.chal.URL, struct{}{}, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	for _, p := range challenges {
+		err := c.poll(func() (bool, error) {
+			authz := new(authorization)
+			if _, err := c.post(p.authzURL, nil, authz); err != nil {
+				return false, err
+			}
+			switch authz.Status {
+			case "valid":
+				return true, nil
+			case "pending", "processing":
+				return false, nil
+			}
+			for _, chal := range authz.Challenges {
+				if chal.Type == "dns-01" && chal.Error != nil {
+					return false, chal.Error
+				}
+			}
+			return false, fmt.Errorf("ACME authorization of %s is %s", authz.Identifier.Value, authz.Status)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) poll(fn func() (bool, error)) error {
+	interval := c.PollInterval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	timeout := c.PollTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := fn()
+		if err != nil || done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return errors.New("Timed out waiting on ACME server")
+		}
+		time.Sleep(interval)
+	}
+}
+
+// post sends a JWS signed request. A nil payload is a POST-as-GET. The
+// response body is decoded into out, or left open when out is nil.
+func (c *Client) post(url string, payload interface{}, out interface{}) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.postLocked(url, payload, out)
+}
+
+func (c *Client) postLocked(url string, payload interface{}, out interface{}) (*http.Response, error) {
+	resp, err := c.send(url, payload)
+	if prob, ok := err.(*problem); ok && prob.Type == "urn:ietf:params:acme:error:badNonce" {
+		resp, err = c.send(url, payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out != nil {
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (c *Client) send(url string, payload interface{}) (*http.Response, error) {
+	body, err := c.sign(url, payload)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Post(url, "application/jose+json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.nonce = resp.Header.Get("Replay-Nonce")
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		prob := new(problem)
+		if err := json.NewDecoder(resp.Body).Decode(prob); err != nil || prob.Type == "" {
+			return nil, fmt.Errorf("ACME server responded %s to %s", resp.Status, url)
+		}
+		return nil, prob
+	}
+	return resp, nil
+}
+
+// sign wraps the payload in a flattened JWS, signed with ES256.
+func (c *Client) sign(url string, payload interface{}) ([]byte, error) {
+	if c.nonce == "" {
+		resp, err := c.httpClient().Head(c.dir.NewNonce)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		c.nonce = resp.Header.Get("Replay-Nonce")
+	}
+
+	protected := map[string]interface{}{
+		"alg":   "ES256",
+		"nonce": c.nonce,
+		"url":   url,
+	}
+	c.nonce = ""
+	if c.kid != "" {
+		protected["kid"] = c.kid
+	} else {
+		protected["jwk"] = c.jwk()
+	}
+	protectedJSON, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload64 string
+	if payload != nil {
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		payload64 = encode(payloadJSON)
+	}
+
+	signingInput := encode(protectedJSON) + "." + payload64
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.Key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	signature := append(padded(r, 32), padded(s, 32)...)
+
+	return json.Marshal(map[string]string{
+		"protected": encode(protectedJSON),
+		"payload":   payload64,
+		"signature": encode(signature),
+	})
+}
+
+func (c *Client) jwk() map[string]string {
+	return map[string]string{
+		"crv": "P-256",
+		"kty": "EC",
+		"x":   encode(padded(c.Key.X, 32)),
+		"y":   encode(padded(c.Key.Y, 32)),
+	}
+}
+
+// padded returns the big-endian bytes of n, padded with leading zeros to size.
+func padded(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// thumbprint is the RFC 7638 thumbprint of the account key.
+func (c *Client) thumbprint() string {
+	jwk := c.jwk()
+	// Members in lexicographic order, without whitespace
+	canonical := fmt.Sprintf(`{"crv":"%s","kty":"%s","x":"%s","y":"%s"}`, jwk["crv"], jwk["kty"], jwk["x"], jwk["y"])
+	digest := sha256.Sum256([]byte(canonical))
+	return encode(digest[:])
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/imdario/mergo"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const maxBulkOperations = 500
+
+// bulkMethods are the request methods of the ops of BulkOperations.
+var bulkMethods = map[string]string{
+	"create": "POST",
+	"update": "PATCH",
+	"delete": "DELETE",
+	"action": "POST",
+}
+
+// bulkNameRxp matches the names of resources and actions, which are path
+// segments of the routes.
+var bulkNameRxp = regexp.MustCompile(`^\w+$`)
+
+// bulkRoutes are the routes operations are run through. They're set on init,
+// since RunBulk is one of them.
+var bulkRoutes []*route
+
+func init() {
+	bulkRoutes = routes
+}
+
+type bulkError struct { // status bad request
+	err error
+}
+
+func (e *bulkError) Error() string {
+	return "Invalid bulk request: " + e.err.Error()
+}
+
+// RunBulk runs the operations of a Bulk in order, each through the handler of
+// its route, and responds with the result of each. Operations which fail don't
+// stop the others, unless the Bulk is Atomic and they fail validation.
+func RunBulk(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	bulk := new(model.Bulk)
+	if err := json.NewDecoder(r.Body).Decode(bulk); err != nil {
+		return nil, &bodyDecodingError{err}
+	}
+	if len(bulk.Operations) == 0 {
+		return nil, &bulkError{errors.New("no operations")}
+	}
+	if len(bulk.Operations) > maxBulkOperations {
+		return nil, &bulkError{fmt.Errorf("more than %d operations", maxBulkOperations)}
+	}
+
+	router := newBulkRouter(core, user)
+
+	requests := make([]*bulkRequest, len(bulk.Operations))
+	for i, op := range bulk.Operations {
+		req, err := router.resolve(op)
+		if err != nil {
+			return nil, &bulkError{fmt.Errorf("operation %d: %s", i, err)}
+		}
+		requests[i] = req
+	}
+
+	bulk.Results = make([]*model.BulkResult, len(requests))
+
+	if bulk.Atomic {
+		invalid := -1
+		for i, req := range requests {
+			if err := router.validate(req); err != nil {
+				bulk.Results[i] = bulkErrorResult(errorHTTPStatus(err), err.Error())
+				if invalid < 0 {
+					invalid = i
+				}
+			}
+		}
+		if invalid >= 0 {
+			for i, result := range bulk.Results {
+				if result == nil {
+					bulk.Results[i] = bulkErrorResult(http.StatusFailedDependency, fmt.Sprintf("Not run, since operation %d is invalid", invalid))
+				}
+			}
+			return &Response{http.StatusOK, bulk}, nil
+		}
+	}
+
+	// Actions are of the same version of the API as the Bulk
+	prefix := strings.TrimSuffix(r.URL.Path, "/bulk")
+	for i, req := range requests {
+		bulk.Results[i] = router.run(req, prefix)
+	}
+	return &Response{http.StatusOK, bulk}, nil
+}
+
+func bulkErrorResult(status int, message string) *model.BulkResult {
+	return &model.BulkResult{
+		Status: status,
+		Error:  &model.Error{Status: status, Message: message},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// bulkRouter routes the operations of a Bulk to the handlers of the routes,
+// as the user who made it.
+type bulkRouter struct {
+	*mux.Router
+	core   *core.Core
+	user   *model.User
+	routes map[*mux.Route]*route
+}
+
+// bulkRequest is a BulkOperation resolved to its route.
+type bulkRequest struct {
+	op     *model.BulkOperation
+	method string
+	path   string
+	route  *route
+	// item is the type of the resource operated on, which must exist for
+	// anything but a create.
+	item reflect.Type
+}
+
+func newBulkRouter(c *core.Core, user *model.User) *bulkRouter {
+	router := &bulkRouter{mux.NewRouter(), c, user, make(map[*mux.Route]*route)}
+	for _, rt := range bulkRoutes {
+		if rt.handler == nil {
+			continue
+		}
+		handler := rt.authorizedHandler()
+		muxRoute := router.HandleFunc(rt.path, func(w http.ResponseWriter, r *http.Request) {
+			resp, err := handler(c, user, r)
+			respond(w, resp, err)
+		}).Methods(rt.methods...)
+		router.routes[muxRoute] = rt
+	}
+	return router
+}
+
+func (router *bulkRouter) match(method string, path string) *route {
+	r, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return nil
+	}
+	var match mux.RouteMatch
+	if !router.Match(r, &match) {
+		return nil
+	}
+	return router.routes[match.Route]
+}
+
+func (router *bulkRouter) resolve(op *model.BulkOperation) (*bulkRequest, error) {
+	method, ok := bulkMethods[op.Op]
+	if !ok {
+		return nil, fmt.Errorf("op must be create, update, delete or action, not '%s'", op.Op)
+	}
+	switch {
+	case op.Resource == "":
+		return nil, errors.New("resource required")
+	case !bulkNameRxp.MatchString(op.Resource):
+		return nil, fmt.Errorf("no resource '%s'", op.Resource)
+	case op.Resource == "bulk", op.Resource == "sessions":
+		return nil, fmt.Errorf("%s can't be changed in bulk", op.Resource)
+	}
+
+	req := &bulkRequest{op: op, method: method, path: "/" + op.Resource}
+	if op.Op != "create" {
+		if op.ID == nil {
+			return nil, fmt.Errorf("id required to %s %s", op.Op, op.Resource)
+		}
+		req.path += "/" + strconv.FormatInt(*op.ID, 10)
+
+		get := router.match("GET", req.path)
+		if get == nil || get.response == nil {
+			return nil, fmt.Errorf("no resource '%s'", op.Resource)
+		}
+		req.item = reflect.TypeOf(get.response).Elem()
+	}
+	if op.Op == "action" {
+		if op.Action == "" {
+			return nil, fmt.Errorf("action required to act on %s", op.Resource)
+		}
+		if !bulkNameRxp.MatchString(op.Action) {
+			return nil, fmt.Errorf("%s have no action '%s'", op.Resource, op.Action)
+		}
+		req.path += "/" + op.Action
+	}
+
+	if req.route = router.match(req.method, req.path); req.route == nil {
+		if op.Op == "action" {
+			return nil, fmt.Errorf("%s have no action '%s'", op.Resource, op.Action)
+		}
+		return nil, fmt.Errorf("can't %s %s", op.Op, op.Resource)
+	}
+	return req, nil
+}
+
+// validate checks an operation without running it: the user must be
+// authorized to make it, the resource must exist, the item must decode, and
+// created or updated items must be valid as they would be saved.
+func (router *bulkRouter) validate(req *bulkRequest) error {
+	var item interface{}
+	var decodeErr error
+	if req.route.request != nil {
+		item = reflect.New(reflect.TypeOf(req.route.request).Elem()).Interface()
+		body := req.op.Item
+		if len(body) == 0 {
+			body = json.RawMessage("{}")
+		}
+		if err := json.Unmarshal(body, item); err != nil {
+			item, decodeErr = nil, &bodyDecodingError{err}
+		}
+	}
+	if req.route.authorize != nil {
+		if err := req.route.authorize(router.core, router.user, req.op.ID, item); err != nil {
+			return err
+		}
+	}
+
+	var existing model.Model
+	if req.item != nil {
+		existing = reflect.New(req.item).Interface().(model.Model)
+		if err := router.core.DB.First(existing, *req.op.ID); err != nil {
+			return err
+		}
+	}
+	if decodeErr != nil || item == nil {
+		return decodeErr
+	}
+
+	m, isModel := item.(model.Model)
+	if !isModel || req.op.Op == "action" {
+		return nil
+	}
+	model.ZeroReadonlyFields(m)
+	if req.op.Op == "update" {
+		if err := model.CheckImmutableFields(m); err != nil {
+			return err
+		}
+		if _, err := core.CheckResourceVersion(existing, m); err != nil {
+			return err
+		}
+		if err := mergo.Merge(m, existing); err != nil {
+			return err
+		}
+	}
+	return router.core.DB.Validate(m)
+}
+
+// run makes the request of an operation. Items with an Action running once
+// it's made (such as a Kube being deleted) have its handle in the result.
+func (router *bulkRouter) run(req *bulkRequest, prefix string) *model.BulkResult {
+	r, err := http.NewRequest(req.method, req.path, bytes.NewReader(req.op.Item))
+	if err != nil {
+		return bulkErrorResult(http.StatusInternalServerError, err.Error())
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	result := &model.BulkResult{Status: w.Code}
+	if w.Code >= 400 {
+		result.Error = new(model.Error)
+		if err := json.Unmarshal(w.Body.Bytes(), result.Error); err != nil {
+			result.Error = &model.Error{Status: w.Code, Message: w.Body.String()}
+		}
+		return result
+	}
+	result.Item = json.RawMessage(bytes.TrimSpace(w.Body.Bytes()))
+
+	var rendered struct {
+		ID     *int64              `json:"id"`
+		Status *model.ActionStatus `json:"status"`
+	}
+	if err := json.Unmarshal(result.Item, &rendered); err == nil && rendered.Status != nil {
+		id := req.op.ID
+		if id == nil {
+			id = rendered.ID
+		}
+		if id != nil {
+			result.Action = &model.BulkAction{
+				Path:   prefix + "/" + req.op.Resource + "/" + strconv.FormatInt(*id, 10),
+				Status: rendered.Status,
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+func ListDNSZones(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	return handleList(core, r, new(model.DNSZone), new(model.DNSZoneList))
+}
+
+func CreateDNSZone(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.DNSZone)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	if err := core.DNSZones.Create(item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusCreated)
+}
+
+func UpdateDNSZone(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	item := new(model.DNSZone)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	if err := core.DNSZones.Update(id, new(model.DNSZone), item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
+
+func GetDNSZone(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.DNSZone)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.DNSZones.Get(id, item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusOK)
+}
+
+func DeleteDNSZone(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.DNSZone)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.DNSZones.Delete(id, item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
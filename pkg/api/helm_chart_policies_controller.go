@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+func ListHelmChartPolicies(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	return handleList(core, r, new(model.HelmChartPolicy), new(model.HelmChartPolicyList))
+}
+
+func CreateHelmChartPolicy(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmChartPolicy)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	if err := core.HelmChartPolicies.Create(item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusCreated)
+}
+
+func UpdateHelmChartPolicy(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	item := new(model.HelmChartPolicy)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	if err := core.HelmChartPolicies.Update(id, new(model.HelmChartPolicy), item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
+
+func GetHelmChartPolicy(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmChartPolicy)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmChartPolicies.Get(id, item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusOK)
+}
+
+func DeleteHelmChartPolicy(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmChartPolicy)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmChartPolicies.Delete(id, item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/supergiant/supergiant/pkg/core"
@@ -11,6 +12,23 @@ func ListHelmCharts(core *core.Core, user *model.User, r *http.Request) (*Respon
 	return handleList(core, r, new(model.HelmChart), new(model.HelmChartList))
 }
 
+func SearchHelmCharts(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	search, err := parseHelmChartSearch(r)
+	if err != nil {
+		return nil, err
+	}
+	items, err := core.HelmCharts.Search(search)
+	if err != nil {
+		return nil, err
+	}
+	list := &model.HelmChartList{Items: items}
+	list.Total = int64(len(items))
+	for _, item := range items {
+		item.SetPassiveStatus()
+	}
+	return &Response{http.StatusOK, list}, nil
+}
+
 func CreateHelmChart(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
 	item := new(model.HelmChart)
 	if err := decodeBodyInto(r, item); err != nil {
@@ -22,6 +40,18 @@ func CreateHelmChart(core *core.Core, user *model.User, r *http.Request) (*Respo
 	return itemResponse(core, item, http.StatusCreated)
 }
 
+func UploadHelmChart(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	upload := new(model.HelmChartUpload)
+	if err := json.NewDecoder(r.Body).Decode(upload); err != nil {
+		return nil, &bodyDecodingError{err}
+	}
+	item, err := core.HelmCharts.Upload(upload)
+	if err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusCreated)
+}
+
 func UpdateHelmChart(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
 	id, err := parseID(r)
 	if err != nil {
@@ -60,3 +90,7 @@ func DeleteHelmChart(core *core.Core, user *model.User, r *http.Request) (*Respo
 	}
 	return itemResponse(core, item, http.StatusAccepted)
 }
+
+func parseHelmChartSearch(r *http.Request) (*model.HelmChartSearch, error) {
+	return core.ParseHelmChartSearch(r.URL.Query())
+}
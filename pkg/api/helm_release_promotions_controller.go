@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+type errorPromotionApprovalRequired struct { // status forbidden
+	kubeName string
+}
+
+func (e *errorPromotionApprovalRequired) Error() string {
+	return fmt.Sprintf("HelmReleases of Kube %s can only be changed by an admin, or by promoting them to it", e.kubeName)
+}
+
+// ensurePromotionApproval returns an error unless the user is an admin, or the
+// Kube doesn't require promotions to it to be approved. Otherwise a user could
+// change its HelmReleases directly, without approval.
+func ensurePromotionApproval(c *core.Core, user *model.User, kubeName string) error {
+	if user.Role == model.UserRoleAdmin {
+		return nil
+	}
+	kube := new(model.Kube)
+	err := c.DB.Where("name = ?", kubeName).First(kube)
+	if err == gorm.ErrRecordNotFound {
+		return nil // reported by the handler
+	}
+	if err != nil {
+		return err
+	}
+	if kube.PromotionApproval {
+		return &errorPromotionApprovalRequired{kubeName}
+	}
+	return nil
+}
+
+// authorizeHelmReleaseChange lets only admins change the HelmReleases of a Kube
+// which requires promotions to it to be approved, other than by promoting them.
+func authorizeHelmReleaseChange(c *core.Core, user *model.User, id *int64, item interface{}) error {
+	if id == nil {
+		release, ok := item.(*model.HelmRelease)
+		if !ok {
+			return nil // reported by the handler
+		}
+		return ensurePromotionApproval(c, user, release.KubeName)
+	}
+	release := new(model.HelmRelease)
+	err := c.DB.First(release, *id)
+	if err == gorm.ErrRecordNotFound {
+		return nil // reported by the handler
+	}
+	if err != nil {
+		return err
+	}
+	return ensurePromotionApproval(c, user, release.KubeName)
+}
+
+// authorizeKubeChange lets only admins set promotion_approval of a Kube.
+func authorizeKubeChange(c *core.Core, user *model.User, id *int64, item interface{}) error {
+	// false leaves it as it is, since unset fields of updates aren't changed
+	kube, ok := item.(*model.Kube)
+	if !ok || !kube.PromotionApproval || user.Role == model.UserRoleAdmin {
+		return nil
+	}
+	if id != nil {
+		existing := new(model.Kube)
+		err := c.DB.First(existing, *id)
+		if err == gorm.ErrRecordNotFound {
+			return nil // reported by the handler
+		}
+		if err != nil {
+			return err
+		}
+		if existing.PromotionApproval {
+			return nil
+		}
+	}
+	return &errorForbidden{user}
+}
+
+func ListHelmReleasePromotions(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	return handleList(core, r, new(model.HelmReleasePromotion), new(model.HelmReleasePromotionList))
+}
+
+func GetHelmReleasePromotion(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmReleasePromotion)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmReleasePromotions.Get(id, item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusOK)
+}
+
+func ApproveHelmReleasePromotion(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmReleasePromotion)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmReleasePromotions.Approve(id, item, user.Username); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
+
+func RejectHelmReleasePromotion(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmReleasePromotion)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmReleasePromotions.Reject(id, item, user.Username); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusOK)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/supergiant/supergiant/pkg/core"
@@ -8,7 +9,11 @@ import (
 )
 
 func ListHelmReleases(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
-	return handleList(core, r, new(model.HelmRelease), new(model.HelmReleaseList))
+	scope, err := helmReleasesScope(core, r)
+	if err != nil {
+		return nil, err
+	}
+	return handleScopedList(core, r, scope, new(model.HelmRelease), new(model.HelmReleaseList))
 }
 
 func CreateHelmRelease(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
@@ -60,3 +65,84 @@ func DeleteHelmRelease(core *core.Core, user *model.User, r *http.Request) (*Res
 	}
 	return itemResponse(core, item, http.StatusAccepted)
 }
+
+func GetHelmReleaseHistory(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmRelease)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmReleases.GetWithIncludes(id, item, []string{"Kube"}); err != nil {
+		return nil, err
+	}
+	revisions, err := core.HelmReleases.History(item)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{http.StatusOK, &model.HelmReleaseHistory{Items: revisions}}, nil
+}
+
+func RollbackHelmRelease(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmRelease)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	rollback := new(model.HelmReleaseRollback)
+	if err := json.NewDecoder(r.Body).Decode(rollback); err != nil {
+		return nil, &bodyDecodingError{err}
+	}
+	if err := core.HelmReleases.GetWithIncludes(id, item, []string{"Kube"}); err != nil {
+		return nil, err
+	}
+	if err := core.HelmReleases.Rollback(id, item, rollback); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
+
+func PromoteHelmRelease(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	item := new(model.HelmReleasePromotion)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	item.HelmReleaseID = id
+	item.RequestedBy = user.Username
+	if err := core.HelmReleasePromotions.Create(item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
+
+func GetHelmReleaseDrift(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.HelmRelease)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.HelmReleases.Get(id, item); err != nil {
+		return nil, err
+	}
+	items, err := core.HelmReleases.Drift(item)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{http.StatusOK, &model.HelmReleaseDrift{Items: items}}, nil
+}
+
+// helmReleasesScope filters HelmReleases by ?outdated=true or false.
+func helmReleasesScope(c *core.Core, r *http.Request) (core.DBInterface, error) {
+	scope := c.DB
+	if outdated := r.URL.Query().Get("outdated"); outdated != "" {
+		value, err := core.ParseBoolParam("outdated", outdated)
+		if err != nil {
+			return nil, err
+		}
+		scope = scope.Where("outdated = ?", value)
+	}
+	return scope, nil
+}
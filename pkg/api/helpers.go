@@ -1,15 +1,21 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
@@ -22,6 +28,30 @@ type Response struct {
 	Object interface{}
 }
 
+// rawBody is a Response Object which is written as-is instead of as JSON,
+// with the headers given (if any), such as those of a replayed response.
+type rawBody struct {
+	contentType string
+	body        []byte
+	header      http.Header
+}
+
+// streamBody is a Response Object which is copied to the response as it's
+// read, such as a followed log, and closed once it ends.
+type streamBody struct {
+	contentType string
+	body        io.ReadCloser
+}
+
+func csvResponse(records [][]string) (*Response, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return &Response{http.StatusOK, &rawBody{"text/csv; charset=utf-8", buf.Bytes(), nil}}, nil
+}
+
 //------------------------------------------------------------------------------
 
 type bodyDecodingError struct { // status bad request
@@ -32,9 +62,19 @@ func (e *bodyDecodingError) Error() string {
 	return "Error decoding JSON body: " + e.err.Error()
 }
 
+type queryParamError struct { // status bad request
+	name string
+	err  error
+}
+
+func (e *queryParamError) Error() string {
+	return "Invalid " + e.name + " parameter: " + e.err.Error()
+}
+
 var (
 	errorUnauthorized  = errors.New("Unauthorized")
 	errorBadAuthHeader = errors.New("Improperly formatted Authorization header")
+	errorBadIfMatch    = errors.New(`Improperly formatted If-Match header, it must be the ETag of the item, such as "3"`)
 )
 
 //------------------------------------------------------------------------------
@@ -43,18 +83,42 @@ func errorHTTPStatus(err error) int {
 	if _, ok := err.(*bodyDecodingError); ok {
 		return 400
 	}
+	if _, ok := err.(*listQueryError); ok {
+		return 400
+	}
+	if _, ok := err.(*bulkError); ok {
+		return 400
+	}
+	if _, ok := err.(*queryParamError); ok {
+		return 400
+	}
 	if err == core.ErrorBadLogin {
 		return 400
 	}
+	if err == errorBadIfMatch || err == errorBadIdempotencyKey {
+		return 400
+	}
 	if err == errorUnauthorized || err == errorBadAuthHeader {
 		return 401
 	}
 	if _, ok := err.(*errorForbidden); ok {
 		return 403
 	}
+	if _, ok := err.(*errorPromotionApprovalRequired); ok {
+		return 403
+	}
 	if err == gorm.ErrRecordNotFound {
 		return 404
 	}
+	if _, ok := err.(*core.ErrorConflict); ok {
+		return 409
+	}
+	if err == core.ErrorIdempotencyKeyInProgress {
+		return 409
+	}
+	if err == core.ErrorResourceVersionRequired {
+		return 428
+	}
 	// TODO we can probably consolidate all same error codes (would need to be in
 	// model if that's where we keep the immutability check on fields).
 	if _, ok := err.(*core.ErrorMissingRequiredParent); ok {
@@ -66,13 +130,22 @@ func errorHTTPStatus(err error) int {
 	if _, ok := err.(*model.ErrorChangedImmutableField); ok {
 		return 422
 	}
+	if _, ok := err.(*core.ErrorBudgetExceeded); ok {
+		return 422
+	}
+	if _, ok := err.(*core.ErrorIdempotencyKeyReused); ok {
+		return 422
+	}
+	if _, ok := err.(*core.ErrorKubeResourceNotPod); ok {
+		return 422
+	}
 	return 500
 }
 
 const logViewBytesize int64 = 4096
 
 func logHandler(core *core.Core) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return instrumented(core, func(w http.ResponseWriter, r *http.Request) {
 		if user := loadUser(core, w, r); user == nil {
 			return
 		}
@@ -113,7 +186,7 @@ func logHandler(core *core.Core) func(http.ResponseWriter, *http.Request) {
 			panic(err)
 		}
 		w.Write(buf)
-	}
+	})
 }
 
 func loadUser(core *core.Core, w http.ResponseWriter, r *http.Request) *model.User {
@@ -160,6 +233,27 @@ func respond(w http.ResponseWriter, resp *Response, err error) {
 			},
 		}
 	}
+	// The ETag of an item is its resource version, for updates to give as
+	// If-Match
+	if versioned, ok := resp.Object.(model.Versioned); ok && versioned.GetResourceVersion() > 0 {
+		w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(versioned.GetResourceVersion(), 10)))
+	}
+	if raw, ok := resp.Object.(*rawBody); ok {
+		for name, values := range raw.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", raw.contentType)
+		w.WriteHeader(resp.Status)
+		w.Write(raw.body)
+		return
+	}
+	if stream, ok := resp.Object.(*streamBody); ok {
+		defer stream.body.Close()
+		w.Header().Set("Content-Type", stream.contentType)
+		w.WriteHeader(resp.Status)
+		writeStream(w, stream.body)
+		return
+	}
 	body, marshalErr := json.MarshalIndent(resp.Object, "", "  ")
 	if marshalErr != nil {
 		panic(marshalErr)
@@ -168,21 +262,89 @@ func respond(w http.ResponseWriter, resp *Response, err error) {
 	w.Write(append(body, []byte{10}...)) // add line break (without string conversion)
 }
 
+// writeStream copies r to w, flushing what's read as it's written.
+func writeStream(w http.ResponseWriter, r io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
 func openHandler(c *core.Core, fn func(*core.Core, *http.Request) (*Response, error)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return instrumented(c, func(w http.ResponseWriter, r *http.Request) {
 		resp, err := fn(c, r)
 		respond(w, resp, err)
-	}
+	})
 }
 
 func restrictedHandler(core *core.Core, fn func(*core.Core, *model.User, *http.Request) (*Response, error)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return instrumented(core, func(w http.ResponseWriter, r *http.Request) {
 		user := loadUser(core, w, r)
 		if user == nil {
 			return
 		}
 		resp, err := fn(core, user, r)
 		respond(w, resp, err)
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streamed responses be flushed through the recorder.
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSockets be upgraded through the recorder.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Response can't be hijacked")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// instrumented wraps a handler to record its latency by route and status.
+func instrumented(c *core.Core, fn func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{w, http.StatusOK}
+
+		fn(rec, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		c.Metrics.ObserveAPIRequest(route, r.Method, rec.status, time.Since(started))
 	}
 }
 
@@ -202,6 +364,25 @@ func decodeBodyInto(r *http.Request, item model.Model) error {
 		return &bodyDecodingError{err}
 	}
 	model.ZeroReadonlyFields(item)
+	if r.Method == "PATCH" || r.Method == "PUT" {
+		return setIfMatchVersion(r, item)
+	}
+	return nil
+}
+
+// setIfMatchVersion sets the resource version an update is made from to that
+// of If-Match, which takes precedence over the resource_version of the body.
+func setIfMatchVersion(r *http.Request, item model.Model) error {
+	match := r.Header.Get("If-Match")
+	versioned, ok := item.(model.Versioned)
+	if match == "" || !ok {
+		return nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(match, "W/"), `"`), 10, 64)
+	if err != nil || version < 1 {
+		return errorBadIfMatch
+	}
+	versioned.SetResourceVersion(version)
 	return nil
 }
 
@@ -213,7 +394,13 @@ func itemResponse(core *core.Core, item model.Model, status int) (*Response, err
 
 const defaultListLimit = 25
 
-func handleList(core *core.Core, r *http.Request, m model.Model, listPtr interface{}) (resp *Response, err error) {
+func handleList(core *core.Core, r *http.Request, m model.Model, listPtr interface{}) (*Response, error) {
+	return handleScopedList(core, r, core.DB, m, listPtr)
+}
+
+// handleScopedList lists the items in scope, for filters which handleList
+// can't express.
+func handleScopedList(core *core.Core, r *http.Request, baseScope core.DBInterface, m model.Model, listPtr interface{}) (resp *Response, err error) {
 	listValue := reflect.ValueOf(listPtr).Elem()
 
 	slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(m)), 0, 0)
@@ -222,60 +409,93 @@ func handleList(core *core.Core, r *http.Request, m model.Model, listPtr interfa
 
 	qstr := r.URL.Query()
 
-	var andQueries []string
-	for _, field := range model.RootFieldJSONNames(m) {
-
-		// ?filter.name=this&filter.name=that
-		filterValues := qstr["filter."+field]
-
-		var orQueries []string
-		for _, val := range filterValues {
-			orQueries = append(orQueries, fmt.Sprintf("%s = '%s'", field, val))
-		}
-
-		if len(orQueries) > 0 {
-			andQueries = append(andQueries, "("+strings.Join(orQueries, " OR ")+")")
-		}
-	}
-	andQuery := strings.Join(andQueries, " AND ")
-
-	baseScope := core.DB
-	if andQuery != "" {
-		baseScope = baseScope.Where(andQuery)
+	query, err := parseListQuery(m, qstr)
+	if err != nil {
+		return nil, err
 	}
+	baseScope = query.scope(baseScope)
 
 	// BaseList
 	pagination := model.BaseList{}
 
-	if err := baseScope.Model(m).Count(&pagination.Total); err != nil {
-		return nil, err
-	}
 	offsetParam := qstr.Get("offset")
 	limitParam := qstr.Get("limit")
 
 	pagination.Limit = defaultListLimit
 	if limitParam != "" {
-		if pagination.Limit, err = strconv.ParseInt(limitParam, 10, 64); err != nil {
-			return nil, err
+		if pagination.Limit, err = strconv.ParseInt(limitParam, 10, 64); err != nil || pagination.Limit < 0 {
+			return nil, &listQueryError{fmt.Errorf("limit must be a number of items, got '%s'", limitParam)}
 		}
 	}
 
 	if offsetParam != "" {
-		if pagination.Offset, err = strconv.ParseInt(offsetParam, 10, 64); err != nil {
-			return nil, err
+		if pagination.Offset, err = strconv.ParseInt(offsetParam, 10, 64); err != nil || pagination.Offset < 0 {
+			return nil, &listQueryError{fmt.Errorf("offset must be a number of items, got '%s'", offsetParam)}
 		}
 	}
 
-	// TODO we may want to actually allow 0 limits here, and instead use pointers
-	// to int64, because limit 0 will still return total count.
-	scope := baseScope
-	if pagination.Limit != 0 {
-		scope = scope.Limit(pagination.Limit)
+	modelType := reflect.TypeOf(m).Elem()
+
+	if len(query.jsonConditions) > 0 {
+		// Conditions on fields stored as JSON are matched on every item in scope,
+		// and the matches paginated.
+		if err := query.selectColumns(query.ordered(baseScope), modelType).Find(items.Addr().Interface()); err != nil {
+			return nil, err
+		}
+		matched := query.matchJSON(items)
+		pagination.Total = int64(matched.Len())
+
+		if query.paged {
+			if query.after != "" {
+				if err := query.selectColumns(query.ordered(query.afterCursor(baseScope)), modelType).Find(items.Addr().Interface()); err != nil {
+					return nil, err
+				}
+				matched = query.matchJSON(items)
+			}
+			items.Set(matched)
+
+		} else {
+			start := pagination.Offset
+			if start > pagination.Total {
+				start = pagination.Total
+			}
+			end := pagination.Total
+			if pagination.Limit != 0 && pagination.Limit < end-start {
+				end = start + pagination.Limit
+			}
+			items.Set(matched.Slice(int(start), int(end)))
+		}
+
+	} else {
+		if err := baseScope.Model(m).Count(&pagination.Total); err != nil {
+			return nil, err
+		}
+
+		// TODO we may want to actually allow 0 limits here, and instead use pointers
+		// to int64, because limit 0 will still return total count.
+		scope := query.selectColumns(query.ordered(query.afterCursor(baseScope)), modelType)
+		switch {
+		case query.paged && pagination.Limit != 0:
+			// One more than the page, to know if there's a next
+			scope = scope.Limit(pagination.Limit + 1)
+		case query.paged:
+		case pagination.Limit != 0:
+			scope = scope.Limit(pagination.Limit).Offset(pagination.Offset)
+		default:
+			scope = scope.Offset(pagination.Offset)
+		}
+
+		if err := scope.Find(items.Addr().Interface()); err != nil {
+			return nil, err
+		}
 	}
-	scope = scope.Offset(pagination.Offset)
 
-	if err := scope.Find(items.Addr().Interface()); err != nil {
-		return nil, err
+	if query.paged {
+		pagination.Offset = 0
+		if pagination.Limit != 0 && int64(items.Len()) > pagination.Limit {
+			items.Set(items.Slice(0, int(pagination.Limit)))
+			pagination.NextCursor = query.nextCursor(items.Index(items.Len() - 1))
+		}
 	}
 
 	for i := 0; i < items.Len(); i++ {
@@ -287,6 +507,14 @@ func handleList(core *core.Core, r *http.Request, m model.Model, listPtr interfa
 	// Yeah... kinda nasty
 	listValue.FieldByName("BaseList").Set(reflect.ValueOf(pagination))
 
+	if query.projection != nil {
+		projected, err := query.project(listPtr)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{http.StatusOK, projected}, nil
+	}
+
 	return &Response{
 		http.StatusOK,
 		listPtr,
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const maxIdempotencyKeyLength = 255
+
+var errorBadIdempotencyKey = errors.New("Improperly formatted Idempotency-Key header, it must be at most 255 characters")
+
+// apiVersionPrefixRxp matches the version of the API in a path, so a key
+// is of the same request under any version.
+var apiVersionPrefixRxp = regexp.MustCompile(`^/api/v\d+`)
+
+// idempotent makes a handler of creates and actions replay its response to
+// requests made again with the same Idempotency-Key header (such as retries
+// after a timeout), instead of creating or acting again. Server errors aren't
+// stored, so those requests can be retried.
+func idempotent(fn routeHandler) routeHandler {
+	return func(c *core.Core, user *model.User, r *http.Request) (*Response, error) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			return fn(c, user, r)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return nil, errorBadIdempotencyKey
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+
+		claim := &model.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			Method:      r.Method,
+			Path:        apiVersionPrefixRxp.ReplaceAllString(r.URL.Path, ""),
+			RequestHash: hex.EncodeToString(hash[:]),
+		}
+		stored, err := c.IdempotencyKeys.Claim(claim)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			header := make(http.Header)
+			if stored.ResponseETag != "" {
+				header.Set("ETag", stored.ResponseETag)
+			}
+			return &Response{stored.ResponseStatus, &rawBody{"application/json", []byte(stored.ResponseBody), header}}, nil
+		}
+
+		// The key is released if the handler panics, or requests made again
+		// would be in progress until it expires.
+		responded := false
+		defer func() {
+			if responded {
+				return
+			}
+			if err := c.IdempotencyKeys.Release(claim); err != nil {
+				c.Log.Errorf("Error releasing Idempotency-Key %s: %s", key, err)
+			}
+		}()
+
+		w := httptest.NewRecorder()
+		resp, err := fn(c, user, r)
+		respond(w, resp, err)
+		responded = true
+
+		if w.Code >= 500 {
+			err = c.IdempotencyKeys.Release(claim)
+		} else {
+			err = c.IdempotencyKeys.Complete(claim, w.Code, w.Header().Get("ETag"), w.Body.Bytes())
+		}
+		if err != nil {
+			c.Log.Errorf("Error storing Idempotency-Key %s: %s", key, err)
+		}
+		return &Response{w.Code, &rawBody{"application/json", w.Body.Bytes(), w.Header()}}, nil
+	}
+}
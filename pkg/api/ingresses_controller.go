@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+func ListIngresses(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	return handleList(core, r, new(model.Ingress), new(model.IngressList))
+}
+
+func CreateIngress(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.Ingress)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	if err := core.Ingresses.Create(item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusCreated)
+}
+
+func UpdateIngress(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	item := new(model.Ingress)
+	if err := decodeBodyInto(r, item); err != nil {
+		return nil, err
+	}
+	if err := core.Ingresses.Update(id, new(model.Ingress), item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
+
+func GetIngress(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.Ingress)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Ingresses.Get(id, item); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusOK)
+}
+
+func DeleteIngress(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.Ingress)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Ingresses.Delete(id, item).Async(); err != nil {
+		return nil, err
+	}
+	return itemResponse(core, item, http.StatusAccepted)
+}
@@ -1,8 +1,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
 )
@@ -84,3 +90,144 @@ func DeleteKubeResource(core *core.Core, user *model.User, r *http.Request) (*Re
 	}
 	return itemResponse(core, item, http.StatusAccepted)
 }
+
+func GetKubeResourceLogs(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := parsePodLogOptions(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	body, err := core.KubeResources.Logs(id, new(model.KubeResource), opts)
+	if err != nil {
+		return nil, err
+	}
+	// A followed log is only closed by Kubernetes once the container stops, so
+	// it's closed once the client is gone.
+	go func() {
+		<-r.Context().Done()
+		body.Close()
+	}()
+	return &Response{http.StatusOK, &streamBody{"text/plain; charset=utf-8", body}}, nil
+}
+
+func parsePodLogOptions(query url.Values) (opts *model.PodLogOptions, err error) {
+	opts = &model.PodLogOptions{Container: query.Get("container")}
+	if follow := query.Get("follow"); follow != "" {
+		if opts.Follow, err = strconv.ParseBool(follow); err != nil {
+			return nil, &queryParamError{"follow", fmt.Errorf("must be true or false, got '%s'", follow)}
+		}
+	}
+	if tail := query.Get("tail"); tail != "" {
+		lines, err := strconv.ParseInt(tail, 10, 64)
+		if err != nil || lines < 0 {
+			return nil, &queryParamError{"tail", fmt.Errorf("must be a number of lines, got '%s'", tail)}
+		}
+		opts.TailLines = &lines
+	}
+	if since := query.Get("since"); since != "" {
+		seconds, err := parseSinceSeconds(since)
+		if err != nil {
+			return nil, &queryParamError{"since", fmt.Errorf("must be a duration such as 10m, or a number of seconds, got '%s'", since)}
+		}
+		opts.SinceSeconds = &seconds
+	}
+	return opts, nil
+}
+
+// parseSinceSeconds parses a duration such as 10m, or a number of seconds, into
+// seconds. Kubernetes rejects 0, so durations are rounded up.
+func parseSinceSeconds(since string) (int64, error) {
+	seconds, err := strconv.ParseInt(since, 10, 64)
+	if err != nil {
+		duration, durationErr := time.ParseDuration(since)
+		if durationErr != nil {
+			return 0, durationErr
+		}
+		seconds = int64((duration + time.Second - 1) / time.Second)
+	}
+	if seconds < 1 {
+		return 0, errors.New("not positive")
+	}
+	return seconds, nil
+}
+
+//------------------------------------------------------------------------------
+
+// execUpgrader upgrades the requests of exec sessions. Its subprotocol is
+// that of the session with Kubernetes, so it's set for each.
+var execUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// execHandler runs a command in a container of a Pod KubeResource, proxying
+// the WebSocket of the client to one with Kubernetes. Both speak the
+// channel.k8s.io protocol of kubectl exec. It isn't in the routes, since it
+// doesn't respond as they do once the session is open.
+func execHandler(core *core.Core) func(http.ResponseWriter, *http.Request) {
+	return instrumented(core, func(w http.ResponseWriter, r *http.Request) {
+		if user := loadUser(core, w, r); user == nil {
+			return
+		}
+		id, err := parseID(r)
+		if err != nil {
+			respond(w, nil, err)
+			return
+		}
+		query := r.URL.Query()
+		opts := &model.PodExecOptions{Container: query.Get("container"), Command: query["command"]}
+		if len(opts.Command) == 0 {
+			respond(w, nil, &queryParamError{"command", errors.New("required")})
+			return
+		}
+		opts.Stdin, _ = strconv.ParseBool(query.Get("stdin"))
+		opts.TTY, _ = strconv.ParseBool(query.Get("tty"))
+
+		// The session with Kubernetes is opened first, for its errors to be
+		// responded as those of any request.
+		kubeConn, err := core.KubeResources.Exec(id, new(model.KubeResource), opts)
+		if err != nil {
+			respond(w, nil, err)
+			return
+		}
+		defer kubeConn.Close()
+
+		upgrader := execUpgrader
+		if protocol := kubeConn.Subprotocol(); protocol != "" {
+			upgrader.Subprotocols = []string{protocol}
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // the upgrader responded
+		}
+		defer conn.Close()
+
+		done := make(chan struct{}, 2)
+		go proxyWebSocket(kubeConn, conn, done)
+		go proxyWebSocket(conn, kubeConn, done)
+		<-done
+	})
+}
+
+// proxyWebSocket copies the messages of src to dst until src is closed, and
+// then closes dst with the same close code.
+func proxyWebSocket(dst *websocket.Conn, src *websocket.Conn, done chan<- struct{}) {
+	defer func() { done <- struct{}{} }()
+	for {
+		messageType, message, err := src.ReadMessage()
+		if err != nil {
+			code, text := websocket.CloseNormalClosure, ""
+			if closeErr, ok := err.(*websocket.CloseError); ok && closeErr.Code != websocket.CloseNoStatusReceived {
+				code, text = closeErr.Code, closeErr.Text
+			}
+			dst.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+			return
+		}
+		if err := dst.WriteMessage(messageType, message); err != nil {
+			return
+		}
+	}
+}
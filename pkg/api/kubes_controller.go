@@ -2,6 +2,8 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
@@ -72,3 +74,69 @@ func ProvisionKube(core *core.Core, user *model.User, r *http.Request) (*Respons
 	}
 	return itemResponse(core, item, http.StatusAccepted)
 }
+
+func GetKubeCosts(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	item := new(model.Kube)
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+	month, err := parseCostMonth(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Kubes.GetWithIncludes(id, item, []string{"CloudAccount"}); err != nil {
+		return nil, err
+	}
+	costs, err := core.Kubes.Costs(item, month)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		records := [][]string{{"kind", "name", "size", "hours", "cost"}}
+		for _, node := range costs.Nodes {
+			kind := "node"
+			if node.Master {
+				kind = "master"
+			}
+			records = append(records, []string{kind, node.Name, node.Size, formatFloat(node.Hours), formatFloat(node.Cost)})
+		}
+		for _, item := range costs.Namespaces {
+			records = append(records, []string{model.CostAllocationKindNamespace, item.Name, "", "", formatFloat(item.Cost)})
+		}
+		for _, item := range costs.HelmReleases {
+			records = append(records, []string{model.CostAllocationKindHelmRelease, item.Name, "", "", formatFloat(item.Cost)})
+		}
+		return csvResponse(records)
+	}
+	return &Response{http.StatusOK, costs}, nil
+}
+
+func GetChargeback(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
+	month, err := parseCostMonth(r)
+	if err != nil {
+		return nil, err
+	}
+	chargeback, err := core.Kubes.Chargeback(month)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		records := [][]string{{"month", "kube_name", "namespace", "cost"}}
+		for _, item := range chargeback.Items {
+			records = append(records, []string{chargeback.Month, item.KubeName, item.Namespace, formatFloat(item.Cost)})
+		}
+		return csvResponse(records)
+	}
+	return &Response{http.StatusOK, chargeback}, nil
+}
+
+func parseCostMonth(r *http.Request) (time.Time, error) {
+	return core.ParseCostMonth(r.URL.Query().Get("month"))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+var errorInvalidCursor = errors.New("invalid cursor")
+
+// listCursor is the position of the last item of a page, by the values of
+// the fields sorted on and its ID. The next page is of the items after it,
+// so items created or deleted meanwhile don't shift pages as they do with
+// offsets. It's encoded as opaque base64.
+type listCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     int64    `json:"id"`
+}
+
+// sortParam returns the sort of the query as a sort parameter.
+func (q *listQuery) sortParam() string {
+	var names []string
+	for _, s := range q.sorts {
+		if s.desc {
+			names = append(names, "-"+s.name)
+		} else {
+			names = append(names, s.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// parseCursor pages the query by cursor, from after the cursor given or the
+// start when it's empty.
+func (q *listQuery) parseCursor(param string) error {
+	// NULLs can't be compared to a cursor, and aren't sorted the same way by
+	// every database
+	for _, s := range q.sorts {
+		if s.field.nullable {
+			return &listQueryError{fmt.Errorf("can't page by cursor when sorted on '%s', which may be empty; page by offset instead", s.name)}
+		}
+	}
+
+	q.paged = true
+	if param == "" {
+		return nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(param)
+	if err != nil {
+		return &listQueryError{errorInvalidCursor}
+	}
+	cursor := new(listCursor)
+	if err := json.Unmarshal(raw, cursor); err != nil {
+		return &listQueryError{errorInvalidCursor}
+	}
+	if cursor.Sort != q.sortParam() || len(cursor.Values) != len(q.sorts) {
+		return &listQueryError{fmt.Errorf("the cursor is of a list sorted by '%s', not '%s'", cursor.Sort, q.sortParam())}
+	}
+
+	// (a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?)
+	var (
+		columns   []string
+		operators []string
+		values    []interface{}
+	)
+	for i, s := range q.sorts {
+		value, err := listArg(s.field.typ, cursor.Values[i])
+		if err != nil {
+			return &listQueryError{errorInvalidCursor}
+		}
+		columns = append(columns, s.field.column)
+		if s.desc {
+			operators = append(operators, "<")
+		} else {
+			operators = append(operators, ">")
+		}
+		values = append(values, value)
+	}
+	columns = append(columns, "id")
+	operators = append(operators, ">")
+	values = append(values, cursor.ID)
+
+	var ors []string
+	for i := range columns {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j]+" = ?")
+			q.afterArgs = append(q.afterArgs, values[j])
+		}
+		ands = append(ands, columns[i]+" "+operators[i]+" ?")
+		q.afterArgs = append(q.afterArgs, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	q.after = strings.Join(ors, " OR ")
+	return nil
+}
+
+// afterCursor applies the condition of the items after the cursor given.
+func (q *listQuery) afterCursor(db core.DBInterface) core.DBInterface {
+	if q.after != "" {
+		db = db.Where(q.after, q.afterArgs...)
+	}
+	return db
+}
+
+// nextCursor returns the cursor of the page after the item.
+func (q *listQuery) nextCursor(item reflect.Value) string {
+	cursor := &listCursor{
+		Sort: q.sortParam(),
+		ID:   *item.Interface().(model.Model).GetID().(*int64),
+	}
+	for _, s := range q.sorts {
+		cursor.Values = append(cursor.Values, cursorValue(item.Elem().FieldByIndex(s.field.index)))
+	}
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func cursorValue(value reflect.Value) string {
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem() // never nil, since nullable fields can't be paged on
+	}
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+//------------------------------------------------------------------------------
+
+// parseProjection parses the fields to render, given as either
+// ?fields=name,kube_name (the ID is always rendered) or ?exclude=extra_data.
+// Fields stored as JSON which aren't rendered aren't loaded either.
+func (q *listQuery) parseProjection(t reflect.Type, qstr url.Values) error {
+	fields := listParamNames(qstr["fields"])
+	exclude := listParamNames(qstr["exclude"])
+	if len(fields) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	gatherJSONNamesInto(t, names)
+
+	q.projection = make(map[string]bool)
+	if len(fields) > 0 {
+		q.projection["id"] = true
+		for _, name := range fields {
+			if !names[name] {
+				return &listQueryError{fmt.Errorf("no field '%s'", name)}
+			}
+			q.projection[name] = true
+		}
+	} else {
+		for name := range names {
+			q.projection[name] = true
+		}
+	}
+	for _, name := range exclude {
+		if !names[name] {
+			return &listQueryError{fmt.Errorf("no field '%s'", name)}
+		}
+		delete(q.projection, name)
+	}
+
+	filtered := make(map[string]bool)
+	for _, condition := range q.jsonConditions {
+		filtered[condition.condition.path[0]] = true
+	}
+	needed := make(map[string]bool)
+	for name, field := range listFieldsOf(t) {
+		if field.storedIn != "" {
+			needed[field.storedIn] = needed[field.storedIn] || q.projection[name] || filtered[name]
+		}
+	}
+	for column, isNeeded := range needed {
+		if !isNeeded {
+			q.omitColumns = append(q.omitColumns, column)
+		}
+	}
+	sort.Strings(q.omitColumns)
+	return nil
+}
+
+func listParamNames(params []string) (names []string) {
+	for _, param := range params {
+		for _, name := range strings.Split(param, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func gatherJSONNamesInto(t reflect.Type, names map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			gatherJSONNamesInto(field.Type, names)
+			continue
+		}
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+}
+
+// selectColumns leaves the columns which aren't needed out of the query.
+func (q *listQuery) selectColumns(db core.DBInterface, t reflect.Type) core.DBInterface {
+	if len(q.omitColumns) == 0 {
+		return db
+	}
+	omit := make(map[string]bool)
+	for _, column := range q.omitColumns {
+		omit[column] = true
+	}
+	var columns []string
+	for _, column := range columnsOf(t) {
+		if !omit[column] {
+			columns = append(columns, column)
+		}
+	}
+	return db.Select(strings.Join(columns, ", "))
+}
+
+// columnsOf returns the columns of a model type, as gorm names them.
+func columnsOf(t reflect.Type) (columns []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, columnsOf(field.Type)...)
+			continue
+		}
+		if field.PkgPath != "" || field.Tag.Get("gorm") == "-" {
+			continue
+		}
+		typ := field.Type
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		switch {
+		case typ == timeType, typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		case typ.Kind() == reflect.Struct, typ.Kind() == reflect.Slice, typ.Kind() == reflect.Map, typ.Kind() == reflect.Interface:
+			// associations
+			continue
+		}
+		columns = append(columns, gorm.ToDBName(field.Name))
+	}
+	return columns
+}
+
+// project renders a list with only the fields of the projection.
+func (q *listQuery) project(listPtr interface{}) (interface{}, error) {
+	raw, err := json.Marshal(listPtr)
+	if err != nil {
+		return nil, err
+	}
+	var list map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&list); err != nil {
+		return nil, err
+	}
+	items, _ := list["items"].([]interface{})
+	for _, item := range items {
+		fields := item.(map[string]interface{})
+		for name := range fields {
+			if !q.projection[name] {
+				delete(fields, name)
+			}
+		}
+	}
+	return list, nil
+}
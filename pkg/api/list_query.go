@@ -0,0 +1,576 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/inflection"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+type listQueryError struct { // status bad request
+	err error
+}
+
+func (e *listQueryError) Error() string {
+	return "Invalid list query: " + e.err.Error()
+}
+
+//------------------------------------------------------------------------------
+
+// listQuery is the filtering, sorting, paging and projection asked for by
+// the query parameters of a list request:
+//
+//	?filter.name=this&filter.name=that    (legacy, values are ORed)
+//	?filter=kube.cloud_account_name!=aws  (expressions, all of which must match)
+//	?sort=name,-created_at
+//	?cursor=...                           (see list_cursor.go)
+//	?fields=name,kube_name or ?exclude=extra_data
+//
+// Values are always passed to the database as parameters. Conditions on
+// fields stored as JSON (such as the Config of a HelmRelease) can't be
+// expressed in SQL, so they are matched after the items are loaded.
+type listQuery struct {
+	where          []string
+	args           []interface{}
+	sorts          []*listSort
+	jsonConditions []*jsonCondition
+
+	// paged is true when paging by cursor, and after is the condition of the
+	// items after the cursor given.
+	paged     bool
+	after     string
+	afterArgs []interface{}
+
+	// Only the fields in projection are rendered, when it's not nil, and the
+	// columns in omitColumns aren't loaded.
+	projection  map[string]bool
+	omitColumns []string
+}
+
+type listSort struct {
+	name  string
+	field *listField
+	desc  bool
+}
+
+// listCondition is a parsed filter expression.
+type listCondition struct {
+	path     []string
+	operator string
+	values   []string
+}
+
+// listField is a field of a model which can be filtered or sorted on.
+type listField struct {
+	index []int
+	typ   reflect.Type
+	// column is empty for fields stored as JSON.
+	column string
+	json   bool
+	// parent is the model of a belongs_to association, whose foreign key is
+	// column.
+	parent reflect.Type
+	// storedIn is the column a field stored as JSON is stored in.
+	storedIn string
+	// nullable fields, such as those of *time.Time, may be stored as NULL.
+	nullable bool
+}
+
+var (
+	listConditionRxp = regexp.MustCompile(`^\s*(!?)([\w.-]+)(?:\s*(==|!=|<=|>=|=|<|>)(.*)|\s+(in|notin|like)\s+(.*))?$`)
+	listValuesRxp    = regexp.MustCompile(`^\((.*)\)$`)
+	timeType         = reflect.TypeOf(time.Time{})
+)
+
+func parseListQuery(m model.Model, qstr url.Values) (*listQuery, error) {
+	q := new(listQuery)
+	t := reflect.TypeOf(m).Elem()
+
+	var legacyKeys []string
+	for key := range qstr {
+		if strings.HasPrefix(key, "filter.") {
+			legacyKeys = append(legacyKeys, key)
+		}
+	}
+	sort.Strings(legacyKeys)
+
+	var conditions []*listCondition
+	for _, key := range legacyKeys {
+		if values := qstr[key]; len(values) > 0 {
+			path := strings.Split(strings.TrimPrefix(key, "filter."), ".")
+			conditions = append(conditions, &listCondition{path, "in", values})
+		}
+	}
+	for _, expr := range qstr["filter"] {
+		condition, err := parseListCondition(expr)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	for _, condition := range conditions {
+		if err := q.filter(t, condition); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, param := range qstr["sort"] {
+		for _, name := range strings.Split(param, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimLeft(name, "+-")
+
+			field := listFieldsOf(t)[name]
+			if field == nil || field.column == "" || field.parent != nil {
+				return nil, &listQueryError{fmt.Errorf("can't sort on '%s'", name)}
+			}
+			q.sorts = append(q.sorts, &listSort{name, field, desc})
+		}
+	}
+
+	if _, ok := qstr["cursor"]; ok {
+		if err := q.parseCursor(qstr.Get("cursor")); err != nil {
+			return nil, err
+		}
+	}
+	if err := q.parseProjection(t, qstr); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func parseListCondition(expr string) (*listCondition, error) {
+	match := listConditionRxp.FindStringSubmatch(expr)
+	if match == nil {
+		return nil, &listQueryError{fmt.Errorf("can't parse filter '%s'", expr)}
+	}
+	condition := &listCondition{path: strings.Split(match[2], ".")}
+
+	switch {
+	case match[3] == "" && match[5] == "":
+		condition.operator = "exists"
+		if match[1] == "!" {
+			condition.operator = "!exists"
+		}
+		return condition, nil
+
+	case match[1] == "!":
+		return nil, &listQueryError{fmt.Errorf("can't parse filter '%s'", expr)}
+
+	case match[3] != "":
+		condition.operator = match[3]
+		if condition.operator == "==" {
+			condition.operator = "="
+		}
+		condition.values = []string{unquoteListValue(match[4])}
+
+	default:
+		condition.operator = match[5]
+		if condition.operator == "like" {
+			condition.values = []string{unquoteListValue(match[6])}
+			break
+		}
+		values := listValuesRxp.FindStringSubmatch(strings.TrimSpace(match[6]))
+		if values == nil {
+			return nil, &listQueryError{fmt.Errorf("values of '%s' must be in parentheses, such as (this,that)", expr)}
+		}
+		for _, value := range strings.Split(values[1], ",") {
+			condition.values = append(condition.values, unquoteListValue(value))
+		}
+	}
+	return condition, nil
+}
+
+func unquoteListValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// filter adds the condition on a field of the model of type t.
+func (q *listQuery) filter(t reflect.Type, condition *listCondition) error {
+	field := listFieldsOf(t)[condition.path[0]]
+	if field != nil && field.json {
+		q.jsonConditions = append(q.jsonConditions, &jsonCondition{field.index, condition.path[1:], condition})
+		return nil
+	}
+
+	where, args, err := conditionSQL(t, condition.path, condition)
+	if err != nil {
+		return err
+	}
+	q.where = append(q.where, where)
+	q.args = append(q.args, args...)
+	return nil
+}
+
+// conditionSQL returns the SQL of a condition on the path within a model of
+// type t. Paths through a belongs_to association become subqueries of the
+// parent, as in "kube_name IN (SELECT name FROM kubes WHERE ...)".
+func conditionSQL(t reflect.Type, path []string, condition *listCondition) (string, []interface{}, error) {
+	name := strings.Join(condition.path, ".")
+
+	field := listFieldsOf(t)[path[0]]
+	switch {
+	case field == nil:
+		return "", nil, &listQueryError{fmt.Errorf("can't filter on '%s'", name)}
+
+	case field.json:
+		return "", nil, &listQueryError{fmt.Errorf("can't filter on '%s', fields stored as JSON can only be filtered on the items listed", name)}
+
+	case field.parent != nil:
+		if len(path) == 1 {
+			return "", nil, &listQueryError{fmt.Errorf("can't filter on '%s', filter on one of its fields such as '%s.name'", name, name)}
+		}
+		where, args, err := conditionSQL(field.parent, path[1:], condition)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s IN (SELECT name FROM %s WHERE %s)", field.column, inflection.Plural(gorm.ToDBName(field.parent.Name())), where), args, nil
+
+	case len(path) > 1:
+		return "", nil, &listQueryError{fmt.Errorf("can't filter on '%s', %s has no fields", name, path[0])}
+	}
+
+	var args []interface{}
+	for _, value := range condition.values {
+		arg, err := listArg(field.typ, value)
+		if err != nil {
+			return "", nil, &listQueryError{fmt.Errorf("%s: %s", name, err)}
+		}
+		args = append(args, arg)
+	}
+
+	switch condition.operator {
+	case "exists", "!exists":
+		return "", nil, &listQueryError{fmt.Errorf("can't filter on whether '%s' exists, only fields stored as JSON may not", name)}
+
+	case "in", "notin":
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+		if condition.operator == "notin" {
+			return fmt.Sprintf("%s NOT IN (%s)", field.column, placeholders), args, nil
+		}
+		return fmt.Sprintf("%s IN (%s)", field.column, placeholders), args, nil
+
+	case "like":
+		if field.typ.Kind() != reflect.String {
+			return "", nil, &listQueryError{fmt.Errorf("can't filter '%s' with like, it isn't a string", name)}
+		}
+		return field.column + " LIKE ?", args, nil
+
+	case "!=":
+		return field.column + " <> ?", args, nil
+	}
+	return field.column + " " + condition.operator + " ?", args, nil
+}
+
+// listArg parses a filter value as the type of the field it's compared to.
+func listArg(t reflect.Type, value string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var (
+		arg interface{}
+		err error
+	)
+	switch {
+	case t == timeType:
+		arg, err = time.Parse(time.RFC3339, value)
+	case t.Kind() == reflect.String:
+		arg = value
+	case t.Kind() == reflect.Bool:
+		arg, err = strconv.ParseBool(value)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		arg, err = strconv.ParseInt(value, 10, 64)
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		arg, err = strconv.ParseUint(value, 10, 64)
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		arg, err = strconv.ParseFloat(value, 64)
+	default:
+		return nil, fmt.Errorf("can't be filtered on")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s'", value)
+	}
+	return arg, nil
+}
+
+// listFieldsOf returns the fields of a model type by JSON name, including
+// those of embedded structs such as BaseModel. Private and virtual fields
+// are left out.
+func listFieldsOf(t reflect.Type) map[string]*listField {
+	fields := make(map[string]*listField)
+	gatherListFieldsInto(t, nil, fields)
+	return fields
+}
+
+func gatherListFieldsInto(t reflect.Type, index []int, fields map[string]*listField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			gatherListFieldsInto(field.Type, fieldIndex, fields)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		sgTag := field.Tag.Get("sg")
+		if name == "" || name == "-" || strings.Contains(sgTag, "private") {
+			continue
+		}
+		out := &listField{index: fieldIndex, typ: field.Type}
+		out.nullable = field.Type.Kind() == reflect.Ptr && !strings.Contains(field.Tag.Get("gorm"), "primary_key")
+
+		switch {
+		case strings.Contains(sgTag, "store_as_json_in="):
+			out.json = true
+			out.storedIn = gorm.ToDBName(strings.Split(strings.SplitN(sgTag, "store_as_json_in=", 2)[1], ",")[0])
+
+		case field.Tag.Get("gorm") == "-":
+			continue
+
+		case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct && field.Type.Elem() != timeType:
+			// belongs_to, through the sibling foreign key such as KubeName
+			foreignKey, ok := t.FieldByName(field.Name + "Name")
+			if !ok {
+				continue
+			}
+			out.column = gorm.ToDBName(foreignKey.Name)
+			out.parent = field.Type.Elem()
+
+		case field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.Uint8:
+			// has_many
+			continue
+
+		default:
+			out.column = gorm.ToDBName(field.Name)
+		}
+		fields[name] = out
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// jsonCondition is a condition on a field stored as JSON, such as
+// config.image.tag=4.0 on a HelmRelease. Keys within arrays of objects match
+// on any element, and conditions on arrays match any element, so that
+// node_sizes=m4.large lists Kubes with m4.large among their NodeSizes.
+type jsonCondition struct {
+	index     []int
+	keys      []string
+	condition *listCondition
+}
+
+func (c *jsonCondition) matches(item reflect.Value) bool {
+	raw, err := json.Marshal(item.FieldByIndex(c.index).Interface())
+	if err != nil {
+		return false
+	}
+	var root interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return false
+	}
+
+	found := []interface{}{root}
+	if root == nil {
+		found = nil
+	}
+	for _, key := range c.keys {
+		var next []interface{}
+		for _, value := range found {
+			switch value := value.(type) {
+			case map[string]interface{}:
+				if child, ok := value[key]; ok {
+					next = append(next, child)
+				}
+			case []interface{}:
+				for _, element := range value {
+					if object, ok := element.(map[string]interface{}); ok {
+						if child, ok := object[key]; ok {
+							next = append(next, child)
+						}
+					}
+				}
+			}
+		}
+		found = next
+	}
+
+	switch c.condition.operator {
+	case "exists":
+		return len(found) > 0
+	case "!exists":
+		return len(found) == 0
+	}
+
+	var values []interface{}
+	for _, value := range found {
+		if elements, ok := value.([]interface{}); ok {
+			values = append(values, elements...)
+		} else {
+			values = append(values, value)
+		}
+	}
+
+	switch c.condition.operator {
+	case "!=", "notin":
+		for _, value := range values {
+			for _, expected := range c.condition.values {
+				if jsonValueCompare(value, expected) == 0 {
+					return false
+				}
+			}
+		}
+		return true
+
+	case "like":
+		rxp := likeRegexp(c.condition.values[0])
+		for _, value := range values {
+			if rxp.MatchString(jsonValueString(value)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, value := range values {
+		for _, expected := range c.condition.values {
+			cmp := jsonValueCompare(value, expected)
+			switch c.condition.operator {
+			case "=", "in":
+				if cmp == 0 {
+					return true
+				}
+			case "<":
+				if cmp < 0 {
+					return true
+				}
+			case "<=":
+				if cmp <= 0 {
+					return true
+				}
+			case ">":
+				if cmp > 0 {
+					return true
+				}
+			case ">=":
+				if cmp >= 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func jsonValueString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case nil:
+		return "null"
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
+
+// jsonValueCompare compares numbers as numbers, and anything else as strings.
+func jsonValueCompare(value interface{}, expected string) int {
+	str := jsonValueString(value)
+	number, err := strconv.ParseFloat(str, 64)
+	expectedNumber, expectedErr := strconv.ParseFloat(expected, 64)
+	if err == nil && expectedErr == nil {
+		switch {
+		case number < expectedNumber:
+			return -1
+		case number > expectedNumber:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(str, expected)
+}
+
+// likeRegexp translates a SQL LIKE pattern, where % matches any string and _
+// any character.
+func likeRegexp(pattern string) *regexp.Regexp {
+	expr := "(?s)^"
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			expr += ".*"
+		case '_':
+			expr += "."
+		default:
+			expr += regexp.QuoteMeta(string(r))
+		}
+	}
+	return regexp.MustCompile(expr + "$")
+}
+
+//------------------------------------------------------------------------------
+
+// scope applies the conditions which can be expressed in SQL.
+func (q *listQuery) scope(db core.DBInterface) core.DBInterface {
+	if len(q.where) > 0 {
+		db = db.Where(strings.Join(q.where, " AND "), q.args...)
+	}
+	return db
+}
+
+// ordered applies the sort order. Ties are in the order items were created,
+// so pages don't overlap. Items which aren't sorted or paged by cursor are in
+// no particular order.
+func (q *listQuery) ordered(db core.DBInterface) core.DBInterface {
+	if len(q.sorts) == 0 && !q.paged {
+		return db
+	}
+	var order []string
+	for _, s := range q.sorts {
+		if s.desc {
+			order = append(order, s.field.column+" DESC")
+		} else {
+			order = append(order, s.field.column+" ASC")
+		}
+	}
+	return db.Order(strings.Join(append(order, "id ASC"), ", "))
+}
+
+// matchJSON returns the items of a slice which match the conditions on
+// fields stored as JSON.
+func (q *listQuery) matchJSON(items reflect.Value) reflect.Value {
+	matched := reflect.MakeSlice(items.Type(), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		matches := true
+		for _, condition := range q.jsonConditions {
+			if !condition.matches(item.Elem()) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			matched = reflect.Append(matched, item)
+		}
+	}
+	return matched
+}
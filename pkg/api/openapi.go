@@ -0,0 +1,374 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const openAPISchemaPrefix = "#/components/schemas/"
+
+var (
+	routePathParamRxp = regexp.MustCompile(`\{(\w+)\}`)
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+)
+
+func serveOpenAPIDocument(doc map[string]interface{}) func(*core.Core, *http.Request) (*Response, error) {
+	return func(_ *core.Core, _ *http.Request) (*Response, error) {
+		return &Response{http.StatusOK, doc}, nil
+	}
+}
+
+// openAPIDocument returns the OpenAPI 3 document of the routes of /api/v1.
+// The schemas of models are built from their fields the way
+// util.BuildSchema walks them, with their json, validate and sg tags.
+func openAPIDocument(routes []*route) map[string]interface{} {
+	schemas := make(openAPISchemas)
+	paths := make(map[string]interface{})
+
+	for _, rt := range routes {
+		path, ok := paths[rt.path].(map[string]interface{})
+		if !ok {
+			path = make(map[string]interface{})
+			paths[rt.path] = path
+		}
+		for i, method := range rt.methods {
+			operation := schemas.operation(rt)
+			if i > 0 {
+				// Each method of a route is its own operation
+				operation["operationId"] = operation["operationId"].(string) + strings.Title(strings.ToLower(method))
+			}
+			path[strings.ToLower(method)] = operation
+		}
+	}
+
+	errorSchema := schemas.schemaOf(reflect.TypeOf(model.Error{}))
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   "Supergiant API",
+			"version": "v1",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": "/api/v1"},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"SGAPI": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(errorSchema),
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"SGAPI": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": `SGAPI token="<api_token of a User>" or SGAPI session="<id of a Session>"`,
+				},
+			},
+		},
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// openAPISchemas are the schemas of the components of the document, by name.
+type openAPISchemas map[string]interface{}
+
+func (s openAPISchemas) operation(rt *route) map[string]interface{} {
+	var fn interface{} = rt.handler
+	if rt.open != nil {
+		fn = rt.open
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+
+	// IDs are integers, but for those of models which have another type of ID
+	// (Sessions)
+	idSchema := map[string]interface{}{"type": "integer", "format": "int64"}
+	if rt.response != nil {
+		if t := reflect.TypeOf(rt.response).Elem(); t.Kind() == reflect.Struct {
+			if id, ok := t.FieldByName("ID"); ok {
+				idSchema = s.schemaOf(id.Type)
+			}
+		}
+	}
+
+	var params []interface{}
+	for _, match := range routePathParamRxp.FindAllStringSubmatch(rt.path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   idSchema,
+		})
+	}
+	if rt.list {
+		params = append(params, listParams...)
+	}
+	if _, ok := rt.request.(model.Versioned); ok && rt.methods[0] == "PATCH" {
+		params = append(params, ifMatchParam)
+	}
+	if rt.open == nil && rt.methods[0] == "POST" {
+		params = append(params, idempotencyKeyParam)
+	}
+	for _, param := range rt.params {
+		params = append(params, map[string]interface{}{
+			"name":        param.name,
+			"in":          "query",
+			"description": param.description,
+			"schema":      s.schemaOf(reflect.TypeOf(reflect.Zero(kindTypes[param.kind]).Interface())),
+		})
+	}
+
+	response := map[string]interface{}{"description": http.StatusText(rt.status)}
+	if rt.response != nil {
+		response["content"] = jsonContent(s.schemaOf(reflect.TypeOf(rt.response)))
+	}
+
+	operation := map[string]interface{}{
+		"operationId": name[strings.LastIndex(name, ".")+1:],
+		"summary":     rt.summary,
+		"tags":        []string{strings.Split(strings.TrimPrefix(rt.path, "/"), "/")[0]},
+		"responses": map[string]interface{}{
+			strconv.Itoa(rt.status): response,
+			"default":               map[string]interface{}{"$ref": "#/components/responses/Error"},
+		},
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	if rt.request != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(s.schemaOf(reflect.TypeOf(rt.request))),
+		}
+	}
+	if rt.open != nil {
+		operation["security"] = []interface{}{}
+	}
+	return operation
+}
+
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.String: reflect.TypeOf(""),
+	reflect.Bool:   reflect.TypeOf(false),
+	reflect.Int64:  reflect.TypeOf(int64(0)),
+}
+
+func queryParam(name string, schema map[string]interface{}, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      schema,
+	}
+}
+
+// listParams are those of every list route, see listQuery.
+var listParams = []interface{}{
+	queryParam("offset", map[string]interface{}{"type": "integer", "format": "int64"}, "Number of items to skip."),
+	queryParam("limit", map[string]interface{}{"type": "integer", "format": "int64", "default": defaultListLimit}, "Number of items of the page, or 0 for every item."),
+	queryParam("cursor", map[string]interface{}{"type": "string"}, "Pages by cursor instead of offset: empty for the first page, then the next_cursor of the page before."),
+	queryParam("filter", map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, "Filter expressions, all of which must match, such as name!=test or kube.cloud_account_name in (aws,gce)."),
+	queryParam("sort", map[string]interface{}{"type": "string"}, "Fields to sort by, descending when prefixed with -, such as name,-created_at."),
+	queryParam("fields", map[string]interface{}{"type": "string"}, "The only fields of the items to list, along with their id."),
+	queryParam("exclude", map[string]interface{}{"type": "string"}, "Fields of the items to leave out."),
+}
+
+// ifMatchParam is that of update routes, see setIfMatchVersion.
+var ifMatchParam = map[string]interface{}{
+	"name":        "If-Match",
+	"in":          "header",
+	"description": "The ETag of the item the update was made from, instead of its resource_version. Updates of items which have changed since respond 409.",
+	"schema":      map[string]interface{}{"type": "string"},
+}
+
+// idempotencyKeyParam is that of creates and actions, see idempotent.
+var idempotencyKeyParam = map[string]interface{}{
+	"name":        "Idempotency-Key",
+	"in":          "header",
+	"description": "A unique key of the request, such as a UUID. Requests made again with the same key within 24 hours respond as the first did, instead of creating or acting again.",
+	"schema":      map[string]interface{}{"type": "string", "maxLength": maxIdempotencyKeyLength},
+}
+
+// schemaOf returns the schema of a type. Structs are components referred
+// to by name.
+func (s openAPISchemas) schemaOf(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.schemaOf(t.Elem())
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.schemaOf(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return map[string]interface{}{"type": "object"}
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := s[name]; !ok {
+			// Set first, for structs which refer to themselves
+			s[name] = nil
+			properties := make(map[string]interface{})
+			s.gatherPropertiesInto(t, properties)
+			s[name] = map[string]interface{}{"type": "object", "properties": properties}
+		}
+		return map[string]interface{}{"$ref": openAPISchemaPrefix + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (s openAPISchemas) gatherPropertiesInto(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			s.gatherPropertiesInto(field.Type, properties)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.fieldSchema(field)
+	}
+}
+
+// fieldSchema returns the schema of a field of a model, with its sg tags
+// (readonly, private, immutable and default) and validate tags (nonzero, min,
+// max and regexp).
+func (s openAPISchemas) fieldSchema(field reflect.StructField) map[string]interface{} {
+	schema := s.schemaOf(field.Type)
+	constraints := make(map[string]interface{})
+
+	kind := field.Type.Kind()
+	if kind == reflect.Ptr {
+		kind = field.Type.Elem().Kind()
+	}
+
+	for _, part := range strings.Split(field.Tag.Get("sg"), ",") {
+		switch {
+		case part == "readonly":
+			constraints["readOnly"] = true
+		case part == "private":
+			constraints["writeOnly"] = true
+		case part == "immutable":
+			constraints["x-immutable"] = true
+		case strings.HasPrefix(part, "default="):
+			value := strings.TrimPrefix(part, "default=")
+			if n, err := strconv.Atoi(value); err == nil && kind != reflect.String {
+				constraints["default"] = n
+			} else {
+				constraints["default"] = value
+			}
+		}
+	}
+
+	for _, rule := range validateRules(field.Tag.Get("validate")) {
+		name, value := rule[0], rule[1]
+		switch name {
+		case "nonzero":
+			switch kind {
+			case reflect.String:
+				constraints["minLength"] = 1
+			case reflect.Slice:
+				constraints["minItems"] = 1
+			case reflect.Map:
+				constraints["minProperties"] = 1
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch kind {
+			case reflect.String:
+				constraints[name+"Length"] = int(n)
+			case reflect.Slice:
+				constraints[name+"Items"] = int(n)
+			default:
+				constraints[name+"imum"] = n
+			}
+		case "regexp":
+			constraints["pattern"] = value
+		}
+	}
+
+	if len(constraints) == 0 {
+		return schema
+	}
+	// Siblings of a $ref are ignored
+	if _, ok := schema["$ref"]; ok {
+		schema = map[string]interface{}{"allOf": []interface{}{schema}}
+	}
+	for key, value := range constraints {
+		schema[key] = value
+	}
+	return schema
+}
+
+// validateRules splits a validate tag into its rules and their values. A
+// regexp is always the last rule, since it may have commas.
+func validateRules(tag string) (rules [][2]string) {
+	for tag != "" {
+		rule := tag
+		if i := strings.Index(tag, ","); i >= 0 && !strings.HasPrefix(tag, "regexp=") {
+			rule = tag[:i]
+			tag = tag[i+1:]
+		} else {
+			tag = ""
+		}
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		rules = append(rules, [2]string{parts[0], parts[1]})
+	}
+	return rules
+}
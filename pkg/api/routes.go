@@ -0,0 +1,270 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/inflection"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// route is an endpoint of the API, along with what the OpenAPI document says
+// of it.
+type route struct {
+	methods []string
+	path    string
+	handler routeHandler
+	// authorize checks the user may make the request, before the handler runs.
+	// It's separate, so the operations of a Bulk can be checked before any is
+	// run.
+	authorize routeAuthorizer
+	// open routes don't need a user, such as logging in.
+	open func(*core.Core, *http.Request) (*Response, error)
+
+	summary string
+	// request and response are examples of the bodies, nil if there are none.
+	request  interface{}
+	response interface{}
+	status   int
+	// list routes take the parameters of every list (see listQuery), and params
+	// are other query parameters.
+	list   bool
+	params []*routeParam
+}
+
+type routeParam struct {
+	name        string
+	kind        reflect.Kind
+	description string
+}
+
+func (rt *route) handlerFunc(c *core.Core) func(http.ResponseWriter, *http.Request) {
+	if rt.open != nil {
+		return openHandler(c, rt.open)
+	}
+	// Creates and actions
+	if rt.methods[0] == "POST" {
+		return restrictedHandler(c, idempotent(rt.authorizedHandler()))
+	}
+	return restrictedHandler(c, rt.authorizedHandler())
+}
+
+type routeHandler func(*core.Core, *model.User, *http.Request) (*Response, error)
+
+// routeAuthorizer returns an error if the user may not make a request, given
+// the ID in its path (if any) and its body (if any, and nil if it can't be
+// decoded, which the handler reports).
+type routeAuthorizer func(c *core.Core, user *model.User, id *int64, item interface{}) error
+
+// authorizedBy sets the authorization of the route.
+func (rt *route) authorizedBy(fn routeAuthorizer) *route {
+	rt.authorize = fn
+	return rt
+}
+
+// authorizedHandler is the handler of the route, run once the request is
+// authorized.
+func (rt *route) authorizedHandler() routeHandler {
+	if rt.authorize == nil {
+		return rt.handler
+	}
+	return func(c *core.Core, user *model.User, r *http.Request) (*Response, error) {
+		var id *int64
+		if _, ok := mux.Vars(r)["id"]; ok {
+			var err error
+			if id, err = parseID(r); err != nil {
+				return nil, err
+			}
+		}
+
+		var item interface{}
+		if rt.request != nil {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			item = reflect.New(reflect.TypeOf(rt.request).Elem()).Interface()
+			if json.Unmarshal(body, item) != nil {
+				item = nil
+			}
+		}
+
+		if err := rt.authorize(c, user, id, item); err != nil {
+			return nil, err
+		}
+		return rt.handler(c, user, r)
+	}
+}
+
+// authorizeAdmin lets only admins make the request.
+func authorizeAdmin(c *core.Core, user *model.User, id *int64, item interface{}) error {
+	return ensureAdmin(user)
+}
+
+// authorizeSameUser lets only admins, and the User of the ID, make the request.
+func authorizeSameUser(c *core.Core, user *model.User, id *int64, item interface{}) error {
+	if err := ensureSameUser(id, user); err != nil {
+		return ensureAdmin(user)
+	}
+	return nil
+}
+
+func createRoute(path string, fn routeHandler, item model.Model) *route {
+	return &route{methods: []string{"POST"}, path: path, handler: fn, summary: "Create a " + modelName(item), request: item, response: item, status: http.StatusCreated}
+}
+
+func listRoute(path string, fn routeHandler, list interface{}, params ...*routeParam) *route {
+	return &route{methods: []string{"GET"}, path: path, handler: fn, summary: "List " + inflection.Plural(strings.TrimSuffix(modelName(list), "List")), response: list, status: http.StatusOK, list: true, params: params}
+}
+
+func getRoute(path string, fn routeHandler, item model.Model) *route {
+	return &route{methods: []string{"GET"}, path: path, handler: fn, summary: "Get a " + modelName(item), response: item, status: http.StatusOK}
+}
+
+func updateRoute(path string, fn routeHandler, item model.Model) *route {
+	return &route{methods: []string{"PATCH", "PUT"}, path: path, handler: fn, summary: "Update a " + modelName(item), request: item, response: item, status: http.StatusAccepted}
+}
+
+func deleteRoute(path string, fn routeHandler, item model.Model) *route {
+	return &route{methods: []string{"DELETE"}, path: path, handler: fn, summary: "Delete a " + modelName(item), response: item, status: http.StatusAccepted}
+}
+
+func actionRoute(method string, path string, fn routeHandler, summary string, request interface{}, response interface{}, status int, params ...*routeParam) *route {
+	return &route{methods: []string{method}, path: path, handler: fn, summary: summary, request: request, response: response, status: status, params: params}
+}
+
+// modelName returns the name of the type of a model, or of the items of a
+// list, such as "Kube" or "KubeList".
+func modelName(m interface{}) string {
+	return reflect.TypeOf(m).Elem().Name()
+}
+
+var costMonthParam = &routeParam{"month", reflect.String, "Month of the costs, as YYYY-MM. Defaults to the current month."}
+var costFormatParam = &routeParam{"format", reflect.String, "csv for CSV instead of JSON."}
+
+var (
+	podContainerParam = &routeParam{"container", reflect.String, "Container of the Pod, which may be omitted if it has one."}
+	logFollowParam    = &routeParam{"follow", reflect.Bool, "Stream the log as it's written, until the container stops."}
+	logTailParam      = &routeParam{"tail", reflect.Int64, "Number of lines from the end of the log to start from."}
+	logSinceParam     = &routeParam{"since", reflect.String, "How far back the log starts from, as a duration such as 10m, or seconds."}
+)
+
+// routes are those of every version of the API, in the order they're matched.
+var routes = []*route{
+	// Login request can't be authenticated
+	{methods: []string{"POST"}, path: "/sessions", open: CreateSession, summary: "Log in", request: new(model.Session), response: new(model.Session), status: http.StatusCreated},
+
+	getRoute("/sessions/{id}", GetSession, new(model.Session)),
+	listRoute("/sessions", ListSessions, new(model.SessionList)),
+	deleteRoute("/sessions/{id}", DeleteSession, new(model.Session)),
+
+	createRoute("/users", CreateUser, new(model.User)).authorizedBy(authorizeAdmin),
+	listRoute("/users", ListUsers, new(model.UserList)),
+	getRoute("/users/{id}", GetUser, new(model.User)).authorizedBy(authorizeSameUser),
+	updateRoute("/users/{id}", UpdateUser, new(model.User)).authorizedBy(authorizeSameUser),
+	deleteRoute("/users/{id}", DeleteUser, new(model.User)).authorizedBy(authorizeSameUser),
+	actionRoute("POST", "/users/{id}/regenerate_api_token", RegenerateUserAPIToken, "Regenerate the API token of a User", nil, new(model.User), http.StatusAccepted).authorizedBy(authorizeSameUser),
+
+	createRoute("/cloud_accounts", CreateCloudAccount, new(model.CloudAccount)),
+	listRoute("/cloud_accounts", ListCloudAccounts, new(model.CloudAccountList)),
+	actionRoute("GET", "/cloud_accounts/schema", ReturnCloudAccountsSchema, "Get the schema of the credentials of each provider", nil, map[string]interface{}{}, http.StatusCreated),
+	getRoute("/cloud_accounts/{id}", GetCloudAccount, new(model.CloudAccount)),
+	updateRoute("/cloud_accounts/{id}", UpdateCloudAccount, new(model.CloudAccount)),
+	deleteRoute("/cloud_accounts/{id}", DeleteCloudAccount, new(model.CloudAccount)),
+
+	createRoute("/kubes", CreateKube, new(model.Kube)).authorizedBy(authorizeKubeChange),
+	listRoute("/kubes", ListKubes, new(model.KubeList)),
+	getRoute("/kubes/{id}", GetKube, new(model.Kube)),
+	updateRoute("/kubes/{id}", UpdateKube, new(model.Kube)).authorizedBy(authorizeKubeChange),
+	actionRoute("POST", "/kubes/{id}/provision", ProvisionKube, "Provision a Kube", nil, new(model.Kube), http.StatusAccepted),
+	actionRoute("GET", "/kubes/{id}/costs", GetKubeCosts, "Get the costs of a Kube", nil, new(model.KubeCosts), http.StatusOK, costMonthParam, costFormatParam),
+	deleteRoute("/kubes/{id}", DeleteKube, new(model.Kube)),
+
+	actionRoute("GET", "/costs", GetChargeback, "Get the costs of every Kube by namespace", nil, new(model.Chargeback), http.StatusOK, costMonthParam, costFormatParam),
+
+	createRoute("/kube_resources", CreateKubeResource, new(model.KubeResource)),
+	listRoute("/kube_resources", ListKubeResources, new(model.KubeResourceList)),
+	getRoute("/kube_resources/{id}", GetKubeResource, new(model.KubeResource)),
+	updateRoute("/kube_resources/{id}", UpdateKubeResource, new(model.KubeResource)),
+	actionRoute("POST", "/kube_resources/{id}/start", StartKubeResource, "Start a KubeResource", nil, new(model.KubeResource), http.StatusAccepted),
+	actionRoute("POST", "/kube_resources/{id}/stop", StopKubeResource, "Stop a KubeResource", nil, new(model.KubeResource), http.StatusAccepted),
+	deleteRoute("/kube_resources/{id}", DeleteKubeResource, new(model.KubeResource)),
+	actionRoute("GET", "/kube_resources/{id}/logs", GetKubeResourceLogs, "Get the log of a Pod KubeResource, as plain text", nil, nil, http.StatusOK, podContainerParam, logFollowParam, logTailParam, logSinceParam),
+
+	createRoute("/nodes", CreateNode, new(model.Node)),
+	listRoute("/nodes", ListNodes, new(model.NodeList)),
+	getRoute("/nodes/{id}", GetNode, new(model.Node)),
+	updateRoute("/nodes/{id}", UpdateNode, new(model.Node)),
+	deleteRoute("/nodes/{id}", DeleteNode, new(model.Node)),
+
+	createRoute("/load_balancers", CreateLoadBalancer, new(model.LoadBalancer)),
+	listRoute("/load_balancers", ListLoadBalancers, new(model.LoadBalancerList)),
+	getRoute("/load_balancers/{id}", GetLoadBalancer, new(model.LoadBalancer)),
+	updateRoute("/load_balancers/{id}", UpdateLoadBalancer, new(model.LoadBalancer)),
+	deleteRoute("/load_balancers/{id}", DeleteLoadBalancer, new(model.LoadBalancer)),
+
+	createRoute("/dns_zones", CreateDNSZone, new(model.DNSZone)),
+	listRoute("/dns_zones", ListDNSZones, new(model.DNSZoneList)),
+	getRoute("/dns_zones/{id}", GetDNSZone, new(model.DNSZone)),
+	updateRoute("/dns_zones/{id}", UpdateDNSZone, new(model.DNSZone)),
+	deleteRoute("/dns_zones/{id}", DeleteDNSZone, new(model.DNSZone)),
+
+	createRoute("/ingresses", CreateIngress, new(model.Ingress)),
+	listRoute("/ingresses", ListIngresses, new(model.IngressList)),
+	getRoute("/ingresses/{id}", GetIngress, new(model.Ingress)),
+	updateRoute("/ingresses/{id}", UpdateIngress, new(model.Ingress)),
+	deleteRoute("/ingresses/{id}", DeleteIngress, new(model.Ingress)),
+
+	createRoute("/helm_repos", CreateHelmRepo, new(model.HelmRepo)),
+	listRoute("/helm_repos", ListHelmRepos, new(model.HelmRepoList)),
+	getRoute("/helm_repos/{id}", GetHelmRepo, new(model.HelmRepo)),
+	updateRoute("/helm_repos/{id}", UpdateHelmRepo, new(model.HelmRepo)),
+	deleteRoute("/helm_repos/{id}", DeleteHelmRepo, new(model.HelmRepo)),
+
+	createRoute("/helm_charts", CreateHelmChart, new(model.HelmChart)),
+	listRoute("/helm_charts", ListHelmCharts, new(model.HelmChartList)),
+	actionRoute("POST", "/helm_charts/upload", UploadHelmChart, "Upload a chart archive to a local HelmRepo", new(model.HelmChartUpload), new(model.HelmChart), http.StatusCreated),
+	actionRoute("GET", "/helm_charts/search", SearchHelmCharts, "Search the chart catalog", nil, new(model.HelmChartList), http.StatusOK,
+		&routeParam{"q", reflect.String, "Words which must all be in the name, keywords or description of a chart."},
+		&routeParam{"repo_name", reflect.String, "Only charts of this HelmRepo."},
+		&routeParam{"kube_name", reflect.String, "Only charts that HelmChartPolicies allow on this Kube."},
+		&routeParam{"latest", reflect.Bool, "Only the latest version of each chart."},
+		&routeParam{"deprecated", reflect.Bool, "Only deprecated charts, or false to leave them out."},
+	),
+	getRoute("/helm_charts/{id}", GetHelmChart, new(model.HelmChart)),
+	updateRoute("/helm_charts/{id}", UpdateHelmChart, new(model.HelmChart)),
+	deleteRoute("/helm_charts/{id}", DeleteHelmChart, new(model.HelmChart)),
+
+	createRoute("/helm_chart_policies", CreateHelmChartPolicy, new(model.HelmChartPolicy)).authorizedBy(authorizeAdmin),
+	listRoute("/helm_chart_policies", ListHelmChartPolicies, new(model.HelmChartPolicyList)),
+	getRoute("/helm_chart_policies/{id}", GetHelmChartPolicy, new(model.HelmChartPolicy)),
+	updateRoute("/helm_chart_policies/{id}", UpdateHelmChartPolicy, new(model.HelmChartPolicy)).authorizedBy(authorizeAdmin),
+	deleteRoute("/helm_chart_policies/{id}", DeleteHelmChartPolicy, new(model.HelmChartPolicy)).authorizedBy(authorizeAdmin),
+
+	createRoute("/helm_releases", CreateHelmRelease, new(model.HelmRelease)).authorizedBy(authorizeHelmReleaseChange),
+	listRoute("/helm_releases", ListHelmReleases, new(model.HelmReleaseList),
+		&routeParam{"outdated", reflect.Bool, "Only releases with a newer chart version, or false for those without."},
+	),
+	getRoute("/helm_releases/{id}", GetHelmRelease, new(model.HelmRelease)),
+	updateRoute("/helm_releases/{id}", UpdateHelmRelease, new(model.HelmRelease)).authorizedBy(authorizeHelmReleaseChange),
+	actionRoute("GET", "/helm_releases/{id}/history", GetHelmReleaseHistory, "Get the revisions of a HelmRelease", nil, new(model.HelmReleaseHistory), http.StatusOK),
+	actionRoute("POST", "/helm_releases/{id}/rollback", RollbackHelmRelease, "Roll a HelmRelease back to a revision", new(model.HelmReleaseRollback), new(model.HelmRelease), http.StatusAccepted).authorizedBy(authorizeHelmReleaseChange),
+	actionRoute("POST", "/helm_releases/{id}/promote", PromoteHelmRelease, "Promote a HelmRelease to another Kube", new(model.HelmReleasePromotion), new(model.HelmReleasePromotion), http.StatusAccepted),
+	actionRoute("GET", "/helm_releases/{id}/drift", GetHelmReleaseDrift, "Compare a HelmRelease across Kubes", nil, new(model.HelmReleaseDrift), http.StatusOK),
+	deleteRoute("/helm_releases/{id}", DeleteHelmRelease, new(model.HelmRelease)).authorizedBy(authorizeHelmReleaseChange),
+
+	listRoute("/helm_release_promotions", ListHelmReleasePromotions, new(model.HelmReleasePromotionList)),
+	getRoute("/helm_release_promotions/{id}", GetHelmReleasePromotion, new(model.HelmReleasePromotion)),
+	actionRoute("POST", "/helm_release_promotions/{id}/approve", ApproveHelmReleasePromotion, "Approve a HelmReleasePromotion", nil, new(model.HelmReleasePromotion), http.StatusAccepted).authorizedBy(authorizeAdmin),
+	actionRoute("POST", "/helm_release_promotions/{id}/reject", RejectHelmReleasePromotion, "Reject a HelmReleasePromotion", nil, new(model.HelmReleasePromotion), http.StatusOK).authorizedBy(authorizeAdmin),
+
+	actionRoute("POST", "/bulk", RunBulk, "Create, update, delete and act on resources in bulk", new(model.Bulk), new(model.Bulk), http.StatusOK),
+}
@@ -54,10 +54,6 @@ func ListUsers(core *core.Core, user *model.User, r *http.Request) (*Response, e
 }
 
 func CreateUser(core *core.Core, user *model.User, r *http.Request) (*Response, error) {
-	if err := ensureAdmin(user); err != nil {
-		return nil, err
-	}
-
 	item := new(model.User)
 	if err := decodeBodyInto(r, item); err != nil {
 		return nil, err
@@ -74,13 +70,6 @@ func UpdateUser(core *core.Core, user *model.User, r *http.Request) (*Response,
 		return nil, err
 	}
 
-	// Ensure the requester is this User, or an Admin
-	if err := ensureSameUser(id, user); err != nil {
-		if err = ensureAdmin(user); err != nil {
-			return nil, err
-		}
-	}
-
 	item := new(model.User)
 	if err := decodeBodyInto(r, item); err != nil {
 		return nil, err
@@ -104,13 +93,6 @@ func GetUser(core *core.Core, user *model.User, r *http.Request) (*Response, err
 		return nil, err
 	}
 
-	// Ensure the requester is this User, or an Admin
-	if err := ensureSameUser(id, user); err != nil {
-		if err = ensureAdmin(user); err != nil {
-			return nil, err
-		}
-	}
-
 	if err := core.Users.Get(id, item); err != nil {
 		return nil, err
 	}
@@ -124,13 +106,6 @@ func DeleteUser(core *core.Core, user *model.User, r *http.Request) (*Response,
 		return nil, err
 	}
 
-	// Ensure the requester is this User, or an Admin
-	if err := ensureSameUser(id, user); err != nil {
-		if err = ensureAdmin(user); err != nil {
-			return nil, err
-		}
-	}
-
 	if err := core.Users.Delete(id, item); err != nil {
 		return nil, err
 	}
@@ -144,13 +119,6 @@ func RegenerateUserAPIToken(core *core.Core, user *model.User, r *http.Request)
 		return nil, err
 	}
 
-	// Ensure the requester is this User, or an Admin
-	if err := ensureSameUser(id, user); err != nil {
-		if err = ensureAdmin(user); err != nil {
-			return nil, err
-		}
-	}
-
 	if err := core.Users.RegenerateAPIToken(id, item); err != nil {
 		return nil, err
 	}
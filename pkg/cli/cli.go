@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"reflect"
+	"strings"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/supergiant/supergiant/pkg/client"
@@ -27,17 +27,21 @@ func init() {
 
 var baseFlags = []cli.Flag{
 	cli.StringFlag{
-		Name:  "server, s",
-		Usage: "Host and port of the Supergiant server",
+		Name:   "server, s",
+		Usage:  "Host and port of the Supergiant server",
+		EnvVar: "SUPERGIANT_SERVER",
 	},
 	cli.StringFlag{
-		Name:  "api-token, t",
-		Usage: "API token of the operating Supergiant User",
+		Name:   "api-token, t",
+		Usage:  "API token of the operating Supergiant User",
+		EnvVar: "SUPERGIANT_API_TOKEN",
 	},
 	cli.StringFlag{
-		Name:  "cert-file, c",
-		Usage: "Filepath of the SSL certificate used by the server. If not provided, the cert must be manually trusted through OS.",
+		Name:   "cert-file, c",
+		Usage:  "Filepath of the SSL certificate used by the server. If not provided, the cert must be manually trusted through OS.",
+		EnvVar: "SUPERGIANT_CERT_FILE",
 	},
+	contextFlag,
 }
 
 type CLI struct {
@@ -53,13 +57,38 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 	sgcli.Usage = "Supergiant CLI " + version
 	sgcli.Version = version
 
+	sgcli.EnableBashCompletion = true
+
 	sgcli.Commands = []cli.Command{
 		{
 			Name:   "configure",
-			Usage:  "globally configure server settings (helpful to prevent repeating flags)",
+			Usage:  "configure the server settings of a context (helpful to prevent repeating flags)",
 			Flags:  baseFlags,
 			Action: sgcli.commandConfigure,
 		},
+		{
+			Name:        "login",
+			Usage:       "log in to the server of a context, storing the token of the Session in it",
+			Description: "Logs in with a username and password, or stores an API token given with --api-token. SSO isn't supported, since the server has no SSO to log in with.",
+			Flags: append(baseFlags, []cli.Flag{
+				cli.StringFlag{
+					Name:  "username, u",
+					Usage: "username of the User, prompted for if not given",
+				},
+				cli.StringFlag{
+					Name:  "password, p",
+					Usage: "password of the User, prompted for if not given",
+				},
+			}...),
+			Action: sgcli.commandLogin,
+		},
+		{
+			Name:   "logout",
+			Usage:  "log out of the server of a context",
+			Flags:  baseFlags,
+			Action: sgcli.commandLogout,
+		},
+		sgcli.commandContext(),
 		{
 			Name:  "kubectl",
 			Usage: "wrapper for Kubectl that auto-populates connection-related tags",
@@ -71,6 +100,40 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 			}...),
 			Action: sgcli.commandKubectl,
 		},
+		{
+			Name:  "bulk",
+			Usage: "run the create, update, delete and action operations of a JSON file in bulk",
+			Flags: append(baseFlags, []cli.Flag{
+				fileFlag,
+				cli.BoolFlag{
+					Name:  "atomic",
+					Usage: "validate every operation before running any, and run none if any is invalid",
+				},
+			}...),
+			Action: sgcli.commandBulk,
+		},
+		{
+			Name:   "costs",
+			Usage:  "show the chargeback of every Kube by namespace",
+			Flags:  append(baseFlags, monthFlag),
+			Action: sgcli.commandCosts,
+		},
+		{
+			Name:   "log",
+			Usage:  "show the log of the Supergiant server",
+			Flags:  baseFlags,
+			Action: sgcli.commandLog,
+		},
+		sgcli.commandLogs(),
+		sgcli.commandExec(),
+		sgcli.commandCompletion(),
+		{
+			Name:  "actions",
+			Usage: "actions for the Actions running on resources",
+			Subcommands: []cli.Command{
+				sgcli.commandWatchActions(),
+			},
+		},
 		{
 			Name:  "cloud_accounts",
 			Usage: "actions for CloudAccounts",
@@ -80,6 +143,7 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 				sgcli.commandGet("CloudAccounts", new(model.CloudAccount)),
 				sgcli.commandUpdate("CloudAccounts", new(model.CloudAccount)),
 				sgcli.commandAction("delete", "Delete", "CloudAccounts", new(model.CloudAccount)),
+				sgcli.commandSchemaCloudAccounts(),
 			},
 		},
 		{
@@ -91,6 +155,9 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 				sgcli.commandGet("Kubes", new(model.Kube)),
 				sgcli.commandUpdate("Kubes", new(model.Kube)),
 				sgcli.commandAction("delete", "Delete", "Kubes", new(model.Kube)),
+				sgcli.commandAction("provision", "Provision", "Kubes", new(model.Kube)),
+				sgcli.commandKubeCosts(),
+				sgcli.commandWatchKubes(),
 			},
 		},
 		{
@@ -123,6 +190,7 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 				sgcli.commandGet("Users", new(model.User)),
 				sgcli.commandUpdate("Users", new(model.User)),
 				sgcli.commandAction("delete", "Delete", "Users", new(model.User)),
+				sgcli.commandAction("regenerate_api_token", "RegenerateAPIToken", "Users", new(model.User)),
 			},
 		},
 		{
@@ -138,6 +206,109 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 				sgcli.commandAction("stop", "Stop", "KubeResources", new(model.KubeResource)),
 			},
 		},
+		{
+			Name:  "load_balancers",
+			Usage: "actions for LoadBalancers",
+			Subcommands: []cli.Command{
+				sgcli.commandList("LoadBalancers", new(model.LoadBalancerList)),
+				sgcli.commandCreate("LoadBalancers", new(model.LoadBalancer)),
+				sgcli.commandGet("LoadBalancers", new(model.LoadBalancer)),
+				sgcli.commandUpdate("LoadBalancers", new(model.LoadBalancer)),
+				sgcli.commandAction("delete", "Delete", "LoadBalancers", new(model.LoadBalancer)),
+			},
+		},
+		{
+			Name:  "dns_zones",
+			Usage: "actions for DNSZones",
+			Subcommands: []cli.Command{
+				sgcli.commandList("DNSZones", new(model.DNSZoneList)),
+				sgcli.commandCreate("DNSZones", new(model.DNSZone)),
+				sgcli.commandGet("DNSZones", new(model.DNSZone)),
+				sgcli.commandUpdate("DNSZones", new(model.DNSZone)),
+				sgcli.commandAction("delete", "Delete", "DNSZones", new(model.DNSZone)),
+			},
+		},
+		{
+			Name:  "ingresses",
+			Usage: "actions for Ingresses",
+			Subcommands: []cli.Command{
+				sgcli.commandList("Ingresses", new(model.IngressList)),
+				sgcli.commandCreate("Ingresses", new(model.Ingress)),
+				sgcli.commandGet("Ingresses", new(model.Ingress)),
+				sgcli.commandUpdate("Ingresses", new(model.Ingress)),
+				sgcli.commandAction("delete", "Delete", "Ingresses", new(model.Ingress)),
+			},
+		},
+		{
+			Name:  "helm_repos",
+			Usage: "actions for HelmRepos",
+			Subcommands: []cli.Command{
+				sgcli.commandList("HelmRepos", new(model.HelmRepoList)),
+				sgcli.commandCreate("HelmRepos", new(model.HelmRepo)),
+				sgcli.commandGet("HelmRepos", new(model.HelmRepo)),
+				sgcli.commandUpdate("HelmRepos", new(model.HelmRepo)),
+				sgcli.commandAction("delete", "Delete", "HelmRepos", new(model.HelmRepo)),
+			},
+		},
+		{
+			Name:  "helm_charts",
+			Usage: "actions for HelmCharts",
+			Subcommands: []cli.Command{
+				sgcli.commandList("HelmCharts", new(model.HelmChartList)),
+				sgcli.commandCreate("HelmCharts", new(model.HelmChart)),
+				sgcli.commandGet("HelmCharts", new(model.HelmChart)),
+				sgcli.commandUpdate("HelmCharts", new(model.HelmChart)),
+				sgcli.commandAction("delete", "Delete", "HelmCharts", new(model.HelmChart)),
+				sgcli.commandSearchHelmCharts(),
+				sgcli.commandUploadHelmChart(),
+			},
+		},
+		{
+			Name:  "helm_chart_policies",
+			Usage: "actions for HelmChartPolicies",
+			Subcommands: []cli.Command{
+				sgcli.commandList("HelmChartPolicies", new(model.HelmChartPolicyList)),
+				sgcli.commandCreate("HelmChartPolicies", new(model.HelmChartPolicy)),
+				sgcli.commandGet("HelmChartPolicies", new(model.HelmChartPolicy)),
+				sgcli.commandUpdate("HelmChartPolicies", new(model.HelmChartPolicy)),
+				sgcli.commandAction("delete", "Delete", "HelmChartPolicies", new(model.HelmChartPolicy)),
+			},
+		},
+		{
+			Name:  "helm_releases",
+			Usage: "actions for HelmReleases",
+			Subcommands: []cli.Command{
+				sgcli.commandList("HelmReleases", new(model.HelmReleaseList)),
+				sgcli.commandCreateHelmRelease(),
+				sgcli.commandGet("HelmReleases", new(model.HelmRelease)),
+				sgcli.commandUpdateHelmRelease(),
+				sgcli.commandAction("delete", "Delete", "HelmReleases", new(model.HelmRelease)),
+				sgcli.commandAction("history", "History", "HelmReleases", new(model.HelmReleaseHistory)),
+				sgcli.commandRollbackHelmRelease(),
+				sgcli.commandPromoteHelmRelease(),
+				sgcli.commandAction("drift", "Drift", "HelmReleases", new(model.HelmReleaseDrift)),
+			},
+		},
+		{
+			Name:  "helm_release_promotions",
+			Usage: "actions for HelmReleasePromotions",
+			Subcommands: []cli.Command{
+				sgcli.commandList("HelmReleasePromotions", new(model.HelmReleasePromotionList)),
+				sgcli.commandGet("HelmReleasePromotions", new(model.HelmReleasePromotion)),
+				sgcli.commandAction("approve", "Approve", "HelmReleasePromotions", new(model.HelmReleasePromotion)),
+				sgcli.commandAction("reject", "Reject", "HelmReleasePromotions", new(model.HelmReleasePromotion)),
+			},
+		},
+	}
+
+	for i, command := range sgcli.Commands {
+		switch command.Name {
+		// Commands of the config file itself run in contexts which may not be
+		// usable yet
+		case "configure", "login", "logout", "context", "completion":
+		default:
+			withContext(sgcli.Commands[i : i+1])
+		}
 	}
 
 	return sgcli
@@ -145,22 +316,40 @@ func New(clientFn func(*cli.Context) *client.Client, stdin *os.File, version str
 
 // Private
 
+var idFlag = cli.StringFlag{
+	Name:  "id",
+	Usage: "the resource ID or name, which may also be given as the first argument",
+}
+
+var fileFlag = cli.StringFlag{
+	Name:  "file, f",
+	Usage: "JSON input file",
+}
+
 func (sgcli *CLI) commandList(collectionName string, list model.List) cli.Command {
+	flags := append(baseFlags, []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "--filter=name:this,or,that --filter=other_field:value --filter='kube.cloud_account_name!=aws' --filter='name in (this,that)'",
+		},
+		cli.StringFlag{
+			Name:  "sort",
+			Usage: "--sort=name,-created_at",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "--format=\"{{ .ThisField }}\"",
+		},
+		outputFlag,
+		columnsFlag,
+	}...)
 	return cli.Command{
-		Name:  "list",
-		Usage: "list " + collectionName,
-		Flags: append(baseFlags, []cli.Flag{
-			cli.StringSliceFlag{
-				Name:  "filter",
-				Usage: "--filter=name:this,or,that --filter=other_field:value",
-			},
-			cli.StringFlag{
-				Name:  "format",
-				Usage: "--format=\"{{ .ThisField }}\"",
-			},
-		}...),
+		Name:         "list",
+		Usage:        "list " + collectionName,
+		Flags:        flags,
+		BashComplete: sgcli.complete("", flags),
 		Action: func(c *cli.Context) error {
-			filters, err := listFilters(c)
+			filters, query, err := listFilters(c)
 			if err != nil {
 				return err
 			}
@@ -169,6 +358,8 @@ func (sgcli *CLI) commandList(collectionName string, list model.List) cli.Comman
 
 			// Set filters
 			reflectList.Elem().FieldByName("Filters").Set(reflect.ValueOf(filters))
+			reflectList.Elem().FieldByName("Query").Set(reflect.ValueOf(query))
+			reflectList.Elem().FieldByName("Sort").Set(reflect.ValueOf(listSort(c)))
 
 			fn := reflect.ValueOf(sgcli.Client(c)).Elem().FieldByName(collectionName).MethodByName("List")
 			ret := fn.Call([]reflect.Value{reflectList})
@@ -176,23 +367,23 @@ func (sgcli *CLI) commandList(collectionName string, list model.List) cli.Comman
 			if err := ret[0].Interface(); err != nil {
 				return err.(error)
 			}
-			return printList(c, list)
+			return printList(c, collectionName, list)
 		},
 	}
 }
 
+// commandCreate creates an item from the input file, or from flags of its
+// fields, such as --kube and --size of a Node. Flags are set over the file.
 func (sgcli *CLI) commandCreate(collectionName string, item model.Model) cli.Command {
+	flags := append(baseFlags, fileFlag)
+	allFlags := withFieldFlags(item, flags)
 	return cli.Command{
-		Name:  "create",
-		Usage: "create new " + collectionName,
-		Flags: append(baseFlags, []cli.Flag{
-			cli.StringFlag{
-				Name:  "file, f",
-				Usage: "JSON input file",
-			},
-		}...),
+		Name:         "create",
+		Usage:        "create new " + collectionName,
+		Flags:        allFlags,
+		BashComplete: sgcli.complete("", allFlags),
 		Action: func(c *cli.Context) error {
-			if err := sgcli.decodeInputFileInto(c, item); err != nil {
+			if err := sgcli.decodeItem(c, item, flags); err != nil {
 				return err
 			}
 
@@ -208,20 +399,21 @@ func (sgcli *CLI) commandCreate(collectionName string, item model.Model) cli.Com
 }
 
 func (sgcli *CLI) commandGet(collectionName string, item model.Model) cli.Command {
+	flags := append(baseFlags, idFlag)
 	return cli.Command{
-		Name:  "get",
-		Usage: "get " + collectionName,
-		Flags: append(baseFlags, []cli.Flag{
-			cli.StringFlag{
-				Name:  "id",
-				Usage: "the resource ID",
-			},
-		}...),
+		Name:         "get",
+		Usage:        "get " + collectionName,
+		Flags:        flags,
+		BashComplete: sgcli.complete(collectionName, flags),
 		Action: func(c *cli.Context) error {
-			id := c.Int64("id")
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, collectionName)
+			if err != nil {
+				return err
+			}
 
-			fn := reflect.ValueOf(sgcli.Client(c)).Elem().FieldByName(collectionName).MethodByName("Get")
-			ret := fn.Call([]reflect.Value{reflect.ValueOf(&id), reflect.ValueOf(item)})
+			fn := reflect.ValueOf(sg).Elem().FieldByName(collectionName).MethodByName("Get")
+			ret := fn.Call([]reflect.Value{reflect.ValueOf(id), reflect.ValueOf(item)})
 			if err := ret[0].Interface(); err != nil {
 				return err.(error)
 			}
@@ -231,30 +423,30 @@ func (sgcli *CLI) commandGet(collectionName string, item model.Model) cli.Comman
 	}
 }
 
+// commandUpdate is commandCreate, of the item with the ID or name given.
 func (sgcli *CLI) commandUpdate(collectionName string, item model.Model) cli.Command {
+	flags := append(baseFlags, idFlag, fileFlag, resourceVersionFlag)
+	allFlags := withFieldFlags(item, flags)
 	return cli.Command{
-		Name:  "update",
-		Usage: "update " + collectionName,
-		Flags: append(baseFlags, []cli.Flag{
-			cli.StringFlag{
-				Name:  "id",
-				Usage: "the resource ID",
-			},
-			cli.StringFlag{
-				Name:  "file, f",
-				Usage: "JSON input file",
-			},
-		}...),
+		Name:         "update",
+		Usage:        "update " + collectionName,
+		Flags:        allFlags,
+		BashComplete: sgcli.complete(collectionName, allFlags),
 		Action: func(c *cli.Context) error {
-			id := c.Int64("id")
-			if err := sgcli.decodeInputFileInto(c, item); err != nil {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, collectionName)
+			if err != nil {
+				return err
+			}
+			if err := sgcli.decodeItem(c, item, flags); err != nil {
 				return err
 			}
+			setResourceVersion(c, item)
 
-			fn := reflect.ValueOf(sgcli.Client(c)).Elem().FieldByName(collectionName).MethodByName("Update")
-			ret := fn.Call([]reflect.Value{reflect.ValueOf(&id), reflect.ValueOf(item)})
+			fn := reflect.ValueOf(sg).Elem().FieldByName(collectionName).MethodByName("Update")
+			ret := fn.Call([]reflect.Value{reflect.ValueOf(id), reflect.ValueOf(item)})
 			if err := ret[0].Interface(); err != nil {
-				return err.(error)
+				return updateError(collectionName, id, err.(error))
 			}
 
 			return printObj(item)
@@ -262,21 +454,25 @@ func (sgcli *CLI) commandUpdate(collectionName string, item model.Model) cli.Com
 	}
 }
 
-func (sgcli *CLI) commandAction(action string, methodName string, collectionName string, item model.Model) cli.Command {
+// commandAction calls a method of a collection which takes the ID of an item
+// and what it responds with, such as Start of KubeResources or History of
+// HelmReleases.
+func (sgcli *CLI) commandAction(action string, methodName string, collectionName string, item interface{}) cli.Command {
+	flags := append(baseFlags, idFlag)
 	return cli.Command{
-		Name:  action,
-		Usage: action + " " + collectionName,
-		Flags: append(baseFlags, []cli.Flag{
-			cli.StringFlag{
-				Name:  "id",
-				Usage: "the resource ID",
-			},
-		}...),
+		Name:         action,
+		Usage:        action + " " + collectionName,
+		Flags:        flags,
+		BashComplete: sgcli.complete(collectionName, flags),
 		Action: func(c *cli.Context) error {
-			id := c.Int64("id")
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, collectionName)
+			if err != nil {
+				return err
+			}
 
-			fn := reflect.ValueOf(sgcli.Client(c)).Elem().FieldByName(collectionName).MethodByName(methodName)
-			ret := fn.Call([]reflect.Value{reflect.ValueOf(&id), reflect.ValueOf(item)})
+			fn := reflect.ValueOf(sg).Elem().FieldByName(collectionName).MethodByName(methodName)
+			ret := fn.Call([]reflect.Value{reflect.ValueOf(id), reflect.ValueOf(item)})
 			if err := ret[0].Interface(); err != nil {
 				return err.(error)
 			}
@@ -286,21 +482,285 @@ func (sgcli *CLI) commandAction(action string, methodName string, collectionName
 	}
 }
 
-// Root commands
+// Helm commands
+
+var chartFlag = cli.StringFlag{
+	Name:  "chart",
+	Usage: "chart directory or packaged chart (.tgz) to upload",
+}
+
+var resourceVersionFlag = cli.Int64Flag{
+	Name:  "resource-version",
+	Usage: "the resource version the update is made from, if not that of the input file",
+}
+
+var valuesFlag = cli.StringSliceFlag{
+	Name:  "values",
+	Usage: "values YAML file, can be repeated (later files override earlier ones, config overrides them all)",
+}
+
+func (sgcli *CLI) commandUploadHelmChart() cli.Command {
+	return cli.Command{
+		Name:  "upload",
+		Usage: "upload a chart to the local HelmRepo",
+		Flags: append(baseFlags, chartFlag),
+		Action: func(c *cli.Context) error {
+			chart, err := sgcli.uploadChart(c)
+			if err != nil {
+				return err
+			}
+			return printObj(chart)
+		},
+	}
+}
+
+// commandCreateHelmRelease is commandCreate, but with --chart the chart is
+// uploaded first, and the release is of it. Files given with --values are
+// added to the values of the release.
+func (sgcli *CLI) commandCreateHelmRelease() cli.Command {
+	item := new(model.HelmRelease)
+	flags := append(baseFlags, fileFlag, chartFlag, valuesFlag)
+	allFlags := withFieldFlags(item, flags)
+	return cli.Command{
+		Name:         "create",
+		Usage:        "create new HelmReleases",
+		Flags:        allFlags,
+		BashComplete: sgcli.complete("", allFlags),
+		Action: func(c *cli.Context) error {
+			if err := sgcli.decodeItem(c, item, flags); err != nil {
+				return err
+			}
+			if err := readValuesFiles(c, &item.Values); err != nil {
+				return err
+			}
+
+			if c.String("chart") != "" {
+				chart, err := sgcli.uploadChart(c)
+				if err != nil {
+					return err
+				}
+				item.RepoName = chart.RepoName
+				item.ChartName = chart.Name
+				item.ChartVersion = chart.Version
+			}
+
+			if err := sgcli.Client(c).HelmReleases.Create(item); err != nil {
+				return err
+			}
+			return printObj(item)
+		},
+	}
+}
 
-func (sgcli *CLI) commandConfigure(c *cli.Context) error {
-	conf := newGlobalConf(c)
-	b, err := json.Marshal(conf)
+// commandUpdateHelmRelease is commandUpdate, with the --values of
+// commandCreateHelmRelease, which replace the values documents of the release.
+func (sgcli *CLI) commandUpdateHelmRelease() cli.Command {
+	item := new(model.HelmRelease)
+	flags := append(baseFlags, idFlag, fileFlag, valuesFlag, resourceVersionFlag)
+	allFlags := withFieldFlags(item, flags)
+	return cli.Command{
+		Name:         "update",
+		Usage:        "update HelmReleases",
+		Flags:        allFlags,
+		BashComplete: sgcli.complete("HelmReleases", allFlags),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, "HelmReleases")
+			if err != nil {
+				return err
+			}
+			if err := sgcli.decodeItem(c, item, flags); err != nil {
+				return err
+			}
+			if err := readValuesFiles(c, &item.Values); err != nil {
+				return err
+			}
+			setResourceVersion(c, item)
+
+			if err := sg.HelmReleases.Update(id, item); err != nil {
+				return updateError("HelmReleases", id, err)
+			}
+			return printObj(item)
+		},
+	}
+}
+
+// commandRollbackHelmRelease rolls a release back to a revision of its
+// history, given with --revision.
+func (sgcli *CLI) commandRollbackHelmRelease() cli.Command {
+	rollback := new(model.HelmReleaseRollback)
+	flags := append(baseFlags, idFlag, fileFlag)
+	allFlags := withFieldFlags(rollback, flags)
+	return cli.Command{
+		Name:         "rollback",
+		Usage:        "roll HelmReleases back to a revision",
+		Flags:        allFlags,
+		BashComplete: sgcli.complete("HelmReleases", allFlags),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, "HelmReleases")
+			if err != nil {
+				return err
+			}
+			if err := sgcli.decodeItem(c, rollback, flags); err != nil {
+				return err
+			}
+
+			item := new(model.HelmRelease)
+			if err := sg.HelmReleases.Rollback(id.(*int64), rollback, item); err != nil {
+				return err
+			}
+			return printObj(item)
+		},
+	}
+}
+
+// commandPromoteHelmRelease promotes a release to the Kube given with --kube,
+// with the --values files as overrides.
+func (sgcli *CLI) commandPromoteHelmRelease() cli.Command {
+	promotion := new(model.HelmReleasePromotion)
+	flags := append(baseFlags, idFlag, fileFlag, valuesFlag)
+	allFlags := withFieldFlags(promotion, flags)
+	return cli.Command{
+		Name:         "promote",
+		Usage:        "promote HelmReleases to another Kube",
+		Flags:        allFlags,
+		BashComplete: sgcli.complete("HelmReleases", allFlags),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, "HelmReleases")
+			if err != nil {
+				return err
+			}
+			if err := sgcli.decodeItem(c, promotion, flags); err != nil {
+				return err
+			}
+			if err := readValuesFiles(c, &promotion.Values); err != nil {
+				return err
+			}
+
+			if err := sg.HelmReleases.Promote(id.(*int64), promotion); err != nil {
+				return err
+			}
+			return printObj(promotion)
+		},
+	}
+}
+
+func (sgcli *CLI) commandSearchHelmCharts() cli.Command {
+	flags := append(baseFlags, []cli.Flag{
+		cli.StringFlag{
+			Name:  "query, q",
+			Usage: "words which must all be in the name, keywords or description of a chart",
+		},
+		cli.StringFlag{
+			Name:  "repo",
+			Usage: "only charts of this HelmRepo",
+		},
+		cli.StringFlag{
+			Name:  "kube",
+			Usage: "only charts that HelmChartPolicies allow on this Kube",
+		},
+		cli.BoolFlag{
+			Name:  "latest",
+			Usage: "only the latest version of each chart",
+		},
+		cli.BoolFlag{
+			Name:  "deprecated",
+			Usage: "only deprecated charts, or with --deprecated=false those which aren't",
+		},
+		outputFlag,
+		columnsFlag,
+	}...)
+	return cli.Command{
+		Name:         "search",
+		Usage:        "search HelmCharts",
+		Flags:        flags,
+		BashComplete: sgcli.complete("", flags),
+		Action: func(c *cli.Context) error {
+			search := &model.HelmChartSearch{
+				Query:    strings.Join(append([]string{c.String("query")}, c.Args()...), " "),
+				RepoName: c.String("repo"),
+				KubeName: c.String("kube"),
+				Latest:   c.Bool("latest"),
+			}
+			if c.IsSet("deprecated") {
+				deprecated := c.Bool("deprecated")
+				search.Deprecated = &deprecated
+			}
+			search.Query = strings.TrimSpace(search.Query)
+
+			list := new(model.HelmChartList)
+			if err := sgcli.Client(c).HelmCharts.Search(search, list); err != nil {
+				return err
+			}
+			return printList(c, "HelmCharts", list)
+		},
+	}
+}
+
+func (sgcli *CLI) commandSchemaCloudAccounts() cli.Command {
+	return cli.Command{
+		Name:         "schema",
+		Usage:        "show the credentials each provider of CloudAccounts takes",
+		Flags:        baseFlags,
+		BashComplete: sgcli.complete("", baseFlags),
+		Action: func(c *cli.Context) error {
+			var schema map[string]interface{}
+			if err := sgcli.Client(c).CloudAccounts.Schema(&schema); err != nil {
+				return err
+			}
+			return printObj(schema)
+		},
+	}
+}
+
+var monthFlag = cli.StringFlag{
+	Name:  "month",
+	Usage: "month of the costs, as YYYY-MM (defaults to the current month)",
+}
+
+func (sgcli *CLI) commandKubeCosts() cli.Command {
+	flags := append(baseFlags, idFlag, monthFlag)
+	return cli.Command{
+		Name:         "costs",
+		Usage:        "show the costs of Kubes, by Node, namespace and HelmRelease",
+		Flags:        flags,
+		BashComplete: sgcli.complete("Kubes", flags),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, "Kubes")
+			if err != nil {
+				return err
+			}
+
+			costs := new(model.KubeCosts)
+			if err := sg.Kubes.Costs(id.(*int64), c.String("month"), costs); err != nil {
+				return err
+			}
+			return printObj(costs)
+		},
+	}
+}
+
+func (sgcli *CLI) uploadChart(c *cli.Context) (*model.HelmChart, error) {
+	path := c.String("chart")
+	if path == "" {
+		return nil, errors.New("--chart required")
+	}
+	archive, err := chartArchive(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := ioutil.WriteFile(globalConfFile, b, 0600); err != nil {
-		return err
+	chart := new(model.HelmChart)
+	if err := sgcli.Client(c).HelmCharts.Upload(&model.HelmChartUpload{Archive: archive}, chart); err != nil {
+		return nil, err
 	}
-	fmt.Println("Written to " + globalConfFile)
-	return nil
+	return chart, nil
 }
 
+// Root commands
+
 func (sgcli *CLI) commandKubectl(c *cli.Context) error {
 	id := c.Int64("kube-id")
 	kube := new(model.Kube)
@@ -326,9 +786,51 @@ func (sgcli *CLI) commandKubectl(c *cli.Context) error {
 	return cmd.Run()
 }
 
+// commandCosts prints the chargeback of every Kube by namespace.
+func (sgcli *CLI) commandCosts(c *cli.Context) error {
+	chargeback := new(model.Chargeback)
+	if err := sgcli.Client(c).Kubes.Chargeback(c.String("month"), chargeback); err != nil {
+		return err
+	}
+	return printObj(chargeback)
+}
+
+func (sgcli *CLI) commandLog(c *cli.Context) error {
+	return sgcli.Client(c).Log(os.Stdout)
+}
+
+// commandBulk prints the results of the operations, and fails if any of them
+// did.
+func (sgcli *CLI) commandBulk(c *cli.Context) error {
+	bulk := new(model.Bulk)
+	if err := sgcli.decodeInputFileInto(c, bulk); err != nil {
+		return err
+	}
+	if c.Bool("atomic") {
+		bulk.Atomic = true
+	}
+	if err := sgcli.Client(c).Bulk.Run(bulk); err != nil {
+		return err
+	}
+	if err := printObj(bulk.Results); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, result := range bulk.Results {
+		if result.Error != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d operations failed", failed, len(bulk.Results))
+	}
+	return nil
+}
+
 // Helpers
 
-func (sgcli *CLI) decodeInputFileInto(c *cli.Context, item model.Model) (err error) {
+func (sgcli *CLI) decodeInputFileInto(c *cli.Context, item interface{}) (err error) {
 	var file *os.File
 
 	switch filepath := c.String("f"); filepath {
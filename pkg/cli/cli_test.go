@@ -1,8 +1,11 @@
 package cli_test
 
 import (
+	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/supergiant/supergiant/pkg/cli"
@@ -10,6 +13,7 @@ import (
 	"github.com/supergiant/supergiant/pkg/model"
 	"github.com/supergiant/supergiant/test/fake_client"
 	cli_lib "github.com/urfave/cli"
+	"k8s.io/helm/pkg/chartutil"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -19,6 +23,13 @@ func idInt64(n int64) *int64 { return &n }
 
 func TestCLIRun(t *testing.T) {
 	Convey("CLI works correctly", t, func() {
+		chartDir, _ := ioutil.TempDir(os.TempDir(), "chart")
+		defer os.RemoveAll(chartDir)
+		ioutil.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("name: mychart\nversion: 0.1.0\ndescription: test\n"), 0644)
+		ioutil.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("replicas: 1\n"), 0644)
+		valuesFile := filepath.Join(chartDir, "production.yaml")
+		ioutil.WriteFile(valuesFile, []byte("replicas: 3\n"), 0644)
+
 		table := []struct {
 			// Input
 			command []string
@@ -40,6 +51,28 @@ func TestCLIRun(t *testing.T) {
 					},
 				},
 			},
+			// Kubes List, with legacy filters, filter expressions and sorting
+			{
+				command:             []string{"supergiant", "kubes", "list", "--filter=name:this,that", "--filter=cloud_account.provider!=gce", "--filter=node_sizes in (m4.large)", "--sort=name,-created_at"},
+				clientCommandCalled: "Kubes.List",
+				clientCommandArgs: []interface{}{
+					&model.KubeList{
+						BaseList: model.BaseList{
+							Filters: map[string][]string{"name": []string{"this", "that"}},
+							Query:   []string{"cloud_account.provider!=gce", "node_sizes in (m4.large)"},
+							Sort:    []string{"name", "-created_at"},
+						},
+					},
+				},
+			},
+			// Kubes Watch, which lists Kubes and then their Nodes
+			{
+				command:             []string{"supergiant", "kubes", "watch", "-n", "1"},
+				clientCommandCalled: "Nodes.List",
+				clientCommandArgs: []interface{}{
+					&model.NodeList{},
+				},
+			},
 			// CloudAccounts Create
 			{
 				command: []string{"supergiant", "cloud_accounts", "create", "-f", "-"},
@@ -53,6 +86,17 @@ func TestCLIRun(t *testing.T) {
 					},
 				},
 			},
+			// Nodes Create, from flags of the fields instead of a file
+			{
+				command:             []string{"supergiant", "nodes", "create", "--kube", "prod", "--size", "m4.large"},
+				clientCommandCalled: "Nodes.Create",
+				clientCommandArgs: []interface{}{
+					&model.Node{
+						KubeName: "prod",
+						Size:     "m4.large",
+					},
+				},
+			},
 			// CloudAccounts Get
 			{
 				command:             []string{"supergiant", "cloud_accounts", "get", "--id=1"},
@@ -62,6 +106,24 @@ func TestCLIRun(t *testing.T) {
 					&model.CloudAccount{},
 				},
 			},
+			// Kubes Get, by name
+			{
+				command:             []string{"supergiant", "kubes", "get", "prod"},
+				clientCommandCalled: "Kubes.Get",
+				clientCommandArgs: []interface{}{
+					idInt64(5),
+					&model.Kube{},
+				},
+			},
+			// Kubes Provision, by name given as the ID
+			{
+				command:             []string{"supergiant", "kubes", "provision", "--id=prod"},
+				clientCommandCalled: "Kubes.Provision",
+				clientCommandArgs: []interface{}{
+					idInt64(5),
+					&model.Kube{},
+				},
+			},
 			// CloudAccounts Update
 			{
 				command: []string{"supergiant", "cloud_accounts", "update", "--id=1", "-f", "-"},
@@ -76,6 +138,47 @@ func TestCLIRun(t *testing.T) {
 					},
 				},
 			},
+			// Kubes Update, from a resource version
+			{
+				command: []string{"supergiant", "kubes", "update", "--id=1", "-f", "-", "--resource-version=3"},
+				stdin: `{
+          "name": "test"
+        }`,
+				clientCommandCalled: "Kubes.Update",
+				clientCommandArgs: []interface{}{
+					idInt64(1),
+					&model.Kube{
+						BaseModel: model.BaseModel{ResourceVersion: 3},
+						Name:      "test",
+					},
+				},
+			},
+			// Kubes Update, with a flag of a field set over the file
+			{
+				command: []string{"supergiant", "kubes", "update", "prod", "-f", "-", "--kubernetes-version=1.5.7", "--resource-version=3"},
+				stdin: `{
+          "name": "test",
+          "kubernetes_version": "1.5.1"
+        }`,
+				clientCommandCalled: "Kubes.Update",
+				clientCommandArgs: []interface{}{
+					idInt64(5),
+					&model.Kube{
+						BaseModel:         model.BaseModel{ResourceVersion: 3},
+						Name:              "test",
+						KubernetesVersion: "1.5.7",
+					},
+				},
+			},
+			// Users RegenerateAPIToken
+			{
+				command:             []string{"supergiant", "users", "regenerate_api_token", "--id=1"},
+				clientCommandCalled: "Users.RegenerateAPIToken",
+				clientCommandArgs: []interface{}{
+					idInt64(1),
+					&model.User{},
+				},
+			},
 			// CloudAccounts Delete
 			{
 				command:             []string{"supergiant", "cloud_accounts", "delete", "--id=1"},
@@ -85,6 +188,64 @@ func TestCLIRun(t *testing.T) {
 					&model.CloudAccount{},
 				},
 			},
+			// HelmReleases Create, with a chart directory which is uploaded
+			{
+				command: []string{"supergiant", "helm_releases", "create", "-f", "-", "--chart", chartDir},
+				stdin: `{
+          "name": "test",
+          "kube_name": "test"
+        }`,
+				clientCommandCalled: "HelmReleases.Create",
+				clientCommandArgs: []interface{}{
+					&model.HelmRelease{
+						Name:         "test",
+						KubeName:     "test",
+						RepoName:     "local",
+						ChartName:    "mychart",
+						ChartVersion: "0.1.0",
+					},
+				},
+			},
+			// HelmReleases Update, with values files
+			{
+				command: []string{"supergiant", "helm_releases", "update", "--id=1", "-f", "-", "--values", valuesFile},
+				stdin: `{
+          "config": {"persistence": false}
+        }`,
+				clientCommandCalled: "HelmReleases.Update",
+				clientCommandArgs: []interface{}{
+					idInt64(1),
+					&model.HelmRelease{
+						Values: []string{"replicas: 3\n"},
+						Config: map[string]interface{}{"persistence": false},
+					},
+				},
+			},
+			// KubeResources Logs, followed from 5 minutes ago
+			{
+				command:             []string{"supergiant", "logs", "--id=3", "--container", "nginx", "--follow", "--tail", "20", "--since", "5m"},
+				clientCommandCalled: "KubeResources.Logs",
+				clientCommandArgs: []interface{}{
+					idInt64(3),
+					&model.PodLogOptions{Container: "nginx", Follow: true, TailLines: idInt64(20), SinceSeconds: idInt64(300)},
+				},
+			},
+			// Bulk, made atomic with --atomic
+			{
+				command: []string{"supergiant", "bulk", "-f", "-", "--atomic"},
+				stdin: `{
+          "operations": [{"op": "action", "resource": "kube_resources", "id": 1, "action": "start"}]
+        }`,
+				clientCommandCalled: "Bulk.Run",
+				clientCommandArgs: []interface{}{
+					&model.Bulk{
+						Atomic: true,
+						Operations: []*model.BulkOperation{
+							{Op: "action", Resource: "kube_resources", ID: idInt64(1), Action: "start"},
+						},
+					},
+				},
+			},
 		}
 
 		for _, item := range table {
@@ -94,6 +255,13 @@ func TestCLIRun(t *testing.T) {
 
 			clientFn := func(_ *cli_lib.Context) *client.Client {
 				return &client.Client{
+					Bulk: &fake_client.Bulk{
+						RunFn: func(m *model.Bulk) error {
+							clientCommandCalled = "Bulk.Run"
+							clientCommandArgs = []interface{}{m}
+							return nil
+						},
+					},
 					Sessions: &fake_client.Sessions{
 						Collection: fake_client.Collection{
 							ListFn: func(list model.List) error {
@@ -191,6 +359,10 @@ func TestCLIRun(t *testing.T) {
 							ListFn: func(list model.List) error {
 								clientCommandCalled = "Kubes.List"
 								clientCommandArgs = []interface{}{list}
+								// The Kube named prod is looked up by name
+								if kubes := list.(*model.KubeList); len(kubes.Query) == 1 && kubes.Query[0] == "name=prod" {
+									kubes.Items = []*model.Kube{{BaseModel: model.BaseModel{ID: idInt64(5)}, Name: "prod"}}
+								}
 								return nil
 							},
 							CreateFn: func(m model.Model) error {
@@ -214,6 +386,11 @@ func TestCLIRun(t *testing.T) {
 								return nil
 							},
 						},
+						ProvisionFn: func(id *int64, m *model.Kube) error {
+							clientCommandCalled = "Kubes.Provision"
+							clientCommandArgs = []interface{}{id, m}
+							return nil
+						},
 					},
 					KubeResources: &fake_client.KubeResources{
 						Collection: fake_client.Collection{
@@ -253,6 +430,37 @@ func TestCLIRun(t *testing.T) {
 							clientCommandArgs = []interface{}{id, m}
 							return nil
 						},
+						LogsFn: func(id *int64, opts *model.PodLogOptions, _ io.Writer) error {
+							clientCommandCalled = "KubeResources.Logs"
+							clientCommandArgs = []interface{}{id, opts}
+							return nil
+						},
+					},
+					HelmCharts: &fake_client.HelmCharts{
+						UploadFn: func(upload *model.HelmChartUpload, m *model.HelmChart) error {
+							chart, err := chartutil.LoadArchive(bytes.NewReader(upload.Archive))
+							if err != nil {
+								return err
+							}
+							m.RepoName = "local"
+							m.Name = chart.Metadata.Name
+							m.Version = chart.Metadata.Version
+							return nil
+						},
+					},
+					HelmReleases: &fake_client.HelmReleases{
+						Collection: fake_client.Collection{
+							CreateFn: func(m model.Model) error {
+								clientCommandCalled = "HelmReleases.Create"
+								clientCommandArgs = []interface{}{m}
+								return nil
+							},
+							UpdateFn: func(id interface{}, m model.Model) error {
+								clientCommandCalled = "HelmReleases.Update"
+								clientCommandArgs = []interface{}{id, m}
+								return nil
+							},
+						},
 					},
 					Nodes: &fake_client.Nodes{
 						Collection: fake_client.Collection{
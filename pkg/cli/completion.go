@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+)
+
+// bashCompletion completes commands by running them with
+// --generate-bash-completion, which prints the words that can come next.
+const bashCompletion = `_supergiant_complete() {
+  local cur opts
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion 2>/dev/null )
+  COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+  return 0
+}
+
+complete -o default -F _supergiant_complete supergiant
+`
+
+// zshCompletion is bashCompletion, through the bash completion of zsh.
+const zshCompletion = `autoload -U +X compinit && compinit
+autoload -U +X bashcompinit && bashcompinit
+
+` + bashCompletion
+
+func (sgcli *CLI) commandCompletion() cli.Command {
+	return cli.Command{
+		Name:      "completion",
+		Usage:     "print the shell completion script of bash or zsh, such as for `source <(supergiant completion bash)`",
+		ArgsUsage: "bash|zsh",
+		Action: func(c *cli.Context) error {
+			switch shell := c.Args().First(); shell {
+			case "bash":
+				fmt.Fprint(c.App.Writer, bashCompletion)
+			case "zsh":
+				fmt.Fprint(c.App.Writer, zshCompletion)
+			default:
+				return fmt.Errorf("No completion for shell '%s', only bash and zsh", shell)
+			}
+			return nil
+		},
+	}
+}
+
+// complete completes the flags of a command, and the names of the items of
+// its collection, if it has any and a server is configured.
+func (sgcli *CLI) complete(collectionName string, flags []cli.Flag) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		for _, flag := range flags {
+			for _, name := range strings.Split(flag.GetName(), ",") {
+				if name = strings.TrimSpace(name); len(name) == 1 {
+					fmt.Fprintln(c.App.Writer, "-"+name)
+				} else {
+					fmt.Fprintln(c.App.Writer, "--"+name)
+				}
+			}
+		}
+
+		field, ok := nameFields[collectionName]
+		if !ok {
+			return
+		}
+		sg := sgcli.Client(c)
+		if sg.BaseURL == "" {
+			return
+		}
+		list := reflect.New(listTypes[collectionName]).Interface().(model.List)
+		items, err := listAll(sg, collectionName, list)
+		if err != nil {
+			return
+		}
+		for _, item := range items {
+			if fields, err := itemFields(item); err == nil {
+				fmt.Fprintln(c.App.Writer, formatValue(fields[field]))
+			}
+		}
+	}
+}
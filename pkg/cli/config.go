@@ -0,0 +1,447 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+)
+
+const defaultContext = "default"
+
+// GlobalConfig is the server and credentials of a context.
+type GlobalConfig struct {
+	Server   string `json:"server"`
+	Token    string `json:"token"`
+	CertFile string `json:"cert_file"`
+	// AuthType is "token" for API tokens, or "session" for the Sessions of
+	// supergiant login, which expire at ExpiresAt.
+	AuthType  string     `json:"auth_type,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Config is the config file: named contexts, each of a server and its
+// credentials, and the current one commands run in.
+type Config struct {
+	CurrentContext string                   `json:"current_context,omitempty"`
+	Contexts       map[string]*GlobalConfig `json:"contexts"`
+}
+
+// readConfig reads the config file, if there is one. Files written before
+// contexts, of a single server, are read as the default context.
+func readConfig() (*Config, error) {
+	conf := &Config{Contexts: make(map[string]*GlobalConfig)}
+	b, err := ioutil.ReadFile(globalConfFile)
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, conf); err != nil {
+		return nil, fmt.Errorf("Invalid config file %s: %s", globalConfFile, err)
+	}
+	if conf.Contexts == nil {
+		conf.Contexts = make(map[string]*GlobalConfig)
+	}
+
+	if len(conf.Contexts) == 0 {
+		legacy := new(GlobalConfig)
+		json.Unmarshal(b, legacy)
+		if legacy.Server != "" || legacy.Token != "" || legacy.CertFile != "" {
+			conf.Contexts[defaultContext] = legacy
+			conf.CurrentContext = defaultContext
+		}
+	}
+	return conf, nil
+}
+
+func (conf *Config) write() error {
+	b, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(globalConfFile, b, 0600)
+}
+
+// contextName returns the name of the context a command runs in: that of
+// --context (or SUPERGIANT_CONTEXT), or the current context.
+func (conf *Config) contextName(c *cli.Context) string {
+	if name := c.String("context"); name != "" {
+		return name
+	}
+	if conf.CurrentContext != "" {
+		return conf.CurrentContext
+	}
+	return defaultContext
+}
+
+// settings returns the settings a command runs with: those of its flags (or
+// their env vars, such as SUPERGIANT_SERVER) over those of its context. A
+// token given is an API token, even in the context of a Session.
+func (conf *Config) settings(c *cli.Context) *GlobalConfig {
+	settings := new(GlobalConfig)
+	if stored, ok := conf.Contexts[conf.contextName(c)]; ok {
+		*settings = *stored
+	}
+	if server := c.String("server"); server != "" {
+		settings.Server = server
+	}
+	if certFile := c.String("cert-file"); certFile != "" {
+		settings.CertFile = certFile
+	}
+	if token := c.String("api-token"); token != "" {
+		settings.Token = token
+		settings.AuthType = "token"
+		settings.ExpiresAt = nil
+	}
+	if settings.AuthType == "" {
+		settings.AuthType = "token"
+	}
+	return settings
+}
+
+func Client(c *cli.Context) *client.Client {
+	// NOTE no error handling here, since commands check their context first
+	conf, err := readConfig()
+	if err != nil {
+		conf = &Config{}
+	}
+	settings := conf.settings(c)
+	return client.New(settings.Server, settings.AuthType, settings.Token, settings.CertFile)
+}
+
+//------------------------------------------------------------------------------
+
+// checkContext fails commands which can't run in their context: one which
+// doesn't exist, or of a Session which expired.
+func checkContext(c *cli.Context) error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+	name := conf.contextName(c)
+	if _, ok := conf.Contexts[name]; !ok && c.String("context") != "" {
+		return fmt.Errorf("No context '%s'", name)
+	}
+	settings := conf.settings(c)
+	if settings.AuthType == "session" && settings.ExpiresAt != nil && time.Now().After(*settings.ExpiresAt) {
+		return sessionExpiredError(name, settings)
+	}
+	return nil
+}
+
+func sessionExpiredError(name string, settings *GlobalConfig) error {
+	at := ""
+	if settings.ExpiresAt != nil {
+		at = " at " + settings.ExpiresAt.Local().Format("2006-01-02 15:04")
+	}
+	return fmt.Errorf("The session of context '%s' expired%s. Log in again with `supergiant login`.", name, at)
+}
+
+// withContext checks the context of commands before running them, and
+// explains unauthorized errors of Sessions, which the server has expired.
+func withContext(commands []cli.Command) {
+	for i := range commands {
+		if action, ok := commands[i].Action.(func(*cli.Context) error); ok {
+			commands[i].Action = func(c *cli.Context) error {
+				if err := checkContext(c); err != nil {
+					return err
+				}
+				err := action(c)
+				if client.IsUnauthorized(err) {
+					if conf, confErr := readConfig(); confErr == nil {
+						if settings := conf.settings(c); settings.AuthType == "session" {
+							return sessionExpiredError(conf.contextName(c), settings)
+						}
+					}
+				}
+				return err
+			}
+		}
+		withContext(commands[i].Subcommands)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var contextFlag = cli.StringFlag{
+	Name:   "context",
+	Usage:  "name of the context to run in, instead of the current one",
+	EnvVar: "SUPERGIANT_CONTEXT",
+}
+
+// commandConfigure sets the server settings given of the context (creating
+// it), and makes it current if none is.
+func (sgcli *CLI) commandConfigure(c *cli.Context) error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+	name := conf.contextName(c)
+	settings, ok := conf.Contexts[name]
+	if !ok {
+		settings = new(GlobalConfig)
+		conf.Contexts[name] = settings
+	}
+	if server := c.String("server"); server != "" {
+		settings.Server = server
+	}
+	if certFile := c.String("cert-file"); certFile != "" {
+		settings.CertFile = certFile
+	}
+	if token := c.String("api-token"); token != "" {
+		settings.Token = token
+		settings.AuthType = "token"
+		settings.ExpiresAt = nil
+	}
+	if conf.CurrentContext == "" {
+		conf.CurrentContext = name
+	}
+
+	if err := conf.write(); err != nil {
+		return err
+	}
+	fmt.Printf("Written to %s (context '%s')\n", globalConfFile, name)
+	return nil
+}
+
+// commandLogin creates a Session with the username and password given (or
+// prompted for), and stores its token in the context, which becomes current.
+// An API token given is stored instead.
+func (sgcli *CLI) commandLogin(c *cli.Context) error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+	name := conf.contextName(c)
+	settings := conf.settings(c)
+
+	if c.String("api-token") == "" {
+		stdin := bufio.NewReader(sgcli.Stdin)
+		username := c.String("username")
+		if username == "" {
+			fmt.Fprint(os.Stderr, "Username: ")
+			if username, err = readLine(stdin); err != nil {
+				return err
+			}
+		}
+		password := c.String("password")
+		if password == "" {
+			fmt.Fprint(os.Stderr, "Password: ")
+			if password, err = sgcli.readPassword(stdin); err != nil {
+				return err
+			}
+		}
+
+		session := &model.Session{User: &model.User{Username: username, Password: password}}
+		if err := sgcli.Client(c).Sessions.Create(session); err != nil {
+			return err
+		}
+		settings.Token = session.ID
+		settings.AuthType = "session"
+		settings.ExpiresAt = &session.ExpiresAt
+	}
+
+	conf.Contexts[name] = settings
+	conf.CurrentContext = name
+	if err := conf.write(); err != nil {
+		return err
+	}
+	if settings.AuthType == "session" {
+		fmt.Printf("Logged in to %s in context '%s', until %s\n", settings.Server, name, settings.ExpiresAt.Local().Format("2006-01-02 15:04"))
+	} else {
+		fmt.Printf("Logged in to %s in context '%s', with an API token\n", settings.Server, name)
+	}
+	return nil
+}
+
+// commandLogout deletes the Session of the context, if it has one, and the
+// token of the context.
+func (sgcli *CLI) commandLogout(c *cli.Context) error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+	name := conf.contextName(c)
+	settings, ok := conf.Contexts[name]
+	if !ok || settings.Token == "" {
+		return fmt.Errorf("Not logged in, in context '%s'", name)
+	}
+
+	if settings.AuthType == "session" {
+		// Sessions which expired are already gone
+		err := sgcli.Client(c).Sessions.Delete(settings.Token, new(model.Session))
+		if err != nil && !client.IsUnauthorized(err) {
+			return err
+		}
+	}
+	settings.Token = ""
+	settings.AuthType = ""
+	settings.ExpiresAt = nil
+
+	if err := conf.write(); err != nil {
+		return err
+	}
+	fmt.Printf("Logged out, in context '%s'\n", name)
+	return nil
+}
+
+func (sgcli *CLI) commandContext() cli.Command {
+	return cli.Command{
+		Name:  "context",
+		Usage: "actions for the contexts of the config file, each of a server and its credentials",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "list contexts",
+				Action: commandListContexts,
+			},
+			{
+				Name:      "use",
+				Usage:     "make a context current",
+				ArgsUsage: "NAME",
+				Action:    commandUseContext,
+			},
+			{
+				Name:   "current",
+				Usage:  "print the name of the current context",
+				Action: commandCurrentContext,
+			},
+			{
+				Name:      "delete",
+				Usage:     "delete a context",
+				ArgsUsage: "NAME",
+				Action:    commandDeleteContext,
+			},
+		},
+	}
+}
+
+func commandListContexts(c *cli.Context) error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range conf.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tNAME\tSERVER\tAUTH\tEXPIRES")
+	for _, name := range names {
+		settings := conf.Contexts[name]
+		current := ""
+		if name == conf.CurrentContext {
+			current = "*"
+		}
+		auth := settings.AuthType
+		if auth == "" && settings.Token != "" {
+			auth = "token"
+		}
+		expires := ""
+		if settings.ExpiresAt != nil {
+			expires = settings.ExpiresAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, name, settings.Server, auth, expires)
+	}
+	return w.Flush()
+}
+
+func commandUseContext(c *cli.Context) error {
+	conf, name, err := namedContext(c)
+	if err != nil {
+		return err
+	}
+	conf.CurrentContext = name
+	if err := conf.write(); err != nil {
+		return err
+	}
+	fmt.Printf("Switched to context '%s'\n", name)
+	return nil
+}
+
+func commandCurrentContext(c *cli.Context) error {
+	conf, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if conf.CurrentContext == "" {
+		return errors.New("No current context")
+	}
+	fmt.Println(conf.CurrentContext)
+	return nil
+}
+
+func commandDeleteContext(c *cli.Context) error {
+	conf, name, err := namedContext(c)
+	if err != nil {
+		return err
+	}
+	delete(conf.Contexts, name)
+	if conf.CurrentContext == name {
+		conf.CurrentContext = ""
+	}
+	if err := conf.write(); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted context '%s'\n", name)
+	return nil
+}
+
+// namedContext returns the config and the name of the context given as the
+// first argument, which must exist.
+func namedContext(c *cli.Context) (*Config, string, error) {
+	name := c.Args().First()
+	if name == "" {
+		return nil, "", errors.New("Name of the context required")
+	}
+	conf, err := readConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	if _, ok := conf.Contexts[name]; !ok {
+		return nil, "", fmt.Errorf("No context '%s'", name)
+	}
+	return conf, name, nil
+}
+
+//------------------------------------------------------------------------------
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPassword reads a line of stdin, without echoing it if it's a terminal.
+func (sgcli *CLI) readPassword(r *bufio.Reader) (string, error) {
+	if isTerminal(sgcli.Stdin) {
+		sgcli.stty("-echo")
+		defer fmt.Fprintln(os.Stderr)
+		defer sgcli.stty("echo")
+	}
+	return readLine(r)
+}
+
+// stty sets the modes of the terminal of stdin.
+func (sgcli *CLI) stty(args ...string) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = sgcli.Stdin
+	cmd.Run()
+}
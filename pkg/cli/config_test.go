@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withConfigFile runs fn with the config file at a temp path, with content.
+func withConfigFile(content string, fn func()) {
+	dir, _ := ioutil.TempDir("", "supergiant-config")
+	defer os.RemoveAll(dir)
+	original := globalConfFile
+	defer func() { globalConfFile = original }()
+
+	globalConfFile = filepath.Join(dir, ".supergiant")
+	if content != "" {
+		ioutil.WriteFile(globalConfFile, []byte(content), 0600)
+	}
+	fn()
+}
+
+// newFlagsContext returns the context of a command of baseFlags, run with
+// args.
+func newFlagsContext(args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range baseFlags {
+		f.Apply(set)
+	}
+	set.Parse(args)
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestConfigSettings(t *testing.T) {
+	Convey("Commands run with the settings of their context, flags and env vars", t, func() {
+		config := `{
+			"current_context": "staging",
+			"contexts": {
+				"staging": {"server": "https://staging", "token": "staging-session", "auth_type": "session"},
+				"prod": {"server": "https://prod", "token": "prod-token", "cert_file": "prod.pem"}
+			}
+		}`
+
+		table := []struct {
+			// Input
+			config string
+			args   []string
+			env    map[string]string
+			// Expectations
+			settings *GlobalConfig
+		}{
+			// The current context
+			{
+				config:   config,
+				settings: &GlobalConfig{Server: "https://staging", Token: "staging-session", AuthType: "session"},
+			},
+			// Another context, with a flag over it
+			{
+				config:   config,
+				args:     []string{"--context", "prod", "--server", "https://prod:8443"},
+				settings: &GlobalConfig{Server: "https://prod:8443", Token: "prod-token", CertFile: "prod.pem", AuthType: "token"},
+			},
+			// Env vars, such as of CI jobs, over the context
+			{
+				config:   config,
+				env:      map[string]string{"SUPERGIANT_CONTEXT": "prod", "SUPERGIANT_API_TOKEN": "ci-token"},
+				settings: &GlobalConfig{Server: "https://prod", Token: "ci-token", CertFile: "prod.pem", AuthType: "token"},
+			},
+			// An API token given over the context of a Session
+			{
+				config:   config,
+				args:     []string{"--api-token", "other-token"},
+				settings: &GlobalConfig{Server: "https://staging", Token: "other-token", AuthType: "token"},
+			},
+			// A file of before contexts, of a single server
+			{
+				config:   `{"server": "https://old", "token": "old-token", "cert_file": ""}`,
+				settings: &GlobalConfig{Server: "https://old", Token: "old-token", AuthType: "token"},
+			},
+			// No file
+			{
+				settings: &GlobalConfig{AuthType: "token"},
+			},
+		}
+
+		for _, item := range table {
+			for key, value := range item.env {
+				os.Setenv(key, value)
+			}
+			withConfigFile(item.config, func() {
+				conf, err := readConfig()
+				So(err, ShouldBeNil)
+				So(conf.settings(newFlagsContext(item.args...)), ShouldResemble, item.settings)
+			})
+			for key := range item.env {
+				os.Unsetenv(key)
+			}
+		}
+	})
+
+	Convey("Commands fail in contexts which don't exist, or of Sessions which expired", t, func() {
+		expired := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		config := `{
+			"current_context": "staging",
+			"contexts": {
+				"staging": {"server": "https://staging", "token": "staging-session", "auth_type": "session", "expires_at": "` + expired + `"},
+				"prod": {"server": "https://prod", "token": "prod-token"}
+			}
+		}`
+
+		withConfigFile(config, func() {
+			So(checkContext(newFlagsContext("--context", "prod")), ShouldBeNil)
+			So(checkContext(newFlagsContext("--context", "dev")).Error(), ShouldEqual, "No context 'dev'")
+			So(checkContext(newFlagsContext()).Error(), ShouldStartWith, "The session of context 'staging' expired at ")
+			// Unless a token is given
+			So(checkContext(newFlagsContext("--api-token", "ci-token")), ShouldBeNil)
+		})
+	})
+}
+
+func TestCommandLogin(t *testing.T) {
+	Convey("Login stores the token of a Session in the context, which becomes current", t, func() {
+		expiresAt := time.Now().Add(3 * time.Hour).Round(time.Second)
+		var login model.Session
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&login)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&model.Session{ID: "session", ExpiresAt: expiresAt})
+		}))
+		defer srv.Close()
+
+		stdin, _ := ioutil.TempFile("", "stdin")
+		defer os.Remove(stdin.Name())
+		stdin.WriteString("password\n")
+		stdin.Seek(0, os.SEEK_SET)
+
+		withConfigFile(`{"server": "https://old", "token": "old-token"}`, func() {
+			err := New(Client, stdin, "unversioned").Run([]string{"supergiant", "login", "--context", "dev", "-s", srv.URL, "-u", "user"})
+			So(err, ShouldBeNil)
+			So(login.User.Username, ShouldEqual, "user")
+			So(login.User.Password, ShouldEqual, "password")
+
+			conf, err := readConfig()
+			So(err, ShouldBeNil)
+			So(conf.CurrentContext, ShouldEqual, "dev")
+			So(conf.Contexts["dev"].Server, ShouldEqual, srv.URL)
+			So(conf.Contexts["dev"].Token, ShouldEqual, "session")
+			So(conf.Contexts["dev"].AuthType, ShouldEqual, "session")
+			So(conf.Contexts["dev"].ExpiresAt.Equal(expiresAt), ShouldBeTrue)
+			So(conf.Contexts["default"].Token, ShouldEqual, "old-token")
+		})
+	})
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+)
+
+var baseModelType = reflect.TypeOf(model.BaseModel{})
+
+// fieldFlag is the flag of a field of a model.
+type fieldFlag struct {
+	name  string
+	alias string
+	index []int
+	typ   reflect.Type
+}
+
+// fieldFlags returns the flags of the fields of an item which can be given on
+// the command line (strings, numbers, bools and lists of strings), named
+// after their JSON names, such as --kube-name for kube_name. Fields of the
+// name of a parent are also flags of the parent, such as --kube. Readonly
+// fields, and those named as the flags of the command, have none.
+func fieldFlags(item interface{}, commandFlags []cli.Flag) (flags []*fieldFlag) {
+	taken := make(map[string]bool)
+	for _, flag := range commandFlags {
+		for _, name := range strings.Split(flag.GetName(), ",") {
+			taken[strings.TrimSpace(name)] = true
+		}
+	}
+
+	t := reflect.TypeOf(item).Elem()
+	var gather func(t reflect.Type, index []int)
+	gather = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIndex := append(append([]int{}, index...), i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if field.Type != baseModelType {
+					gather(field.Type, fieldIndex)
+				}
+				continue
+			}
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.PkgPath != "" || jsonName == "" || jsonName == "-" || !flagType(field.Type) {
+				continue
+			}
+			if strings.Contains(field.Tag.Get("sg"), "readonly") {
+				continue
+			}
+			name := strings.Replace(jsonName, "_", "-", -1)
+			if taken[name] {
+				continue
+			}
+			flag := &fieldFlag{name: name, index: fieldIndex, typ: field.Type}
+			if parent := strings.TrimSuffix(name, "-name"); parent != name && !taken[parent] {
+				if _, ok := t.FieldByName(strings.TrimSuffix(field.Name, "Name")); ok {
+					flag.alias = parent
+				}
+			}
+			flags = append(flags, flag)
+		}
+	}
+	gather(t, nil)
+	return flags
+}
+
+func flagType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// cliFlag returns the flag itself.
+func (f *fieldFlag) cliFlag() cli.Flag {
+	name := f.name
+	if f.alias != "" {
+		name += ", " + f.alias
+	}
+	usage := "the " + strings.Replace(f.name, "-", " ", -1)
+
+	t := f.typ
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return cli.BoolFlag{Name: name, Usage: usage}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return cli.Int64Flag{Name: name, Usage: usage}
+	case reflect.Float64:
+		return cli.Float64Flag{Name: name, Usage: usage}
+	case reflect.Slice:
+		return cli.StringSliceFlag{Name: name, Usage: usage + ", can be repeated"}
+	}
+	return cli.StringFlag{Name: name, Usage: usage}
+}
+
+// givenAs returns the name the flag was given as, if it was.
+func (f *fieldFlag) givenAs(c *cli.Context) string {
+	if c.IsSet(f.name) {
+		return f.name
+	}
+	if f.alias != "" && c.IsSet(f.alias) {
+		return f.alias
+	}
+	return ""
+}
+
+// set sets the field of an item to the value of the flag, if it was given.
+func (f *fieldFlag) set(c *cli.Context, item interface{}) {
+	name := f.givenAs(c)
+	if name == "" {
+		return
+	}
+	field := reflect.ValueOf(item).Elem().FieldByIndex(f.index)
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		field.SetBool(c.Bool(name))
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		field.SetInt(c.Int64(name))
+	case reflect.Float64:
+		field.SetFloat(c.Float64(name))
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(c.StringSlice(name)))
+	default:
+		field.SetString(c.String(name))
+	}
+}
+
+// withFieldFlags adds the flags of the fields of an item to those of a
+// command.
+func withFieldFlags(item interface{}, flags []cli.Flag) []cli.Flag {
+	for _, flag := range fieldFlags(item, flags) {
+		flags = append(flags, flag.cliFlag())
+	}
+	return flags
+}
+
+// decodeItem decodes the input file into an item, then sets the fields given
+// as flags over it. Either is enough, so the file is only required if no
+// flags of fields were given.
+func (sgcli *CLI) decodeItem(c *cli.Context, item interface{}, commandFlags []cli.Flag) error {
+	flags := fieldFlags(item, commandFlags)
+	given := false
+	for _, flag := range flags {
+		if flag.givenAs(c) != "" {
+			given = true
+		}
+	}
+	if c.String("file") != "" || !given {
+		if err := sgcli.decodeInputFileInto(c, item); err != nil {
+			return err
+		}
+	}
+	for _, flag := range flags {
+		flag.set(c, item)
+	}
+	return nil
+}
@@ -2,50 +2,116 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 
-	"github.com/imdario/mergo"
+	"github.com/jinzhu/inflection"
 	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/model"
 	"github.com/urfave/cli"
+	"k8s.io/helm/pkg/chartutil"
 )
 
-type GlobalConfig struct {
-	Server   string `json:"server"`
-	Token    string `json:"token"`
-	CertFile string `json:"cert_file"`
-}
+//------------------------------------------------------------------------------
 
-func newGlobalConf(c *cli.Context) GlobalConfig {
-	return GlobalConfig{
-		c.String("server"),
-		c.String("api-token"),
-		c.String("cert-file"),
-	}
+// listTypes are the types of the lists of the collections.
+var listTypes = map[string]reflect.Type{
+	"Sessions":              reflect.TypeOf(model.SessionList{}),
+	"Users":                 reflect.TypeOf(model.UserList{}),
+	"CloudAccounts":         reflect.TypeOf(model.CloudAccountList{}),
+	"Kubes":                 reflect.TypeOf(model.KubeList{}),
+	"KubeResources":         reflect.TypeOf(model.KubeResourceList{}),
+	"Nodes":                 reflect.TypeOf(model.NodeList{}),
+	"LoadBalancers":         reflect.TypeOf(model.LoadBalancerList{}),
+	"DNSZones":              reflect.TypeOf(model.DNSZoneList{}),
+	"Ingresses":             reflect.TypeOf(model.IngressList{}),
+	"HelmRepos":             reflect.TypeOf(model.HelmRepoList{}),
+	"HelmCharts":            reflect.TypeOf(model.HelmChartList{}),
+	"HelmReleases":          reflect.TypeOf(model.HelmReleaseList{}),
+	"HelmReleasePromotions": reflect.TypeOf(model.HelmReleasePromotionList{}),
+	"HelmChartPolicies":     reflect.TypeOf(model.HelmChartPolicyList{}),
 }
 
-func Client(c *cli.Context) *client.Client {
-	globalConf := GlobalConfig{}
+// nameFields are the fields items are looked up by when given by name, of
+// the collections which have them.
+var nameFields = map[string]string{
+	"Users":         "username",
+	"CloudAccounts": "name",
+	"Kubes":         "name",
+	"KubeResources": "name",
+	"Nodes":         "name",
+	"LoadBalancers": "name",
+	"DNSZones":      "name",
+	"Ingresses":     "name",
+	"HelmRepos":     "name",
+	"HelmCharts":    "name",
+	"HelmReleases":  "name",
+}
 
-	if b, err := ioutil.ReadFile(globalConfFile); err == nil {
-		// NOTE no error handling here, silently continues
-		json.Unmarshal(b, &globalConf)
+// itemID returns the ID of the item given with --id, or as the first
+// argument. Items with names can be given by name, such as a Kube named prod,
+// which is looked up. Sessions have string IDs, and the others int64 IDs.
+func itemID(c *cli.Context, sg *client.Client, collectionName string) (interface{}, error) {
+	value := c.String("id")
+	if value == "" {
+		value = c.Args().First()
+	}
+	if value == "" {
+		return nil, errors.New("--id required")
+	}
+	if collectionName == "Sessions" {
+		return value, nil
+	}
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return &id, nil
 	}
 
-	conf := newGlobalConf(c)
-
-	if err := mergo.Merge(&conf, globalConf); err != nil {
-		panic(err)
+	field, ok := nameFields[collectionName]
+	if !ok {
+		return nil, fmt.Errorf("Invalid ID '%s', %s are only given by ID", value, collectionName)
+	}
+	list := reflect.New(listTypes[collectionName]).Interface().(model.List)
+	reflect.ValueOf(list).Elem().FieldByName("Query").Set(reflect.ValueOf([]string{field + "=" + value}))
+	items, err := listAll(sg, collectionName, list)
+	if err != nil {
+		return nil, err
+	}
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("No %s named '%s'", inflection.Singular(collectionName), value)
+	case 1:
+		return reflect.ValueOf(items[0]).Elem().FieldByName("ID").Interface(), nil
 	}
+	return nil, fmt.Errorf("%d %s are named '%s', give the ID of one with --id", len(items), collectionName, value)
+}
 
-	return client.New(conf.Server, "token", conf.Token, conf.CertFile)
+// setResourceVersion sets the resource version an update is made from to that
+// of --resource-version, if given.
+func setResourceVersion(c *cli.Context, item model.Model) {
+	versioned, ok := item.(model.Versioned)
+	if version := c.Int64("resource-version"); ok && version > 0 {
+		versioned.SetResourceVersion(version)
+	}
 }
 
-//------------------------------------------------------------------------------
+// updateError explains conflicting updates, which are retried by getting the
+// item again.
+func updateError(collectionName string, id interface{}, err error) error {
+	if !client.IsConflict(err) {
+		return err
+	}
+	if n, ok := id.(*int64); ok {
+		id = *n
+	}
+	return fmt.Errorf("%s\n%s %v was changed since it was read. Get it again, make the update on that, and retry.", err, collectionName, id)
+}
 
 func printObj(obj interface{}) error {
 	out, err := json.MarshalIndent(obj, "", "  ")
@@ -56,7 +122,7 @@ func printObj(obj interface{}) error {
 	return nil
 }
 
-func printList(c *cli.Context, list interface{}) error {
+func printList(c *cli.Context, collectionName string, list interface{}) error {
 	// Optional formatting
 	if format := c.String("format"); format != "" {
 		tmpl, err := template.New("format").Parse(format)
@@ -71,19 +137,80 @@ func printList(c *cli.Context, list interface{}) error {
 		}
 		return nil
 	}
-	return printObj(list)
+	return writeOutput(os.Stdout, c, collectionName, list)
 }
 
-func listFilters(c *cli.Context) (map[string][]string, error) {
+var legacyListFilterRxp = regexp.MustCompile(`^[\w.]+:`)
+
+// listFilters returns the legacy filters (name:this,or,that) and the filter
+// expressions (name!=that) of the filter flags.
+func listFilters(c *cli.Context) (map[string][]string, []string, error) {
 	filters := make(map[string][]string)
+	var query []string
 	for _, filter := range c.StringSlice("filter") {
+		if !legacyListFilterRxp.MatchString(filter) {
+			query = append(query, filter)
+			continue
+		}
 		segments := strings.Split(filter, ":")
 		if len(segments) != 2 {
-			return nil, fmt.Errorf("Invalid filter flag '%s'", filter)
+			return nil, nil, fmt.Errorf("Invalid filter flag '%s'", filter)
 		}
 		field := segments[0]
 		values := strings.Split(segments[1], ",")
 		filters[field] = values
 	}
-	return filters, nil
+	return filters, query, nil
+}
+
+// listSort returns the fields of the sort flag.
+func listSort(c *cli.Context) (fields []string) {
+	for _, field := range strings.Split(c.String("sort"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+//------------------------------------------------------------------------------
+
+// chartArchive returns the packaged chart at the path, packaging it first if
+// it's a directory.
+func chartArchive(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return ioutil.ReadFile(path)
+	}
+
+	chart, err := chartutil.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "supergiant-chart")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	archivePath, err := chartutil.Save(chart, dir)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(archivePath)
+}
+
+// readValuesFiles appends the --values files to the values of a release or
+// promotion.
+func readValuesFiles(c *cli.Context, documents *[]string) error {
+	for _, path := range c.StringSlice("values") {
+		values, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		*documents = append(*documents, string(values))
+	}
+	return nil
 }
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli"
+)
+
+var outputFlag = cli.StringFlag{
+	Name:  "output, o",
+	Value: "json",
+	Usage: "output format, json, yaml, table or wide (table with more columns)",
+}
+
+var columnsFlag = cli.StringFlag{
+	Name:  "columns",
+	Usage: "columns of table output, the fields of the items (such as --columns=id,name,kube.cloud_account_name,status)",
+}
+
+// tableColumns are the columns of the table output of a collection, and those
+// wide output adds.
+type tableColumns struct {
+	table []string
+	wide  []string
+}
+
+// defaultColumns are those of collections which aren't in listColumns.
+var defaultColumns = tableColumns{[]string{"id", "name"}, []string{"created_at"}}
+
+var listColumns = map[string]tableColumns{
+	"CloudAccounts": {[]string{"id", "name", "provider"}, []string{"created_at"}},
+	"Kubes":         {[]string{"id", "name", "cloud_account_name", "kubernetes_version", "ready", "status"}, []string{"master_node_size", "master_public_ip", "node_sizes", "created_at"}},
+	"Nodes":         {[]string{"id", "name", "kube_name", "size", "status"}, []string{"external_ip", "provider_id", "created_at"}},
+	"Sessions":      {[]string{"id", "user.username", "created_at"}, nil},
+	"Users":         {[]string{"id", "username", "role"}, []string{"created_at"}},
+	"KubeResources": {[]string{"id", "name", "kube_name", "namespace", "kind", "status"}, []string{"started", "created_at"}},
+	"DNSZones":      {[]string{"id", "name", "cloud_account_name", "provider"}, []string{"ttl", "created_at"}},
+	"Ingresses":     {[]string{"id", "name", "kube_name", "namespace", "address", "status"}, []string{"tls_secret_name", "certificate_expires_at", "created_at"}},
+	"LoadBalancers": {[]string{"id", "name", "kube_name", "namespace", "address", "status"}, []string{"protocol", "internal", "created_at"}},
+	"HelmRepos":     {[]string{"id", "name", "url"}, []string{"created_at"}},
+	"HelmCharts":    {[]string{"id", "repo_name", "name", "version"}, []string{"deprecated", "description"}},
+	"HelmReleases":  {[]string{"id", "name", "kube_name", "chart_name", "chart_version", "status_value", "status"}, []string{"namespace", "repo_name", "revision", "outdated", "latest_chart_version", "updated_value"}},
+}
+
+// outputColumns returns the columns of table or wide output of a collection,
+// which --columns overrides.
+func outputColumns(c *cli.Context, collectionName string) []string {
+	if columns := c.String("columns"); columns != "" {
+		return strings.Split(columns, ",")
+	}
+	columns, ok := listColumns[collectionName]
+	if !ok {
+		columns = defaultColumns
+	}
+	if c.String("output") == "wide" {
+		return append(append([]string{}, columns.table...), columns.wide...)
+	}
+	return columns.table
+}
+
+// writeOutput writes a list in the format of --output.
+func writeOutput(w io.Writer, c *cli.Context, collectionName string, list interface{}) error {
+	switch output := c.String("output"); output {
+	case "", "json":
+		out, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	case "yaml":
+		return writeYAML(w, list)
+	case "table", "wide":
+		return writeTable(w, outputColumns(c, collectionName), listItems(list))
+	default:
+		return fmt.Errorf("Invalid output '%s', it must be json, yaml, table or wide", output)
+	}
+}
+
+func writeYAML(w io.Writer, obj interface{}) error {
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// listItems returns the Items of a list.
+func listItems(list interface{}) (items []interface{}) {
+	value := reflect.Indirect(reflect.ValueOf(list)).FieldByName("Items")
+	for i := 0; i < value.Len(); i++ {
+		items = append(items, value.Index(i).Interface())
+	}
+	return items
+}
+
+// writeTable writes a row of the columns of each item, under a header of
+// their names.
+func writeTable(w io.Writer, columns []string, items []interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(strings.NewReplacer("_", " ", ".", " ").Replace(column))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, item := range items {
+		fields, err := itemFields(item)
+		if err != nil {
+			return err
+		}
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = columnValue(fields, column)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// itemFields returns the fields of an item as they're rendered in JSON.
+func itemFields(item interface{}) (map[string]interface{}, error) {
+	out, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	return fields, json.Unmarshal(out, &fields)
+}
+
+// columnValue returns the value of a field, nested ones separated by dots.
+// The status column is the status of the Action running on the item, or its
+// passive status.
+func columnValue(fields map[string]interface{}, column string) string {
+	if column == "status" {
+		return itemStatus(fields)
+	}
+	var value interface{} = fields
+	for _, key := range strings.Split(column, ".") {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = nested[key]
+	}
+	return formatValue(value)
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, elem := range v {
+			values[i] = formatValue(elem)
+		}
+		return strings.Join(values, ",")
+	case map[string]interface{}:
+		out, _ := json.Marshal(v)
+		return string(out)
+	}
+	return fmt.Sprint(value)
+}
+
+func itemStatus(fields map[string]interface{}) string {
+	status, ok := fields["status"].(map[string]interface{})
+	if !ok {
+		return formatValue(fields["passive_status"])
+	}
+	description := formatValue(status["description"])
+	if total, _ := status["total_steps"].(float64); total > 0 {
+		completed, _ := status["steps_completed"].(float64)
+		description += " " + progressBar(int(completed), int(total))
+	}
+	if retries, _ := status["retries"].(float64); retries > 0 {
+		description += fmt.Sprintf(" (retry %d/%v)", int(retries), status["max_retries"])
+	}
+	return description
+}
+
+const progressBarWidth = 20
+
+// progressBar renders the steps completed of an Action, such as
+// [##########----------] 3/6.
+func progressBar(completed int, total int) string {
+	if completed > total {
+		completed = total
+	}
+	filled := progressBarWidth * completed / total
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), completed, total)
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteOutput(t *testing.T) {
+	Convey("Lists are written in the format of --output", t, func() {
+		id := int64(1)
+		list := &model.KubeList{Items: []*model.Kube{
+			{
+				BaseModel:        model.BaseModel{ID: &id, Status: &model.ActionStatus{Description: "provisioning", TotalSteps: 4, StepsCompleted: 1, Retries: 1, MaxRetries: 5}},
+				Name:             "test",
+				CloudAccountName: "aws",
+				NodeSizes:        []string{"m4.large", "m4.xlarge"},
+			},
+			{
+				BaseModel: model.BaseModel{PassiveStatus: "running"},
+				Name:      "ready",
+				Ready:     true,
+			},
+		}}
+
+		table := []struct {
+			// Input
+			output  string
+			columns string
+			// Expectations
+			out string
+			err error
+		}{
+			{
+				output: "table",
+				out: "ID  NAME   CLOUD ACCOUNT NAME  KUBERNETES VERSION  READY  STATUS\n" +
+					"1   test   aws                                     false  provisioning [#####---------------] 1/4 (retry 1/5)\n" +
+					"    ready                                          true   running\n",
+			},
+			{
+				output:  "wide",
+				columns: "name,node_sizes,cloud_account.provider",
+				out: "NAME   NODE SIZES          CLOUD ACCOUNT PROVIDER\n" +
+					"test   m4.large,m4.xlarge  \n" +
+					"ready                      \n",
+			},
+			{
+				output: "csv",
+				err:    errors.New("Invalid output 'csv', it must be json, yaml, table or wide"),
+			},
+		}
+
+		for _, item := range table {
+			set := flag.NewFlagSet("list", flag.ContinueOnError)
+			set.String("output", item.output, "")
+			set.String("columns", item.columns, "")
+			out := new(bytes.Buffer)
+
+			err := writeOutput(out, cli.NewContext(nil, set, nil), "Kubes", list)
+
+			So(err, ShouldResemble, item.err)
+			So(out.String(), ShouldEqual, item.out)
+		}
+	})
+
+	Convey("YAML output has the JSON names of fields", t, func() {
+		out := new(bytes.Buffer)
+		err := writeYAML(out, &model.User{Username: "bossman", Role: "admin"})
+
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldContainSubstring, "username: bossman\n")
+		So(out.String(), ShouldContainSubstring, "role: admin\n")
+	})
+}
+
+func TestWatchViewErrors(t *testing.T) {
+	Convey("A watch has the tail of the errors of Actions, each once", t, func() {
+		view := newWatchView()
+		view.now = func() time.Time { return time.Date(2017, 1, 1, 15, 4, 5, 0, time.UTC) }
+
+		failing := &model.Node{Name: "node-1", BaseModel: model.BaseModel{Status: &model.ActionStatus{Error: "quota exceeded"}}}
+		for i := 0; i < 2; i++ {
+			So(view.observeErrors("Nodes", []interface{}{failing, &model.Node{Name: "node-2"}}), ShouldBeNil)
+		}
+		out := new(bytes.Buffer)
+		view.writeErrors(out)
+
+		So(out.String(), ShouldEqual, "Errors\n15:04:05  Node node-1: quota exceeded\n")
+	})
+}
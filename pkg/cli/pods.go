@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+)
+
+var containerFlag = cli.StringFlag{
+	Name:  "container",
+	Usage: "container of the Pod, which may be omitted if it has one",
+}
+
+func (sgcli *CLI) commandLogs() cli.Command {
+	flags := append(baseFlags, idFlag, containerFlag,
+		cli.BoolFlag{
+			Name:  "follow",
+			Usage: "stream the log as it's written, until the container stops",
+		},
+		cli.StringFlag{
+			Name:  "tail",
+			Usage: "number of lines from the end of the log to start from",
+		},
+		cli.DurationFlag{
+			Name:  "since",
+			Usage: "how far back the log starts from, such as 10m",
+		},
+	)
+	return cli.Command{
+		Name:         "logs",
+		Usage:        "show the log of a Pod KubeResource",
+		ArgsUsage:    "[KUBE_RESOURCE]",
+		Flags:        flags,
+		BashComplete: sgcli.complete("KubeResources", flags),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, "KubeResources")
+			if err != nil {
+				return err
+			}
+
+			opts := &model.PodLogOptions{Container: c.String("container"), Follow: c.Bool("follow")}
+			if tail := c.String("tail"); tail != "" {
+				lines, err := strconv.ParseInt(tail, 10, 64)
+				if err != nil {
+					return fmt.Errorf("Invalid --tail '%s', it must be a number of lines", tail)
+				}
+				opts.TailLines = &lines
+			}
+			if since := c.Duration("since"); since > 0 {
+				seconds := int64((since + time.Second - 1) / time.Second)
+				opts.SinceSeconds = &seconds
+			}
+			return sg.KubeResources.Logs(id.(*int64), opts, os.Stdout)
+		},
+	}
+}
+
+// commandExec runs the command given after the Pod KubeResource (and --) in a
+// container of it, as kubectl exec does.
+func (sgcli *CLI) commandExec() cli.Command {
+	flags := append(baseFlags, idFlag, containerFlag,
+		cli.BoolFlag{
+			Name:  "stdin, i",
+			Usage: "send stdin to the command",
+		},
+		cli.BoolFlag{
+			Name:  "tty",
+			Usage: "allocate a terminal for the command, such as for a shell",
+		},
+	)
+	return cli.Command{
+		Name:         "exec",
+		Usage:        "run a command in a container of a Pod KubeResource",
+		ArgsUsage:    "[KUBE_RESOURCE] -- COMMAND [ARGS...]",
+		Flags:        flags,
+		BashComplete: sgcli.complete("KubeResources", flags),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			id, err := itemID(c, sg, "KubeResources")
+			if err != nil {
+				return err
+			}
+
+			command := []string(c.Args())
+			if c.String("id") == "" && len(command) > 0 {
+				command = command[1:]
+			}
+			if len(command) > 0 && command[0] == "--" {
+				command = command[1:]
+			}
+			if len(command) == 0 {
+				return errors.New("Command required, after the KubeResource and --")
+			}
+
+			opts := &model.PodExecOptions{
+				Container: c.String("container"),
+				Command:   command,
+				Stdin:     c.Bool("stdin"),
+				TTY:       c.Bool("tty"),
+			}
+			conn, err := sg.KubeResources.Exec(id.(*int64), opts)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			if opts.TTY && isTerminal(sgcli.Stdin) {
+				sgcli.stty("raw", "-echo")
+				defer sgcli.stty("-raw", "echo")
+			}
+			var stdin io.Reader
+			if opts.Stdin {
+				stdin = sgcli.Stdin
+			}
+			return runExecSession(conn, stdin, os.Stdout, os.Stderr)
+		},
+	}
+}
+
+// The streams of exec sessions, which prefix each message.
+const (
+	execStdin byte = iota
+	execStdout
+	execStderr
+	execStatus
+)
+
+// execStatusMessage is the status of the command of a v4.channel.k8s.io exec
+// session, sent once it exits.
+type execStatusMessage struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Details struct {
+		Causes []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"causes"`
+	} `json:"details"`
+}
+
+// runExecSession sends stdin (if any) to the command of an exec session, and
+// writes its stdout and stderr, until the session ends. Commands which exit
+// with a non-zero code return an error with the same exit code.
+func runExecSession(conn *websocket.Conn, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if stdin != nil {
+		go func() {
+			buf := make([]byte, 4096)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 {
+					if conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStdin}, buf[:n]...)) != nil {
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	var status error
+	exited := false
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if exited || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return status
+			}
+			return err
+		}
+		if len(message) < 2 {
+			continue
+		}
+		switch message[0] {
+		case execStdout:
+			stdout.Write(message[1:])
+		case execStderr:
+			stderr.Write(message[1:])
+		case execStatus:
+			status, exited = execStatusError(conn.Subprotocol(), message[1:]), true
+		}
+	}
+}
+
+// execStatusError returns the error of the status a command exited with, or nil
+// if it succeeded.
+func execStatusError(protocol string, message []byte) error {
+	// Before v4, only errors are sent, as text
+	if protocol != "v4.channel.k8s.io" {
+		return errors.New(string(message))
+	}
+	status := new(execStatusMessage)
+	if err := json.Unmarshal(message, status); err != nil {
+		return errors.New(string(message))
+	}
+	if status.Status == "Success" {
+		return nil
+	}
+	if status.Reason == "NonZeroExitCode" {
+		for _, cause := range status.Details.Causes {
+			if code, err := strconv.Atoi(cause.Message); err == nil && cause.Reason == "ExitCode" {
+				return cli.NewExitError("", code)
+			}
+		}
+	}
+	return errors.New(status.Message)
+}
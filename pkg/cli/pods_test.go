@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/urfave/cli"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunExecSession(t *testing.T) {
+	Convey("Exec sessions write the output of the command, and return how it exited", t, func() {
+		table := []struct {
+			// Input
+			stdin string
+			// Mocks
+			protocol string
+			status   string
+			// Expectations
+			stdout string
+			stderr string
+			err    error
+		}{
+			// A command which succeeds
+			{
+				stdin:    "hello",
+				protocol: "v4.channel.k8s.io",
+				status:   `{"status":"Success"}`,
+				stdout:   "hello",
+				stderr:   "warning",
+			},
+			// A command which exits with a code
+			{
+				protocol: "v4.channel.k8s.io",
+				status:   `{"status":"Failure","message":"command terminated with non-zero exit code","reason":"NonZeroExitCode","details":{"causes":[{"reason":"ExitCode","message":"3"}]}}`,
+				stderr:   "warning",
+				err:      cli.NewExitError("", 3),
+			},
+			// Errors before v4 are text
+			{
+				protocol: "channel.k8s.io",
+				status:   "executable file not found",
+				stderr:   "warning",
+				err:      errors.New("executable file not found"),
+			},
+		}
+
+		for _, item := range table {
+			upgrader := websocket.Upgrader{Subprotocols: []string{item.protocol}}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				if item.stdin != "" {
+					_, message, _ := conn.ReadMessage()
+					conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStdout}, message[1:]...))
+				}
+				conn.WriteMessage(websocket.BinaryMessage, []byte{execStdout})
+				conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStderr}, "warning"...))
+				conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStatus}, item.status...))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			}))
+
+			dialer := &websocket.Dialer{Subprotocols: []string{"v4.channel.k8s.io", "channel.k8s.io"}}
+			conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			So(err, ShouldBeNil)
+
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			var stdin io.Reader
+			if item.stdin != "" {
+				stdin = strings.NewReader(item.stdin)
+			}
+			err = runExecSession(conn, stdin, stdout, stderr)
+
+			So(err, ShouldResemble, item.err)
+			So(stdout.String(), ShouldEqual, item.stdout)
+			So(stderr.String(), ShouldEqual, item.stderr)
+
+			conn.Close()
+			srv.Close()
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/inflection"
+	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/urfave/cli"
+)
+
+// maxWatchErrors is the length of the tail of errors of a watch.
+const maxWatchErrors = 10
+
+var watchFlags = []cli.Flag{
+	cli.DurationFlag{
+		Name:  "interval",
+		Value: 2 * time.Second,
+		Usage: "time between refreshes",
+	},
+	cli.IntFlag{
+		Name:  "count, n",
+		Usage: "number of refreshes before exiting, 0 to refresh until interrupted",
+	},
+}
+
+// watchSection is a table of the items of a collection in a watch.
+type watchSection struct {
+	title          string
+	collectionName string
+	list           model.List
+	columns        []string
+}
+
+// actionCollections are those of items which Actions run on.
+var actionCollections = []string{"Kubes", "Nodes", "KubeResources", "LoadBalancers", "Ingresses", "HelmReleases"}
+
+func (sgcli *CLI) commandWatchKubes() cli.Command {
+	return cli.Command{
+		Name:  "watch",
+		Usage: "watch Kubes and their Nodes, with the progress of running Actions and their errors",
+		Flags: append(baseFlags, watchFlags...),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			view := newWatchView()
+			return watch(c, func(w io.Writer) error {
+				return view.writeSections(w, sg, []*watchSection{
+					{"Kubes", "Kubes", new(model.KubeList), []string{"id", "name", "cloud_account_name", "ready", "status"}},
+					{"Nodes", "Nodes", new(model.NodeList), []string{"id", "name", "kube_name", "size", "status"}},
+				})
+			})
+		},
+	}
+}
+
+func (sgcli *CLI) commandWatchActions() cli.Command {
+	return cli.Command{
+		Name:  "watch",
+		Usage: "watch the Actions running on Kubes, Nodes, Kube Resources, Load Balancers, Ingresses and Helm Releases, and their errors",
+		Flags: append(baseFlags, watchFlags...),
+		Action: func(c *cli.Context) error {
+			sg := sgcli.Client(c)
+			view := newWatchView()
+			return watch(c, func(w io.Writer) error {
+				return view.writeActions(w, sg)
+			})
+		},
+	}
+}
+
+// watch writes a view every --interval, over the one before when writing to
+// a terminal.
+func watch(c *cli.Context, write func(io.Writer) error) error {
+	interactive := isTerminal(os.Stdout)
+	for i := 0; c.Int("count") == 0 || i < c.Int("count"); i++ {
+		if i > 0 {
+			time.Sleep(c.Duration("interval"))
+		}
+		buf := new(bytes.Buffer)
+		if err := write(buf); err != nil {
+			return err
+		}
+		if interactive {
+			// Move to the top left and clear the screen
+			fmt.Fprint(os.Stdout, "\033[H\033[2J")
+		}
+		fmt.Fprintf(os.Stdout, "Every %s: %s\n\n", c.Duration("interval"), time.Now().Format(time.RFC1123))
+		buf.WriteTo(os.Stdout)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+//------------------------------------------------------------------------------
+
+// watchView renders the items of a watch, and keeps the tail of the errors
+// of the Actions running on them.
+type watchView struct {
+	errors []*watchError
+	// lastErrors are the last errors of each item, so they're in the tail once.
+	lastErrors map[string]string
+	now        func() time.Time
+}
+
+type watchError struct {
+	at      time.Time
+	item    string
+	message string
+}
+
+func newWatchView() *watchView {
+	return &watchView{lastErrors: make(map[string]string), now: time.Now}
+}
+
+func (v *watchView) writeSections(w io.Writer, sg *client.Client, sections []*watchSection) error {
+	for _, section := range sections {
+		items, err := listAll(sg, section.collectionName, section.list)
+		if err != nil {
+			return err
+		}
+		if err := v.observeErrors(section.collectionName, items); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, section.title)
+		if err := writeTable(w, section.columns, items); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	v.writeErrors(w)
+	return nil
+}
+
+func (v *watchView) writeActions(w io.Writer, sg *client.Client) error {
+	var running []interface{}
+	for _, collectionName := range actionCollections {
+		list := reflect.New(listTypes[collectionName]).Interface().(model.List)
+		items, err := listAll(sg, collectionName, list)
+		if err != nil {
+			return err
+		}
+		if err := v.observeErrors(collectionName, items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			fields, err := itemFields(item)
+			if err != nil {
+				return err
+			}
+			if fields["status"] == nil {
+				continue
+			}
+			fields["resource"] = inflection.Singular(collectionName)
+			running = append(running, fields)
+		}
+	}
+
+	fmt.Fprintln(w, "Actions")
+	if err := writeTable(w, []string{"resource", "id", "name", "kube_name", "status"}, running); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	v.writeErrors(w)
+	return nil
+}
+
+// observeErrors adds the errors of the Actions of items to the tail, when
+// they're new.
+func (v *watchView) observeErrors(collectionName string, items []interface{}) error {
+	for _, item := range items {
+		fields, err := itemFields(item)
+		if err != nil {
+			return err
+		}
+		status, _ := fields["status"].(map[string]interface{})
+		message := formatValue(status["error"])
+		name := formatValue(fields["name"])
+		if name == "" {
+			name = formatValue(fields["id"])
+		}
+		key := inflection.Singular(collectionName) + " " + name
+		if message == "" || v.lastErrors[key] == message {
+			continue
+		}
+		v.lastErrors[key] = message
+		v.errors = append(v.errors, &watchError{v.now(), key, message})
+		if len(v.errors) > maxWatchErrors {
+			v.errors = v.errors[len(v.errors)-maxWatchErrors:]
+		}
+	}
+	return nil
+}
+
+func (v *watchView) writeErrors(w io.Writer) {
+	if len(v.errors) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Errors")
+	for _, err := range v.errors {
+		fmt.Fprintf(w, "%s  %s: %s\n", err.at.Format("15:04:05"), err.item, err.message)
+	}
+}
+
+// listAll lists every item of a collection.
+func listAll(sg *client.Client, collectionName string, list model.List) ([]interface{}, error) {
+	// Otherwise the items of the refresh before would be decoded into
+	items := reflect.ValueOf(list).Elem().FieldByName("Items")
+	items.Set(reflect.Zero(items.Type()))
+
+	fn := reflect.ValueOf(sg).Elem().FieldByName(collectionName).MethodByName("List")
+	if err := fn.Call([]reflect.Value{reflect.ValueOf(list)})[0].Interface(); err != nil {
+		return nil, err.(error)
+	}
+	return listItems(list), nil
+}
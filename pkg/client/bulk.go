@@ -0,0 +1,17 @@
+package client
+
+import "github.com/supergiant/supergiant/pkg/model"
+
+type BulkInterface interface {
+	Run(*model.Bulk) error
+}
+
+type Bulk struct {
+	client *Client
+}
+
+// Run runs the operations of a Bulk, and sets its Results. Operations fail on
+// their own, so each result has to be checked.
+func (c *Bulk) Run(m *model.Bulk) error {
+	return c.client.request("POST", "bulk", m, m, nil)
+}
@@ -6,10 +6,14 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/satori/go.uuid"
 	"github.com/supergiant/supergiant/pkg/model"
 )
 
@@ -29,9 +33,16 @@ type Client struct {
 	KubeResources KubeResourcesInterface
 	Nodes         NodesInterface
 	LoadBalancers LoadBalancersInterface
+	DNSZones      DNSZonesInterface
+	Ingresses     IngressesInterface
 	HelmRepos     HelmReposInterface
 	HelmCharts    HelmChartsInterface
 	HelmReleases  HelmReleasesInterface
+
+	HelmReleasePromotions HelmReleasePromotionsInterface
+	HelmChartPolicies     HelmChartPoliciesInterface
+
+	Bulk BulkInterface
 }
 
 func New(url string, authType string, authToken string, certFile string) *Client {
@@ -68,13 +79,25 @@ func New(url string, authType string, authToken string, certFile string) *Client
 	client.KubeResources = &KubeResources{Collection{client, "kube_resources"}}
 	client.Nodes = &Nodes{Collection{client, "nodes"}}
 	client.LoadBalancers = &LoadBalancers{Collection{client, "load_balancers"}}
+	client.DNSZones = &DNSZones{Collection{client, "dns_zones"}}
+	client.Ingresses = &Ingresses{Collection{client, "ingresses"}}
 	client.HelmRepos = &HelmRepos{Collection{client, "helm_repos"}}
 	client.HelmCharts = &HelmCharts{Collection{client, "helm_charts"}}
 	client.HelmReleases = &HelmReleases{Collection{client, "helm_releases"}}
+	client.HelmReleasePromotions = &HelmReleasePromotions{Collection{client, "helm_release_promotions"}}
+	client.HelmChartPolicies = &HelmChartPolicies{Collection{client, "helm_chart_policies"}}
+	client.Bulk = &Bulk{client}
 
 	return client
 }
 
+// requestRetries is the number of times requests which fail to be made (such
+// as when the connection drops) are retried. Creates and actions are retried
+// with the same Idempotency-Key, so they're made at most once.
+const requestRetries = 2
+
+var requestRetryWait = time.Second
+
 func (c *Client) request(method string, path string, in interface{}, out interface{}, queryValues map[string][]string) error {
 	body := new(bytes.Buffer)
 	if in != nil {
@@ -83,7 +106,7 @@ func (c *Client) request(method string, path string, in interface{}, out interfa
 		}
 	}
 
-	requestURL, err := url.Parse(c.BaseURL + "/api/v0/" + path)
+	requestURL, err := url.Parse(c.BaseURL + "/api/v1/" + path)
 	if err != nil {
 		return err
 	}
@@ -96,32 +119,43 @@ func (c *Client) request(method string, path string, in interface{}, out interfa
 	}
 	requestURL.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(method, requestURL.String(), body)
-	if err != nil {
-		return err
+	var idempotencyKey string
+	if method == "POST" {
+		idempotencyKey = uuid.NewV4().String()
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf(`SGAPI %s="%s"`, c.AuthType, c.AuthToken))
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, requestURL.String(), bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return err
+		}
 
-	req.Close = true
+		req.Header.Set("Authorization", fmt.Sprintf(`SGAPI %s="%s"`, c.AuthType, c.AuthToken))
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
+		req.Close = true
+
+		resp, err = c.httpClient.Do(req)
+		if err == nil {
+			break
+		}
+		if attempt == requestRetries || (method != "GET" && method != "POST") {
+			return err
+		}
+		time.Sleep(requestRetryWait)
 	}
 
 	if resp.Status[:2] != "20" {
+		return responseError(resp)
+	}
+
+	if w, ok := out.(io.Writer); ok {
 		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		errModel := new(model.Error)
-		if err := json.Unmarshal(body, errModel); err != nil {
-			// If unmarshalling failed, we have to fallback to capturing the full text
-			errModel.Message = string(body)
-		}
-		return errModel
+		_, err = io.Copy(w, resp.Body)
+		return err
 	}
 
 	if out != nil {
@@ -133,3 +167,67 @@ func (c *Client) request(method string, path string, in interface{}, out interfa
 
 	return nil
 }
+
+// responseError returns the error a response responded with.
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	errModel := new(model.Error)
+	if err := json.Unmarshal(body, errModel); err != nil {
+		// If unmarshalling failed, we have to fallback to capturing the full text
+		errModel.Message = string(body)
+	}
+	return errModel
+}
+
+// dial opens a WebSocket to the API, such as that of an exec session.
+func (c *Client) dial(path string, queryValues url.Values, subprotocols []string) (*websocket.Conn, error) {
+	requestURL, err := url.Parse(c.BaseURL + "/api/v1/" + path)
+	if err != nil {
+		return nil, err
+	}
+	if requestURL.Scheme == "https" {
+		requestURL.Scheme = "wss"
+	} else {
+		requestURL.Scheme = "ws"
+	}
+	requestURL.RawQuery = queryValues.Encode()
+
+	dialer := &websocket.Dialer{
+		Proxy:        http.ProxyFromEnvironment,
+		Subprotocols: subprotocols,
+	}
+	if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	header := make(http.Header)
+	header.Set("Authorization", fmt.Sprintf(`SGAPI %s="%s"`, c.AuthType, c.AuthToken))
+
+	conn, resp, err := dialer.Dial(requestURL.String(), header)
+	if err == websocket.ErrBadHandshake {
+		return nil, responseError(resp)
+	}
+	return conn, err
+}
+
+// Log writes the end of the log of the server.
+func (c *Client) Log(w io.Writer) error {
+	return c.request("GET", "log", nil, w, nil)
+}
+
+// IsConflict returns whether an error is that of an update of an item which
+// changed since the resource version it was made from.
+func IsConflict(err error) bool {
+	errModel, ok := err.(*model.Error)
+	return ok && errModel.Status == http.StatusConflict
+}
+
+// IsUnauthorized returns whether an error is that of a request whose token or
+// Session doesn't authenticate a User, such as a Session which expired.
+func IsUnauthorized(err error) bool {
+	errModel, ok := err.(*model.Error)
+	return ok && errModel.Status == http.StatusUnauthorized
+}
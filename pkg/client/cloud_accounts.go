@@ -2,8 +2,14 @@ package client
 
 type CloudAccountsInterface interface {
 	CollectionInterface
+	Schema(*map[string]interface{}) error
 }
 
 type CloudAccounts struct {
 	Collection
 }
+
+// Schema gets the schema of the credentials of each provider.
+func (c *CloudAccounts) Schema(schema *map[string]interface{}) error {
+	return c.client.request("GET", c.basePath+"/schema", nil, schema, nil)
+}
@@ -10,6 +10,7 @@ import (
 // We don't use this directly, but instead compose other fake collections with
 type CollectionInterface interface {
 	List(model.List) error
+	Iterate(model.List) *ListIterator
 	Create(model.Model) error
 	Get(interface{}, model.Model) error
 	GetWithIncludes(interface{}, model.Model, []string) error
@@ -26,6 +27,11 @@ func (c *Collection) List(list model.List) error {
 	return c.client.request("GET", c.basePath, nil, list, list.QueryValues())
 }
 
+// Iterate returns an iterator of every item of the list, page by page.
+func (c *Collection) Iterate(list model.List) *ListIterator {
+	return NewListIterator(c, list)
+}
+
 func (c *Collection) Get(id interface{}, item model.Model) error {
 	return c.client.request("GET", c.memberPath(id), nil, item, nil)
 }
@@ -0,0 +1,9 @@
+package client
+
+type DNSZonesInterface interface {
+	CollectionInterface
+}
+
+type DNSZones struct {
+	Collection
+}
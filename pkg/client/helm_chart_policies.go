@@ -0,0 +1,9 @@
+package client
+
+type HelmChartPoliciesInterface interface {
+	CollectionInterface
+}
+
+type HelmChartPolicies struct {
+	Collection
+}
@@ -1,9 +1,34 @@
 package client
 
+import (
+	"strconv"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
 type HelmChartsInterface interface {
 	CollectionInterface
+	Upload(*model.HelmChartUpload, *model.HelmChart) error
+	Search(*model.HelmChartSearch, *model.HelmChartList) error
 }
 
 type HelmCharts struct {
 	Collection
 }
+
+func (c *HelmCharts) Upload(upload *model.HelmChartUpload, m *model.HelmChart) error {
+	return c.client.request("POST", c.basePath+"/upload", upload, m, nil)
+}
+
+func (c *HelmCharts) Search(search *model.HelmChartSearch, list *model.HelmChartList) error {
+	queryValues := map[string][]string{
+		"q":         {search.Query},
+		"repo_name": {search.RepoName},
+		"kube_name": {search.KubeName},
+		"latest":    {strconv.FormatBool(search.Latest)},
+	}
+	if search.Deprecated != nil {
+		queryValues["deprecated"] = []string{strconv.FormatBool(*search.Deprecated)}
+	}
+	return c.client.request("GET", c.basePath+"/search", nil, list, queryValues)
+}
@@ -0,0 +1,21 @@
+package client
+
+import "github.com/supergiant/supergiant/pkg/model"
+
+type HelmReleasePromotionsInterface interface {
+	CollectionInterface
+	Approve(*int64, *model.HelmReleasePromotion) error
+	Reject(*int64, *model.HelmReleasePromotion) error
+}
+
+type HelmReleasePromotions struct {
+	Collection
+}
+
+func (c *HelmReleasePromotions) Approve(id *int64, m *model.HelmReleasePromotion) error {
+	return c.client.request("POST", c.memberPath(id)+"/approve", nil, m, nil)
+}
+
+func (c *HelmReleasePromotions) Reject(id *int64, m *model.HelmReleasePromotion) error {
+	return c.client.request("POST", c.memberPath(id)+"/reject", nil, m, nil)
+}
@@ -1,9 +1,31 @@
 package client
 
+import "github.com/supergiant/supergiant/pkg/model"
+
 type HelmReleasesInterface interface {
 	CollectionInterface
+	History(*int64, *model.HelmReleaseHistory) error
+	Rollback(*int64, *model.HelmReleaseRollback, *model.HelmRelease) error
+	Promote(*int64, *model.HelmReleasePromotion) error
+	Drift(*int64, *model.HelmReleaseDrift) error
 }
 
 type HelmReleases struct {
 	Collection
 }
+
+func (c *HelmReleases) History(id *int64, history *model.HelmReleaseHistory) error {
+	return c.client.request("GET", c.memberPath(id)+"/history", nil, history, nil)
+}
+
+func (c *HelmReleases) Rollback(id *int64, rollback *model.HelmReleaseRollback, m *model.HelmRelease) error {
+	return c.client.request("POST", c.memberPath(id)+"/rollback", rollback, m, nil)
+}
+
+func (c *HelmReleases) Promote(id *int64, promotion *model.HelmReleasePromotion) error {
+	return c.client.request("POST", c.memberPath(id)+"/promote", promotion, promotion, nil)
+}
+
+func (c *HelmReleases) Drift(id *int64, drift *model.HelmReleaseDrift) error {
+	return c.client.request("GET", c.memberPath(id)+"/drift", nil, drift, nil)
+}
@@ -0,0 +1,9 @@
+package client
+
+type IngressesInterface interface {
+	CollectionInterface
+}
+
+type Ingresses struct {
+	Collection
+}
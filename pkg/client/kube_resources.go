@@ -1,11 +1,20 @@
 package client
 
-import "github.com/supergiant/supergiant/pkg/model"
+import (
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type KubeResourcesInterface interface {
 	CollectionInterface
 	Start(*int64, *model.KubeResource) error
 	Stop(*int64, *model.KubeResource) error
+	Logs(*int64, *model.PodLogOptions, io.Writer) error
+	Exec(*int64, *model.PodExecOptions) (*websocket.Conn, error)
 }
 
 type KubeResources struct {
@@ -19,3 +28,39 @@ func (c *KubeResources) Start(id *int64, m *model.KubeResource) error {
 func (c *KubeResources) Stop(id *int64, m *model.KubeResource) error {
 	return c.client.request("POST", c.memberPath(id)+"/stop", nil, m, nil)
 }
+
+// Logs writes the log of a Pod to w, which is streamed as it's written if the
+// options follow it.
+func (c *KubeResources) Logs(id *int64, opts *model.PodLogOptions, w io.Writer) error {
+	query := make(url.Values)
+	if opts.Container != "" {
+		query.Set("container", opts.Container)
+	}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+	if opts.TailLines != nil {
+		query.Set("tail", strconv.FormatInt(*opts.TailLines, 10))
+	}
+	if opts.SinceSeconds != nil {
+		query.Set("since", strconv.FormatInt(*opts.SinceSeconds, 10))
+	}
+	return c.client.request("GET", c.memberPath(id)+"/logs", nil, w, query)
+}
+
+// ExecProtocols are the subprotocols of exec sessions, which are those of
+// kubectl exec, newest first.
+var ExecProtocols = []string{"v4.channel.k8s.io", "channel.k8s.io"}
+
+// Exec runs a command in a container of a Pod, returning the WebSocket of the
+// session. Each message is prefixed with the byte of its stream: 0 for stdin,
+// 1 for stdout, 2 for stderr and 3 for the status of the command.
+func (c *KubeResources) Exec(id *int64, opts *model.PodExecOptions) (*websocket.Conn, error) {
+	query := url.Values{"command": opts.Command}
+	if opts.Container != "" {
+		query.Set("container", opts.Container)
+	}
+	query.Set("stdin", strconv.FormatBool(opts.Stdin))
+	query.Set("tty", strconv.FormatBool(opts.TTY))
+	return c.client.dial(c.memberPath(id)+"/exec", query, ExecProtocols)
+}
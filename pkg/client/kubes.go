@@ -5,6 +5,8 @@ import "github.com/supergiant/supergiant/pkg/model"
 type KubesInterface interface {
 	CollectionInterface
 	Provision(*int64, *model.Kube) error
+	Costs(*int64, string, *model.KubeCosts) error
+	Chargeback(string, *model.Chargeback) error
 }
 
 type Kubes struct {
@@ -14,3 +16,22 @@ type Kubes struct {
 func (c *Kubes) Provision(id *int64, m *model.Kube) error {
 	return c.client.request("POST", c.memberPath(id)+"/provision", nil, m, nil)
 }
+
+// Costs gets the costs of a Kube in a month (as 2006-01), the current one if
+// empty.
+func (c *Kubes) Costs(id *int64, month string, m *model.KubeCosts) error {
+	return c.client.request("GET", c.memberPath(id)+"/costs", nil, m, costMonthValues(month))
+}
+
+// Chargeback gets the costs of every Kube by namespace in a month, the
+// current one if empty.
+func (c *Kubes) Chargeback(month string, m *model.Chargeback) error {
+	return c.client.request("GET", "costs", nil, m, costMonthValues(month))
+}
+
+func costMonthValues(month string) map[string][]string {
+	if month == "" {
+		return nil
+	}
+	return map[string][]string{"month": {month}}
+}
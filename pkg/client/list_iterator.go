@@ -0,0 +1,91 @@
+package client
+
+import (
+	"reflect"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// DefaultIteratorPageSize is the Limit of the pages of a ListIterator when the
+// list has none.
+const DefaultIteratorPageSize = 100
+
+// Lister is anything that lists, such as a Collection.
+type Lister interface {
+	List(model.List) error
+}
+
+// ListIterator walks every item of a list, requesting a page at a time by
+// cursor:
+//
+//	iter := sg.KubeResources.Iterate(new(model.KubeResourceList))
+//	for iter.Next() {
+//		resource := iter.Item().(*model.KubeResource)
+//	}
+//	if err := iter.Err(); err != nil {
+//		...
+//	}
+//
+// The filters, sort and fields of the list apply to every page.
+type ListIterator struct {
+	lister Lister
+	list   model.List
+
+	items  reflect.Value
+	index  int
+	cursor string
+	paged  bool
+	err    error
+}
+
+// NewListIterator returns an iterator of the items the lister lists.
+func NewListIterator(lister Lister, list model.List) *ListIterator {
+	return &ListIterator{lister: lister, list: list, index: -1}
+}
+
+// Next advances to the next item, requesting the next page when needed. It
+// returns false when there are no more items, or on error.
+func (it *ListIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.items.IsValid() && it.index+1 < it.items.Len() {
+		it.index++
+		return true
+	}
+	if it.paged && it.cursor == "" {
+		return false
+	}
+
+	listValue := reflect.ValueOf(it.list).Elem()
+	baseList := listValue.FieldByName("BaseList").Addr().Interface().(*model.BaseList)
+	if baseList.Limit == 0 {
+		baseList.Limit = DefaultIteratorPageSize
+	}
+	cursor := it.cursor
+	baseList.Cursor = &cursor
+	baseList.NextCursor = ""
+
+	// Otherwise the items of the page before would be decoded into
+	items := listValue.FieldByName("Items")
+	items.Set(reflect.Zero(items.Type()))
+
+	if it.err = it.lister.List(it.list); it.err != nil {
+		return false
+	}
+	it.paged = true
+	it.cursor = baseList.NextCursor
+	it.items = items
+	it.index = 0
+	return items.Len() > 0
+}
+
+// Item returns the current item.
+func (it *ListIterator) Item() model.Model {
+	return it.items.Index(it.index).Interface().(model.Model)
+}
+
+// Err returns the error of the last request, if any.
+func (it *ListIterator) Err() error {
+	return it.err
+}
@@ -53,7 +53,11 @@ func (a *Action) Now() error {
 	// Remove Action from map regardless of success or failure
 	defer a.stopUnlessCancelled()
 
-	return a.Fn(a)
+	err := a.Fn(a)
+	if err != nil {
+		a.Core.Metrics.actionFailed(a)
+	}
+	return err
 }
 
 func (a *Action) Async() error {
@@ -79,6 +83,7 @@ func (a *Action) Async() error {
 			a.Core.Log.Error(err)
 
 			if a.Status.Retries >= a.Status.MaxRetries {
+				a.Core.Metrics.actionFailed(a)
 				return // Don't goto Remove from Actions
 			}
 
@@ -86,6 +91,7 @@ func (a *Action) Async() error {
 			time.Sleep(time.Second)
 
 			a.Status.Retries++
+			a.Core.Metrics.actionRetried(a)
 		}
 
 		// Remove from Actions
@@ -111,8 +117,17 @@ func (a *Action) GetStatus() *model.ActionStatus {
 // Private
 
 func (a *Action) description() string {
-	modelType := strings.Split(reflect.TypeOf(a.Model).String(), ".")[1]
-	return fmt.Sprintf("%s %s %s", a.Status.Description, modelType, a.ResourceID)
+	return fmt.Sprintf("%s %s %s", a.Status.Description, a.resourceType(), a.ResourceID)
+}
+
+func (a *Action) resourceType() string {
+	return strings.Split(reflect.TypeOf(a.Model).String(), ".")[1]
+}
+
+// failed returns true if the Action errored and has no retries left (it then
+// remains in the Actions map so that its error can be displayed).
+func (a *Action) failed() bool {
+	return a.Status.Error != "" && a.Status.Retries >= a.Status.MaxRetries
 }
 
 func (a *Action) prepare() error {
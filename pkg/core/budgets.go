@@ -0,0 +1,186 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+var defaultBudgetAlertThresholds = []float64{50, 80, 100}
+
+// ErrorBudgetExceeded is returned when creating a Node for a CloudAccount or
+// Kube whose budget, with BudgetHardStop set, has been spent this month.
+type ErrorBudgetExceeded struct {
+	resource string
+	name     string
+	budget   float64
+	spend    float64
+}
+
+func (err *ErrorBudgetExceeded) Error() string {
+	return fmt.Sprintf("Monthly budget of %.2f for %s '%s' is exceeded (%.2f spent), so no Nodes can be created", err.budget, err.resource, err.name, err.spend)
+}
+
+// Spend returns what the CloudAccount has spent in the month starting at
+// from, including Nodes and Kubes which have since been deleted.
+func (c *CloudAccounts) Spend(m *model.CloudAccount, from time.Time) (float64, error) {
+	to := from.AddDate(0, 1, 0)
+	now := time.Now()
+
+	var costs []*model.NodeCost
+
+	var kubes []*model.Kube
+	if err := c.Core.DB.Find(&kubes, "cloud_account_name = ?", m.Name); err != nil {
+		return 0, err
+	}
+	for _, kube := range kubes {
+		kube.CloudAccount = m
+		running, err := c.Core.Kubes.runningUsage(kube, now)
+		if err != nil {
+			return 0, err
+		}
+		for _, usage := range running {
+			costs = appendNodeCost(costs, usage, nil, from, to, now)
+		}
+	}
+
+	var usages []*model.NodeUsage
+	if err := c.Core.DB.Where("cloud_account_name = ? AND ended_at > ? AND started_at < ?", m.Name, from, to).Find(&usages); err != nil {
+		return 0, err
+	}
+	for _, usage := range usages {
+		endedAt := usage.EndedAt
+		costs = appendNodeCost(costs, usage, &endedAt, from, to, now)
+	}
+
+	var total float64
+	for _, cost := range costs {
+		total += cost.Cost
+	}
+	return total, nil
+}
+
+// Spend returns what the Kube has spent in the month starting at from.
+func (c *Kubes) Spend(m *model.Kube, from time.Time) (float64, error) {
+	costs, err := c.Costs(m, from)
+	if err != nil {
+		return 0, err
+	}
+	return costs.Total, nil
+}
+
+// checkBudget returns an *ErrorBudgetExceeded if the Kube, or its CloudAccount
+// (which must be loaded), has a hard stop budget that is spent this month.
+func (c *Core) checkBudget(kube *model.Kube) error {
+	from, _ := ParseCostMonth("")
+
+	if account := kube.CloudAccount; budgetHardStops(&account.Budget) {
+		spend, err := c.CloudAccounts.Spend(account, from)
+		if err != nil {
+			return err
+		}
+		if spend >= account.MonthlyBudget {
+			return &ErrorBudgetExceeded{"CloudAccount", account.Name, account.MonthlyBudget, spend}
+		}
+	}
+
+	if budgetHardStops(&kube.Budget) {
+		spend, err := c.Kubes.Spend(kube, from)
+		if err != nil {
+			return err
+		}
+		if spend >= kube.MonthlyBudget {
+			return &ErrorBudgetExceeded{"Kube", kube.Name, kube.MonthlyBudget, spend}
+		}
+	}
+	return nil
+}
+
+func budgetHardStops(budget *model.Budget) bool {
+	return budget.BudgetHardStop && budget.MonthlyBudget > 0
+}
+
+//------------------------------------------------------------------------------
+
+// BudgetService records the spend of every CloudAccount and Kube that has a
+// MonthlyBudget, and sends an alert (see Core.Notify) the first time in a
+// month that spend crosses each of its BudgetAlertThresholds.
+type BudgetService struct {
+	Core *Core
+}
+
+func (s *BudgetService) Perform() error {
+	from, _ := ParseCostMonth("")
+
+	// A record which fails is left to be checked next time, without holding up
+	// the alerts of the others
+	var errs serviceErrors
+
+	var accounts []*model.CloudAccount
+	if err := s.Core.DB.Find(&accounts, "monthly_budget > ?", 0); err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		spend, err := s.Core.CloudAccounts.Spend(account, from)
+		if err == nil {
+			err = s.check(account, "CloudAccount", account.Name, &account.Budget, spend, from)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Budget service error on CloudAccount %s: %s", account.Name, err))
+		}
+	}
+
+	var kubes []*model.Kube
+	if err := s.Core.DB.Preload("CloudAccount").Find(&kubes, "monthly_budget > ?", 0); err != nil {
+		return err
+	}
+	for _, kube := range kubes {
+		spend, err := s.Core.Kubes.Spend(kube, from)
+		if err == nil {
+			err = s.check(kube, "Kube", kube.Name, &kube.Budget, spend, from)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Budget service error on Kube %s: %s", kube.Name, err))
+		}
+	}
+	return errs.err()
+}
+
+func (s *BudgetService) check(m model.Model, resource string, name string, budget *model.Budget, spend float64, from time.Time) error {
+	month := from.Format(costMonthFormat)
+	updates := map[string]interface{}{
+		"budget_spend":      spend,
+		"budget_checked_at": time.Now(),
+	}
+
+	alerted := budget.BudgetAlertPercent
+	if budget.BudgetAlertMonth != month {
+		alerted = 0
+	}
+	thresholds := budget.BudgetAlertThresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultBudgetAlertThresholds
+	}
+
+	percent := spend / budget.MonthlyBudget * 100
+	var crossed float64
+	for _, threshold := range thresholds {
+		if percent >= threshold && threshold > alerted && threshold > crossed {
+			crossed = threshold
+		}
+	}
+
+	if crossed > 0 {
+		message := fmt.Sprintf("%s '%s' has spent %.2f of its monthly budget of %.2f (%.0f%%, over the %.0f%% alert threshold)", resource, name, spend, budget.MonthlyBudget, percent, crossed)
+		if budget.BudgetHardStop && percent >= 100 {
+			message += "; no more Nodes will be created this month"
+		}
+		s.Core.Notify(message)
+
+		updates["budget_alert_month"] = month
+		updates["budget_alert_percent"] = crossed
+	}
+
+	return s.Core.DB.Model(m).Update(updates)
+}
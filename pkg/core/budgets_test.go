@@ -0,0 +1,202 @@
+package core_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+)
+
+func TestBudgetServicePerform(t *testing.T) {
+	Convey("BudgetService Perform alerts once per threshold per month", t, func() {
+		month := time.Now().UTC().Format("2006-01")
+
+		table := []struct {
+			// Input
+			budget         model.Budget
+			percentSpent   float64
+			alertMonth     string
+			alertedPercent float64
+			// Expectations
+			alerts       []string
+			alertPercent interface{}
+		}{
+			// First threshold crossed
+			{
+				budget:       model.Budget{MonthlyBudget: 100},
+				percentSpent: 60,
+				alerts:       []string{"CloudAccount 'test' has spent 60.00 of its monthly budget of 100.00 (60%, over the 50% alert threshold)"},
+				alertPercent: float64(50),
+			},
+			// Only the highest of several thresholds crossed at once is sent
+			{
+				budget:       model.Budget{MonthlyBudget: 100, BudgetHardStop: true},
+				percentSpent: 110,
+				alerts:       []string{"CloudAccount 'test' has spent 110.00 of its monthly budget of 100.00 (110%, over the 100% alert threshold); no more Nodes will be created this month"},
+				alertPercent: float64(100),
+			},
+			// Already alerted on this threshold this month
+			{
+				budget:         model.Budget{MonthlyBudget: 100},
+				percentSpent:   85,
+				alertMonth:     month,
+				alertedPercent: 80,
+				alerts:         nil,
+				alertPercent:   nil,
+			},
+			// Alerted on this threshold last month
+			{
+				budget:         model.Budget{MonthlyBudget: 100},
+				percentSpent:   85,
+				alertMonth:     "2000-01",
+				alertedPercent: 80,
+				alerts:         []string{"CloudAccount 'test' has spent 85.00 of its monthly budget of 100.00 (85%, over the 80% alert threshold)"},
+				alertPercent:   float64(80),
+			},
+			// Custom thresholds
+			{
+				budget:       model.Budget{MonthlyBudget: 200, BudgetAlertThresholds: []float64{25}},
+				percentSpent: 30,
+				alerts:       []string{"CloudAccount 'test' has spent 60.00 of its monthly budget of 200.00 (30%, over the 25% alert threshold)"},
+				alertPercent: float64(25),
+			},
+		}
+
+		for _, item := range table {
+			var alerts []string
+			webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload := make(map[string]string)
+				json.NewDecoder(r.Body).Decode(&payload)
+				alerts = append(alerts, payload["text"])
+			}))
+
+			account := &model.CloudAccount{
+				Name:   "test",
+				Budget: item.budget,
+			}
+			account.BudgetAlertMonth = item.alertMonth
+			account.BudgetAlertPercent = item.alertedPercent
+
+			// A Node which ran for the last minute, priced to have spent the
+			// percentage of the budget under test.
+			now := time.Now()
+			usage := &model.NodeUsage{
+				CloudAccountName: "test",
+				HourlyPrice:      item.budget.MonthlyBudget * item.percentSpent / 100 * 60,
+				StartedAt:        now.Add(-time.Minute),
+				EndedAt:          now,
+			}
+
+			var updates map[string]interface{}
+
+			db := new(fake_core.DB)
+			db.FindFn = func(out interface{}, where ...interface{}) error {
+				switch reflect.TypeOf(out).String() {
+				case "*[]*model.CloudAccount":
+					reflect.ValueOf(out).Elem().Set(reflect.ValueOf([]*model.CloudAccount{account}))
+				case "*[]*model.NodeUsage":
+					reflect.ValueOf(out).Elem().Set(reflect.ValueOf([]*model.NodeUsage{usage}))
+				}
+				return nil
+			}
+			db.ModelFn = func(value interface{}) core.DBInterface {
+				return &fake_core.DB{
+					UpdateFn: func(attrs ...interface{}) error {
+						updates = attrs[0].(map[string]interface{})
+						return nil
+					},
+				}
+			}
+
+			c := &core.Core{
+				Log: logrus.New(),
+				DB:  db,
+			}
+			c.AlertWebhookURL = webhook.URL
+			c.CloudAccounts = &core.CloudAccounts{core.Collection{c}}
+			c.Kubes = &core.Kubes{core.Collection{c}}
+
+			service := &core.BudgetService{c}
+			err := service.Perform()
+			webhook.Close()
+
+			So(err, ShouldBeNil)
+			So(alerts, ShouldResemble, item.alerts)
+			So(updates["budget_spend"], ShouldAlmostEqual, item.budget.MonthlyBudget*item.percentSpent/100, 0.01)
+			So(updates["budget_alert_percent"], ShouldEqual, item.alertPercent)
+		}
+	})
+}
+
+func TestBudgetServicePerformErrors(t *testing.T) {
+	Convey("BudgetService Perform goes on with other records when one fails", t, func() {
+		var alerts []string
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload := make(map[string]string)
+			json.NewDecoder(r.Body).Decode(&payload)
+			alerts = append(alerts, payload["text"])
+		}))
+		defer webhook.Close()
+
+		broken := &model.CloudAccount{Name: "broken", Budget: model.Budget{MonthlyBudget: 100}}
+		account := &model.CloudAccount{Name: "test", Budget: model.Budget{MonthlyBudget: 100}}
+
+		// A Node which ran for the last minute, having spent 60% of the budget
+		now := time.Now()
+		usage := &model.NodeUsage{
+			HourlyPrice: 60 * 60,
+			StartedAt:   now.Add(-time.Minute),
+			EndedAt:     now,
+		}
+
+		updated := make(map[string]bool)
+
+		db := new(fake_core.DB)
+		db.FindFn = func(out interface{}, where ...interface{}) error {
+			switch reflect.TypeOf(out).String() {
+			case "*[]*model.CloudAccount":
+				reflect.ValueOf(out).Elem().Set(reflect.ValueOf([]*model.CloudAccount{broken, account}))
+			case "*[]*model.NodeUsage":
+				reflect.ValueOf(out).Elem().Set(reflect.ValueOf([]*model.NodeUsage{usage}))
+			}
+			return nil
+		}
+		db.ModelFn = func(value interface{}) core.DBInterface {
+			return &fake_core.DB{
+				UpdateFn: func(attrs ...interface{}) error {
+					name := value.(*model.CloudAccount).Name
+					if name == "broken" {
+						return errors.New("database is locked")
+					}
+					updated[name] = true
+					return nil
+				},
+			}
+		}
+
+		c := &core.Core{
+			Log: logrus.New(),
+			DB:  db,
+		}
+		c.AlertWebhookURL = webhook.URL
+		c.CloudAccounts = &core.CloudAccounts{core.Collection{c}}
+		c.Kubes = &core.Kubes{core.Collection{c}}
+
+		service := &core.BudgetService{c}
+		err := service.Perform()
+
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Budget service error on CloudAccount broken: database is locked")
+		So(updated, ShouldResemble, map[string]bool{"test": true})
+		So(alerts, ShouldContain, "CloudAccount 'test' has spent 60.00 of its monthly budget of 100.00 (60%, over the 50% alert threshold)")
+	})
+}
@@ -178,6 +178,7 @@ func (s *KubeScaler) Scale() error {
 			if err := s.service.Core.Nodes.Delete(node.ID, node).Now(); err != nil {
 				return fmt.Errorf("Capacity service error when deleting Node: %s", err)
 			}
+			s.service.Core.Metrics.capacityNodeDeleted(s.kube)
 		}
 	}
 
@@ -195,6 +196,16 @@ func (s *KubeScaler) Scale() error {
 	// }
 	//----------------------------------------------------------------------------
 
+	if len(projectedNodes) > 0 {
+		if err := s.service.Core.checkBudget(s.kube); err != nil {
+			if _, ok := err.(*ErrorBudgetExceeded); ok {
+				s.service.Core.Log.Warnf("Capacity service is not adding Nodes to Kube %s: %s", s.kube.Name, err)
+				return nil
+			}
+			return fmt.Errorf("Capacity service error when checking budget: %s", err)
+		}
+	}
+
 	for _, pnode := range projectedNodes {
 		node := &model.Node{
 			KubeName: s.kube.Name,
@@ -226,6 +237,7 @@ func (s *KubeScaler) Scale() error {
 		if err := s.service.Core.Nodes.Create(node); err != nil {
 			return fmt.Errorf("Capacity service error when creating Node: %s", err)
 		}
+		s.service.Core.Metrics.capacityNodeCreated(s.kube)
 	}
 	return nil
 }
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/acme"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const (
+	defaultACMEDirectoryURL    = "https://acme-v02.api.letsencrypt.org/directory"
+	defaultACMEPropagationWait = 60 * time.Second
+)
+
+// issueCertificate issues a certificate for the hosts of the Ingress through
+// ACME, and stores it in the Ingress' TLS Secret.
+func (c *Ingresses) issueCertificate(m *model.Ingress) error {
+	client, err := c.Core.acmeClient()
+	if err != nil {
+		return err
+	}
+	cert, err := client.Obtain(ingressHosts(m), &dnsSolver{c.Core.DNSZones})
+	if err != nil {
+		return err
+	}
+
+	secret := &kubernetes.Secret{
+		Metadata: kubernetes.Metadata{
+			Name: ingressSecretName(m),
+		},
+		Type: "kubernetes.io/tls",
+		Data: map[string][]byte{
+			"tls.crt": cert.CertPEM,
+			"tls.key": cert.KeyPEM,
+		},
+	}
+
+	k8s := c.Core.K8S(m.Kube)
+	err = k8s.GetResource("api/v1", "Secret", m.Namespace, secret.Metadata.Name, new(kubernetes.Secret))
+	switch {
+	case err == nil:
+		patch := map[string]interface{}{"data": secret.Data}
+		err = k8s.UpdateResource("api/v1", "Secret", m.Namespace, secret.Metadata.Name, patch, nil)
+	case strings.Contains(err.Error(), "404"):
+		err = k8s.CreateResource("api/v1", "Secret", m.Namespace, secret, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.CertificateExpiresAt = &cert.NotAfter
+	return c.Core.DB.Model(m).Update("certificate_expires_at", cert.NotAfter)
+}
+
+// acmeClient returns the client certificates are issued with, creating it on
+// first use.
+func (c *Core) acmeClient() (*acme.Client, error) {
+	c.acmeMutex.Lock()
+	defer c.acmeMutex.Unlock()
+
+	if c.ACME != nil {
+		return c.ACME, nil
+	}
+
+	key, err := c.acmeAccountKey()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if c.ACMECAFile != "" {
+		caPEM, err := ioutil.ReadFile(c.ACMECAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("No certificates found in " + c.ACMECAFile)
+		}
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	directoryURL := c.ACMEDirectoryURL
+	if directoryURL == "" {
+		directoryURL = defaultACMEDirectoryURL
+	}
+	propagationWait := time.Duration(c.ACMEPropagationSeconds) * time.Second
+	if propagationWait == 0 {
+		propagationWait = defaultACMEPropagationWait
+	}
+
+	c.ACME = &acme.Client{
+		DirectoryURL:    directoryURL,
+		Key:             key,
+		Email:           c.ACMEEmail,
+		HTTPClient:      httpClient,
+		PropagationWait: propagationWait,
+	}
+	return c.ACME, nil
+}
+
+// acmeAccountKey loads the account key from ACMEAccountKeyFile, or generates
+// it (saving it to the file, if set).
+func (c *Core) acmeAccountKey() (*ecdsa.PrivateKey, error) {
+	if c.ACMEAccountKeyFile != "" {
+		keyPEM, err := ioutil.ReadFile(c.ACMEAccountKeyFile)
+		if err == nil {
+			block, _ := pem.Decode(keyPEM)
+			if block == nil {
+				return nil, errors.New("No PEM key found in " + c.ACMEAccountKeyFile)
+			}
+			return x509.ParseECPrivateKey(block.Bytes)
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	key, err := acme.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	if c.ACMEAccountKeyFile != "" {
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+		if err := ioutil.WriteFile(c.ACMEAccountKeyFile, keyPEM, 0600); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+//------------------------------------------------------------------------------
+
+// CertificateRenewer reissues the certificates of Ingresses which expire
+// within RenewBefore.
+type CertificateRenewer struct {
+	Core        *Core
+	RenewBefore time.Duration
+}
+
+func (s *CertificateRenewer) Perform() error {
+	var ingresses []*model.Ingress
+	if err := s.Core.DB.Preload("Kube").Where("tls = ? AND tls_secret_name = ?", true, "").Find(&ingresses); err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, ingress := range ingresses {
+		// Of a Kube which is gone, so there's nowhere to renew it
+		if ingress.Kube == nil {
+			continue
+		}
+		// Not issued yet, which the provisioning action is still doing or retrying
+		if ingress.CertificateExpiresAt == nil {
+			continue
+		}
+		if ingress.CertificateExpiresAt.Sub(time.Now()) > s.RenewBefore {
+			continue
+		}
+
+		s.Core.Log.Infof("Renewing certificate of Ingress %s, which expires at %s", ingress.Name, ingress.CertificateExpiresAt)
+		if err := s.Core.Ingresses.issueCertificate(ingress); err != nil {
+			s.Core.Log.Errorf("Error renewing certificate of Ingress %s: %s", ingress.Name, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
@@ -29,6 +29,13 @@ func (c *CloudAccounts) Delete(id *int64, m *model.CloudAccount) error {
 	if len(m.Kubes) > 0 {
 		return &ErrorValidationFailed{errors.New("Cannot delete CloudAccount that has active Kubes")}
 	}
+	var zones []*model.DNSZone
+	if err := c.Core.DB.Where("cloud_account_name = ?", m.Name).Find(&zones); err != nil {
+		return err
+	}
+	if len(zones) > 0 {
+		return &ErrorValidationFailed{errors.New("Cannot delete CloudAccount that has DNSZones")}
+	}
 	return c.Collection.Delete(id, m)
 }
 
@@ -51,11 +51,15 @@ func (c *Collection) mergeUpdate(id *int64, oldM model.Model, m model.Model) err
 	if err := c.Core.DB.First(oldM, *id); err != nil {
 		return err
 	}
+	version, err := CheckResourceVersion(oldM, m)
+	if err != nil {
+		return err
+	}
 	// Merge old item attributes into the empty fields of the newItem
 	if err := mergo.Merge(m, oldM); err != nil {
 		return err
 	}
-	return c.Core.DB.Save(m)
+	return c.Core.DB.SaveVersion(m, version)
 }
 
 func (c *Collection) inParallel(model interface{}, fn func(interface{}) error) (err error) {
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/imdario/mergo"
+	"github.com/supergiant/supergiant/pkg/acme"
 	"github.com/supergiant/supergiant/pkg/client"
 	"github.com/supergiant/supergiant/pkg/kubernetes"
 	"github.com/supergiant/supergiant/pkg/model"
@@ -21,9 +24,10 @@ import (
 )
 
 type NodeSize struct {
-	Name     string  `json:"name"`
-	RAMGIB   float64 `json:"ram_gib"`
-	CPUCores float64 `json:"cpu_cores"`
+	Name        string  `json:"name"`
+	RAMGIB      float64 `json:"ram_gib"`
+	CPUCores    float64 `json:"cpu_cores"`
+	HourlyPrice float64 `json:"hourly_price"`
 }
 
 type Settings struct {
@@ -44,9 +48,33 @@ type Settings struct {
 	SupportPassword        string `json:"support_password"`
 	UIEnabled              bool   `json:"ui_enabled"`
 	CapacityServiceEnabled bool   `json:"capacity_service_enabled"`
+	AlertWebhookURL        string `json:"alert_webhook_url"`
+
+	// ACME issues the certificates of Ingresses, through Let's Encrypt unless
+	// ACMEDirectoryURL is set. ACMECAFile is the CA of a server with a
+	// certificate not trusted by the OS, such as a local pebble.
+	ACMEDirectoryURL       string `json:"acme_directory_url"`
+	ACMEEmail              string `json:"acme_email"`
+	ACMEAccountKeyFile     string `json:"acme_account_key_file"`
+	ACMECAFile             string `json:"acme_ca_file"`
+	ACMEPropagationSeconds int    `json:"acme_propagation_seconds"`
+
+	// Helm commands run in pods of HelmWorkerImage, in HelmWorkerNamespace of
+	// each Kube.
+	HelmWorkerImage     string `json:"helm_worker_image"`
+	HelmWorkerNamespace string `json:"helm_worker_namespace"`
+
+	// Uploaded charts are stored in HelmLocalRepoDir, a "charts" directory next
+	// to SQLiteFile by default.
+	HelmLocalRepoDir string `json:"helm_local_repo_dir"`
+
+	// HelmUpdateNotifications sends an alert (see Core.Notify) when a newer
+	// version of the chart of a HelmRelease is found.
+	HelmUpdateNotifications bool `json:"helm_update_notifications"`
 
 	// NOTE these MUST be provided in ascending order by cost in order to
-	// correctly provision the smallest size on Kube creation
+	// correctly provision the smallest size on Kube creation, unless every size
+	// of a provider has an hourly_price, in which case they are sorted by it.
 	//
 	// NodeSizes is a map of provider name (ex. "aws") and node sizes
 	NodeSizes map[string][]*NodeSize `json:"node_sizes"`
@@ -72,26 +100,55 @@ type Core struct {
 	// A little different from the above
 	K8SProvider Provider
 
+	// DNS providers of DNSZones, also set in cmd/server.go
+	Route53Provider func(map[string]string) DNSProvider
+	DODNSProvider   func(map[string]string) DNSProvider
+	RFC2136Provider DNSProvider
+
 	K8S func(*model.Kube) kubernetes.ClientInterface
 
 	DefaultProvisioner Provisioner
 
 	APIClient func(authType string, authToken string) *client.Client
 
+	// ACME is created on first use, unless set (by tests).
+	ACME      *acme.Client
+	acmeMutex sync.Mutex
+
+	// Mutexes of helm commands on each Kube, the URLs of HelmRepos (cleared
+	// once per helmReposGeneration), and the helm workers of each Kube, created
+	// on first use.
+	helmMutex           sync.Mutex
+	helmKubeMutexes     map[string]*sync.Mutex
+	helmRepoURLs        map[string]string
+	helmReposGeneration int
+	helmWorkers         map[string]*helmWorker
+
+	// Held while a chart is uploaded, so the index of the local repo has all of
+	// them.
+	helmUploadMutex sync.Mutex
+
 	Log *logrus.Logger
 
+	Metrics *Metrics
+
 	DB DBInterface
 
-	Sessions      SessionsInterface
-	Users         *Users
-	CloudAccounts *CloudAccounts
-	Kubes         *Kubes
-	KubeResources KubeResourcesInterface
-	Nodes         NodesInterface
-	LoadBalancers *LoadBalancers
-	HelmRepos     *HelmRepos
-	HelmCharts    *HelmCharts
-	HelmReleases  *HelmReleases
+	Sessions              SessionsInterface
+	Users                 *Users
+	CloudAccounts         *CloudAccounts
+	Kubes                 *Kubes
+	KubeResources         KubeResourcesInterface
+	Nodes                 NodesInterface
+	LoadBalancers         *LoadBalancers
+	HelmRepos             *HelmRepos
+	HelmCharts            *HelmCharts
+	HelmChartPolicies     *HelmChartPolicies
+	HelmReleases          *HelmReleases
+	HelmReleasePromotions *HelmReleasePromotions
+	DNSZones              *DNSZones
+	Ingresses             *Ingresses
+	IdempotencyKeys       *IdempotencyKeys
 
 	// TODO should this be a pseudo-collection like Sessions?
 	Actions *SafeMap
@@ -136,6 +193,8 @@ func (c *Core) InitializeForeground() error {
 		}
 	}
 
+	c.sortNodeSizesByPrice()
+
 	requiredFlags := map[string]string{
 		"publish-host": c.PublishHost,
 		"http-port":    c.HTTPPort,
@@ -186,7 +245,15 @@ func (c *Core) InitializeForeground() error {
 		&model.LoadBalancer{},
 		&model.HelmRepo{},
 		&model.HelmChart{},
+		&model.HelmChartPolicy{},
 		&model.HelmRelease{},
+		&model.HelmReleasePromotion{},
+		&model.NodeUsage{},
+		&model.CostAllocation{},
+		&model.CostAccrual{},
+		&model.DNSZone{},
+		&model.Ingress{},
+		&model.IdempotencyKey{},
 	).Error
 	if err != nil {
 		return err
@@ -202,12 +269,19 @@ func (c *Core) InitializeForeground() error {
 	c.LoadBalancers = &LoadBalancers{Collection{c}}
 	c.HelmRepos = &HelmRepos{Collection{c}}
 	c.HelmCharts = &HelmCharts{Collection{c}}
+	c.HelmChartPolicies = &HelmChartPolicies{Collection{c}}
 	c.HelmReleases = &HelmReleases{Collection{c}}
+	c.HelmReleasePromotions = &HelmReleasePromotions{Collection{c}}
+	c.DNSZones = &DNSZones{Collection{c}}
+	c.Ingresses = &Ingresses{Collection{c}}
+	c.IdempotencyKeys = &IdempotencyKeys{Collection{c}}
 	c.Sessions = NewSessions(c)
 
 	// Actions for async work
 	c.Actions = NewSafeMap(c)
 
+	c.Metrics = NewMetrics(c)
+
 	// Kubernetes Client
 	c.K8S = func(kube *model.Kube) kubernetes.ClientInterface {
 		return &kubernetes.Client{
@@ -256,6 +330,33 @@ func (c *Core) InitializeBackground() {
 	}
 	go nodeObserver.Run()
 
+	costService := &RecurringService{
+		core:     c,
+		service:  &CostService{Core: c},
+		interval: 5 * time.Minute,
+		tag:      "Cost Service",
+	}
+	go costService.Run()
+
+	budgetService := &RecurringService{
+		core:     c,
+		service:  &BudgetService{c},
+		interval: 5 * time.Minute,
+		tag:      "Budget Service",
+	}
+	go budgetService.Run()
+
+	certificateRenewer := &RecurringService{
+		core: c,
+		service: &CertificateRenewer{
+			Core:        c,
+			RenewBefore: 30 * 24 * time.Hour,
+		},
+		interval: 12 * time.Hour,
+		tag:      "Certificate Renewer",
+	}
+	go certificateRenewer.Run()
+
 	// TODO we probably don't need both this and the observer
 	kubeResourcePopulater := &RecurringService{
 		core:     c,
@@ -281,6 +382,14 @@ func (c *Core) InitializeBackground() {
 	}
 	go helmReleasePopulater.Run()
 
+	helmReleaseUpdateChecker := &RecurringService{
+		core:     c,
+		service:  &HelmReleaseUpdateChecker{c},
+		interval: 5 * time.Minute,
+		tag:      "Helm Release Update Checker",
+	}
+	go helmReleaseUpdateChecker.Run()
+
 	sessionExpirer := &RecurringService{
 		core:     c,
 		service:  &SessionExpirer{c},
@@ -288,10 +397,56 @@ func (c *Core) InitializeBackground() {
 		tag:      "Session Expire",
 	}
 	go sessionExpirer.Run()
+
+	idempotencyKeyExpirer := &RecurringService{
+		core:     c,
+		service:  &IdempotencyKeyExpirer{c},
+		interval: 1 * time.Hour,
+		tag:      "Idempotency Key Expirer",
+	}
+	go idempotencyKeyExpirer.Run()
 }
 
 //------------------------------------------------------------------------------
 
+// nodeSize returns the NodeSize of the provider with the given name, or nil if
+// it is not configured.
+func (c *Core) nodeSize(provider string, name string) *NodeSize {
+	for _, nodeSize := range c.NodeSizes[provider] {
+		if nodeSize.Name == name {
+			return nodeSize
+		}
+	}
+	return nil
+}
+
+// sortNodeSizesByPrice orders the NodeSizes of every provider which has an
+// hourly price on all of them from cheapest to most expensive. Providers
+// missing any price keep the order in which they were configured.
+func (c *Core) sortNodeSizesByPrice() {
+	for _, nodeSizes := range c.NodeSizes {
+		priced := true
+		for _, nodeSize := range nodeSizes {
+			if nodeSize.HourlyPrice <= 0 {
+				priced = false
+				break
+			}
+		}
+		if !priced {
+			continue
+		}
+		sort.Stable(nodeSizesByPrice(nodeSizes))
+	}
+}
+
+type nodeSizesByPrice []*NodeSize
+
+func (sizes nodeSizesByPrice) Len() int           { return len(sizes) }
+func (sizes nodeSizesByPrice) Swap(i, j int)      { sizes[i], sizes[j] = sizes[j], sizes[i] }
+func (sizes nodeSizesByPrice) Less(i, j int) bool { return sizes[i].HourlyPrice < sizes[j].HourlyPrice }
+
+//------------------------------------------------------------------------------
+
 func (c *Core) SSLEnabled() bool {
 	return c.HTTPSPort != "" && c.SSLCertFile != "" && c.SSLKeyFile != ""
 }
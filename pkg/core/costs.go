@@ -0,0 +1,475 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const costMonthFormat = "2006-01"
+
+// ParseCostMonth parses a month formatted as 2006-01, returning the start of
+// that month in UTC. An empty string is the current month.
+func ParseCostMonth(month string) (time.Time, error) {
+	if month == "" {
+		now := time.Now().UTC()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	t, err := time.Parse(costMonthFormat, month)
+	if err != nil {
+		return t, &ErrorValidationFailed{fmt.Errorf("month must be formatted as YYYY-MM, got '%s'", month)}
+	}
+	return t, nil
+}
+
+// Costs returns the cost report of the Kube for the month starting at from.
+// Node spend is computed from uptime and the hourly price of each NodeSize;
+// namespace and HelmRelease spend is what the CostService has accrued.
+func (c *Kubes) Costs(kube *model.Kube, from time.Time) (*model.KubeCosts, error) {
+	to := from.AddDate(0, 1, 0)
+	now := time.Now()
+
+	report := &model.KubeCosts{
+		KubeName:     kube.Name,
+		Month:        from.Format(costMonthFormat),
+		Nodes:        []*model.NodeCost{},
+		Namespaces:   []*model.CostItem{},
+		HelmReleases: []*model.CostItem{},
+	}
+
+	// Running masters and Nodes
+	running, err := c.runningUsage(kube, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, usage := range running {
+		report.HourlyRate += usage.HourlyPrice
+		report.Nodes = appendNodeCost(report.Nodes, usage, nil, from, to, now)
+	}
+
+	// Deleted masters and Nodes
+	var usages []*model.NodeUsage
+	if err := c.Core.DB.Where("kube_name = ? AND ended_at > ? AND started_at < ?", kube.Name, from, to).Find(&usages); err != nil {
+		return nil, err
+	}
+	for _, usage := range usages {
+		endedAt := usage.EndedAt
+		report.Nodes = appendNodeCost(report.Nodes, usage, &endedAt, from, to, now)
+	}
+
+	for _, nodeCost := range report.Nodes {
+		report.Total += nodeCost.Cost
+	}
+
+	// Allocations
+	var allocations []*model.CostAllocation
+	if err := c.Core.DB.Where("kube_name = ? AND month = ?", kube.Name, report.Month).Find(&allocations); err != nil {
+		return nil, err
+	}
+	for _, allocation := range allocations {
+		item := &model.CostItem{Name: allocation.Name, Cost: allocation.Amount}
+		switch allocation.Kind {
+		case model.CostAllocationKindNamespace:
+			report.Namespaces = append(report.Namespaces, item)
+		case model.CostAllocationKindHelmRelease:
+			report.HelmReleases = append(report.HelmReleases, item)
+		}
+	}
+	sortCostItems(report.Namespaces)
+	sortCostItems(report.HelmReleases)
+
+	return report, nil
+}
+
+// Chargeback returns the spend accrued to every namespace of every Kube in the
+// month starting at from.
+func (c *Kubes) Chargeback(from time.Time) (*model.Chargeback, error) {
+	chargeback := &model.Chargeback{
+		Month: from.Format(costMonthFormat),
+		Items: []*model.ChargebackItem{},
+	}
+
+	var allocations []*model.CostAllocation
+	if err := c.Core.DB.Where("month = ? AND kind = ?", chargeback.Month, model.CostAllocationKindNamespace).Find(&allocations); err != nil {
+		return nil, err
+	}
+	for _, allocation := range allocations {
+		chargeback.Items = append(chargeback.Items, &model.ChargebackItem{
+			KubeName:  allocation.KubeName,
+			Namespace: allocation.Name,
+			Cost:      allocation.Amount,
+		})
+	}
+	sort.Sort(chargebackItemsByName(chargeback.Items))
+	return chargeback, nil
+}
+
+type chargebackItemsByName []*model.ChargebackItem
+
+func (items chargebackItemsByName) Len() int      { return len(items) }
+func (items chargebackItemsByName) Swap(i, j int) { items[i], items[j] = items[j], items[i] }
+func (items chargebackItemsByName) Less(i, j int) bool {
+	if items[i].KubeName != items[j].KubeName {
+		return items[i].KubeName < items[j].KubeName
+	}
+	return items[i].Namespace < items[j].Namespace
+}
+
+// runningUsage returns the usage, up to now, of the masters and running Nodes
+// of the Kube, which must have its CloudAccount loaded.
+func (c *Kubes) runningUsage(kube *model.Kube, now time.Time) ([]*model.NodeUsage, error) {
+	var usages []*model.NodeUsage
+	if masters := c.Core.masterUsage(kube, now); masters != nil {
+		usages = append(usages, masters)
+	}
+
+	var nodes []*model.Node
+	if err := c.Core.DB.Find(&nodes, "kube_name = ?", kube.Name); err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if node.ProviderCreationTimestamp.IsZero() {
+			continue // not yet running
+		}
+		usages = append(usages, c.Core.nodeUsage(kube, node, now))
+	}
+	return usages, nil
+}
+
+//------------------------------------------------------------------------------
+
+// recordNodeUsage persists the uptime of a Node that is being deleted.
+func (c *Core) recordNodeUsage(kube *model.Kube, node *model.Node) error {
+	if node.ProviderCreationTimestamp.IsZero() {
+		return nil // never ran
+	}
+	return c.saveNodeUsage(c.nodeUsage(kube, node, time.Now()))
+}
+
+// recordMasterUsage persists the uptime of the masters of a Kube that is being
+// deleted.
+func (c *Core) recordMasterUsage(kube *model.Kube) error {
+	usage := c.masterUsage(kube, time.Now())
+	if usage == nil {
+		return nil
+	}
+	return c.saveNodeUsage(usage)
+}
+
+// saveNodeUsage creates the NodeUsage, or updates the one already recorded of
+// the same Node (such as by a retry of the Action deleting it), so that its
+// uptime only counts once.
+func (c *Core) saveNodeUsage(usage *model.NodeUsage) error {
+	recorded := new(model.NodeUsage)
+	err := c.DB.Where("kube_name = ? AND node_name = ? AND master = ? AND started_at = ?", usage.KubeName, usage.NodeName, usage.Master, usage.StartedAt).First(recorded)
+	if err == gorm.ErrRecordNotFound {
+		return c.DB.Create(usage)
+	}
+	if err != nil {
+		return err
+	}
+	recorded.EndedAt = usage.EndedAt
+	return c.DB.Save(recorded)
+}
+
+func (c *Core) nodeUsage(kube *model.Kube, node *model.Node, endedAt time.Time) *model.NodeUsage {
+	usage := &model.NodeUsage{
+		CloudAccountName: kube.CloudAccountName,
+		KubeName:         kube.Name,
+		NodeName:         node.Name,
+		Size:             node.Size,
+		StartedAt:        node.ProviderCreationTimestamp.UTC(),
+		EndedAt:          endedAt.UTC(),
+	}
+	if nodeSize := c.nodeSize(kube.CloudAccount.Provider, node.Size); nodeSize != nil {
+		usage.HourlyPrice = nodeSize.HourlyPrice
+	}
+	return usage
+}
+
+// masterUsage treats the masters of a Kube as running since the Kube was
+// created. It returns nil if the Kube has not been provisioned.
+func (c *Core) masterUsage(kube *model.Kube, endedAt time.Time) *model.NodeUsage {
+	if kube.MasterNodeSize == "" || kube.CloudAccount == nil {
+		return nil
+	}
+	count := kube.KubeMasterCount
+	if count < 1 {
+		count = 1
+	}
+	usage := &model.NodeUsage{
+		CloudAccountName: kube.CloudAccountName,
+		KubeName:         kube.Name,
+		NodeName:         kube.MasterName,
+		Size:             kube.MasterNodeSize,
+		Master:           true,
+		StartedAt:        kube.CreatedAt.UTC(),
+		EndedAt:          endedAt.UTC(),
+	}
+	if nodeSize := c.nodeSize(kube.CloudAccount.Provider, kube.MasterNodeSize); nodeSize != nil {
+		usage.HourlyPrice = nodeSize.HourlyPrice * float64(count)
+	}
+	return usage
+}
+
+// appendNodeCost adds the part of usage falling within [from, to) to costs.
+// running usages are billed up to now.
+func appendNodeCost(costs []*model.NodeCost, usage *model.NodeUsage, endedAt *time.Time, from, to, now time.Time) []*model.NodeCost {
+	start, end := usage.StartedAt, now
+	if endedAt != nil {
+		end = *endedAt
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return costs
+	}
+	hours := end.Sub(start).Hours()
+	return append(costs, &model.NodeCost{
+		Name:        usage.NodeName,
+		Size:        usage.Size,
+		Master:      usage.Master,
+		HourlyPrice: usage.HourlyPrice,
+		StartedAt:   usage.StartedAt,
+		EndedAt:     endedAt,
+		Hours:       hours,
+		Cost:        hours * usage.HourlyPrice,
+	})
+}
+
+func sortCostItems(items []*model.CostItem) {
+	sort.Sort(costItemsByName(items))
+}
+
+type costItemsByName []*model.CostItem
+
+func (items costItemsByName) Len() int           { return len(items) }
+func (items costItemsByName) Swap(i, j int)      { items[i], items[j] = items[j], items[i] }
+func (items costItemsByName) Less(i, j int) bool { return items[i].Name < items[j].Name }
+
+//------------------------------------------------------------------------------
+
+// CostService accrues the spend of every ready Kube to its namespaces and
+// HelmReleases. The whole hourly rate of a Kube (masters included) is split by
+// the share of Node capacity reserved by the resource requests of running
+// pods; whatever is not reserved is accrued as UnallocatedCostName.
+type CostService struct {
+	Core *Core
+}
+
+func (s *CostService) Perform() error {
+	var kubes []*model.Kube
+	if err := s.Core.DB.Preload("CloudAccount").Preload("Nodes", "provider_id <> ?", "").Find(&kubes, "ready = ?", true); err != nil {
+		return err
+	}
+
+	// A Kube which fails is left to be accrued next time, without holding up
+	// the others
+	var errs serviceErrors
+	for _, kube := range kubes {
+		if err := s.perform(kube); err != nil {
+			errs = append(errs, fmt.Errorf("Cost service error on Kube %s: %s", kube.Name, err))
+		}
+	}
+	return errs.err()
+}
+
+func (s *CostService) perform(kube *model.Kube) error {
+	now := time.Now()
+	accrual := new(model.CostAccrual)
+	err := s.Core.DB.Where("kube_name = ?", kube.Name).First(accrual)
+	if err == gorm.ErrRecordNotFound {
+		// Nothing to accrue until the next run
+		return s.Core.DB.Create(&model.CostAccrual{KubeName: kube.Name, AccruedAt: now})
+	}
+	if err != nil {
+		return err
+	}
+
+	// An accrual from before the Kube was created is of a deleted Kube of the
+	// same name, which has nothing more to accrue
+	var allocations []*model.CostAllocation
+	if !accrual.AccruedAt.Before(kube.CreatedAt) {
+		if allocations, err = s.accrue(kube, now.Sub(accrual.AccruedAt), now); err != nil {
+			return err
+		}
+	}
+
+	// Together, so that spend is never accrued twice, or without being marked
+	// accrued
+	return s.Core.DB.Transaction(func(tx DBInterface) error {
+		for _, allocation := range allocations {
+			if err := addAllocation(tx, allocation); err != nil {
+				return err
+			}
+		}
+		accrual.AccruedAt = now
+		return tx.Save(accrual)
+	})
+}
+
+// accrue returns the amounts of the spend of the Kube over elapsed to add to
+// its namespaces and HelmReleases.
+func (s *CostService) accrue(kube *model.Kube, elapsed time.Duration, now time.Time) ([]*model.CostAllocation, error) {
+	var hourlyRate float64
+	if masters := s.Core.masterUsage(kube, now); masters != nil {
+		hourlyRate += masters.HourlyPrice
+	}
+	for _, node := range kube.Nodes {
+		if !node.ProviderCreationTimestamp.IsZero() {
+			hourlyRate += s.Core.nodeUsage(kube, node, now).HourlyPrice
+		}
+	}
+	spend := hourlyRate * elapsed.Hours()
+	if spend == 0 {
+		return nil, nil
+	}
+
+	namespaceShares, releaseShares, err := s.podShares(kube)
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []*model.CostAllocation
+	month := now.UTC().Format(costMonthFormat)
+	for name, share := range namespaceShares {
+		allocations = append(allocations, &model.CostAllocation{
+			KubeName: kube.Name,
+			Month:    month,
+			Kind:     model.CostAllocationKindNamespace,
+			Name:     name,
+			Amount:   spend * share,
+		})
+	}
+	for name, share := range releaseShares {
+		allocations = append(allocations, &model.CostAllocation{
+			KubeName: kube.Name,
+			Month:    month,
+			Kind:     model.CostAllocationKindHelmRelease,
+			Name:     name,
+			Amount:   spend * share,
+		})
+	}
+	return allocations, nil
+}
+
+// podShares returns the fraction of the Kube's Node capacity reserved by the
+// running pods of each namespace and each HelmRelease. Namespace shares always
+// sum to 1.
+func (s *CostService) podShares(kube *model.Kube) (namespaces map[string]float64, releases map[string]float64, err error) {
+	namespaces = make(map[string]float64)
+	releases = make(map[string]float64)
+
+	var capacityCPU, capacityRAM float64
+	nodeNames := make(map[string]bool)
+	for _, node := range kube.Nodes {
+		if nodeSize := s.Core.nodeSize(kube.CloudAccount.Provider, node.Size); nodeSize != nil {
+			capacityCPU += nodeSize.CPUCores
+			capacityRAM += nodeSize.RAMGIB
+			nodeNames[node.Name] = true
+		}
+	}
+	if capacityCPU == 0 || capacityRAM == 0 {
+		namespaces[model.UnallocatedCostName] = 1
+		return namespaces, releases, nil
+	}
+
+	var helmReleases []*model.HelmRelease
+	if err := s.Core.DB.Find(&helmReleases, "kube_name = ?", kube.Name); err != nil {
+		return nil, nil, err
+	}
+	releaseNames := make(map[string]bool)
+	for _, release := range helmReleases {
+		releaseNames[release.Name] = true
+	}
+
+	pods, err := s.Core.K8S(kube).ListPods("fieldSelector=status.phase=Running")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var allocated float64
+	for _, pod := range pods {
+		if !nodeNames[pod.Spec.NodeName] {
+			continue // e.g. on a master
+		}
+		cpu, ram, err := podRequests(pod)
+		if err != nil {
+			// Accrued as unallocated, rather than holding up the whole Kube
+			s.Core.Log.Warnf("Cost service skipping pod %s/%s of Kube %s: %s", pod.Metadata.Namespace, pod.Metadata.Name, kube.Name, err)
+			continue
+		}
+		share := (cpu/capacityCPU + ram/capacityRAM) / 2
+		if share == 0 {
+			continue
+		}
+		allocated += share
+		namespaces[pod.Metadata.Namespace] += share
+
+		// Charts conventionally label their pods with the release name
+		release := pod.Metadata.Labels["release"]
+		if release == "" {
+			release = pod.Metadata.Labels["app.kubernetes.io/instance"]
+		}
+		if releaseNames[release] {
+			releases[release] += share
+		}
+	}
+
+	// Requests can't normally exceed capacity, but pending scale-downs and
+	// unknown NodeSizes can make it look that way.
+	if allocated > 1 {
+		for name := range namespaces {
+			namespaces[name] /= allocated
+		}
+		for name := range releases {
+			releases[name] /= allocated
+		}
+		allocated = 1
+	}
+	if allocated < 1 {
+		namespaces[model.UnallocatedCostName] += 1 - allocated
+	}
+	return namespaces, releases, nil
+}
+
+// podRequests returns the cores and GiB of RAM requested by the containers of
+// the pod.
+func podRequests(pod *kubernetes.Pod) (cpu float64, ram float64, err error) {
+	for _, container := range pod.Spec.Containers {
+		cores, err := kubernetes.CoresFromCPUString(container.Resources.Requests.CPU)
+		if err != nil {
+			return 0, 0, err
+		}
+		gib, err := kubernetes.GiBFromMemString(container.Resources.Requests.Memory)
+		if err != nil {
+			return 0, 0, err
+		}
+		cpu += cores
+		ram += gib
+	}
+	return cpu, ram, nil
+}
+
+// addAllocation adds the amount of the allocation to the one of the same
+// month, kind and name, creating it if there's none.
+func addAllocation(db DBInterface, add *model.CostAllocation) error {
+	allocation := new(model.CostAllocation)
+	err := db.Where("kube_name = ? AND month = ? AND kind = ? AND name = ?", add.KubeName, add.Month, add.Kind, add.Name).First(allocation)
+	if err == gorm.ErrRecordNotFound {
+		return db.Create(add)
+	}
+	if err != nil {
+		return err
+	}
+	allocation.Amount += add.Amount
+	return db.Save(allocation)
+}
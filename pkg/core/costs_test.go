@@ -0,0 +1,459 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+)
+
+func TestCostServicePerform(t *testing.T) {
+	Convey("CostService Perform splits Kube spend by pod resource requests", t, func() {
+		table := []struct {
+			// Mocks / Input
+			nodes        []*model.Node
+			pods         []*kubernetes.Pod
+			helmReleases []*model.HelmRelease
+
+			// Expectations
+			namespaceShares map[string]float64
+			releaseShares   map[string]float64
+		}{
+			// Requests are split by namespace and release; the rest is unallocated
+			{
+				nodes: []*model.Node{
+					{
+						Name:                      "node-1",
+						Size:                      "2-core",
+						ProviderCreationTimestamp: time.Now().Add(-time.Hour),
+					},
+				},
+				pods: []*kubernetes.Pod{
+					newCostTestPod("team-a", "node-1", "web", "1", "2Gi"),
+					newCostTestPod("team-b", "node-1", "", "500m", "1Gi"),
+					// Not on a Node we know the price of
+					newCostTestPod("kube-system", "master", "", "1", "1Gi"),
+				},
+				helmReleases: []*model.HelmRelease{
+					{Name: "web"},
+				},
+				namespaceShares: map[string]float64{
+					"team-a":                  0.5,
+					"team-b":                  0.25,
+					model.UnallocatedCostName: 0.25,
+				},
+				releaseShares: map[string]float64{
+					"web": 0.5,
+				},
+			},
+
+			// Over-committed requests are scaled down to the whole Kube
+			{
+				nodes: []*model.Node{
+					{
+						Name:                      "node-1",
+						Size:                      "2-core",
+						ProviderCreationTimestamp: time.Now().Add(-time.Hour),
+					},
+				},
+				pods: []*kubernetes.Pod{
+					newCostTestPod("team-a", "node-1", "", "2", "4Gi"),
+					newCostTestPod("team-b", "node-1", "", "2", "4Gi"),
+				},
+				namespaceShares: map[string]float64{
+					"team-a": 0.5,
+					"team-b": 0.5,
+				},
+				releaseShares: map[string]float64{},
+			},
+
+			// Requests in any quantity format; pods with requests which can't be
+			// parsed are left unallocated
+			{
+				nodes: []*model.Node{
+					{
+						Name:                      "node-1",
+						Size:                      "2-core",
+						ProviderCreationTimestamp: time.Now().Add(-time.Hour),
+					},
+				},
+				pods: []*kubernetes.Pod{
+					newCostTestPod("team-a", "node-1", "", "1", "2.147483648G"),
+					newCostTestPod("team-b", "node-1", "", "500m", "1073741824e0"),
+					newCostTestPod("team-c", "node-1", "", "1", "lots"),
+				},
+				namespaceShares: map[string]float64{
+					"team-a":                  0.5,
+					"team-b":                  0.25,
+					model.UnallocatedCostName: 0.25,
+				},
+				releaseShares: map[string]float64{},
+			},
+
+			// Nothing running yet
+			{
+				namespaceShares: map[string]float64{},
+				releaseShares:   map[string]float64{},
+			},
+		}
+
+		for _, item := range table {
+			namespaceSpend := make(map[string]float64)
+			releaseSpend := make(map[string]float64)
+			var accrual *model.CostAccrual
+
+			kube := &model.Kube{
+				CloudAccount: &model.CloudAccount{
+					Provider: "test-provider",
+				},
+				Name:  "test-kube",
+				Nodes: item.nodes,
+			}
+
+			c := &core.Core{
+				Log: logrus.New(),
+
+				Settings: core.Settings{
+					NodeSizes: map[string][]*core.NodeSize{
+						"test-provider": []*core.NodeSize{
+							{
+								Name:        "2-core",
+								RAMGIB:      4,
+								CPUCores:    2,
+								HourlyPrice: 1,
+							},
+						},
+					},
+				},
+
+				DB: &fake_core.DB{
+					FindFn: func(out interface{}, where ...interface{}) error {
+						switch reflect.TypeOf(out).String() {
+						case "*[]*model.Kube":
+							reflect.ValueOf(out).Elem().Set(reflect.ValueOf([]*model.Kube{kube}))
+						case "*[]*model.HelmRelease":
+							reflect.ValueOf(out).Elem().Set(reflect.ValueOf(item.helmReleases))
+						}
+						return nil
+					},
+					FirstFn: func(out interface{}, where ...interface{}) error {
+						if out, ok := out.(*model.CostAccrual); ok && accrual != nil {
+							*out = *accrual
+							return nil
+						}
+						return gorm.ErrRecordNotFound
+					},
+					SaveFn: func(m model.Model) error {
+						accrual = m.(*model.CostAccrual)
+						return nil
+					},
+					CreateFn: func(m model.Model) error {
+						if m, ok := m.(*model.CostAccrual); ok {
+							accrual = m
+							return nil
+						}
+						allocation := m.(*model.CostAllocation)
+						switch allocation.Kind {
+						case model.CostAllocationKindNamespace:
+							namespaceSpend[allocation.Name] += allocation.Amount
+						case model.CostAllocationKindHelmRelease:
+							releaseSpend[allocation.Name] += allocation.Amount
+						}
+						return nil
+					},
+				},
+
+				K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+					return &fake_core.KubernetesClient{
+						ListPodsFn: func(query string) ([]*kubernetes.Pod, error) {
+							return item.pods, nil
+						},
+					}
+				},
+			}
+
+			service := &core.CostService{Core: c}
+
+			// The first run only records when spend is accrued from
+			So(service.Perform(), ShouldBeNil)
+			So(namespaceSpend, ShouldBeEmpty)
+			So(accrual, ShouldNotBeNil)
+
+			time.Sleep(10 * time.Millisecond)
+			So(service.Perform(), ShouldBeNil)
+
+			var total float64
+			for _, amount := range namespaceSpend {
+				total += amount
+			}
+			So(namespaceSpend, ShouldHaveLength, len(item.namespaceShares))
+			for name, share := range item.namespaceShares {
+				So(namespaceSpend[name]/total, ShouldAlmostEqual, share)
+			}
+			So(releaseSpend, ShouldHaveLength, len(item.releaseShares))
+			for name, share := range item.releaseShares {
+				So(releaseSpend[name]/total, ShouldAlmostEqual, share)
+			}
+		}
+	})
+}
+
+func TestCostServicePerformAccruals(t *testing.T) {
+	Convey("CostService Perform accrues spend from the last accrual, which is only advanced once accrued", t, func() {
+		hourAgo := time.Now().Add(-time.Hour)
+		accrual := &model.CostAccrual{KubeName: "test-kube", AccruedAt: hourAgo}
+		spend := make(map[string]float64)
+		var listPodsErr error
+
+		kube := &model.Kube{
+			BaseModel: model.BaseModel{
+				CreatedAt: hourAgo.Add(-time.Hour),
+			},
+			CloudAccount: &model.CloudAccount{
+				Provider: "test-provider",
+			},
+			Name: "test-kube",
+			Nodes: []*model.Node{
+				{
+					Name:                      "node-1",
+					Size:                      "2-core",
+					ProviderCreationTimestamp: hourAgo.Add(-time.Hour),
+				},
+			},
+		}
+
+		c := &core.Core{
+			Log: logrus.New(),
+
+			Settings: core.Settings{
+				NodeSizes: map[string][]*core.NodeSize{
+					"test-provider": []*core.NodeSize{
+						{
+							Name:        "2-core",
+							RAMGIB:      4,
+							CPUCores:    2,
+							HourlyPrice: 1,
+						},
+					},
+				},
+			},
+
+			DB: &fake_core.DB{
+				FindFn: func(out interface{}, where ...interface{}) error {
+					if out, ok := out.(*[]*model.Kube); ok {
+						*out = []*model.Kube{kube}
+					}
+					return nil
+				},
+				FirstFn: func(out interface{}, where ...interface{}) error {
+					if out, ok := out.(*model.CostAccrual); ok {
+						*out = *accrual
+						return nil
+					}
+					return gorm.ErrRecordNotFound
+				},
+				SaveFn: func(m model.Model) error {
+					accrual = m.(*model.CostAccrual)
+					return nil
+				},
+				CreateFn: func(m model.Model) error {
+					allocation := m.(*model.CostAllocation)
+					spend[allocation.Name] += allocation.Amount
+					return nil
+				},
+			},
+
+			K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+				return &fake_core.KubernetesClient{
+					ListPodsFn: func(query string) ([]*kubernetes.Pod, error) {
+						return nil, listPodsErr
+					},
+				}
+			},
+		}
+
+		// Such as after a restart
+		service := &core.CostService{Core: c}
+
+		Convey("A failed accrual isn't advanced past", func() {
+			listPodsErr = errors.New("connection refused")
+			So(service.Perform(), ShouldNotBeNil)
+			So(spend, ShouldBeEmpty)
+			So(accrual.AccruedAt, ShouldResemble, hourAgo)
+
+			listPodsErr = nil
+			So(service.Perform(), ShouldBeNil)
+			So(spend[model.UnallocatedCostName], ShouldAlmostEqual, 1, 0.001)
+			So(accrual.AccruedAt, ShouldHappenAfter, hourAgo)
+		})
+
+		Convey("An accrual of a deleted Kube of the same name isn't accrued from", func() {
+			kube.CreatedAt = time.Now().Add(-time.Minute)
+			So(service.Perform(), ShouldBeNil)
+			So(spend, ShouldBeEmpty)
+			So(accrual.AccruedAt, ShouldHappenAfter, kube.CreatedAt)
+		})
+	})
+}
+
+func TestCostServicePerformErrors(t *testing.T) {
+	Convey("CostService Perform goes on with other Kubes when one fails, and accrues each one at once", t, func() {
+		hourAgo := time.Now().Add(-time.Hour)
+		accruals := map[string]*model.CostAccrual{
+			"broken-kube": {KubeName: "broken-kube", AccruedAt: hourAgo},
+			"test-kube":   {KubeName: "test-kube", AccruedAt: hourAgo},
+		}
+		spend := make(map[string]float64)
+		var kubeName string
+		var commitErr error
+
+		var kubes []*model.Kube
+		for _, name := range []string{"broken-kube", "test-kube"} {
+			kubes = append(kubes, &model.Kube{
+				BaseModel: model.BaseModel{
+					CreatedAt: hourAgo.Add(-time.Hour),
+				},
+				CloudAccount: &model.CloudAccount{
+					Provider: "test-provider",
+				},
+				Name: name,
+				Nodes: []*model.Node{
+					{
+						Name:                      "node-1",
+						Size:                      "2-core",
+						ProviderCreationTimestamp: hourAgo.Add(-time.Hour),
+					},
+				},
+			})
+		}
+
+		db := new(fake_core.DB)
+		db.WhereFn = func(query interface{}, args ...interface{}) core.DBInterface {
+			kubeName = args[0].(string)
+			return db
+		}
+		db.FindFn = func(out interface{}, where ...interface{}) error {
+			if out, ok := out.(*[]*model.Kube); ok {
+				*out = kubes
+			}
+			return nil
+		}
+		db.FirstFn = func(out interface{}, where ...interface{}) error {
+			if out, ok := out.(*model.CostAccrual); ok {
+				*out = *accruals[kubeName]
+				return nil
+			}
+			return gorm.ErrRecordNotFound
+		}
+		db.SaveFn = func(m model.Model) error {
+			accrual := m.(*model.CostAccrual)
+			accruals[accrual.KubeName] = accrual
+			return nil
+		}
+		db.CreateFn = func(m model.Model) error {
+			allocation := m.(*model.CostAllocation)
+			spend[allocation.KubeName] += allocation.Amount
+			return nil
+		}
+		// Rolls back what's changed if commitErr is set
+		db.TransactionFn = func(fn func(tx core.DBInterface) error) error {
+			accrual := *accruals[kubeName]
+			spent := spend[kubeName]
+			if err := fn(db); err != nil {
+				return err
+			}
+			if commitErr != nil {
+				accruals[accrual.KubeName] = &accrual
+				spend[accrual.KubeName] = spent
+			}
+			return commitErr
+		}
+
+		c := &core.Core{
+			Log: logrus.New(),
+
+			Settings: core.Settings{
+				NodeSizes: map[string][]*core.NodeSize{
+					"test-provider": []*core.NodeSize{
+						{
+							Name:        "2-core",
+							RAMGIB:      4,
+							CPUCores:    2,
+							HourlyPrice: 1,
+						},
+					},
+				},
+			},
+
+			DB: db,
+
+			K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+				return &fake_core.KubernetesClient{
+					ListPodsFn: func(query string) ([]*kubernetes.Pod, error) {
+						if kube.Name == "broken-kube" {
+							return nil, errors.New("connection refused")
+						}
+						return nil, nil
+					},
+				}
+			},
+		}
+
+		service := &core.CostService{Core: c}
+
+		Convey("A Kube which fails doesn't stop the others from being accrued", func() {
+			err := service.Perform()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Cost service error on Kube broken-kube: connection refused")
+			So(spend["broken-kube"], ShouldEqual, 0)
+			So(accruals["broken-kube"].AccruedAt, ShouldResemble, hourAgo)
+			So(spend["test-kube"], ShouldAlmostEqual, 1, 0.001)
+			So(accruals["test-kube"].AccruedAt, ShouldHappenAfter, hourAgo)
+		})
+
+		Convey("Spend which fails to be saved isn't marked accrued, so it's accrued once when retried", func() {
+			commitErr = errors.New("database is locked")
+			So(service.Perform(), ShouldNotBeNil)
+			So(spend["test-kube"], ShouldEqual, 0)
+			So(accruals["test-kube"].AccruedAt, ShouldResemble, hourAgo)
+
+			commitErr = nil
+			So(service.Perform(), ShouldNotBeNil) // broken-kube
+			So(spend["test-kube"], ShouldAlmostEqual, 1, 0.001)
+		})
+	})
+}
+
+func newCostTestPod(namespace, nodeName, release, cpu, memory string) *kubernetes.Pod {
+	pod := &kubernetes.Pod{
+		Metadata: kubernetes.Metadata{
+			Namespace: namespace,
+			Labels:    map[string]string{},
+		},
+		Spec: kubernetes.PodSpec{
+			NodeName: nodeName,
+			Containers: []kubernetes.Container{
+				{
+					Resources: kubernetes.Resources{
+						Requests: kubernetes.ResourceValues{
+							CPU:    cpu,
+							Memory: memory,
+						},
+					},
+				},
+			},
+		},
+	}
+	if release != "" {
+		pod.Metadata.Labels["release"] = release
+	}
+	return pod
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -13,7 +14,9 @@ import (
 
 type DBInterface interface {
 	Create(model.Model) error
+	Validate(model.Model) error
 	Save(model.Model) error
+	SaveVersion(m model.Model, version int64) error
 	Find(out interface{}, where ...interface{}) error
 	First(out interface{}, where ...interface{}) error
 	Delete(m model.Model) error
@@ -21,9 +24,12 @@ type DBInterface interface {
 	Where(query interface{}, args ...interface{}) DBInterface
 	Limit(limit interface{}) DBInterface
 	Offset(offset interface{}) DBInterface
+	Order(value interface{}) DBInterface
+	Select(query interface{}, args ...interface{}) DBInterface
 	Model(value interface{}) DBInterface
 	Update(attrs ...interface{}) error
 	Count(interface{}) error
+	Transaction(fn func(tx DBInterface) error) error
 }
 
 type DB struct {
@@ -33,23 +39,87 @@ type DB struct {
 
 func (db *DB) Create(m model.Model) error {
 	m.SetUUID()
+	if versioned, ok := m.(model.Versioned); ok {
+		versioned.SetResourceVersion(1)
+	}
+	if err := db.Validate(m); err != nil {
+		return err
+	}
+	return db.Set("gorm:save_associations", true).Create(m).Error
+}
+
+// Validate sets the defaults of a model and checks it as Create does, without
+// saving it.
+func (db *DB) Validate(m model.Model) error {
 	setDefaultFields(m)
 	marshalSerializedFields(m)
 	if err := db.validateBelongsTos(m); err != nil {
 		return err
 	}
+	return validateFields(m)
+}
+
+// Save saves a model over the stored one. The resource_version of a Versioned
+// model is incremented from that stored, rather than that of the model, which
+// may have been read before other saves, and read back into it.
+func (db *DB) Save(m model.Model) error {
+	marshalSerializedFields(m)
 	if err := validateFields(m); err != nil {
 		return err
 	}
-	return db.Set("gorm:save_associations", true).Create(m).Error
+	versioned, ok := m.(model.Versioned)
+	if !ok {
+		return db.Set("gorm:save_associations", false).Save(m).Error
+	}
+	if db.NewScope(m).PrimaryKeyZero() {
+		versioned.SetResourceVersion(versioned.GetResourceVersion() + 1)
+		return db.Set("gorm:save_associations", false).Save(m).Error
+	}
+	return db.Transaction(func(tx DBInterface) error {
+		gormTx := tx.(*DB).DB
+		if err := gormTx.Set("gorm:save_associations", false).Omit("resource_version").Save(m).Error; err != nil {
+			return err
+		}
+		if err := gormTx.Model(m).UpdateColumn("resource_version", gorm.Expr("resource_version + 1")).Error; err != nil {
+			return err
+		}
+		var versions []int64
+		if err := gormTx.Model(m).Pluck("resource_version", &versions).Error; err != nil {
+			return err
+		}
+		if len(versions) == 1 {
+			versioned.SetResourceVersion(versions[0])
+		}
+		return nil
+	})
 }
 
-func (db *DB) Save(m model.Model) error {
+// SaveVersion saves a Versioned model only if the stored one is still at the
+// version given, which is that it was changed from. Otherwise it returns an
+// *ErrorConflict, rather than overwriting the changes made since.
+func (db *DB) SaveVersion(m model.Model, version int64) error {
+	versioned, ok := m.(model.Versioned)
+	if !ok {
+		return db.Save(m)
+	}
 	marshalSerializedFields(m)
 	if err := validateFields(m); err != nil {
 		return err
 	}
-	return db.Set("gorm:save_associations", false).Save(m).Error
+	return db.Transaction(func(tx DBInterface) error {
+		gormTx := tx.(*DB).DB
+		// Claim the next version first, so that of concurrent saves from the
+		// same version only one is made
+		claim := gormTx.Model(m).Where("resource_version = ?", version).UpdateColumn("resource_version", version+1)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return newErrorConflict(m, version)
+		}
+		versioned.SetResourceVersion(version + 1)
+		return gormTx.Set("gorm:save_associations", false).Save(m).Error
+	})
 }
 
 func (db *DB) Find(out interface{}, where ...interface{}) error {
@@ -110,6 +180,20 @@ func (db *DB) Offset(offset interface{}) DBInterface {
 	}
 }
 
+func (db *DB) Order(value interface{}) DBInterface {
+	return &DB{
+		db.core,
+		db.DB.Order(value),
+	}
+}
+
+func (db *DB) Select(query interface{}, args ...interface{}) DBInterface {
+	return &DB{
+		db.core,
+		db.DB.Select(query, args...),
+	}
+}
+
 func (db *DB) Model(value interface{}) DBInterface {
 	return &DB{
 		db.core,
@@ -125,6 +209,30 @@ func (db *DB) Count(value interface{}) error {
 	return db.DB.Count(value).Error
 }
 
+// Transaction runs fn with a DB whose changes are committed if it returns nil,
+// and rolled back if it returns an error or panics. Within a transaction
+// already, fn is run as part of it.
+func (db *DB) Transaction(fn func(tx DBInterface) error) error {
+	if _, ok := db.DB.CommonDB().(*sql.Tx); ok {
+		return fn(db)
+	}
+	tx := db.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+	if err := fn(&DB{db.core, tx}); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit().Error
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Private methods                                                            //
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// DNSProvider manages the records of DNSZones.
+type DNSProvider interface {
+	// UpsertRecord creates the record, or replaces the values of any existing
+	// record of the same name and type.
+	UpsertRecord(*model.DNSZone, *DNSRecord) error
+	// DeleteRecord deletes all records of the name and type, if any.
+	DeleteRecord(*model.DNSZone, *DNSRecord) error
+}
+
+// DNSRecord is a single resource record. Name is fully qualified, without the
+// trailing dot.
+type DNSRecord struct {
+	Name  string
+	Type  string
+	Value string
+	TTL   int
+}
+
+type DNSZones struct {
+	Collection
+}
+
+func (c *DNSZones) Create(m *model.DNSZone) error {
+	// NOTE we have to do pre-validation here in order to make sure provider is correct
+	if err := validateFields(m); err != nil {
+		return err
+	}
+	if err := c.validate(m); err != nil {
+		return err
+	}
+	return c.Collection.Create(m)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Private methods                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+// cloudAccountProviders are the CloudAccount providers whose credentials the
+// DNS providers use.
+var cloudAccountProviders = map[string]string{
+	"route53":      "aws",
+	"digitalocean": "digitalocean",
+}
+
+func (c *DNSZones) validate(m *model.DNSZone) error {
+	if accountProvider, ok := cloudAccountProviders[m.Provider]; ok {
+		if m.CloudAccountName == "" {
+			return &ErrorValidationFailed{fmt.Errorf("cloud_account_name is required for the %s provider", m.Provider)}
+		}
+		account := new(model.CloudAccount)
+		if err := c.Core.DB.First(account, "name = ?", m.CloudAccountName); err != nil {
+			return &ErrorMissingRequiredParent{"CloudAccountName", "DNSZone"}
+		}
+		if account.Provider != accountProvider {
+			return &ErrorValidationFailed{fmt.Errorf("The %s provider requires a CloudAccount of provider %s", m.Provider, accountProvider)}
+		}
+	}
+	if m.Provider == "rfc2136" {
+		if _, _, err := net.SplitHostPort(m.RFC2136Server); err != nil {
+			return &ErrorValidationFailed{errors.New("rfc2136_server must be a host:port")}
+		}
+		if (m.RFC2136TSIGKeyName == "") != (m.RFC2136TSIGSecret == "") {
+			return &ErrorValidationFailed{errors.New("rfc2136_tsig_key_name and rfc2136_tsig_secret must be set together")}
+		}
+	}
+	return nil
+}
+
+func (c *DNSZones) provider(m *model.DNSZone) DNSProvider {
+	switch m.Provider {
+	case "route53":
+		return c.Core.Route53Provider(m.CloudAccount.Credentials)
+	case "digitalocean":
+		return c.Core.DODNSProvider(m.CloudAccount.Credentials)
+	case "rfc2136":
+		return c.Core.RFC2136Provider
+	default:
+		panic("Could not load DNS provider interface for " + m.Provider)
+	}
+}
+
+// zoneFor returns the most specific DNSZone the host is within, or nil.
+func (c *DNSZones) zoneFor(host string) (*model.DNSZone, error) {
+	var zones []*model.DNSZone
+	if err := c.Core.DB.Preload("CloudAccount").Find(&zones); err != nil {
+		return nil, err
+	}
+	var match *model.DNSZone
+	for _, zone := range zones {
+		if host != zone.Name && !strings.HasSuffix(host, "."+zone.Name) {
+			continue
+		}
+		if match == nil || len(zone.Name) > len(match.Name) {
+			match = zone
+		}
+	}
+	return match, nil
+}
+
+// pointHost points the host at the address, if the host is within a DNSZone.
+func (c *DNSZones) pointHost(host string, address string) error {
+	zone, err := c.zoneFor(host)
+	if err != nil || zone == nil {
+		return err
+	}
+	record := &DNSRecord{
+		Name:  host,
+		Type:  "CNAME",
+		Value: address,
+		TTL:   zone.TTL,
+	}
+	if ip := net.ParseIP(address); ip != nil {
+		record.Type = "A"
+		if ip.To4() == nil {
+			record.Type = "AAAA"
+		}
+	} else if host == zone.Name {
+		return &ErrorValidationFailed{fmt.Errorf("Cannot point %s at %s, as the apex of a DNSZone cannot be a CNAME", host, address)}
+	}
+	return c.provider(zone).UpsertRecord(zone, record)
+}
+
+// unpointHost deletes the records pointHost may have created for the host.
+func (c *DNSZones) unpointHost(host string) error {
+	zone, err := c.zoneFor(host)
+	if err != nil || zone == nil {
+		return err
+	}
+	for _, recordType := range []string{"CNAME", "A", "AAAA"} {
+		if err := c.provider(zone).DeleteRecord(zone, &DNSRecord{Name: host, Type: recordType}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// acmeChallengeTTL is short, as challenge records only live for minutes.
+const acmeChallengeTTL = 60
+
+// dnsSolver publishes the records of ACME DNS-01 challenges in DNSZones.
+type dnsSolver struct {
+	zones *DNSZones
+}
+
+func (s *dnsSolver) Present(fqdn string, value string) error {
+	zone, err := s.zones.zoneFor(fqdn)
+	if err != nil {
+		return err
+	}
+	if zone == nil {
+		return fmt.Errorf("No DNSZone for %s, which is needed to issue a certificate", fqdn)
+	}
+	record := &DNSRecord{
+		Name:  fqdn,
+		Type:  "TXT",
+		Value: value,
+		TTL:   acmeChallengeTTL,
+	}
+	return s.zones.provider(zone).UpsertRecord(zone, record)
+}
+
+func (s *dnsSolver) CleanUp(fqdn string, value string) error {
+	zone, err := s.zones.zoneFor(fqdn)
+	if err != nil || zone == nil {
+		return err
+	}
+	return s.zones.provider(zone).DeleteRecord(zone, &DNSRecord{Name: fqdn, Type: "TXT"})
+}
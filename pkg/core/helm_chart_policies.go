@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+type HelmChartPolicies struct {
+	Collection
+}
+
+func (c *HelmChartPolicies) Create(m *model.HelmChartPolicy) error {
+	if err := validateHelmChartPolicy(m); err != nil {
+		return err
+	}
+	return c.Collection.Create(m)
+}
+
+func (c *HelmChartPolicies) Update(id *int64, oldM *model.HelmChartPolicy, m *model.HelmChartPolicy) error {
+	if err := validateHelmChartPolicy(m); err != nil {
+		return err
+	}
+	return c.Collection.Update(id, oldM, m)
+}
+
+// check returns a validation error if the policies don't allow installing the
+// chart version of the release on its Kube.
+func (c *HelmChartPolicies) check(m *model.HelmRelease) error {
+	var policies []*model.HelmChartPolicy
+	if err := c.Core.DB.Find(&policies); err != nil {
+		return err
+	}
+	if reason := helmChartPolicyDenial(policies, m.KubeName, m.RepoName, m.ChartName, m.ChartVersion); reason != "" {
+		return &ErrorValidationFailed{fmt.Errorf("%s/%s %s can't be installed on Kube %s, %s", m.RepoName, m.ChartName, m.ChartVersion, m.KubeName, reason)}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// validateHelmChartPolicy checks the patterns and version constraint of the
+// policy, which may be empty.
+func validateHelmChartPolicy(m *model.HelmChartPolicy) error {
+	patterns := []struct {
+		field   string
+		pattern string
+	}{
+		{"kube_name", m.KubeName},
+		{"repo_name", m.RepoName},
+		{"chart_name", m.ChartName},
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p.pattern, ""); err != nil {
+			return &ErrorValidationFailed{fmt.Errorf("%s: invalid pattern '%s'", p.field, p.pattern)}
+		}
+	}
+	if m.Versions != "" {
+		if _, err := semver.NewConstraint(m.Versions); err != nil {
+			return &ErrorValidationFailed{fmt.Errorf("versions: %s", err)}
+		}
+	}
+	return nil
+}
+
+// helmChartPolicyDenial returns why the policies don't allow the chart version
+// on the Kube, or "" if they do.
+func helmChartPolicyDenial(policies []*model.HelmChartPolicy, kubeName, repoName, chartName, version string) string {
+	allowed, allowlisted := false, false
+	for _, policy := range policies {
+		if !helmChartPolicyMatches(policy.KubeName, kubeName) {
+			continue
+		}
+		matches := helmChartPolicyMatches(policy.RepoName, repoName) &&
+			helmChartPolicyMatches(policy.ChartName, chartName) &&
+			helmChartVersionMatches(policy.Versions, version)
+
+		switch policy.Effect {
+		case model.HelmChartPolicyDeny:
+			if matches {
+				return fmt.Sprintf("denied by HelmChartPolicy %d", *policy.ID)
+			}
+		case model.HelmChartPolicyAllow:
+			allowlisted = true
+			allowed = allowed || matches
+		}
+	}
+	if allowlisted && !allowed {
+		return "no HelmChartPolicy allows it"
+	}
+	return ""
+}
+
+func helmChartPolicyMatches(pattern string, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+func helmChartVersionMatches(constraint string, version string) bool {
+	if constraint == "" {
+		return true
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
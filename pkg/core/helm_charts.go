@@ -1,17 +1,23 @@
 package core
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/ghodss/yaml"
-
+	"github.com/Masterminds/semver"
 	"k8s.io/helm/cmd/helm/downloader"
 	"k8s.io/helm/cmd/helm/helmpath"
 	"k8s.io/helm/cmd/helm/search"
 	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/repo"
 
 	"github.com/supergiant/supergiant/pkg/model"
@@ -28,68 +34,315 @@ func (c *HelmCharts) Populate() error {
 	}
 
 	for _, repoModel := range repos {
-		results, err := searchHelmRepo(repoModel)
-		if err != nil {
+		if err := c.populateRepo(repoModel); err != nil {
 			return err
 		}
+	}
 
-		chartsToDelete := repoModel.Charts
+	// So helm workers fetch the indexes again, with the charts listed now
+	c.Core.forgetHelmRepos()
+	return nil
+}
 
-		for _, result := range results {
+// populateRepo creates, updates and deletes the charts of the repo (with its
+// Charts loaded) to match its index.
+func (c *HelmCharts) populateRepo(repoModel *model.HelmRepo) error {
+	results, err := searchHelmRepo(repoModel)
+	if err != nil {
+		return err
+	}
 
-			var existingChart *model.HelmChart
-			existingIndex := 0
-			newChart := &model.HelmChart{
-				Repo:        repoModel,
-				RepoName:    repoModel.Name,
-				Name:        result.Chart.Name,
-				Version:     result.Chart.Version,
-				Description: result.Chart.Description,
-			}
+	chartsToDelete := repoModel.Charts
+
+	for _, result := range results {
+
+		var existingChart *model.HelmChart
+		existingIndex := 0
+		newChart := &model.HelmChart{
+			Repo:        repoModel,
+			RepoName:    repoModel.Name,
+			Name:        result.Chart.Name,
+			Version:     result.Chart.Version,
+			Description: result.Chart.Description,
+			Home:        result.Chart.Home,
+			Icon:        result.Chart.Icon,
+			Keywords:    result.Chart.Keywords,
+			Deprecated:  strings.HasPrefix(strings.ToUpper(result.Chart.Description), "DEPRECATED"),
+		}
+		for _, maintainer := range result.Chart.Maintainers {
+			newChart.Maintainers = append(newChart.Maintainers, &model.HelmChartMaintainer{
+				Name:  maintainer.Name,
+				Email: maintainer.Email,
+			})
+		}
 
-			for i, chart := range repoModel.Charts {
-				err := c.Get(chart.ID, new(model.HelmChart)) // TODO: Shuould we only look if null?
-				if err != nil {
-					fmt.Println("Chart Config Load Error:", err)
-				}
-
-				if chart.Version == newChart.Version && chart.Name == newChart.Name {
-					existingChart = chart
-					existingIndex = i
-					break
-				}
+		for i, chart := range repoModel.Charts {
+			err := c.Get(chart.ID, new(model.HelmChart)) // TODO: Shuould we only look if null?
+			if err != nil {
+				fmt.Println("Chart Config Load Error:", err)
 			}
 
-			if existingChart != nil {
-				// remove from chartsToDelete
-				chartsToDelete = append(chartsToDelete[:existingIndex], chartsToDelete[existingIndex+1:]...)
-
-				// if !reflect.DeepEqual(existingChart, newChart) {
-				// update chart
-				// NOTE we're not using the collection's Update method here to avoid immutability constraints
-				if err := c.mergeUpdate(existingChart.ID, existingChart, newChart); err != nil {
-					return err
-				}
-				// }
-			} else {
-				// create new
-				c.Core.Log.Info("New Chart Version Detected for Chart:", newChart.Name)
-				if err := c.Core.HelmCharts.Create(newChart); err != nil {
-					return err
-				}
+			if chart.Version == newChart.Version && chart.Name == newChart.Name {
+				existingChart = chart
+				existingIndex = i
+				break
 			}
 		}
 
-		for _, chartToDelete := range chartsToDelete {
-			if err := c.Core.HelmCharts.Delete(chartToDelete.ID, chartToDelete); err != nil {
+		if existingChart != nil {
+			// remove from chartsToDelete
+			chartsToDelete = append(chartsToDelete[:existingIndex], chartsToDelete[existingIndex+1:]...)
+
+			// if !reflect.DeepEqual(existingChart, newChart) {
+			// update chart
+			// NOTE we're not using the collection's Update method here to avoid immutability constraints
+			newChart.ResourceVersion = existingChart.ResourceVersion
+			if err := c.mergeUpdate(existingChart.ID, existingChart, newChart); err != nil {
+				return err
+			}
+			// }
+		} else {
+			// create new
+			c.Core.Log.Info("New Chart Version Detected for Chart:", newChart.Name)
+			if err := c.Core.HelmCharts.Create(newChart); err != nil {
 				return err
 			}
 		}
 	}
 
+	for _, chartToDelete := range chartsToDelete {
+		if err := c.Core.HelmCharts.Delete(chartToDelete.ID, chartToDelete); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Upload stores the packaged chart in the local HelmRepo, replacing the same
+// version if it was uploaded before, and returns its HelmChart.
+func (c *HelmCharts) Upload(upload *model.HelmChartUpload) (*model.HelmChart, error) {
+	loadedChart, err := chartutil.LoadArchive(bytes.NewReader(upload.Archive))
+	if err != nil {
+		return nil, &ErrorValidationFailed{fmt.Errorf("archive: %s", err)}
+	}
+	meta := loadedChart.Metadata
+	if meta == nil || meta.Name == "" || meta.Version == "" || meta.Description == "" {
+		return nil, &ErrorValidationFailed{errors.New("archive: Chart.yaml must have a name, version and description")}
+	}
+	filename := meta.Name + "-" + meta.Version + ".tgz"
+	if filename != filepath.Base(filename) {
+		return nil, &ErrorValidationFailed{fmt.Errorf("archive: invalid chart name or version '%s'", filename)}
+	}
+	defaultConfig, valuesSchema, err := chartValuesOf(loadedChart)
+	if err != nil {
+		return nil, &ErrorValidationFailed{fmt.Errorf("archive: %s", err)}
+	}
+
+	c.Core.helmUploadMutex.Lock()
+	defer c.Core.helmUploadMutex.Unlock()
+
+	dir, err := c.Core.helmLocalRepoDir()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, filename), upload.Archive, 0644); err != nil {
+		return nil, err
+	}
+	index, err := repo.IndexDirectory(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	index.SortEntries()
+	if err = index.WriteFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		return nil, err
+	}
+
+	repoModel, err := c.Core.HelmRepos.local()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.populateRepo(repoModel); err != nil {
+		return nil, err
+	}
+
+	m := new(model.HelmChart)
+	if err = c.Core.DB.Where("repo_name = ? AND name = ? AND version = ?", localHelmRepoName, meta.Name, meta.Version).First(m); err != nil {
+		return nil, err
+	}
+	// The values may have changed since the version was last uploaded
+	m.DefaultConfig, m.ValuesSchema = defaultConfig, valuesSchema
+	m.DefaultConfigJSON, m.ValuesSchemaJSON = nil, nil
+	return m, c.Core.DB.Save(m)
+}
+
+//------------------------------------------------------------------------------
+
+// ParseHelmChartSearch parses the query string of a search of the catalog,
+// such as q=redis&latest=true&deprecated=false&kube_name=production.
+func ParseHelmChartSearch(query url.Values) (*model.HelmChartSearch, error) {
+	search := &model.HelmChartSearch{
+		Query:    query.Get("q"),
+		RepoName: query.Get("repo_name"),
+		KubeName: query.Get("kube_name"),
+	}
+	if latest := query.Get("latest"); latest != "" {
+		value, err := ParseBoolParam("latest", latest)
+		if err != nil {
+			return nil, err
+		}
+		search.Latest = value
+	}
+	if deprecated := query.Get("deprecated"); deprecated != "" {
+		value, err := ParseBoolParam("deprecated", deprecated)
+		if err != nil {
+			return nil, err
+		}
+		search.Deprecated = &value
+	}
+	return search, nil
+}
+
+// ParseBoolParam parses the value of a query string parameter which must be
+// true or false.
+func ParseBoolParam(name string, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &ErrorValidationFailed{fmt.Errorf("%s must be true or false, got '%s'", name, value)}
+	}
+	return b, nil
+}
+
+// Search returns the charts matching the search, best matches first. Charts
+// which match as well are sorted by repo and name, newest version first.
+func (c *HelmCharts) Search(search *model.HelmChartSearch) ([]*model.HelmChart, error) {
+	scope := c.Core.DB
+	if search.RepoName != "" {
+		scope = scope.Where("repo_name = ?", search.RepoName)
+	}
+	var charts []*model.HelmChart
+	if err := scope.Find(&charts); err != nil {
+		return nil, err
+	}
+
+	var policies []*model.HelmChartPolicy
+	if search.KubeName != "" {
+		if err := c.Core.DB.Find(&policies); err != nil {
+			return nil, err
+		}
+	}
+
+	terms := strings.Fields(strings.ToLower(search.Query))
+	scores := make(map[*model.HelmChart]int)
+	var results []*model.HelmChart
+
+	for _, chart := range charts {
+		if search.Deprecated != nil && chart.Deprecated != *search.Deprecated {
+			continue
+		}
+		score, ok := helmChartScore(chart, terms)
+		if !ok {
+			continue
+		}
+		if search.KubeName != "" && helmChartPolicyDenial(policies, search.KubeName, chart.RepoName, chart.Name, chart.Version) != "" {
+			continue
+		}
+		scores[chart] = score
+		results = append(results, chart)
+	}
+
+	sort.Sort(&helmChartsByScore{results, scores})
+
+	if search.Latest {
+		// Sorted newest first, so the first of each chart is kept
+		seen := make(map[string]bool)
+		latest := results[:0]
+		for _, chart := range results {
+			key := chart.RepoName + "/" + chart.Name
+			if !seen[key] {
+				seen[key] = true
+				latest = append(latest, chart)
+			}
+		}
+		results = latest
+	}
+
+	return results, nil
+}
+
+// helmChartsByScore orders charts by score, then repo and name, then newest
+// version first.
+type helmChartsByScore struct {
+	charts []*model.HelmChart
+	scores map[*model.HelmChart]int
+}
+
+func (s *helmChartsByScore) Len() int      { return len(s.charts) }
+func (s *helmChartsByScore) Swap(i, j int) { s.charts[i], s.charts[j] = s.charts[j], s.charts[i] }
+func (s *helmChartsByScore) Less(i, j int) bool {
+	a, b := s.charts[i], s.charts[j]
+	if s.scores[a] != s.scores[b] {
+		return s.scores[a] < s.scores[b]
+	}
+	if a.RepoName != b.RepoName {
+		return a.RepoName < b.RepoName
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return helmChartVersionLess(b.Version, a.Version)
+}
+
+// helmChartScore returns how well the chart matches the search terms, lower
+// being better, and false if a term isn't found. A term scores 0 if it is the
+// name, 1 if it is in the name, 2 if it is in a keyword and 3 if it is in the
+// description.
+func helmChartScore(chart *model.HelmChart, terms []string) (score int, ok bool) {
+	name, description := strings.ToLower(chart.Name), strings.ToLower(chart.Description)
+	for _, term := range terms {
+		switch {
+		case name == term:
+		case strings.Contains(name, term):
+			score++
+		case helmChartHasKeyword(chart, term):
+			score += 2
+		case strings.Contains(description, term):
+			score += 3
+		default:
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+func helmChartHasKeyword(chart *model.HelmChart, term string) bool {
+	for _, keyword := range chart.Keywords {
+		if strings.Contains(strings.ToLower(keyword), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// helmChartVersionLess compares semver versions, and other versions as
+// strings, after any semver version.
+func helmChartVersionLess(a string, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		return va.LessThan(vb)
+	case errA == nil:
+		return false
+	case errB == nil:
+		return true
+	}
+	return a < b
+}
+
 //------------------------------------------------------------------------------
 
 func (c *HelmCharts) Get(id *int64, m *model.HelmChart) error {
@@ -110,31 +363,47 @@ func (c *HelmCharts) loadConfig(m *model.HelmChart) error {
 		return nil
 	}
 
-	if err := updateHelmRepoFile(c.Core); err != nil {
+	repoModel := new(model.HelmRepo)
+	if err := c.Core.DB.Where("name = ?", m.RepoName).First(repoModel); err != nil {
 		return err
 	}
 
-	nameWithRepo := m.RepoName + "/" + m.Name
-	chartPath, err := locateChartPath(nameWithRepo, m.Version)
-	if err != nil {
-		return err
+	var loadedChart *chart.Chart
+	if helmRepoIsPrivate(repoModel) {
+		// The downloader of Helm can't authenticate, or read local files
+		archive, err := fetchHelmChart(repoModel, m.Name, m.Version)
+		if err != nil {
+			return err
+		}
+		if loadedChart, err = chartutil.LoadArchive(bytes.NewReader(archive)); err != nil {
+			return err
+		}
+	} else {
+		if err := updateHelmRepoFile(c.Core); err != nil {
+			return err
+		}
+
+		nameWithRepo := m.RepoName + "/" + m.Name
+		chartPath, err := locateChartPath(nameWithRepo, m.Version)
+		if err != nil {
+			return err
+		}
+
+		if loadedChart, err = chartutil.Load(chartPath); err != nil {
+			return err
+		}
 	}
 
-	loadedChart, err := chartutil.Load(chartPath)
+	defaultConfig, valuesSchema, err := chartValuesOf(loadedChart)
 	if err != nil {
 		return err
 	}
 
-	yamlDefaultConfig := loadedChart.GetValues().Raw
-
-	if yamlDefaultConfig == "" {
+	if defaultConfig == nil && valuesSchema == nil {
 		return nil
 	}
 
-	if err = yaml.Unmarshal([]byte(yamlDefaultConfig), &m.DefaultConfig); err != nil {
-		return err
-	}
-
+	m.DefaultConfig, m.ValuesSchema = defaultConfig, valuesSchema
 	return c.Core.DB.Save(m)
 }
 
@@ -165,8 +434,15 @@ func searchHelmRepo(repoModel *model.HelmRepo) ([]*search.Result, error) {
 	home := helmHome()
 	index := search.NewIndex()
 
+	data, err := helmRepoGet(repoModel, "index.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = repo.LoadIndex(data); err != nil {
+		return nil, err
+	}
 	cif := home.CacheIndex(repoModel.Name)
-	if err := repo.DownloadIndexFile(repoModel.Name, repoModel.URL, cif); err != nil {
+	if err = ioutil.WriteFile(cif, data, 0644); err != nil {
 		return nil, err
 	}
 
@@ -175,11 +451,33 @@ func searchHelmRepo(repoModel *model.HelmRepo) ([]*search.Result, error) {
 		return nil, err
 	}
 
-	index.AddRepo(repoModel.Name, ind, false)
+	// Every uploaded version is listed, since they are all being tried out
+	index.AddRepo(repoModel.Name, ind, helmRepoIsLocal(repoModel))
 
 	return index.All(), nil
 }
 
+// fetchHelmChart returns the archive of the version of the chart, looked up in
+// the index of the repo.
+func fetchHelmChart(repoModel *model.HelmRepo, name, version string) ([]byte, error) {
+	data, err := helmRepoGet(repoModel, "index.yaml")
+	if err != nil {
+		return nil, err
+	}
+	index, err := repo.LoadIndex(data)
+	if err != nil {
+		return nil, err
+	}
+	chartVersion, err := index.Get(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(chartVersion.URLs) == 0 {
+		return nil, fmt.Errorf("HelmRepo %s has no URL for %s-%s", repoModel.Name, name, version)
+	}
+	return helmRepoGet(repoModel, chartVersion.URLs[0])
+}
+
 //------------------------------------------------------------------------------
 
 func updateHelmRepoFile(c *Core) error {
@@ -197,7 +495,7 @@ func updateHelmRepoFile(c *Core) error {
 	}
 
 	for _, repoModel := range repos {
-		if !r.Has(repoModel.Name) {
+		if !r.Has(repoModel.Name) && !helmRepoIsPrivate(repoModel) {
 			r.Add(&repo.Entry{
 				Name:  repoModel.Name,
 				URL:   repoModel.URL,
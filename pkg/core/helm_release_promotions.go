@@ -0,0 +1,196 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
+	"github.com/jinzhu/gorm"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+type HelmReleasePromotions struct {
+	Collection
+}
+
+// Create records the promotion of its HelmRelease, and promotes it unless the
+// Kube promoted to requires approval.
+func (c *HelmReleasePromotions) Create(m *model.HelmReleasePromotion) error {
+	source := new(model.HelmRelease)
+	if err := c.Core.DB.First(source, *m.HelmReleaseID); err != nil {
+		return err
+	}
+	if m.KubeName == source.KubeName {
+		return &ErrorValidationFailed{fmt.Errorf("kube_name: HelmRelease %s is on Kube %s already", source.Name, source.KubeName)}
+	}
+
+	m.ReleaseName = source.Name
+	m.RepoName = source.RepoName
+	m.ChartName = source.ChartName
+	m.ChartVersion = source.ChartVersion
+	m.SourceValues = append([]string{}, source.Values...)
+	if len(source.Config) > 0 {
+		config, err := yaml.Marshal(source.Config)
+		if err != nil {
+			return err
+		}
+		m.SourceValues = append(m.SourceValues, string(config))
+	}
+	if m.Namespace == "" {
+		m.Namespace = source.Namespace
+	}
+	m.State = model.HelmReleasePromotionPending
+
+	if err := c.Collection.Create(m); err != nil {
+		return err
+	}
+	if m.Kube.PromotionApproval {
+		return nil
+	}
+	return c.promote(m)
+}
+
+// Approve promotes the release of a promotion pending approval.
+func (c *HelmReleasePromotions) Approve(id *int64, m *model.HelmReleasePromotion, reviewer string) error {
+	if err := c.pending(id, m); err != nil {
+		return err
+	}
+	m.ReviewedBy = reviewer
+	return c.promote(m)
+}
+
+// Reject closes a promotion pending approval without promoting it.
+func (c *HelmReleasePromotions) Reject(id *int64, m *model.HelmReleasePromotion, reviewer string) error {
+	if err := c.pending(id, m); err != nil {
+		return err
+	}
+	m.ReviewedBy = reviewer
+	m.State = model.HelmReleasePromotionRejected
+	return c.Core.DB.Save(m)
+}
+
+//------------------------------------------------------------------------------
+
+func (c *HelmReleasePromotions) pending(id *int64, m *model.HelmReleasePromotion) error {
+	if err := c.Core.DB.First(m, *id); err != nil {
+		return err
+	}
+	if m.State != model.HelmReleasePromotionPending {
+		return &ErrorValidationFailed{fmt.Errorf("HelmReleasePromotion is %s, not %s", m.State, model.HelmReleasePromotionPending)}
+	}
+	return nil
+}
+
+// promote installs the release on the Kube of the promotion, or upgrades the
+// release of the same name there. The Config of a release being upgraded is
+// kept unless the promotion has one, so the overrides of an environment only
+// need to be given once.
+func (c *HelmReleasePromotions) promote(m *model.HelmReleasePromotion) error {
+	release := &model.HelmRelease{
+		ChartVersion: m.ChartVersion,
+		Values:       append(append([]string{}, m.SourceValues...), m.Values...),
+		Config:       m.Config,
+		Wait:         m.Wait,
+		Timeout:      m.Timeout,
+	}
+
+	target := new(model.HelmRelease)
+	err := c.Core.DB.Where("kube_name = ? AND name = ?", m.KubeName, m.ReleaseName).First(target)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		release.KubeName = m.KubeName
+		release.RepoName = m.RepoName
+		release.ChartName = m.ChartName
+		release.Name = m.ReleaseName
+		release.Namespace = m.Namespace
+		err = c.Core.HelmReleases.Create(release)
+
+	case err == nil:
+		if target.RepoName != m.RepoName || target.ChartName != m.ChartName {
+			return &ErrorValidationFailed{fmt.Errorf("HelmRelease %s on Kube %s is of chart %s/%s, not %s/%s", target.Name, m.KubeName, target.RepoName, target.ChartName, m.RepoName, m.ChartName)}
+		}
+		// Otherwise the old Values of the target would be kept
+		if len(release.Values) == 0 {
+			release.Values = []string{"{}"}
+		}
+		release.ResourceVersion = target.ResourceVersion
+		err = c.Core.HelmReleases.Update(target.ID, new(model.HelmRelease), release)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.State = model.HelmReleasePromotionPromoted
+	m.TargetReleaseID = release.ID
+	return c.Core.DB.Save(m)
+}
+
+//------------------------------------------------------------------------------
+
+// Drift lists the releases of the same name and chart as the release, on
+// every Kube (the release included), by Kube name.
+func (c *HelmReleases) Drift(m *model.HelmRelease) ([]*model.HelmReleaseDriftItem, error) {
+	var releases []*model.HelmRelease
+	if err := c.Core.DB.Where("name = ? AND repo_name = ? AND chart_name = ?", m.Name, m.RepoName, m.ChartName).Find(&releases); err != nil {
+		return nil, err
+	}
+	sort.Sort(helmReleasesByKubeName(releases))
+
+	values, err := helmReleaseValues(m)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*model.HelmReleaseDriftItem, 0, len(releases))
+	for _, release := range releases {
+		releaseValues, err := helmReleaseValues(release)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &model.HelmReleaseDriftItem{
+			ID:           release.ID,
+			KubeName:     release.KubeName,
+			Namespace:    release.Namespace,
+			ChartVersion: release.ChartVersion,
+			AppVersion:   release.AppVersion,
+			Revision:     release.Revision,
+			StatusValue:  release.StatusValue,
+			Drift:        chartVersionDrift(m.ChartVersion, release.ChartVersion),
+			ValuesDiffer: !reflect.DeepEqual(values, releaseValues),
+		})
+	}
+	return items, nil
+}
+
+// chartVersionDrift returns whether version is "same", "behind" or "ahead" of
+// base, or "unknown" if either isn't semver.
+func chartVersionDrift(base string, version string) string {
+	baseVersion, err := semver.NewVersion(base)
+	if err != nil {
+		return "unknown"
+	}
+	other, err := semver.NewVersion(version)
+	if err != nil {
+		return "unknown"
+	}
+	switch other.Compare(baseVersion) {
+	case -1:
+		return "behind"
+	case 1:
+		return "ahead"
+	}
+	return "same"
+}
+
+type helmReleasesByKubeName []*model.HelmRelease
+
+func (releases helmReleasesByKubeName) Len() int { return len(releases) }
+func (releases helmReleasesByKubeName) Swap(i, j int) {
+	releases[i], releases[j] = releases[j], releases[i]
+}
+func (releases helmReleasesByKubeName) Less(i, j int) bool {
+	return releases[i].KubeName < releases[j].KubeName
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// setChartUpdate sets the LatestChartVersion, ChartUpdate and Outdated fields
+// of the release from the versions of its chart in the catalog. Releases of a
+// version which isn't semver are never outdated.
+func (c *HelmReleases) setChartUpdate(m *model.HelmRelease) error {
+	var charts []*model.HelmChart
+	if err := c.Core.DB.Where("repo_name = ? AND name = ?", m.RepoName, m.ChartName).Find(&charts); err != nil {
+		return err
+	}
+
+	m.LatestChartVersion, m.ChartUpdate, m.Outdated = "", "", false
+
+	current, err := semver.NewVersion(m.ChartVersion)
+	if err != nil {
+		return nil
+	}
+	var latest *semver.Version
+	for _, chart := range charts {
+		version, err := semver.NewVersion(chart.Version)
+		if err != nil {
+			continue
+		}
+		// Pre-releases are only offered to releases of a pre-release
+		if version.Prerelease() != "" && current.Prerelease() == "" {
+			continue
+		}
+		if latest == nil || version.GreaterThan(latest) {
+			latest = version
+			m.LatestChartVersion = chart.Version
+		}
+	}
+
+	if latest != nil && latest.GreaterThan(current) {
+		m.Outdated = true
+		m.ChartUpdate = chartUpdateType(current, latest)
+	}
+	return nil
+}
+
+// chartUpdateType returns whether going from current to latest is a "major",
+// "minor" or "patch" update.
+func chartUpdateType(current *semver.Version, latest *semver.Version) string {
+	switch {
+	case latest.Major() != current.Major():
+		return "major"
+	case latest.Minor() != current.Minor():
+		return "minor"
+	}
+	return "patch"
+}
+
+//------------------------------------------------------------------------------
+
+// HelmReleaseUpdateChecker compares the ChartVersion of every HelmRelease with
+// the versions of its chart synced by the Helm Chart Populator, flagging those
+// which are outdated. With HelmUpdateNotifications set, it sends an alert (see
+// Core.Notify) the first time each newer version is found.
+type HelmReleaseUpdateChecker struct {
+	Core *Core
+}
+
+func (s *HelmReleaseUpdateChecker) Perform() error {
+	var releases []*model.HelmRelease
+	if err := s.Core.DB.Find(&releases); err != nil {
+		return err
+	}
+
+	for _, release := range releases {
+		previous := release.LatestChartVersion
+		outdated := release.Outdated
+
+		if err := s.Core.HelmReleases.setChartUpdate(release); err != nil {
+			return err
+		}
+		if release.LatestChartVersion == previous && release.Outdated == outdated {
+			continue
+		}
+
+		if err := s.Core.DB.Model(release).Update(map[string]interface{}{
+			"latest_chart_version": release.LatestChartVersion,
+			"chart_update":         release.ChartUpdate,
+			"outdated":             release.Outdated,
+		}); err != nil {
+			return err
+		}
+
+		if release.Outdated && s.Core.HelmUpdateNotifications {
+			s.Core.Notify(fmt.Sprintf("HelmRelease '%s' on Kube '%s' runs %s/%s %s; %s is available (%s update)", release.Name, release.KubeName, release.RepoName, release.ChartName, release.ChartVersion, release.LatestChartVersion, release.ChartUpdate))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package core_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+)
+
+func TestHelmReleaseUpdateCheckerPerform(t *testing.T) {
+	Convey("HelmReleaseUpdateChecker Perform flags outdated releases", t, func() {
+		table := []struct {
+			// Input
+			release       *model.HelmRelease
+			chartVersions []string
+			notifications bool
+			// Expectations
+			updates map[string]interface{}
+			alerts  []string
+		}{
+			// A patch
+			{
+				release:       &model.HelmRelease{ChartVersion: "1.2.3"},
+				chartVersions: []string{"1.2.3", "1.2.4"},
+				notifications: true,
+				updates:       map[string]interface{}{"latest_chart_version": "1.2.4", "chart_update": "patch", "outdated": true},
+				alerts:        []string{"HelmRelease 'test' on Kube 'test' runs stable/redis 1.2.3; 1.2.4 is available (patch update)"},
+			},
+			// The newest version is a minor update
+			{
+				release:       &model.HelmRelease{ChartVersion: "1.2.3"},
+				chartVersions: []string{"1.3.0", "1.2.4", "1.2.3"},
+				notifications: true,
+				updates:       map[string]interface{}{"latest_chart_version": "1.3.0", "chart_update": "minor", "outdated": true},
+				alerts:        []string{"HelmRelease 'test' on Kube 'test' runs stable/redis 1.2.3; 1.3.0 is available (minor update)"},
+			},
+			// A major update, without notifications
+			{
+				release:       &model.HelmRelease{ChartVersion: "1.2.3"},
+				chartVersions: []string{"2.0.0"},
+				updates:       map[string]interface{}{"latest_chart_version": "2.0.0", "chart_update": "major", "outdated": true},
+			},
+			// Pre-releases aren't offered to releases of a stable version
+			{
+				release:       &model.HelmRelease{ChartVersion: "1.2.3"},
+				chartVersions: []string{"1.2.3", "1.3.0-rc.1"},
+				notifications: true,
+				updates:       map[string]interface{}{"latest_chart_version": "1.2.3", "chart_update": "", "outdated": false},
+			},
+			// Already flagged
+			{
+				release:       &model.HelmRelease{ChartVersion: "1.2.3", LatestChartVersion: "1.3.0", ChartUpdate: "minor", Outdated: true},
+				chartVersions: []string{"1.3.0"},
+				notifications: true,
+			},
+			// Upgraded since it was flagged
+			{
+				release:       &model.HelmRelease{ChartVersion: "1.3.0", LatestChartVersion: "1.3.0", ChartUpdate: "minor", Outdated: true},
+				chartVersions: []string{"1.3.0"},
+				notifications: true,
+				updates:       map[string]interface{}{"latest_chart_version": "1.3.0", "chart_update": "", "outdated": false},
+			},
+			// Versions which aren't semver are never outdated
+			{
+				release:       &model.HelmRelease{ChartVersion: "latest"},
+				chartVersions: []string{"1.3.0"},
+				notifications: true,
+			},
+		}
+
+		for _, item := range table {
+			var alerts []string
+			webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload := make(map[string]string)
+				json.NewDecoder(r.Body).Decode(&payload)
+				alerts = append(alerts, payload["text"])
+			}))
+
+			release := item.release
+			release.Name = "test"
+			release.KubeName = "test"
+			release.RepoName = "stable"
+			release.ChartName = "redis"
+
+			var charts []*model.HelmChart
+			for _, version := range item.chartVersions {
+				charts = append(charts, &model.HelmChart{RepoName: "stable", Name: "redis", Version: version})
+			}
+
+			var updates map[string]interface{}
+
+			db := new(fake_core.DB)
+			db.FindFn = func(out interface{}, where ...interface{}) error {
+				switch reflect.TypeOf(out).String() {
+				case "*[]*model.HelmRelease":
+					reflect.ValueOf(out).Elem().Set(reflect.ValueOf([]*model.HelmRelease{release}))
+				case "*[]*model.HelmChart":
+					reflect.ValueOf(out).Elem().Set(reflect.ValueOf(charts))
+				}
+				return nil
+			}
+			db.ModelFn = func(value interface{}) core.DBInterface {
+				return &fake_core.DB{
+					UpdateFn: func(attrs ...interface{}) error {
+						updates = attrs[0].(map[string]interface{})
+						return nil
+					},
+				}
+			}
+
+			c := &core.Core{
+				Log: logrus.New(),
+				DB:  db,
+			}
+			c.AlertWebhookURL = webhook.URL
+			c.HelmUpdateNotifications = item.notifications
+			c.HelmReleases = &core.HelmReleases{core.Collection{c}}
+
+			service := &core.HelmReleaseUpdateChecker{c}
+			err := service.Perform()
+			webhook.Close()
+
+			So(err, ShouldBeNil)
+			So(updates, ShouldResemble, item.updates)
+			So(alerts, ShouldResemble, item.alerts)
+		}
+	})
+}
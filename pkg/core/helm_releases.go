@@ -1,17 +1,18 @@
 package core
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"regexp"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/ghodss/yaml"
+	"github.com/imdario/mergo"
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/helm"
 	"github.com/supergiant/supergiant/pkg/model"
-	"github.com/supergiant/supergiant/pkg/util"
 	"github.com/technosophos/moniker"
 )
 
@@ -50,14 +51,29 @@ func (c *HelmReleases) Populate() error {
 				// remove from oldReleases
 				oldReleases = append(oldReleases[:oldIndex], oldReleases[oldIndex+1:]...)
 
-				// update chart if changed
-				// if !reflect.DeepEqual(oldRelease, newRelease) {
-				// NOTE we're not using the collection's Update method here to avoid immutability constraints
-				if err := c.mergeUpdate(oldRelease.ID, oldRelease, newRelease); err != nil {
+				// NOTE we're not using the collection's Update method here to avoid
+				// immutability constraints, or upgrading the release. Values and
+				// Config are left as they are, since Tiller only keeps them merged.
+				// Releases changed meanwhile are left to be synced next time.
+				if err := c.Core.DB.Model(oldRelease).Where("resource_version = ?", oldRelease.ResourceVersion).Update(map[string]interface{}{
+					"resource_version": oldRelease.ResourceVersion + 1,
+					"chart_name":       newRelease.ChartName,
+					"chart_version":    newRelease.ChartVersion,
+					"app_version":      newRelease.AppVersion,
+					"namespace":        newRelease.Namespace,
+					"revision":         newRelease.Revision,
+					"status_value":     newRelease.StatusValue,
+					"updated_value":    newRelease.UpdatedValue,
+				}); err != nil {
 					return err
 				}
-				// }
 			} else {
+				// Releases of charts from repos Supergiant doesn't know of can't be
+				// upgraded or rolled back, so they aren't synced.
+				if newRelease.RepoName == "" {
+					c.Core.Log.Warnf("Not syncing HelmRelease %s on Kube %s, no HelmRepo has chart %s", newRelease.Name, kube.Name, newRelease.ChartName)
+					continue
+				}
 				// create new
 				if err := c.Collection.Create(newRelease); err != nil {
 					return err
@@ -85,10 +101,22 @@ func (c *HelmReleases) Create(m *model.HelmRelease) error {
 		m.Name = moniker.New().NameSep("-")
 	}
 
+	if err := c.validateHelmReleaseValues(m); err != nil {
+		return err
+	}
+	if err := c.Core.HelmChartPolicies.check(m); err != nil {
+		return err
+	}
+	if err := c.setChartUpdate(m); err != nil {
+		return err
+	}
+
 	if err := c.Collection.Create(m); err != nil {
 		return err
 	}
 
+	wait, timeout := m.Wait, m.Timeout
+
 	action := &Action{
 		Status: &model.ActionStatus{
 			Description: "deploying",
@@ -100,10 +128,17 @@ func (c *HelmReleases) Create(m *model.HelmRelease) error {
 		Model: m,
 		ID:    m.ID,
 		Fn: func(a *Action) error {
-			cmd := fmt.Sprintf("install %s/%s", m.RepoName, m.ChartName)
-			if len(m.Config) > 0 {
-				cmd += fmt.Sprintf(" --set %s", strings.Replace(releaseConfigAsFlagValue(m.Config, ""), ",,", ",", -1)) // TODO: This will remove double , but what if we have 3?
+			chart, job, err := c.chartJob(m)
+			if err != nil {
+				return err
 			}
+
+			valuesFlag, err := helmValuesFlag(m, job)
+			if err != nil {
+				return err
+			}
+
+			cmd := "install " + chart + valuesFlag
 			if m.ChartVersion != "" {
 				cmd += " --version " + m.ChartVersion
 			}
@@ -113,9 +148,98 @@ func (c *HelmReleases) Create(m *model.HelmRelease) error {
 			if m.Namespace != "" {
 				cmd += " --namespace " + m.Namespace
 			}
+			cmd += helmWaitFlags(wait, timeout)
 
 			c.Core.Log.Debug("DEBUG - Chart install cmd:", cmd)
-			_, err := execHelmCmd(c.Core, m.Kube, cmd)
+			job.cmd = cmd
+			job.extraTimeout = helmWaitTimeout(wait, timeout)
+			job.action = a
+			_, err = runHelmJob(c.Core, m.Kube, job)
+			return err
+		},
+	}
+	return action.Async()
+}
+
+//------------------------------------------------------------------------------
+
+// Update upgrades the release to the ChartVersion, Values and Config of the
+// update. Values and Config which aren't given are kept.
+func (c *HelmReleases) Update(id *int64, oldM *model.HelmRelease, m *model.HelmRelease) error {
+	if err := model.CheckImmutableFields(m); err != nil {
+		return err
+	}
+
+	// Options aren't stored, so they're captured for the action
+	reuseValues, wait, timeout := m.ReuseValues, m.Wait, m.Timeout
+
+	// Config replaces the values of the release, as it does with helm upgrade,
+	// rather than being merged into the old Config (unless reusing values).
+	config := m.Config
+	if !reuseValues {
+		m.Config = nil
+	}
+	if err := c.Core.DB.First(oldM, *id); err != nil {
+		return err
+	}
+	version, err := CheckResourceVersion(oldM, m)
+	if err != nil {
+		return err
+	}
+	if err := mergo.Merge(m, oldM); err != nil {
+		return err
+	}
+	if config != nil {
+		m.Config = config
+	}
+	if err := c.validateHelmReleaseValues(m); err != nil {
+		return err
+	}
+	if err := c.Core.HelmChartPolicies.check(m); err != nil {
+		return err
+	}
+	if err := c.setChartUpdate(m); err != nil {
+		return err
+	}
+	if err := c.Core.DB.SaveVersion(m, version); err != nil {
+		return err
+	}
+
+	action := &Action{
+		Status: &model.ActionStatus{
+			Description: "upgrading",
+			MaxRetries:  0,
+		},
+		Core:  c.Core,
+		Scope: c.Core.DB.Preload("Kube"),
+		Model: m,
+		ID:    id,
+		Fn: func(a *Action) error {
+			chart, job, err := c.chartJob(m)
+			if err != nil {
+				return err
+			}
+
+			cmd := fmt.Sprintf("upgrade %s %s --version %s", m.Name, chart, m.ChartVersion)
+			if reuseValues {
+				cmd += " --reuse-values"
+			}
+			valuesFlag, err := helmValuesFlag(m, job)
+			if err != nil {
+				return err
+			}
+			cmd += valuesFlag
+			cmd += helmWaitFlags(wait, timeout)
+
+			job.cmd = cmd
+			job.extraTimeout = helmWaitTimeout(wait, timeout)
+			job.action = a
+			_, err = runHelmJob(c.Core, m.Kube, job)
+
+			// The failed revision is recorded too
+			if syncErr := c.syncRevision(m); syncErr != nil {
+				c.Core.Log.Errorf("Error syncing revision of HelmRelease %s: %s", m.Name, syncErr)
+			}
 			return err
 		},
 	}
@@ -149,177 +273,305 @@ func (c *HelmReleases) Delete(id *int64, m *model.HelmRelease) ActionInterface {
 
 //------------------------------------------------------------------------------
 
-func getHelmReleases(c *Core, kube *model.Kube) ([]*model.HelmRelease, error) {
-	log, err := execHelmCmd(c, kube, "list")
+// History returns the revisions of the release kept by Tiller, latest first.
+func (c *HelmReleases) History(m *model.HelmRelease) ([]*model.HelmReleaseRevision, error) {
+	releases, err := tillerReleases(c.Core, m.Kube, "NAME="+m.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(log, "\n")
+	var revisions []*model.HelmReleaseRevision
+	for _, release := range releases {
+		var config map[string]interface{}
+		if err := yaml.Unmarshal([]byte(release.Values), &config); err != nil {
+			return nil, fmt.Errorf("Could not parse values of revision %d: %s", release.Version, err)
+		}
+		// Empty, rather than nil, so a rollback to the revision clears Config
+		if config == nil {
+			config = make(map[string]interface{})
+		}
+		revisions = append(revisions, &model.HelmReleaseRevision{
+			Revision:     release.Version,
+			ChartName:    release.ChartName,
+			ChartVersion: release.ChartVersion,
+			AppVersion:   release.AppVersion,
+			Config:       config,
+			Status:       release.Status,
+			Description:  release.Description,
+			UpdatedAt:    release.LastDeployed,
+		})
+	}
+	sort.Sort(helmReleaseRevisionsNewestFirst(revisions))
+	return revisions, nil
+}
 
-	var headerExists bool
-	var headerLineNo int
+type helmReleaseRevisionsNewestFirst []*model.HelmReleaseRevision
 
-	for lineNo, line := range lines {
-		if regexp.MustCompile(`^NAME`).MatchString(line) {
-			headerExists = true
-			headerLineNo = lineNo
-			break
+func (revisions helmReleaseRevisionsNewestFirst) Len() int { return len(revisions) }
+func (revisions helmReleaseRevisionsNewestFirst) Swap(i, j int) {
+	revisions[i], revisions[j] = revisions[j], revisions[i]
+}
+func (revisions helmReleaseRevisionsNewestFirst) Less(i, j int) bool {
+	return revisions[i].Revision > revisions[j].Revision
+}
+
+// Rollback rolls the release back to a previous revision, which Helm does by
+// deploying it again as a new revision. The release's Kube must be loaded.
+func (c *HelmReleases) Rollback(id *int64, m *model.HelmRelease, rollback *model.HelmReleaseRollback) error {
+	revisions, err := c.History(m)
+	if err != nil {
+		return err
+	}
+	var target *model.HelmReleaseRevision
+	for _, revision := range revisions {
+		if revision.Revision == rollback.Revision {
+			target = revision
 		}
 	}
+	if target == nil {
+		return &ErrorValidationFailed{fmt.Errorf("HelmRelease %s has no revision %d", m.Name, rollback.Revision)}
+	}
 
-	if !headerExists {
-		return nil, nil
+	action := &Action{
+		Status: &model.ActionStatus{
+			Description: "rolling back",
+			MaxRetries:  0,
+		},
+		Core:  c.Core,
+		Scope: c.Core.DB.Preload("Kube"),
+		Model: m,
+		ID:    id,
+		Fn: func(a *Action) error {
+			cmd := fmt.Sprintf("rollback %s %d", m.Name, target.Revision) + helmWaitFlags(rollback.Wait, rollback.Timeout)
+			_, err := runHelmJob(c.Core, m.Kube, &helmJob{
+				cmd:          cmd,
+				extraTimeout: helmWaitTimeout(rollback.Wait, rollback.Timeout),
+				action:       a,
+			})
+			if err == nil {
+				// The release now runs the chart version and values of the revision,
+				// which Tiller keeps merged
+				m.ChartVersion = target.ChartVersion
+				m.Values, m.ValuesJSON = nil, nil
+				m.Config = target.Config
+				err = c.Core.DB.Save(m)
+			}
+
+			if syncErr := c.syncRevision(m); syncErr != nil {
+				c.Core.Log.Errorf("Error syncing revision of HelmRelease %s: %s", m.Name, syncErr)
+			}
+			return err
+		},
 	}
+	return action.Async()
+}
 
-	var releases []*model.HelmRelease
-	for _, line := range lines[headerLineNo+1:] {
-		cols := regexp.MustCompile(" *\t").Split(line, -1)
+// chartJob returns how helm commands refer to the chart of the release, and a
+// helmJob with what they need to find it: the repo to add, or the archive of
+// the chart if the repo is private (as helm jobs aren't given credentials).
+func (c *HelmReleases) chartJob(m *model.HelmRelease) (string, *helmJob, error) {
+	repoModel := new(model.HelmRepo)
+	err := c.Core.DB.Where("name = ?", m.RepoName).First(repoModel)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return "", nil, err
+	}
+	// NOTE helm reports repos which don't exist
+	if err != nil || !helmRepoIsPrivate(repoModel) {
+		return m.RepoName + "/" + m.ChartName, &helmJob{repo: m.RepoName}, nil
+	}
 
-		// NOTE this should really only be the last line causing this to trigger
-		if len(cols) < 5 {
-			continue
-		}
+	archive, err := fetchHelmChart(repoModel, m.ChartName, m.ChartVersion)
+	if err != nil {
+		return "", nil, err
+	}
+	return helmJobFilesPath + "/chart.tgz", &helmJob{files: map[string][]byte{"chart.tgz": archive}}, nil
+}
 
-		chartNameSplit := strings.Split(cols[4], "-")
-
-		release := &model.HelmRelease{
-			KubeName:     kube.Name,
-			Name:         cols[0],
-			Revision:     cols[1],
-			UpdatedValue: cols[2],
-			StatusValue:  cols[3],
-			// TODO this is not full ChartName (does not include Repo)
-			ChartName:    chartNameSplit[0],
-			ChartVersion: chartNameSplit[1],
+// tillerReleases returns the releases in the storage of Tiller which match the
+// label selector.
+func tillerReleases(c *Core, kube *model.Kube, selector string) ([]*helm.Release, error) {
+	if selector != "" {
+		selector = "," + selector
+	}
+	configMaps, err := c.K8S(kube).ListConfigMaps("labelSelector=" + url.QueryEscape(helm.StorageSelector+selector))
+	if err != nil {
+		return nil, err
+	}
+	var releases []*helm.Release
+	for _, configMap := range configMaps {
+		release, err := helm.DecodeRelease(configMap.Data["release"])
+		if err != nil {
+			return nil, fmt.Errorf("%s (ConfigMap %s)", err, configMap.Metadata.Name)
 		}
 		releases = append(releases, release)
 	}
-
 	return releases, nil
 }
 
 //------------------------------------------------------------------------------
 
-// TODO really there should be one for each Kube, but it is hard to prevent race condition in mutex creation
-var globalHelmCmdMutex = new(sync.Mutex)
-
-func execHelmCmd(c *Core, kube *model.Kube, cmd string) (out string, err error) {
-
-	globalHelmCmdMutex.Lock()
-	defer globalHelmCmdMutex.Unlock()
-
-	var repos []*model.HelmRepo
-	if err = c.DB.Find(&repos); err != nil {
-		return
-	}
-	var repoAddCmds []string
-	for _, repo := range repos {
-		repoAddCmd := fmt.Sprintf("/helm repo add %s %s", repo.Name, repo.URL)
-		repoAddCmds = append(repoAddCmds, repoAddCmd)
-	}
-	repoAddCmd := strings.Join(repoAddCmds, " && ")
-
-	fullCmd := "/helm init --client-only"
-	if repoAddCmd != "" {
-		fullCmd += " && " + repoAddCmd
+// syncRevision records the current revision and status of the release.
+func (c *HelmReleases) syncRevision(m *model.HelmRelease) error {
+	releases, err := tillerReleases(c.Core, m.Kube, "NAME="+m.Name)
+	if err != nil {
+		return err
 	}
-	fullCmd += " && /helm " + cmd
-
-	jobLabel := util.RandomString(16)
-
-	pod := &kubernetes.Pod{
-		Metadata: kubernetes.Metadata{
-			Name: "supergiant-helm-job",
-			Labels: map[string]string{
-				"app": "supergiant-helm-job",
-				"job": jobLabel,
-			},
-		},
-		Spec: kubernetes.PodSpec{
-			NodeSelector: map[string]string{
-				"beta.kubernetes.io/arch": "amd64",
-			},
-			Containers: []kubernetes.Container{
-				{
-					Name:  "helm-worker",
-					Image: "supergiant/helm-worker:v2.8.2",
-					// ImagePullPolicy: "Always",
-					Command: []string{"/bin/sh", "-c"},
-					Args:    []string{fullCmd},
-				},
-			},
-			RestartPolicy: "Never",
-		},
+	for _, release := range latestReleases(releases) {
+		synced := helmReleaseFrom(m.Kube, release)
+		m.AppVersion = synced.AppVersion
+		m.Revision = synced.Revision
+		m.StatusValue = synced.StatusValue
+		m.UpdatedValue = synced.UpdatedValue
+		return c.Core.DB.Model(m).Update(map[string]interface{}{
+			"app_version":   m.AppVersion,
+			"revision":      m.Revision,
+			"status_value":  m.StatusValue,
+			"updated_value": m.UpdatedValue,
+		})
 	}
+	return nil
+}
 
-	if err = c.K8S(kube).CreateResource("api/v1", "Pod", "default", pod, pod); err != nil {
-		err = errors.New("Error creating Pod: " + err.Error())
-		return
+// getHelmReleases returns the releases on the Kube, as the latest revision of
+// each release in the storage of Tiller. The repo of each is that of the
+// HelmChart of the same name (preferring one of the same version), if only
+// one HelmRepo has it.
+func getHelmReleases(c *Core, kube *model.Kube) ([]*model.HelmRelease, error) {
+	releases, err := tillerReleases(c, kube, "")
+	if err != nil {
+		return nil, err
 	}
 
-	podName := pod.Metadata.Name
+	var helmReleases []*model.HelmRelease
+	for _, release := range latestReleases(releases) {
+		helmRelease := helmReleaseFrom(kube, release)
 
-	defer c.K8S(kube).DeleteResource("api/v1", "Pod", "default", podName)
-
-	waitErr := util.WaitFor(fmt.Sprintf("Helm cmd '%s'", cmd), c.HelmJobStartTimeout, 1*time.Second, func() (bool, error) {
-		if err = c.K8S(kube).GetResource("api/v1", "Pod", "default", podName, pod); err != nil {
-			if strings.Contains(err.Error(), "404") {
-				// This or the Phase == "Succeeded" line may fire, but this one is much
-				// less likely. The pod seems to linger for a while as we capture the Status
-				return true, nil // done
-			}
-			err = errors.New("Error GETting Pod: " + err.Error())
-			return false, err
+		var charts []*model.HelmChart
+		if err := c.DB.Where("name = ?", release.ChartName).Find(&charts); err != nil {
+			return nil, err
 		}
+		helmRelease.RepoName = repoOfChart(charts, release.ChartVersion)
 
-		// Get log
-		out, _ = c.K8S(kube).GetPodLog("default", podName)
-
-		if pod.Status.Phase == "Failed" {
-			return false, fmt.Errorf("Helm cmd failed: %s\n\n%s", cmd, out)
+		config := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(release.Values), &config); err != nil {
+			return nil, fmt.Errorf("Could not parse values of HelmRelease %s: %s", release.Name, err)
 		}
-		if pod.Status.Phase == "Succeeded" {
-			return true, nil // good to go
+		if len(config) > 0 {
+			helmRelease.Config = config
 		}
 
-		return false, nil // pod still pending / running
-	})
-
-	return out, waitErr
+		helmReleases = append(helmReleases, helmRelease)
+	}
+	return helmReleases, nil
 }
 
-//------------------------------------------------------------------------------
-
-func releaseConfigAsFlagValue(config map[string]interface{}, parent string) (fv string) {
+// latestReleases returns the latest revision of each release, leaving out
+// releases which have been deleted (but not purged), as helm list does.
+func latestReleases(releases []*helm.Release) []*helm.Release {
+	latest := make(map[string]*helm.Release)
+	var names []string
+	for _, release := range releases {
+		current, ok := latest[release.Name]
+		if !ok {
+			names = append(names, release.Name)
+		}
+		if !ok || release.Version > current.Version {
+			latest[release.Name] = release
+		}
+	}
+	sort.Strings(names)
 
-	if parent != "" {
-		parent += "."
+	var out []*helm.Release
+	for _, name := range names {
+		if status := latest[name].Status; status == "DELETED" || status == "DELETING" {
+			continue
+		}
+		out = append(out, latest[name])
 	}
+	return out
+}
 
-	for key, value := range config {
+func helmReleaseFrom(kube *model.Kube, release *helm.Release) *model.HelmRelease {
+	return &model.HelmRelease{
+		KubeName:     kube.Name,
+		Name:         release.Name,
+		Namespace:    release.Namespace,
+		ChartName:    release.ChartName,
+		ChartVersion: release.ChartVersion,
+		AppVersion:   release.AppVersion,
+		Revision:     strconv.Itoa(release.Version),
+		StatusValue:  release.Status,
+		// The format of helm list
+		UpdatedValue: release.LastDeployed.Format(time.ANSIC),
+	}
+}
 
-		if value == nil {
-			value = ""
+// repoOfChart returns the name of the repo of the charts (all of the same
+// name), or an empty string if it is ambiguous.
+func repoOfChart(charts []*model.HelmChart, version string) string {
+	var repos, reposWithVersion []string
+	for _, chart := range charts {
+		if !containsString(repos, chart.RepoName) {
+			repos = append(repos, chart.RepoName)
+		}
+		if chart.Version == version && !containsString(reposWithVersion, chart.RepoName) {
+			reposWithVersion = append(reposWithVersion, chart.RepoName)
 		}
+	}
+	if len(reposWithVersion) == 1 {
+		return reposWithVersion[0]
+	}
+	if len(reposWithVersion) == 0 && len(repos) == 1 {
+		return repos[0]
+	}
+	return ""
+}
 
-		fullKey := parent + key
+//------------------------------------------------------------------------------
 
-		if fv != "" {
-			fv += ","
-		}
+// defaultHelmWaitTimeout is the default --timeout of helm, in seconds.
+const defaultHelmWaitTimeout = 300
 
-		switch reflect.TypeOf(value).Kind() {
+// helmWaitFlags returns the --wait and --timeout flags of helm install and
+// upgrade.
+func helmWaitFlags(wait bool, timeout int) (flags string) {
+	if wait {
+		flags += " --wait"
+	}
+	if timeout > 0 {
+		flags += fmt.Sprintf(" --timeout %d", timeout)
+	}
+	return flags
+}
 
-		case reflect.Map:
-			fv += releaseConfigAsFlagValue(value.(map[string]interface{}), fullKey)
+// helmWaitTimeout is how much longer than usual a helm command may take when
+// it waits for resources.
+func helmWaitTimeout(wait bool, timeout int) time.Duration {
+	if !wait {
+		return 0
+	}
+	if timeout == 0 {
+		timeout = defaultHelmWaitTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
 
-		case reflect.String:
-			fv += fmt.Sprintf(`%s='%v'`, fullKey, value)
+//------------------------------------------------------------------------------
 
-		default:
-			fv += fmt.Sprintf(`%s=%v`, fullKey, value)
-		}
+// helmValuesFlag puts the values of the release in a values file of the job,
+// and returns the flag which passes it to helm ("" if there are none).
+func helmValuesFlag(m *model.HelmRelease, job *helmJob) (string, error) {
+	values, err := helmReleaseValues(m)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	out, err := yaml.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	if job.files == nil {
+		job.files = make(map[string][]byte)
 	}
-	return
+	job.files["values.yaml"] = out
+	return " -f " + helmJobFilesPath + "/values.yaml", nil
 }
@@ -1,11 +1,69 @@
 package core
 
-import "github.com/supergiant/supergiant/pkg/model"
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/imdario/mergo"
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type HelmRepos struct {
 	Collection
 }
 
+func (c *HelmRepos) Create(m *model.HelmRepo) error {
+	if m.Name == localHelmRepoName {
+		return &ErrorValidationFailed{fmt.Errorf("name: '%s' is reserved for uploaded charts", localHelmRepoName)}
+	}
+	if err := validateHelmRepoURL(m.URL); err != nil {
+		return err
+	}
+	if err := validateHelmRepoCredentials(m.Credentials); err != nil {
+		return err
+	}
+	defer c.Core.forgetHelmRepos()
+	return c.Collection.Create(m)
+}
+
+// Update replaces the Credentials with those of the update when it has them (so
+// an empty map removes them), since keys merged into the old ones couldn't be
+// removed.
+func (c *HelmRepos) Update(id *int64, oldM *model.HelmRepo, m *model.HelmRepo) error {
+	if err := model.CheckImmutableFields(m); err != nil {
+		return err
+	}
+	if err := c.Core.DB.First(oldM, *id); err != nil {
+		return err
+	}
+	version, err := CheckResourceVersion(oldM, m)
+	if err != nil {
+		return err
+	}
+	credentials := m.Credentials
+	m.Credentials = nil
+	if err := mergo.Merge(m, oldM); err != nil {
+		return err
+	}
+	if credentials != nil {
+		m.Credentials = credentials
+	}
+	if err := validateHelmRepoCredentials(m.Credentials); err != nil {
+		return err
+	}
+	defer c.Core.forgetHelmRepos()
+	return c.Core.DB.SaveVersion(m, version)
+}
+
 func (c *HelmRepos) Delete(id *int64, m *model.HelmRepo) ActionInterface {
 	return &Action{
 		Status: &model.ActionStatus{
@@ -23,7 +81,168 @@ func (c *HelmRepos) Delete(id *int64, m *model.HelmRepo) ActionInterface {
 					return err
 				}
 			}
+			defer c.Core.forgetHelmRepos()
 			return c.Collection.Delete(id, m)
 		},
 	}
 }
+
+// local returns the HelmRepo of uploaded charts, creating it on first use.
+func (c *HelmRepos) local() (*model.HelmRepo, error) {
+	dir, err := c.Core.helmLocalRepoDir()
+	if err != nil {
+		return nil, err
+	}
+	url := "file://" + filepath.ToSlash(dir)
+
+	m := new(model.HelmRepo)
+	err = c.Core.DB.Preload("Charts").Where("name = ?", localHelmRepoName).First(m)
+	if err == gorm.ErrRecordNotFound {
+		m = &model.HelmRepo{Name: localHelmRepoName, URL: url}
+		defer c.Core.forgetHelmRepos()
+		return m, c.Collection.Create(m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !helmRepoIsLocal(m) {
+		return nil, fmt.Errorf("HelmRepo %s is not the repo of uploaded charts", localHelmRepoName)
+	}
+	// The directory was moved in the settings
+	if m.URL != url {
+		m.URL = url
+		defer c.Core.forgetHelmRepos()
+		return m, c.Core.DB.Save(m)
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// localHelmRepoName is the name of the HelmRepo of uploaded charts, which are
+// stored in HelmLocalRepoDir.
+const localHelmRepoName = "local"
+
+// helmLocalRepoDir returns the absolute path of HelmLocalRepoDir, by default a
+// "charts" directory next to the SQLite file.
+func (c *Core) helmLocalRepoDir() (string, error) {
+	dir := c.HelmLocalRepoDir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(c.SQLiteFile), "charts")
+	}
+	return filepath.Abs(dir)
+}
+
+func validateHelmRepoURL(repoURL string) error {
+	if strings.HasPrefix(repoURL, "file:") {
+		return &ErrorValidationFailed{fmt.Errorf("url: file URLs are only used by the '%s' repo, upload charts to it instead", localHelmRepoName)}
+	}
+	return nil
+}
+
+var helmRepoCredentialKeys = []string{"username", "password", "token", "ca_bundle", "client_cert", "client_key"}
+
+func validateHelmRepoCredentials(credentials map[string]string) error {
+	for key := range credentials {
+		if !containsString(helmRepoCredentialKeys, key) {
+			return &ErrorValidationFailed{fmt.Errorf("credentials: unknown key '%s', expected one of %s", key, strings.Join(helmRepoCredentialKeys, ", "))}
+		}
+	}
+	if (credentials["username"] == "") != (credentials["password"] == "") {
+		return &ErrorValidationFailed{errors.New("credentials: username and password must be given together")}
+	}
+	if credentials["username"] != "" && credentials["token"] != "" {
+		return &ErrorValidationFailed{errors.New("credentials: use either username and password, or token")}
+	}
+	if _, err := helmRepoTLSConfig(credentials); err != nil {
+		return &ErrorValidationFailed{fmt.Errorf("credentials: %s", err)}
+	}
+	return nil
+}
+
+// helmRepoIsPrivate returns true if the repo can't be read anonymously, or is
+// the local repo, which can only be read by Supergiant.
+func helmRepoIsPrivate(m *model.HelmRepo) bool {
+	return len(m.Credentials) > 0 || helmRepoIsLocal(m)
+}
+
+// helmRepoIsLocal returns true if the repo is that of uploaded charts.
+func helmRepoIsLocal(m *model.HelmRepo) bool {
+	return strings.HasPrefix(m.URL, "file://")
+}
+
+// helmRepoTLSConfig returns the TLS config of the credentials, or nil if they
+// have no certificates.
+func helmRepoTLSConfig(credentials map[string]string) (*tls.Config, error) {
+	caBundle, cert, key := credentials["ca_bundle"], credentials["client_cert"], credentials["client_key"]
+	if caBundle == "" && cert == "" && key == "" {
+		return nil, nil
+	}
+	config := new(tls.Config)
+	if caBundle != "" {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, errors.New("ca_bundle has no PEM certificates")
+		}
+	}
+	if cert != "" || key != "" {
+		pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client_cert or client_key: %s", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return config, nil
+}
+
+// helmRepoGet fetches the file of the repo, at a URL relative to that of the
+// repo (such as "index.yaml") or absolute. Credentials are only sent to the
+// host of the repo.
+func helmRepoGet(m *model.HelmRepo, ref string) ([]byte, error) {
+	base, err := url.Parse(strings.TrimSuffix(m.URL, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	target, err := base.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Scheme == "file" {
+		if target.Scheme != "file" {
+			return nil, fmt.Errorf("Could not get %s from HelmRepo %s: not a local file", target, m.Name)
+		}
+		return ioutil.ReadFile(filepath.FromSlash(target.Path))
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	req, err := http.NewRequest("GET", target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if target.Host == base.Host {
+		tlsConfig, err := helmRepoTLSConfig(m.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+		}
+		if username := m.Credentials["username"]; username != "" {
+			req.SetBasicAuth(username, m.Credentials["password"])
+		}
+		if token := m.Credentials["token"]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get %s from HelmRepo %s: %s", target, m.Name, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const testHelmRepoIndex = `apiVersion: v1
+entries:
+  internal-api:
+  - name: internal-api
+    version: 1.2.0
+    urls:
+    - charts/internal-api-1.2.0.tgz
+  - name: internal-api
+    version: 1.1.0
+    urls:
+    - https://mirror.example.com/internal-api-1.1.0.tgz
+`
+
+func TestFetchHelmChart(t *testing.T) {
+	Convey("fetchHelmChart gets charts from private repos", t, func() {
+		var authorization string
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			if authorization == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.URL.Path {
+			case "/charts/index.yaml":
+				w.Write([]byte(testHelmRepoIndex))
+			case "/charts/charts/internal-api-1.2.0.tgz":
+				w.Write([]byte("archive"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		caBundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+		table := []struct {
+			// Input
+			credentials map[string]string
+			version     string
+			// Expectations
+			authorization string
+			archive       string
+			err           string
+		}{
+			// Basic auth
+			{
+				credentials:   map[string]string{"username": "sg", "password": "secret", "ca_bundle": caBundle},
+				version:       "1.2.0",
+				authorization: "Basic c2c6c2VjcmV0",
+				archive:       "archive",
+			},
+			// Bearer token
+			{
+				credentials:   map[string]string{"token": "abc", "ca_bundle": caBundle},
+				version:       "1.2.0",
+				authorization: "Bearer abc",
+				archive:       "archive",
+			},
+			// Untrusted certificate
+			{
+				credentials: map[string]string{"token": "abc"},
+				version:     "1.2.0",
+				err:         "certificate signed by unknown authority",
+			},
+			// Missing credentials
+			{
+				credentials: map[string]string{"ca_bundle": caBundle},
+				version:     "1.2.0",
+				err:         "Could not get " + server.URL + "/charts/index.yaml from HelmRepo internal: 401 Unauthorized",
+			},
+			// Missing version
+			{
+				credentials: map[string]string{"token": "abc", "ca_bundle": caBundle},
+				version:     "2.0.0",
+				err:         "No chart version found for internal-api-2.0.0",
+			},
+		}
+
+		for _, item := range table {
+			authorization = ""
+			repo := &model.HelmRepo{Name: "internal", URL: server.URL + "/charts", Credentials: item.credentials}
+
+			archive, err := fetchHelmChart(repo, "internal-api", item.version)
+
+			if item.err == "" {
+				So(err, ShouldBeNil)
+				So(string(archive), ShouldEqual, item.archive)
+				So(authorization, ShouldEqual, item.authorization)
+			} else {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, item.err)
+			}
+		}
+	})
+}
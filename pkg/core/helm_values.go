@@ -0,0 +1,293 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// helmValuesSchemaFile is the JSON schema of the values of a chart, if it has
+// one.
+const helmValuesSchemaFile = "values.schema.json"
+
+// chartValuesOf returns the default values of the chart, and the schema they
+// are validated against (nil if it has none).
+func chartValuesOf(loadedChart *chart.Chart) (defaults map[string]interface{}, schema map[string]interface{}, err error) {
+	if values := loadedChart.GetValues(); values != nil && values.Raw != "" {
+		if err = yaml.Unmarshal([]byte(values.Raw), &defaults); err != nil {
+			return nil, nil, fmt.Errorf("invalid values.yaml: %s", err)
+		}
+	}
+	for _, file := range loadedChart.Files {
+		if file.TypeUrl == helmValuesSchemaFile {
+			if err = json.Unmarshal(file.Value, &schema); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %s", helmValuesSchemaFile, err)
+			}
+		}
+	}
+	return defaults, schema, nil
+}
+
+// helmReleaseValues returns the values the release is installed with: its
+// Values documents merged in order, then its Config.
+func helmReleaseValues(m *model.HelmRelease) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for i, doc := range m.Values {
+		layer := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(doc), &layer); err != nil {
+			return nil, &ErrorValidationFailed{fmt.Errorf("values[%d]: %s", i, err)}
+		}
+		mergeHelmValues(values, layer)
+	}
+	mergeHelmValues(values, m.Config)
+	return values, nil
+}
+
+// mergeHelmValues merges src into dst the way helm merges values files: maps
+// are merged key by key, and anything else replaces the value in dst.
+func mergeHelmValues(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeHelmValues(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			// Copied, so merging into it later doesn't change src
+			copied := make(map[string]interface{})
+			mergeHelmValues(copied, srcMap)
+			value = copied
+		}
+		dst[key] = value
+	}
+}
+
+// validateHelmReleaseValues checks the values of the release against the
+// schema of its HelmChart, or if it has none, against the types of the
+// chart's defaults. Releases of charts Supergiant hasn't loaded aren't
+// checked; helm reports what's wrong with them.
+func (c *HelmReleases) validateHelmReleaseValues(m *model.HelmRelease) error {
+	values, err := helmReleaseValues(m)
+	if err != nil {
+		return err
+	}
+
+	chart := new(model.HelmChart)
+	err = c.Core.DB.Where("repo_name = ? AND name = ? AND version = ?", m.RepoName, m.ChartName, m.ChartVersion).First(chart)
+	if err != nil {
+		return nil
+	}
+
+	if chart.ValuesSchema != nil {
+		merged := make(map[string]interface{})
+		mergeHelmValues(merged, chart.DefaultConfig)
+		mergeHelmValues(merged, values)
+		err = validateHelmValuesSchema(chart.ValuesSchema, merged, "")
+	} else {
+		err = validateHelmValuesTypes(chart.DefaultConfig, values, "")
+	}
+	if err != nil {
+		return &ErrorValidationFailed{fmt.Errorf("values: %s", err)}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// helmValueType returns the JSON type of the value.
+func helmValueType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func helmValuePath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// validateHelmValuesTypes checks that the values have the types of the
+// defaults. Values without a default (or with a null one) can be anything.
+func validateHelmValuesTypes(defaults map[string]interface{}, values map[string]interface{}, parent string) error {
+	for _, key := range sortedKeys(values) {
+		value, def := values[key], defaults[key]
+		if value == nil || def == nil {
+			continue
+		}
+		path := helmValuePath(parent, key)
+		expected, actual := helmValueType(def), helmValueType(value)
+		// Whole and fractional numbers are both numbers
+		if expected == "integer" {
+			expected = "number"
+		}
+		if actual == "integer" {
+			actual = "number"
+		}
+		if expected != actual {
+			return fmt.Errorf("%s: expected %s, got %s", path, expected, actual)
+		}
+		if expected == "object" {
+			if err := validateHelmValuesTypes(def.(map[string]interface{}), value.(map[string]interface{}), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateHelmValuesSchema checks the value against the JSON schema. Only the
+// type, enum, properties, required, additionalProperties, items, minimum,
+// maximum, minLength, maxLength and pattern keywords are checked.
+func validateHelmValuesSchema(schema map[string]interface{}, value interface{}, path string) error {
+	name := path
+	if name == "" {
+		name = "(root)"
+	}
+	actual := helmValueType(value)
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		ok := false
+		for _, t := range types {
+			if t == actual || (t == "number" && actual == "integer") {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %s", name, strings.Join(types, " or "), actual)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) && helmValueType(allowed) == actual {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: must be one of %s", name, jsonString(enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, key := range required {
+				if v[fmt.Sprint(key)] == nil {
+					return fmt.Errorf("%s: is required", helmValuePath(path, fmt.Sprint(key)))
+				}
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			if v[key] == nil {
+				continue
+			}
+			keyPath := helmValuePath(path, key)
+			if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+				if err := validateHelmValuesSchema(propertySchema, v[key], keyPath); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: is not allowed", keyPath)
+				}
+			case map[string]interface{}:
+				if err := validateHelmValuesSchema(additional, v[key], keyPath); err != nil {
+					return err
+				}
+			}
+		}
+
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateHelmValuesSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && v < minimum {
+			return fmt.Errorf("%s: must be at least %v", name, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && v > maximum {
+			return fmt.Errorf("%s: must be at most %v", name, maximum)
+		}
+
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(len(v)) < minLength {
+			return fmt.Errorf("%s: must be at least %v characters", name, minLength)
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && float64(len(v)) > maxLength {
+			return fmt.Errorf("%s: must be at most %v characters", name, maxLength)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern in schema: %s", name, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: must match %s", name, pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes returns the "type" of a schema, which is a type or a list.
+func schemaTypes(t interface{}) (types []string) {
+	switch t := t.(type) {
+	case string:
+		types = append(types, t)
+	case []interface{}:
+		for _, each := range t {
+			types = append(types, fmt.Sprint(each))
+		}
+	}
+	return types
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonString(v interface{}) string {
+	out, _ := json.Marshal(v)
+	return string(out)
+}
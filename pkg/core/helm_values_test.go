@@ -0,0 +1,145 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+func TestHelmReleaseValues(t *testing.T) {
+	Convey("helmReleaseValues layers the values of a release", t, func() {
+		table := []struct {
+			// Input
+			values []string
+			config map[string]interface{}
+			// Expectations
+			merged map[string]interface{}
+			err    string
+		}{
+			// Later documents override earlier ones, and maps are merged
+			{
+				values: []string{
+					"image:\n  repository: redis\n  tag: \"4.0\"\nhosts: [a, b]\n",
+					"image:\n  tag: \"4.0.8\"\nhosts: [c]\n",
+				},
+				config: map[string]interface{}{"replicas": float64(3)},
+				merged: map[string]interface{}{
+					"image":    map[string]interface{}{"repository": "redis", "tag": "4.0.8"},
+					"hosts":    []interface{}{"c"},
+					"replicas": float64(3),
+				},
+			},
+			// Config overrides every document
+			{
+				values: []string{"image:\n  tag: \"4.0\"\n"},
+				config: map[string]interface{}{"image": map[string]interface{}{"tag": "latest"}},
+				merged: map[string]interface{}{"image": map[string]interface{}{"tag": "latest"}},
+			},
+			// A document which isn't a map
+			{
+				values: []string{"- a\n"},
+				err:    "values[0]: error unmarshaling JSON",
+			},
+		}
+
+		for _, item := range table {
+			merged, err := helmReleaseValues(&model.HelmRelease{Values: item.values, Config: item.config})
+
+			if item.err == "" {
+				So(err, ShouldBeNil)
+				So(merged, ShouldResemble, item.merged)
+			} else {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, item.err)
+			}
+		}
+	})
+}
+
+func TestValidateHelmValues(t *testing.T) {
+	Convey("Values are validated against the schema of the chart", t, func() {
+		var schema map[string]interface{}
+		json.Unmarshal([]byte(`{
+			"type": "object",
+			"required": ["image"],
+			"properties": {
+				"image": {
+					"type": "object",
+					"required": ["repository"],
+					"properties": {
+						"repository": {"type": "string", "minLength": 1},
+						"pullPolicy": {"enum": ["Always", "IfNotPresent"]}
+					}
+				},
+				"replicas": {"type": "integer", "minimum": 1, "maximum": 9},
+				"hosts": {"type": "array", "items": {"type": "string", "pattern": "^[a-z.]+$"}},
+				"resources": {"type": ["object", "null"], "additionalProperties": false, "properties": {"limits": {}}}
+			}
+		}`), &schema)
+
+		table := []struct {
+			values string
+			err    string
+		}{
+			{`{"image": {"repository": "redis", "pullPolicy": "Always"}, "replicas": 3, "hosts": ["a.example.com"]}`, ""},
+			{`{"image": {"repository": "redis"}, "resources": null}`, ""},
+			{`{}`, "image: is required"},
+			{`{"image": {"repository": ""}}`, "image.repository: must be at least 1 characters"},
+			{`{"image": {"repository": "redis", "pullPolicy": "Never"}}`, `image.pullPolicy: must be one of ["Always","IfNotPresent"]`},
+			{`{"image": {"repository": "redis"}, "replicas": 1.5}`, "replicas: expected integer, got number"},
+			{`{"image": {"repository": "redis"}, "replicas": 10}`, "replicas: must be at most 9"},
+			{`{"image": {"repository": "redis"}, "hosts": ["a.example.com", "B"]}`, "hosts[1]: must match ^[a-z.]+$"},
+			{`{"image": {"repository": "redis"}, "resources": {"requests": {}}}`, "resources.requests: is not allowed"},
+			{`{"image": "redis"}`, "image: expected object, got string"},
+		}
+
+		for _, item := range table {
+			var values map[string]interface{}
+			json.Unmarshal([]byte(item.values), &values)
+
+			err := validateHelmValuesSchema(schema, values, "")
+
+			if item.err == "" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, item.err)
+			}
+		}
+	})
+
+	Convey("Values are validated against the types of the chart's defaults without a schema", t, func() {
+		defaults := map[string]interface{}{
+			"replicas": float64(1),
+			"image":    map[string]interface{}{"tag": "4.0"},
+			"extra":    nil,
+		}
+
+		table := []struct {
+			values map[string]interface{}
+			err    string
+		}{
+			{map[string]interface{}{"replicas": float64(3), "image": map[string]interface{}{"tag": "4.0.8"}}, ""},
+			// Values without a default, or with a null one, can be anything
+			{map[string]interface{}{"extra": []interface{}{"a"}, "unknown": true}, ""},
+			// Null resets a value to its default
+			{map[string]interface{}{"image": nil}, ""},
+			{map[string]interface{}{"replicas": "3"}, "replicas: expected number, got string"},
+			{map[string]interface{}{"image": map[string]interface{}{"tag": 4.1}}, "image.tag: expected string, got number"},
+			{map[string]interface{}{"image": "redis:4.0"}, "image: expected object, got string"},
+		}
+
+		for _, item := range table {
+			err := validateHelmValuesTypes(defaults, item.values, "")
+
+			if item.err == "" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, item.err)
+			}
+		}
+	})
+}
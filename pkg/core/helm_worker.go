@@ -0,0 +1,303 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/pkg/util"
+)
+
+const (
+	defaultHelmWorkerImage     = "supergiant/helm-worker:v2.8.2"
+	defaultHelmWorkerNamespace = "default"
+
+	// helmWorkerName is the name of the pod helm commands run in on each Kube.
+	helmWorkerName = "supergiant-helm-worker"
+
+	// maxHelmOutput is how much of the output of a helm command is kept in the
+	// status of its Action.
+	maxHelmOutput = 4096
+
+	// helmJobFilesPath is where the files of a helmJob are in the worker pod.
+	helmJobFilesPath = "/supergiant"
+)
+
+// helmJob is a helm command, run in the worker pod of a Kube.
+type helmJob struct {
+	// cmd is the command, without "helm", e.g. "upgrade my-redis stable/redis".
+	cmd string
+	// repo is the name of the HelmRepo the chart of the command is in, which is
+	// added before running it, unless the worker has it already.
+	repo string
+	// extraTimeout is how much longer than HelmJobStartTimeout the command may
+	// take.
+	extraTimeout time.Duration
+	// action, if set, has the output of the command streamed into its status.
+	action *Action
+	// files are put in helmJobFilesPath of the pod while the command runs, such
+	// as the archive of a chart from a private repo.
+	files map[string][]byte
+}
+
+// helmWorker is what has been set up in the worker pod of a Kube, which is kept
+// between commands so it's only done once.
+type helmWorker struct {
+	// uid is that of the pod, which is a new pod (with nothing set up) if it
+	// changes.
+	uid string
+	// initialized is true once helm has been initialized.
+	initialized bool
+	// repos are the URLs of the repos added, by name, which are added again
+	// once HelmRepos (or their charts) change.
+	repos           map[string]string
+	reposGeneration int
+}
+
+func execHelmCmd(c *Core, kube *model.Kube, cmd string) (out string, err error) {
+	return runHelmJob(c, kube, &helmJob{cmd: cmd})
+}
+
+// runHelmJob runs the helm command in the worker pod of the Kube, which is
+// created if it isn't running, and returns its output. Commands on the same
+// Kube run one at a time, since helm fails when a release is changed
+// concurrently, but commands on different Kubes don't wait for each other.
+func runHelmJob(c *Core, kube *model.Kube, job *helmJob) (out string, err error) {
+	mutex := c.helmKubeMutex(kube.Name)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	started := time.Now()
+	defer func() {
+		c.Metrics.helmJobRan(strings.Fields(job.cmd)[0], time.Since(started), err)
+	}()
+
+	namespace := c.HelmWorkerNamespace
+	if namespace == "" {
+		namespace = defaultHelmWorkerNamespace
+	}
+	worker, err := c.startHelmWorker(kube, namespace, job)
+	if err != nil {
+		return "", err
+	}
+
+	// The stable repo is added by init, but its index is only fetched with the
+	// repo of the command.
+	var setup []string
+	if !worker.initialized {
+		setup = append(setup, "/helm init --client-only --skip-refresh")
+	}
+	var repoURL string
+	if job.repo != "" {
+		if repoURL, err = c.helmRepoURL(job.repo); err != nil {
+			return "", err
+		}
+		// Otherwise helm reports the missing repo
+		if repoURL != "" && worker.repos[job.repo] != repoURL {
+			setup = append(setup, fmt.Sprintf("/helm repo add %s %s", job.repo, repoURL))
+		}
+	}
+	fullCmd := strings.Join(append(setup, "/helm "+job.cmd), " && ")
+
+	if len(job.files) > 0 {
+		defer c.K8S(kube).RunPodCommand(namespace, helmWorkerName, &kubernetes.PodCommand{
+			Command: []string{"rm", "-rf", helmJobFilesPath},
+		})
+		for name, data := range job.files {
+			err = c.K8S(kube).RunPodCommand(namespace, helmWorkerName, &kubernetes.PodCommand{
+				Command: []string{"/bin/sh", "-c", fmt.Sprintf("mkdir -p %s && head -c %d > %s/%s", helmJobFilesPath, len(data), helmJobFilesPath, name)},
+				Stdin:   data,
+				Timeout: c.HelmJobStartTimeout,
+			})
+			if err != nil {
+				return "", fmt.Errorf("Error copying %s to Helm worker: %s", name, err)
+			}
+		}
+	}
+
+	output := &helmOutput{action: job.action}
+	err = c.K8S(kube).RunPodCommand(namespace, helmWorkerName, &kubernetes.PodCommand{
+		Command: []string{"/bin/sh", "-c", fullCmd},
+		Output:  output,
+		Timeout: c.HelmJobStartTimeout + job.extraTimeout,
+	})
+	out = output.String()
+	if err != nil {
+		return out, fmt.Errorf("Helm cmd failed: %s (%s)\n\n%s", job.cmd, err, out)
+	}
+
+	worker.initialized = true
+	if repoURL != "" {
+		worker.repos[job.repo] = repoURL
+	}
+	return out, nil
+}
+
+// helmOutput is the output of a helm command, the end of which is kept in the
+// status of its Action (if any) as it's written.
+type helmOutput struct {
+	bytes.Buffer
+	action *Action
+}
+
+func (o *helmOutput) Write(p []byte) (int, error) {
+	n, err := o.Buffer.Write(p)
+	if o.action != nil {
+		o.action.Status.Output = tailOf(o.Buffer.String(), maxHelmOutput)
+	}
+	return n, err
+}
+
+// startHelmWorker returns what has been set up in the worker pod of the Kube,
+// once it's running. The pod is created if there is none (or it has stopped,
+// or is of another image than HelmWorkerImage). It must be called holding the
+// helmKubeMutex of the Kube.
+func (c *Core) startHelmWorker(kube *model.Kube, namespace string, job *helmJob) (*helmWorker, error) {
+	image := c.HelmWorkerImage
+	if image == "" {
+		image = defaultHelmWorkerImage
+	}
+
+	pod := new(kubernetes.Pod)
+	err := c.K8S(kube).GetResource("api/v1", "Pod", namespace, helmWorkerName, pod)
+	switch {
+	case err != nil && !strings.Contains(err.Error(), "404"):
+		return nil, errors.New("Error GETting Pod: " + err.Error())
+	case err == nil && (pod.Status.Phase == "Failed" || pod.Status.Phase == "Succeeded" || helmWorkerImage(pod) != image):
+		if err := c.K8S(kube).DeleteResource("api/v1", "Pod", namespace, helmWorkerName); err != nil {
+			return nil, errors.New("Error deleting Pod: " + err.Error())
+		}
+		fallthrough
+	case err != nil:
+		pod = newHelmWorkerPod(image)
+		if err := c.K8S(kube).CreateResource("api/v1", "Pod", namespace, pod, pod); err != nil {
+			return nil, errors.New("Error creating Pod: " + err.Error())
+		}
+	}
+
+	err = util.WaitFor(fmt.Sprintf("Helm cmd '%s'", job.cmd), c.HelmJobStartTimeout, 1*time.Second, func() (bool, error) {
+		if pod.Status.Phase == "Running" {
+			return true, nil
+		}
+		if pod.Status.Phase == "Failed" || pod.Status.Phase == "Succeeded" {
+			return false, fmt.Errorf("Helm worker pod stopped: %s", pod.Status.Phase)
+		}
+		if err := c.K8S(kube).GetResource("api/v1", "Pod", namespace, helmWorkerName, pod); err != nil {
+			return false, errors.New("Error GETting Pod: " + err.Error())
+		}
+		return pod.Status.Phase == "Running", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.helmMutex.Lock()
+	defer c.helmMutex.Unlock()
+	if c.helmWorkers == nil {
+		c.helmWorkers = make(map[string]*helmWorker)
+	}
+	worker := c.helmWorkers[kube.Name]
+	if worker == nil || worker.uid != pod.Metadata.UID {
+		worker = &helmWorker{uid: pod.Metadata.UID}
+		c.helmWorkers[kube.Name] = worker
+	}
+	if worker.repos == nil || worker.reposGeneration != c.helmReposGeneration {
+		worker.repos = make(map[string]string)
+		worker.reposGeneration = c.helmReposGeneration
+	}
+	return worker, nil
+}
+
+// helmWorkerImage returns the image of the worker pod, if known.
+func helmWorkerImage(pod *kubernetes.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	return pod.Spec.Containers[0].Image
+}
+
+// newHelmWorkerPod returns the worker pod of a Kube, which only waits for
+// commands to be run in it.
+func newHelmWorkerPod(image string) *kubernetes.Pod {
+	return &kubernetes.Pod{
+		Metadata: kubernetes.Metadata{
+			Name: helmWorkerName,
+			Labels: map[string]string{
+				"app": helmWorkerName,
+			},
+		},
+		Spec: kubernetes.PodSpec{
+			NodeSelector: map[string]string{
+				"beta.kubernetes.io/arch": "amd64",
+			},
+			Containers: []kubernetes.Container{
+				{
+					Name:  "helm-worker",
+					Image: image,
+					// The shell is PID 1, which ignores TERM unless trapped
+					Command: []string{"/bin/sh", "-c"},
+					Args:    []string{"trap 'exit 0' TERM; while true; do sleep 1; done"},
+				},
+			},
+			RestartPolicy: "Always",
+		},
+	}
+}
+
+// helmKubeMutex returns the mutex helm commands on the Kube hold.
+func (c *Core) helmKubeMutex(kubeName string) *sync.Mutex {
+	c.helmMutex.Lock()
+	defer c.helmMutex.Unlock()
+	if c.helmKubeMutexes == nil {
+		c.helmKubeMutexes = make(map[string]*sync.Mutex)
+	}
+	mutex, ok := c.helmKubeMutexes[kubeName]
+	if !ok {
+		mutex = new(sync.Mutex)
+		c.helmKubeMutexes[kubeName] = mutex
+	}
+	return mutex
+}
+
+// helmRepoURL returns the URL of the HelmRepo, or an empty string if there is
+// none of the name (or it is private, since helm jobs can't read it). The
+// URLs of all HelmRepos are cached until one changes.
+func (c *Core) helmRepoURL(name string) (string, error) {
+	c.helmMutex.Lock()
+	defer c.helmMutex.Unlock()
+	if c.helmRepoURLs == nil {
+		var repos []*model.HelmRepo
+		if err := c.DB.Find(&repos); err != nil {
+			return "", err
+		}
+		c.helmRepoURLs = make(map[string]string)
+		for _, repo := range repos {
+			if !helmRepoIsPrivate(repo) {
+				c.helmRepoURLs[repo.Name] = repo.URL
+			}
+		}
+	}
+	return c.helmRepoURLs[name], nil
+}
+
+// forgetHelmRepos clears the cached URLs of HelmRepos, and has them added to
+// helm workers again (which fetches their index, with any new charts).
+func (c *Core) forgetHelmRepos() {
+	c.helmMutex.Lock()
+	defer c.helmMutex.Unlock()
+	c.helmRepoURLs = nil
+	c.helmReposGeneration++
+}
+
+// tailOf returns the last max bytes of s.
+func tailOf(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[len(s)-max:]
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// fakeHelmDB returns the repos from Find, counting the calls.
+type fakeHelmDB struct {
+	DBInterface
+	repos []*model.HelmRepo
+	finds int
+}
+
+func (db *fakeHelmDB) Find(out interface{}, where ...interface{}) error {
+	db.finds++
+	*out.(*[]*model.HelmRepo) = db.repos
+	return nil
+}
+
+// fakeHelmK8S runs a worker pod, which is running once it has been read, and
+// records the commands run in it.
+type fakeHelmK8S struct {
+	kubernetes.ClientInterface
+	pod       *kubernetes.Pod
+	created   int
+	deleted   int
+	namespace string
+	commands  []*kubernetes.PodCommand
+	log       string
+	err       error
+}
+
+func (k *fakeHelmK8S) CreateResource(apiVersion, kind, namespace string, in, out interface{}) error {
+	k.created++
+	k.pod = in.(*kubernetes.Pod)
+	k.pod.Metadata.UID = fmt.Sprintf("uid-%d", k.created)
+	k.namespace = namespace
+	return nil
+}
+
+func (k *fakeHelmK8S) GetResource(apiVersion, kind, namespace, name string, out interface{}) error {
+	if k.pod == nil {
+		return errors.New("K8S 404 error: not found")
+	}
+	*out.(*kubernetes.Pod) = *k.pod
+	if k.pod.Status.Phase == "" {
+		k.pod.Status.Phase = "Running"
+	}
+	return nil
+}
+
+func (k *fakeHelmK8S) DeleteResource(apiVersion, kind, namespace, name string) error {
+	k.deleted++
+	k.pod = nil
+	return nil
+}
+
+func (k *fakeHelmK8S) RunPodCommand(namespace, name string, cmd *kubernetes.PodCommand) error {
+	k.commands = append(k.commands, cmd)
+	if cmd.Output != nil {
+		cmd.Output.Write([]byte(k.log))
+	}
+	return k.err
+}
+
+// helmCommands returns the commands of the shell commands run.
+func (k *fakeHelmK8S) helmCommands() (commands []string) {
+	for _, cmd := range k.commands {
+		if len(cmd.Command) == 3 && cmd.Command[0] == "/bin/sh" {
+			commands = append(commands, cmd.Command[2])
+		}
+	}
+	return commands
+}
+
+func TestRunHelmJob(t *testing.T) {
+	Convey("runHelmJob runs the command in the worker pod of the Kube", t, func() {
+		table := []struct {
+			// Input
+			settings Settings
+			job      *helmJob
+			// Mocks
+			repos []*model.HelmRepo
+			log   string
+			// Expectations
+			commands  []string
+			image     string
+			namespace string
+			output    string
+		}{
+			// Defaults, and no repo
+			{
+				job:       &helmJob{cmd: "delete test --purge"},
+				commands:  []string{"/helm init --client-only --skip-refresh && /helm delete test --purge"},
+				image:     "supergiant/helm-worker:v2.8.2",
+				namespace: "default",
+			},
+			// Only the repo of the command is added, and output is streamed
+			{
+				settings: Settings{HelmWorkerImage: "registry.local/helm:v2.8.2", HelmWorkerNamespace: "supergiant"},
+				job:      &helmJob{cmd: "install stable/redis", repo: "stable", action: &Action{Status: new(model.ActionStatus)}},
+				repos: []*model.HelmRepo{
+					{Name: "stable", URL: "https://kubernetes-charts.storage.googleapis.com"},
+					{Name: "incubator", URL: "https://kubernetes-charts-incubator.storage.googleapis.com"},
+				},
+				log:       "NAME: eponymous-bat\nSTATUS: DEPLOYED\n",
+				commands:  []string{"/helm init --client-only --skip-refresh && /helm repo add stable https://kubernetes-charts.storage.googleapis.com && /helm install stable/redis"},
+				image:     "registry.local/helm:v2.8.2",
+				namespace: "supergiant",
+				output:    "NAME: eponymous-bat\nSTATUS: DEPLOYED\n",
+			},
+			// Files are copied to the pod
+			{
+				job: &helmJob{cmd: "install /supergiant/chart.tgz", files: map[string][]byte{"chart.tgz": []byte("archive")}},
+				commands: []string{
+					"mkdir -p /supergiant && head -c 7 > /supergiant/chart.tgz",
+					"/helm init --client-only --skip-refresh && /helm install /supergiant/chart.tgz",
+				},
+				image:     "supergiant/helm-worker:v2.8.2",
+				namespace: "default",
+			},
+		}
+
+		for _, item := range table {
+			k8s := &fakeHelmK8S{log: item.log}
+			c := &Core{
+				Settings: item.settings,
+				Log:      logrus.New(),
+				DB:       &fakeHelmDB{repos: item.repos},
+				K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+					return k8s
+				},
+			}
+			c.HelmJobStartTimeout = 5 * time.Second
+
+			out, err := runHelmJob(c, &model.Kube{Name: "test"}, item.job)
+
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, item.log)
+			So(k8s.helmCommands(), ShouldResemble, item.commands)
+			So(k8s.pod.Metadata.Name, ShouldEqual, "supergiant-helm-worker")
+			So(k8s.pod.Spec.Containers[0].Image, ShouldEqual, item.image)
+			So(k8s.namespace, ShouldEqual, item.namespace)
+			if item.job.action != nil {
+				So(item.job.action.Status.Output, ShouldEqual, item.output)
+			}
+			if item.job.files != nil {
+				So(k8s.commands[0].Stdin, ShouldResemble, item.job.files["chart.tgz"])
+				So(k8s.commands[len(k8s.commands)-1].Command, ShouldResemble, []string{"rm", "-rf", "/supergiant"})
+			}
+		}
+	})
+
+	Convey("runHelmJob reuses the worker pod, and what's set up in it", t, func() {
+		k8s := new(fakeHelmK8S)
+		db := &fakeHelmDB{repos: []*model.HelmRepo{{Name: "stable", URL: "www.stable.com"}}}
+		c := &Core{
+			Log: logrus.New(),
+			DB:  db,
+			K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+				return k8s
+			},
+		}
+		c.HelmJobStartTimeout = 5 * time.Second
+		kube := &model.Kube{Name: "test"}
+
+		_, err := runHelmJob(c, kube, &helmJob{cmd: "install stable/redis", repo: "stable"})
+		So(err, ShouldBeNil)
+		_, err = runHelmJob(c, kube, &helmJob{cmd: "install stable/mysql", repo: "stable"})
+		So(err, ShouldBeNil)
+
+		So(k8s.created, ShouldEqual, 1)
+		So(k8s.helmCommands(), ShouldResemble, []string{
+			"/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm install stable/redis",
+			"/helm install stable/mysql",
+		})
+
+		Convey("but adds repos again once they change", func() {
+			c.forgetHelmRepos()
+			_, err := runHelmJob(c, kube, &helmJob{cmd: "install stable/redis", repo: "stable"})
+
+			So(err, ShouldBeNil)
+			So(k8s.helmCommands()[2], ShouldEqual, "/helm repo add stable www.stable.com && /helm install stable/redis")
+		})
+
+		Convey("and sets up a new pod, if it stopped", func() {
+			k8s.pod.Status.Phase = "Failed"
+			_, err := runHelmJob(c, kube, &helmJob{cmd: "install stable/redis", repo: "stable"})
+
+			So(err, ShouldBeNil)
+			So(k8s.deleted, ShouldEqual, 1)
+			So(k8s.created, ShouldEqual, 2)
+			So(k8s.helmCommands()[2], ShouldEqual, "/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm install stable/redis")
+		})
+
+		Convey("unless the command failed", func() {
+			k8s.err = errors.New("command terminated with non-zero exit code")
+			c.forgetHelmRepos()
+			_, err := runHelmJob(c, kube, &helmJob{cmd: "install stable/redis", repo: "stable"})
+			So(err, ShouldNotBeNil)
+
+			k8s.err = nil
+			_, err = runHelmJob(c, kube, &helmJob{cmd: "install stable/redis", repo: "stable"})
+			So(err, ShouldBeNil)
+			So(k8s.helmCommands()[3], ShouldEqual, "/helm repo add stable www.stable.com && /helm install stable/redis")
+		})
+	})
+
+	Convey("runHelmJob caches repos until they change", t, func() {
+		db := &fakeHelmDB{repos: []*model.HelmRepo{{Name: "stable", URL: "www.stable.com"}}}
+		c := &Core{Log: logrus.New(), DB: db}
+
+		for i := 0; i < 2; i++ {
+			url, err := c.helmRepoURL("stable")
+			So(err, ShouldBeNil)
+			So(url, ShouldEqual, "www.stable.com")
+		}
+		So(db.finds, ShouldEqual, 1)
+
+		c.forgetHelmRepos()
+		db.repos[0].URL = "www.moved.com"
+
+		url, _ := c.helmRepoURL("stable")
+		So(url, ShouldEqual, "www.moved.com")
+		So(db.finds, ShouldEqual, 2)
+	})
+
+	Convey("runHelmJob only makes commands on the same Kube wait", t, func() {
+		c := &Core{Log: logrus.New()}
+
+		held := c.helmKubeMutex("a")
+		held.Lock()
+		defer held.Unlock()
+
+		locked := make(chan struct{})
+		go func() {
+			mutex := c.helmKubeMutex("b")
+			mutex.Lock()
+			mutex.Unlock()
+			close(locked)
+		}()
+
+		var err error
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			err = errors.New("Kube b waited for Kube a")
+		}
+		So(err, ShouldBeNil)
+		So(c.helmKubeMutex("a"), ShouldEqual, held)
+	})
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const idempotencyKeyTTL = 24 * time.Hour
+
+// ErrorIdempotencyKeyInProgress is returned for requests made again with the
+// key of one which hasn't responded yet.
+var ErrorIdempotencyKeyInProgress = errors.New("A request with this Idempotency-Key is still in progress, retry it once it has responded")
+
+// ErrorIdempotencyKeyReused is returned for requests made with the key of
+// another request.
+type ErrorIdempotencyKeyReused struct {
+	key string
+}
+
+func (err *ErrorIdempotencyKeyReused) Error() string {
+	return fmt.Sprintf("Idempotency-Key '%s' was used for another request, each request must have its own", err.key)
+}
+
+// IdempotencyKeys stores the responses of requests made with an
+// Idempotency-Key, for up to idempotencyKeyTTL.
+type IdempotencyKeys struct {
+	Collection
+}
+
+// Claim claims the key of a request about to be made, or returns the stored
+// response of the request made before with the key.
+func (c *IdempotencyKeys) Claim(m *model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	stored, err := c.stored(m)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		if err := c.Core.DB.Create(m); err != nil {
+			// Claimed meanwhile by the same request, made concurrently
+			if stored, _ = c.stored(m); stored == nil {
+				return nil, err
+			}
+		}
+	}
+	if stored == nil {
+		return nil, nil
+	}
+
+	if stored.Method != m.Method || stored.Path != m.Path || stored.RequestHash != m.RequestHash {
+		return nil, &ErrorIdempotencyKeyReused{m.Key}
+	}
+	if stored.ResponseStatus == 0 {
+		return nil, ErrorIdempotencyKeyInProgress
+	}
+	return stored, nil
+}
+
+// Complete stores the response of the request a key was claimed for.
+func (c *IdempotencyKeys) Complete(m *model.IdempotencyKey, status int, etag string, body []byte) error {
+	return c.Core.DB.Model(m).Update(map[string]interface{}{
+		"response_status": status,
+		"response_etag":   etag,
+		"response_body":   string(body),
+	})
+}
+
+// Release deletes a key, for the request to be made again (such as when it
+// failed with a server error).
+func (c *IdempotencyKeys) Release(m *model.IdempotencyKey) error {
+	return c.Core.DB.Delete(m)
+}
+
+// stored returns the unexpired key stored by the same user, if any.
+func (c *IdempotencyKeys) stored(m *model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	stored := new(model.IdempotencyKey)
+	err := c.Core.DB.Where("user_id = ? AND key = ?", m.UserID, m.Key).First(stored)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case time.Since(stored.CreatedAt) > idempotencyKeyTTL:
+		return nil, c.Release(stored)
+	}
+	return stored, nil
+}
+
+//------------------------------------------------------------------------------
+
+// IdempotencyKeyExpirer deletes keys older than idempotencyKeyTTL.
+type IdempotencyKeyExpirer struct {
+	core *Core
+}
+
+func (s *IdempotencyKeyExpirer) Perform() error {
+	return s.core.DB.Where("created_at < ?", time.Now().Add(-idempotencyKeyTTL)).Delete(new(model.IdempotencyKey))
+}
@@ -0,0 +1,329 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+type Ingresses struct {
+	Collection
+}
+
+func (c *Ingresses) Create(m *model.Ingress) error {
+	if err := validateIngress(m); err != nil {
+		return err
+	}
+	if err := c.Collection.Create(m); err != nil {
+		return err
+	}
+	return c.Core.Ingresses.Provision(m.ID, m).Async()
+}
+
+func (c *Ingresses) Provision(id *int64, m *model.Ingress) ActionInterface {
+	return &Action{
+		Status: &model.ActionStatus{
+			Description: "provisioning",
+			MaxRetries:  5,
+		},
+		Core:  c.Core,
+		Scope: c.Core.DB.Preload("Kube"),
+		Model: m,
+		ID:    id,
+		Fn: func(a *Action) error {
+			return c.apply(a, m, nil)
+		},
+	}
+}
+
+func (c *Ingresses) Update(id *int64, oldM *model.Ingress, m *model.Ingress) error {
+	if err := validateIngress(m); err != nil {
+		return err
+	}
+	if err := c.Collection.Update(id, oldM, m); err != nil {
+		return err
+	}
+	action := &Action{
+		Status: &model.ActionStatus{
+			Description: "updating",
+			MaxRetries:  5,
+		},
+		Core:  c.Core,
+		Scope: c.Core.DB.Preload("Kube"),
+		Model: m,
+		ID:    id,
+		Fn: func(a *Action) error {
+			return c.apply(a, m, oldM)
+		},
+	}
+	return action.Async()
+}
+
+func (c *Ingresses) Delete(id *int64, m *model.Ingress) ActionInterface {
+	return &Action{
+		Status: &model.ActionStatus{
+			Description: "deleting",
+			MaxRetries:  5,
+		},
+		Core:  c.Core,
+		Scope: c.Core.DB.Preload("Kube"),
+		Model: m,
+		ID:    id,
+		Fn: func(a *Action) error {
+			for _, host := range ingressHosts(m) {
+				if err := c.Core.DNSZones.unpointHost(host); err != nil {
+					return err
+				}
+			}
+			k8s := c.Core.K8S(m.Kube)
+			if err := k8s.DeleteResource(ingressAPIVersion, "Ingress", m.Namespace, m.Name); err != nil && !strings.Contains(err.Error(), "404") {
+				return err
+			}
+			if ingressManagesCertificate(m) {
+				if err := k8s.DeleteResource("api/v1", "Secret", m.Namespace, ingressSecretName(m)); err != nil && !strings.Contains(err.Error(), "404") {
+					return err
+				}
+			}
+			return c.Collection.Delete(id, m)
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Private methods                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	ingressAPIVersion = "apis/extensions/v1beta1"
+
+	ingressControllerRepo      = "stable"
+	ingressControllerChart     = "nginx-ingress"
+	ingressControllerNamespace = "kube-system"
+)
+
+// apply creates the Kubernetes Ingress (or patches it on update, when oldM is
+// the Ingress before), points the hosts at its address, and issues its
+// certificate when needed.
+func (c *Ingresses) apply(a *Action, m *model.Ingress, oldM *model.Ingress) error {
+	if m.Kube.IngressController {
+		if err := c.ensureIngressController(m.Kube); err != nil {
+			return err
+		}
+	}
+
+	k8s := c.Core.K8S(m.Kube)
+	ingress := ingressAsKubernetesIngress(m)
+
+	if oldM == nil {
+		if err := k8s.CreateResource(ingressAPIVersion, "Ingress", m.Namespace, ingress, ingress); err != nil {
+			return err
+		}
+	} else {
+		// Lists are replaced whole by a merge patch, and tls is nulled if unset
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": ingress.Metadata.Annotations,
+			},
+			"spec": map[string]interface{}{
+				"rules": ingress.Spec.Rules,
+				"tls":   ingress.Spec.TLS,
+			},
+		}
+		if err := k8s.UpdateResource(ingressAPIVersion, "Ingress", m.Namespace, m.Name, patch, ingress); err != nil {
+			return err
+		}
+	}
+
+	// The controller's load balancer may still be coming up
+	waitDesc := fmt.Sprintf("Ingress %s address", m.Name)
+	err := a.CancellableWaitFor(waitDesc, 10*time.Minute, 4*time.Second, func() (bool, error) {
+		if err := k8s.GetResource(ingressAPIVersion, "Ingress", m.Namespace, m.Name, ingress); err != nil {
+			return false, err
+		}
+		return len(ingress.Status.LoadBalancer.Ingress) > 0, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	lbIngress := ingress.Status.LoadBalancer.Ingress[0]
+	m.Address = lbIngress.Hostname
+	if m.Address == "" {
+		m.Address = lbIngress.IP
+	}
+	if err := c.Core.DB.Model(m).Update("address", m.Address); err != nil {
+		return err
+	}
+
+	hosts := ingressHosts(m)
+	var oldHosts []string
+	if oldM != nil {
+		oldHosts = ingressHosts(oldM)
+	}
+	for _, host := range hosts {
+		if err := c.Core.DNSZones.pointHost(host, m.Address); err != nil {
+			return err
+		}
+	}
+	for _, host := range oldHosts {
+		if !containsString(hosts, host) {
+			if err := c.Core.DNSZones.unpointHost(host); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Hosts of a certificate can't be changed, so a new one is issued
+	if ingressManagesCertificate(m) && (m.CertificateExpiresAt == nil || (oldM != nil && !sameStrings(hosts, oldHosts))) {
+		return c.issueCertificate(m)
+	}
+	return nil
+}
+
+// ensureIngressController installs the nginx ingress controller on the Kube
+// through a HelmRelease, unless it already has one.
+func (c *Ingresses) ensureIngressController(kube *model.Kube) error {
+	if err := c.Core.DB.Where("kube_name = ? AND name = ?", kube.Name, ingressControllerChart).First(new(model.HelmRelease)); err == nil {
+		return nil
+	}
+
+	chart := new(model.HelmChart)
+	if err := c.Core.DB.Where("repo_name = ? AND name = ?", ingressControllerRepo, ingressControllerChart).First(chart); err != nil {
+		return fmt.Errorf("Could not find the %s chart in the %s HelmRepo to install the ingress controller: %s", ingressControllerChart, ingressControllerRepo, err)
+	}
+
+	release := &model.HelmRelease{
+		KubeName:     kube.Name,
+		RepoName:     ingressControllerRepo,
+		ChartName:    ingressControllerChart,
+		ChartVersion: chart.Version,
+		Name:         ingressControllerChart,
+		Namespace:    ingressControllerNamespace,
+		Config: map[string]interface{}{
+			"controller": map[string]interface{}{
+				// Reports the address of the controller's Service on Ingresses
+				"publishService": map[string]interface{}{
+					"enabled": true,
+				},
+			},
+			"rbac": map[string]interface{}{
+				"create": kube.RBACEnabled,
+			},
+		},
+	}
+	return c.Core.HelmReleases.Create(release)
+}
+
+//------------------------------------------------------------------------------
+
+var ingressHostRxp = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)+[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// validateIngress checks the rules, which the validate tags can't.
+func validateIngress(m *model.Ingress) error {
+	for _, rule := range m.Rules {
+		if rule.Host == "" {
+			if m.TLS {
+				return &ErrorValidationFailed{errors.New("rules: a host is required with tls")}
+			}
+		} else if len(rule.Host) > 253 || !ingressHostRxp.MatchString(rule.Host) {
+			return &ErrorValidationFailed{fmt.Errorf("rules: host '%s' must be a lowercase DNS name", rule.Host)}
+		}
+		if len(rule.Paths) == 0 {
+			return &ErrorValidationFailed{fmt.Errorf("rules: host '%s' has no paths", rule.Host)}
+		}
+		for _, path := range rule.Paths {
+			if path.Path != "" && !strings.HasPrefix(path.Path, "/") {
+				return &ErrorValidationFailed{fmt.Errorf("rules: path '%s' must start with /", path.Path)}
+			}
+			if path.ServiceName == "" {
+				return &ErrorValidationFailed{errors.New("rules: service_name is required")}
+			}
+			if path.ServicePort < 1 || path.ServicePort > 65535 {
+				return &ErrorValidationFailed{fmt.Errorf("rules: service_port of '%s' must be between 1 and 65535", path.ServiceName)}
+			}
+		}
+	}
+	return nil
+}
+
+func ingressAsKubernetesIngress(m *model.Ingress) *kubernetes.Ingress {
+	ingress := &kubernetes.Ingress{
+		Metadata: kubernetes.Metadata{
+			Name: m.Name,
+			Annotations: map[string]string{
+				"kubernetes.io/ingress.class": m.IngressClass,
+			},
+		},
+	}
+	for _, rule := range m.Rules {
+		k8sRule := kubernetes.IngressRule{Host: rule.Host}
+		for _, path := range rule.Paths {
+			k8sRule.HTTP.Paths = append(k8sRule.HTTP.Paths, kubernetes.IngressPath{
+				Path: path.Path,
+				Backend: kubernetes.IngressBackend{
+					ServiceName: path.ServiceName,
+					ServicePort: path.ServicePort,
+				},
+			})
+		}
+		ingress.Spec.Rules = append(ingress.Spec.Rules, k8sRule)
+	}
+	if m.TLS {
+		ingress.Spec.TLS = []kubernetes.IngressTLS{
+			{
+				Hosts:      ingressHosts(m),
+				SecretName: ingressSecretName(m),
+			},
+		}
+	}
+	return ingress
+}
+
+// ingressHosts returns the distinct hosts of the rules, in order.
+func ingressHosts(m *model.Ingress) (hosts []string) {
+	for _, rule := range m.Rules {
+		if rule.Host != "" && !containsString(hosts, rule.Host) {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
+}
+
+// ingressManagesCertificate is whether the certificate is issued by Supergiant,
+// rather than provided in an existing Secret.
+func ingressManagesCertificate(m *model.Ingress) bool {
+	return m.TLS && m.TLSSecretName == ""
+}
+
+func ingressSecretName(m *model.Ingress) string {
+	if m.TLSSecretName != "" {
+		return m.TLSSecretName
+	}
+	return m.Name + "-tls"
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
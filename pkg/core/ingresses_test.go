@@ -0,0 +1,240 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+)
+
+// fakeDNSProvider records the records upserted and deleted.
+type fakeDNSProvider struct {
+	upserted []*core.DNSRecord
+	deleted  []*core.DNSRecord
+}
+
+func (p *fakeDNSProvider) UpsertRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	p.upserted = append(p.upserted, record)
+	return nil
+}
+
+func (p *fakeDNSProvider) DeleteRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	p.deleted = append(p.deleted, record)
+	return nil
+}
+
+func TestIngressesCreateValidation(t *testing.T) {
+	Convey("Ingresses Create validates rules", t, func() {
+		path := []*model.IngressPath{{Path: "/", ServiceName: "web", ServicePort: 80}}
+
+		table := []struct {
+			// Input
+			ingress *model.Ingress
+			// Expectations
+			err string
+		}{
+			{
+				ingress: &model.Ingress{Rules: []*model.IngressRule{{Host: "Example.com", Paths: path}}},
+				err:     "Validation failed: rules: host 'Example.com' must be a lowercase DNS name",
+			},
+			{
+				ingress: &model.Ingress{Rules: []*model.IngressRule{{Host: "*.example.com", Paths: path}}},
+				err:     "Validation failed: rules: host '*.example.com' must be a lowercase DNS name",
+			},
+			{
+				ingress: &model.Ingress{TLS: true, Rules: []*model.IngressRule{{Paths: path}}},
+				err:     "Validation failed: rules: a host is required with tls",
+			},
+			{
+				ingress: &model.Ingress{Rules: []*model.IngressRule{{Host: "example.com"}}},
+				err:     "Validation failed: rules: host 'example.com' has no paths",
+			},
+			{
+				ingress: &model.Ingress{Rules: []*model.IngressRule{{Host: "example.com", Paths: []*model.IngressPath{{Path: "api", ServiceName: "web", ServicePort: 80}}}}},
+				err:     "Validation failed: rules: path 'api' must start with /",
+			},
+			{
+				ingress: &model.Ingress{Rules: []*model.IngressRule{{Host: "example.com", Paths: []*model.IngressPath{{ServiceName: "web"}}}}},
+				err:     "Validation failed: rules: service_port of 'web' must be between 1 and 65535",
+			},
+		}
+
+		for _, item := range table {
+			c := &core.Core{Log: logrus.New(), DB: new(fake_core.DB)}
+			c.Ingresses = &core.Ingresses{core.Collection{c}}
+
+			err := c.Ingresses.Create(item.ingress)
+
+			So(err, ShouldHaveSameTypeAs, new(core.ErrorValidationFailed))
+			So(err.Error(), ShouldEqual, item.err)
+		}
+	})
+}
+
+func TestIngressesProvision(t *testing.T) {
+	Convey("Ingresses Provision creates the Ingress and points its hosts at it", t, func() {
+		table := []struct {
+			// Input
+			ingress *model.Ingress
+			// Mocks
+			zones     []*model.DNSZone
+			lbIngress kubernetes.LoadBalancerIngress
+			// Expectations
+			created  *kubernetes.Ingress
+			address  string
+			upserted []*core.DNSRecord
+		}{
+			// Hostname of the controller's load balancer, CNAME in the most specific zone
+			{
+				ingress: &model.Ingress{
+					Name:         "web",
+					Namespace:    "default",
+					IngressClass: "nginx",
+					Rules: []*model.IngressRule{
+						{Host: "www.apps.example.com", Paths: []*model.IngressPath{{Path: "/", ServiceName: "web", ServicePort: 80}}},
+					},
+				},
+				zones: []*model.DNSZone{
+					{Name: "example.com", Provider: "rfc2136", TTL: 300},
+					{Name: "apps.example.com", Provider: "rfc2136", TTL: 60},
+				},
+				lbIngress: kubernetes.LoadBalancerIngress{Hostname: "elb.amazonaws.com"},
+				created: &kubernetes.Ingress{
+					Metadata: kubernetes.Metadata{
+						Name:        "web",
+						Annotations: map[string]string{"kubernetes.io/ingress.class": "nginx"},
+					},
+					Spec: kubernetes.IngressSpec{
+						Rules: []kubernetes.IngressRule{
+							{
+								Host: "www.apps.example.com",
+								HTTP: kubernetes.IngressRuleValue{
+									Paths: []kubernetes.IngressPath{
+										{Path: "/", Backend: kubernetes.IngressBackend{ServiceName: "web", ServicePort: 80}},
+									},
+								},
+							},
+						},
+					},
+				},
+				address: "elb.amazonaws.com",
+				upserted: []*core.DNSRecord{
+					{Name: "www.apps.example.com", Type: "CNAME", Value: "elb.amazonaws.com", TTL: 60},
+				},
+			},
+			// IP of the load balancer, A record for the zone apex, and none for
+			// hosts outside of zones; TLS with an existing Secret
+			{
+				ingress: &model.Ingress{
+					Name:          "web",
+					Namespace:     "default",
+					IngressClass:  "traefik",
+					TLS:           true,
+					TLSSecretName: "wildcard",
+					Rules: []*model.IngressRule{
+						{Host: "example.com", Paths: []*model.IngressPath{{ServiceName: "web", ServicePort: 80}}},
+						{Host: "example.org", Paths: []*model.IngressPath{{ServiceName: "web", ServicePort: 80}}},
+					},
+				},
+				zones: []*model.DNSZone{
+					{Name: "example.com", Provider: "rfc2136", TTL: 300},
+				},
+				lbIngress: kubernetes.LoadBalancerIngress{IP: "10.0.0.1"},
+				created: &kubernetes.Ingress{
+					Metadata: kubernetes.Metadata{
+						Name:        "web",
+						Annotations: map[string]string{"kubernetes.io/ingress.class": "traefik"},
+					},
+					Spec: kubernetes.IngressSpec{
+						TLS: []kubernetes.IngressTLS{
+							{Hosts: []string{"example.com", "example.org"}, SecretName: "wildcard"},
+						},
+						Rules: []kubernetes.IngressRule{
+							{
+								Host: "example.com",
+								HTTP: kubernetes.IngressRuleValue{
+									Paths: []kubernetes.IngressPath{
+										{Backend: kubernetes.IngressBackend{ServiceName: "web", ServicePort: 80}},
+									},
+								},
+							},
+							{
+								Host: "example.org",
+								HTTP: kubernetes.IngressRuleValue{
+									Paths: []kubernetes.IngressPath{
+										{Backend: kubernetes.IngressBackend{ServiceName: "web", ServicePort: 80}},
+									},
+								},
+							},
+						},
+					},
+				},
+				address: "10.0.0.1",
+				upserted: []*core.DNSRecord{
+					{Name: "example.com", Type: "A", Value: "10.0.0.1", TTL: 300},
+				},
+			},
+		}
+
+		for _, item := range table {
+			var created *kubernetes.Ingress
+			var address string
+			dns := new(fakeDNSProvider)
+
+			ingress := item.ingress
+			ingress.UUID = "ingress-uuid"
+			ingress.Kube = &model.Kube{Name: "test"}
+
+			c := &core.Core{
+				Log: logrus.New(),
+				DB: &fake_core.DB{
+					FirstFn: func(out interface{}, where ...interface{}) error {
+						*out.(*model.Ingress) = *ingress
+						return nil
+					},
+					FindFn: func(out interface{}, where ...interface{}) error {
+						*out.(*[]*model.DNSZone) = item.zones
+						return nil
+					},
+					ModelFn: func(value interface{}) core.DBInterface {
+						return &fake_core.DB{
+							UpdateFn: func(attrs ...interface{}) error {
+								address = attrs[1].(string)
+								return nil
+							},
+						}
+					},
+				},
+				K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+					return &fake_core.KubernetesClient{
+						CreateResourceFn: func(apiVersion, kind, namespace string, in, out interface{}) error {
+							created = in.(*kubernetes.Ingress)
+							return nil
+						},
+						GetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
+							out.(*kubernetes.Ingress).Status.LoadBalancer.Ingress = []kubernetes.LoadBalancerIngress{item.lbIngress}
+							return nil
+						},
+					}
+				},
+				RFC2136Provider: dns,
+			}
+			c.Actions = core.NewSafeMap(c)
+			c.DNSZones = &core.DNSZones{core.Collection{c}}
+			c.Ingresses = &core.Ingresses{core.Collection{c}}
+
+			id := int64(1)
+			err := c.Ingresses.Provision(&id, new(model.Ingress)).Now()
+
+			So(err, ShouldBeNil)
+			So(created.Metadata, ShouldResemble, item.created.Metadata)
+			So(created.Spec, ShouldResemble, item.created.Spec)
+			So(address, ShouldEqual, item.address)
+			So(dns.upserted, ShouldResemble, item.upserted)
+		}
+	})
+}
@@ -3,8 +3,10 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/supergiant/supergiant/pkg/kubernetes"
 	"github.com/supergiant/supergiant/pkg/model"
 	"github.com/supergiant/supergiant/pkg/util"
@@ -20,6 +22,19 @@ type KubeResourcesInterface interface {
 	Start(*int64, *model.KubeResource) ActionInterface
 	Stop(*int64, *model.KubeResource) ActionInterface
 	Refresh(*model.KubeResource) error
+	Logs(*int64, *model.KubeResource, *model.PodLogOptions) (io.ReadCloser, error)
+	Exec(*int64, *model.KubeResource, *model.PodExecOptions) (*websocket.Conn, error)
+}
+
+// ErrorKubeResourceNotPod is returned for the logs and exec sessions of
+// KubeResources which aren't Pods.
+type ErrorKubeResourceNotPod struct {
+	id   *int64
+	kind string
+}
+
+func (err *ErrorKubeResourceNotPod) Error() string {
+	return fmt.Sprintf("KubeResource %d is a %s, only Pods have logs and exec sessions", *err.id, err.kind)
 }
 
 type KubeResources struct {
@@ -59,6 +74,7 @@ func (c *KubeResources) Populate() error {
 
 				newResource.ID = oldResource.ID
 				newResource.UUID = oldResource.UUID
+				newResource.ResourceVersion = oldResource.ResourceVersion
 
 				// TODO this is a bit.. freestyle right now.
 				// We set i there and let the Save in Refresh() persist it.
@@ -76,6 +92,9 @@ func (c *KubeResources) Populate() error {
 					}
 				}
 				if err := c.Core.KubeResources.Refresh(newResource); err != nil {
+					if isConflict(err) {
+						continue // changed meanwhile, and populated again next time
+					}
 					return err
 				}
 			} else {
@@ -189,11 +208,39 @@ func (c *KubeResources) Refresh(m *model.KubeResource) (err error) {
 	if err != nil {
 		return err
 	}
-	return c.Core.DB.Save(m)
+	return c.Core.DB.SaveVersion(m, m.ResourceVersion)
+}
+
+// Logs opens the log of a Pod, which is streamed as it's written if the
+// options follow it.
+func (c *KubeResources) Logs(id *int64, m *model.KubeResource, opts *model.PodLogOptions) (io.ReadCloser, error) {
+	if err := c.getPod(id, m); err != nil {
+		return nil, err
+	}
+	return c.Core.K8S(m.Kube).StreamPodLog(m.Namespace, m.Name, opts)
+}
+
+// Exec runs a command in a container of a Pod, returning the WebSocket of the
+// session with Kubernetes.
+func (c *KubeResources) Exec(id *int64, m *model.KubeResource, opts *model.PodExecOptions) (*websocket.Conn, error) {
+	if err := c.getPod(id, m); err != nil {
+		return nil, err
+	}
+	return c.Core.K8S(m.Kube).ExecPod(m.Namespace, m.Name, opts)
 }
 
 // Private
 
+func (c *KubeResources) getPod(id *int64, m *model.KubeResource) error {
+	if err := c.Core.KubeResources.GetWithIncludes(id, m, []string{"Kube"}); err != nil {
+		return err
+	}
+	if m.Kind != "Pod" {
+		return &ErrorKubeResourceNotPod{id, m.Kind}
+	}
+	return nil
+}
+
 func (c *KubeResources) provisioner(m *model.KubeResource) Provisioner {
 	return c.Core.DefaultProvisioner
 }
@@ -53,7 +53,7 @@ func (c *Kubes) Delete(id *int64, m *model.Kube) ActionInterface {
 			MaxRetries:  5,
 		},
 		Core:           c.Core,
-		Scope:          c.Core.DB.Preload("CloudAccount").Preload("KubeResources").Preload("HelmReleases").Preload("LoadBalancers").Preload("Nodes"),
+		Scope:          c.Core.DB.Preload("CloudAccount").Preload("KubeResources").Preload("HelmReleases").Preload("LoadBalancers").Preload("Ingresses").Preload("Nodes"),
 		Model:          m,
 		ID:             id,
 		CancelExisting: true,
@@ -69,6 +69,12 @@ func (c *Kubes) Delete(id *int64, m *model.Kube) ActionInterface {
 					return err
 				}
 			}
+			// Ingresses are deleted for their DNS records to be removed
+			for _, ingress := range m.Ingresses {
+				if err := c.Core.Ingresses.Delete(ingress.ID, ingress).Now(); err != nil {
+					return err
+				}
+			}
 			// Delete nodes first to get rid of any potential hanging volumes
 			for _, node := range m.Nodes {
 				err := c.Core.Nodes.Delete(node.ID, node).Now()
@@ -93,6 +99,9 @@ func (c *Kubes) Delete(id *int64, m *model.Kube) ActionInterface {
 					return err
 				}
 			}
+			if err := c.Core.recordMasterUsage(m); err != nil {
+				return err
+			}
 			return c.Collection.Delete(id, m)
 		},
 	}
@@ -1,12 +1,22 @@
 package core
 
-import "github.com/supergiant/supergiant/pkg/model"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type LoadBalancers struct {
 	Collection
 }
 
 func (c *LoadBalancers) Create(m *model.LoadBalancer) error {
+	if err := validateLoadBalancer(m); err != nil {
+		return err
+	}
 	if err := c.Collection.Create(m); err != nil {
 		return err
 	}
@@ -31,6 +41,9 @@ func (c *LoadBalancers) Provision(id *int64, m *model.LoadBalancer) ActionInterf
 }
 
 func (c *LoadBalancers) Update(id *int64, oldM *model.LoadBalancer, m *model.LoadBalancer) error {
+	if err := validateLoadBalancer(m); err != nil {
+		return err
+	}
 	if err := c.Collection.Update(id, oldM, m); err != nil {
 		return err
 	}
@@ -69,3 +82,33 @@ func (c *LoadBalancers) Delete(id *int64, m *model.LoadBalancer) ActionInterface
 		},
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// Private methods                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+var portNameRxp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// validateLoadBalancer checks the fields that the validate tags can't.
+func validateLoadBalancer(m *model.LoadBalancer) error {
+	for _, port := range m.NamedPorts {
+		if len(port.Name) > 15 || !portNameRxp.MatchString(port.Name) {
+			return &ErrorValidationFailed{fmt.Errorf("named_ports: name '%s' must be at most 15 lowercase alphanumeric characters or '-'", port.Name)}
+		}
+		if port.Port < 1 || port.Port > 65535 || port.TargetPort < 1 || port.TargetPort > 65535 {
+			return &ErrorValidationFailed{fmt.Errorf("named_ports: port and target_port of '%s' must be between 1 and 65535", port.Name)}
+		}
+		if port.Protocol != "" && port.Protocol != "TCP" && port.Protocol != "UDP" {
+			return &ErrorValidationFailed{fmt.Errorf("named_ports: protocol of '%s' must be TCP or UDP", port.Name)}
+		}
+	}
+	for _, cidr := range m.SourceRanges {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return &ErrorValidationFailed{fmt.Errorf("source_ranges: %s", err)}
+		}
+	}
+	if m.HealthCheckInterval < 0 {
+		return &ErrorValidationFailed{errors.New("health_check_interval cannot be negative")}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/metrics"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// Metrics holds the collectors describing the health of the Supergiant server
+// itself. All recording methods are safe to call on a nil *Metrics, so that a
+// Core built by hand (as in tests) does not need to configure them.
+type Metrics struct {
+	*metrics.Registry
+
+	actionsInFlight       *metrics.GaugeVec
+	actionRetries         *metrics.CounterVec
+	actionFailures        *metrics.CounterVec
+	procedureStepDuration *metrics.HistogramVec
+	serviceTickDuration   *metrics.HistogramVec
+	serviceErrors         *metrics.CounterVec
+	apiRequestDuration    *metrics.HistogramVec
+	capacityNodesCreated  *metrics.CounterVec
+	capacityNodesDeleted  *metrics.CounterVec
+	kubes                 *metrics.GaugeVec
+	helmJobDuration       *metrics.HistogramVec
+}
+
+// NewMetrics registers all server metrics. Gauges which mirror state held in
+// the Actions map or the DB are recomputed on every scrape.
+func NewMetrics(c *Core) *Metrics {
+	r := metrics.NewRegistry()
+
+	m := &Metrics{
+		Registry: r,
+
+		actionsInFlight: r.NewGaugeVec(
+			"supergiant_actions_in_flight",
+			"Number of Actions currently running or retrying.",
+			"description", "resource_type",
+		),
+		actionRetries: r.NewCounterVec(
+			"supergiant_action_retries_total",
+			"Number of times an Action has been retried after an error.",
+			"description", "resource_type",
+		),
+		actionFailures: r.NewCounterVec(
+			"supergiant_action_failures_total",
+			"Number of Actions that failed after exhausting their retries.",
+			"description", "resource_type",
+		),
+		procedureStepDuration: r.NewHistogramVec(
+			"supergiant_procedure_step_duration_seconds",
+			"Duration of each Procedure step.",
+			metrics.LongBuckets,
+			"procedure", "step", "result",
+		),
+		serviceTickDuration: r.NewHistogramVec(
+			"supergiant_recurring_service_tick_duration_seconds",
+			"Duration of each RecurringService tick.",
+			metrics.LongBuckets,
+			"tag",
+		),
+		serviceErrors: r.NewCounterVec(
+			"supergiant_recurring_service_errors_total",
+			"Number of RecurringService ticks that returned an error or panicked.",
+			"tag",
+		),
+		apiRequestDuration: r.NewHistogramVec(
+			"supergiant_api_request_duration_seconds",
+			"Latency of API requests by route, method and status code.",
+			metrics.DefBuckets,
+			"route", "method", "status",
+		),
+		capacityNodesCreated: r.NewCounterVec(
+			"supergiant_capacity_service_nodes_created_total",
+			"Number of Nodes created by the CapacityService.",
+			"kube",
+		),
+		capacityNodesDeleted: r.NewCounterVec(
+			"supergiant_capacity_service_nodes_deleted_total",
+			"Number of Nodes deleted by the CapacityService.",
+			"kube",
+		),
+		kubes: r.NewGaugeVec(
+			"supergiant_kubes",
+			"Number of Kubes by ready state.",
+			"ready",
+		),
+		helmJobDuration: r.NewHistogramVec(
+			"supergiant_helm_job_duration_seconds",
+			"Duration of Helm commands run against Kubes.",
+			metrics.LongBuckets,
+			"command", "result",
+		),
+	}
+
+	r.OnCollect(func() {
+		m.actionsInFlight.Reset()
+		for _, ai := range c.Actions.List() {
+			a, ok := ai.(*Action)
+			if !ok || a.failed() {
+				continue
+			}
+			m.actionsInFlight.Inc(a.Status.Description, a.resourceType())
+		}
+	})
+
+	r.OnCollect(func() {
+		m.kubes.Reset()
+		for _, ready := range []bool{true, false} {
+			var count int64
+			if err := c.DB.Model(new(model.Kube)).Where("ready = ?", ready).Count(&count); err != nil {
+				c.Log.Warnf("Could not count Kubes for metrics: %s", err)
+				continue
+			}
+			m.kubes.Set(float64(count), strconv.FormatBool(ready))
+		}
+	})
+
+	return m
+}
+
+//------------------------------------------------------------------------------
+
+func (m *Metrics) actionRetried(a *Action) {
+	if m == nil {
+		return
+	}
+	m.actionRetries.Inc(a.Status.Description, a.resourceType())
+}
+
+func (m *Metrics) actionFailed(a *Action) {
+	if m == nil {
+		return
+	}
+	m.actionFailures.Inc(a.Status.Description, a.resourceType())
+}
+
+func (m *Metrics) procedureStepRan(procedure, step string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.procedureStepDuration.Observe(d.Seconds(), procedure, step, resultLabel(err))
+}
+
+func (m *Metrics) serviceTicked(tag string, d time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+	m.serviceTickDuration.Observe(d.Seconds(), tag)
+	if failed {
+		m.serviceErrors.Inc(tag)
+	}
+}
+
+func (m *Metrics) capacityNodeCreated(kube *model.Kube) {
+	if m == nil {
+		return
+	}
+	m.capacityNodesCreated.Inc(kube.Name)
+}
+
+func (m *Metrics) capacityNodeDeleted(kube *model.Kube) {
+	if m == nil {
+		return
+	}
+	m.capacityNodesDeleted.Inc(kube.Name)
+}
+
+func (m *Metrics) helmJobRan(command string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.helmJobDuration.Observe(d.Seconds(), command, resultLabel(err))
+}
+
+// ObserveAPIRequest records the latency of a single API request. Route should
+// be the path template (e.g. /api/v0/kubes/{id}) to keep cardinality bounded.
+func (m *Metrics) ObserveAPIRequest(route, method string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.apiRequestDuration.Observe(d.Seconds(), route, method, strconv.Itoa(status))
+}
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
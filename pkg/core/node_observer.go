@@ -44,11 +44,17 @@ func (s *NodeObserver) Perform() error {
 			}
 			kube.ExtraData[mets.MetricName] = mets.Metrics
 		}
-		if err := s.core.DB.Save(kube); err != nil {
+		if err := s.core.DB.SaveVersion(kube, kube.ResourceVersion); err != nil {
+			if isConflict(err) {
+				continue // changed meanwhile, and observed again next time
+			}
 			return err
 		}
 
-		if err := s.core.DB.Save(kube); err != nil {
+		if err := s.core.DB.SaveVersion(kube, kube.ResourceVersion); err != nil {
+			if isConflict(err) {
+				continue // changed meanwhile, and observed again next time
+			}
 			return err
 		}
 
@@ -101,13 +107,7 @@ func (s *NodeObserver) Perform() error {
 				}
 			}
 
-			var nodeSize *NodeSize
-			for _, ns := range s.core.NodeSizes[kube.CloudAccount.Provider] {
-				if ns.Name == node.Size {
-					nodeSize = ns
-					break
-				}
-			}
+			nodeSize := s.core.nodeSize(kube.CloudAccount.Provider, node.Size)
 
 			for metricType, metricValue := range metData {
 				switch metricType {
@@ -126,7 +126,10 @@ func (s *NodeObserver) Perform() error {
 				}
 			}
 
-			if err := s.core.DB.Save(node); err != nil {
+			if err := s.core.DB.SaveVersion(node, node.ResourceVersion); err != nil {
+				if isConflict(err) {
+					continue
+				}
 				return err
 			}
 		}
@@ -167,7 +170,10 @@ func (s *NodeObserver) Perform() error {
 			}
 		}
 
-		if err := s.core.DB.Save(kube); err != nil {
+		if err := s.core.DB.SaveVersion(kube, kube.ResourceVersion); err != nil {
+			if isConflict(err) {
+				continue // changed meanwhile, and observed again next time
+			}
 			return err
 		}
 	}
@@ -1,6 +1,9 @@
 package core
 
-import "github.com/supergiant/supergiant/pkg/model"
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type NodesInterface interface {
 	Create(*model.Node) error
@@ -16,6 +19,18 @@ type Nodes struct {
 }
 
 func (c *Nodes) Create(m *model.Node) error {
+	// A missing Kube is reported by Collection.Create
+	kube := new(model.Kube)
+	err := c.Core.DB.Preload("CloudAccount").First(kube, "name = ?", m.KubeName)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == nil {
+		if err := c.Core.checkBudget(kube); err != nil {
+			return err
+		}
+	}
+
 	if err := c.Collection.Create(m); err != nil {
 		return err
 	}
@@ -72,6 +87,9 @@ func (c *Nodes) Delete(id *int64, m *model.Node) ActionInterface {
 					return err
 				}
 			}
+			if err := c.Core.recordNodeUsage(m.Kube, m); err != nil {
+				return err
+			}
 			return c.Collection.Delete(id, m)
 		},
 	}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+var notifyHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Notify logs an alert meant for operators and, if an AlertWebhookURL is
+// configured, posts it there as {"text": message}, the payload accepted by
+// Slack and compatible incoming webhooks. Delivery errors are logged, not
+// returned, so that a broken webhook doesn't stop the caller.
+func (c *Core) Notify(message string) {
+	c.Log.Warn(message)
+
+	if c.AlertWebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		c.Log.Errorf("Could not encode alert: %s", err)
+		return
+	}
+	resp, err := notifyHTTPClient.Post(c.AlertWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		c.Log.Errorf("Could not send alert to webhook: %s", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		c.Log.Errorf("Could not send alert to webhook: status %d", resp.StatusCode)
+	}
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"time"
+
 	"github.com/supergiant/supergiant/pkg/model"
 )
 
@@ -29,7 +31,10 @@ func (p *Procedure) Run() error {
 		}
 
 		p.Core.Log.Infof("Running step of %s procedure: %s", p.Name, step.desc)
-		if err := step.fn(); err != nil {
+		started := time.Now()
+		err := step.fn()
+		p.Core.Metrics.procedureStepRan(p.Name, step.desc, time.Since(started), err)
+		if err != nil {
 			return err
 		}
 
@@ -3,6 +3,7 @@ package core
 import (
 	"reflect"
 	"runtime/debug"
+	"strings"
 	"time"
 )
 
@@ -10,6 +11,26 @@ type Service interface {
 	Perform() error
 }
 
+// serviceErrors are the errors of the items a Service failed on, while it
+// went on with the others.
+type serviceErrors []error
+
+func (errs serviceErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// err returns the errors, or nil if there are none.
+func (errs serviceErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
 type RecurringService struct {
 	core *Core
 
@@ -28,11 +49,19 @@ func (s *RecurringService) Run() {
 }
 
 func (s *RecurringService) tick() {
+	started := time.Now()
+	failed := true
+	defer func() {
+		s.core.Metrics.serviceTicked(s.tag, time.Since(started), failed)
+	}()
 	defer s.recover()
+
 	if err := s.perform(); err != nil {
 
 		s.core.Log.Error("Error in RecurringService ["+s.tag+"] : ", err)
+		return
 	}
+	failed = false
 }
 
 func (s *RecurringService) perform() error {
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// ErrorResourceVersionRequired is returned by updates which don't say the
+// resource version they were made from.
+var ErrorResourceVersionRequired = errors.New("resource_version (or If-Match) required, of the version the update was made from")
+
+// ErrorConflict is returned by updates made from a resource version which
+// isn't the latest, since the model was changed meanwhile.
+type ErrorConflict struct {
+	model   string
+	id      interface{}
+	version int64
+}
+
+func newErrorConflict(m model.Model, version int64) *ErrorConflict {
+	return &ErrorConflict{reflect.TypeOf(m).Elem().Name(), reflect.Indirect(reflect.ValueOf(m.GetID())).Interface(), version}
+}
+
+func (err *ErrorConflict) Error() string {
+	return fmt.Sprintf("%s %v has changed since resource version %d, get it again and retry", err.model, err.id, err.version)
+}
+
+// CheckResourceVersion returns the resource version an update of a model was
+// made from, or an error if there is none or the stored model has changed
+// since.
+func CheckResourceVersion(stored model.Model, update model.Model) (int64, error) {
+	versioned, ok := update.(model.Versioned)
+	if !ok {
+		return 0, nil
+	}
+	version := versioned.GetResourceVersion()
+	if version == 0 {
+		return 0, ErrorResourceVersionRequired
+	}
+	if version != stored.(model.Versioned).GetResourceVersion() {
+		return 0, newErrorConflict(stored, version)
+	}
+	return version, nil
+}
+
+func isConflict(err error) bool {
+	_, ok := err.(*ErrorConflict)
+	return ok
+}
@@ -51,7 +51,7 @@ type SessionExpirer struct {
 
 func (s *SessionExpirer) Perform() error {
 	for _, session := range s.core.Sessions.List() {
-		if time.Since(session.CreatedAt) > sessionTTL {
+		if session.Expired() {
 			if err := s.core.Sessions.Delete(session.ID); err != nil {
 				return err
 			}
@@ -92,10 +92,12 @@ func (c *Sessions) Create(m *model.Session) error {
 	}
 
 	// Build Session (and set to user-passed value)
+	now := time.Now()
 	*m = model.Session{
 		ID:        util.RandomString(32),
 		UserID:    m.User.ID,
-		CreatedAt: time.Now(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(sessionTTL),
 	}
 
 	// Create Session
@@ -114,6 +116,10 @@ func (c *Sessions) Get(id string, m *model.Session) error {
 		return fmt.Errorf("Could not find session %s", id)
 	}
 	session := si.(*model.Session)
+	// Expired Sessions are only kept until the SessionExpirer runs
+	if session.Expired() {
+		return fmt.Errorf("Session %s expired", id)
+	}
 
 	session.User = new(model.User)
 
@@ -0,0 +1,212 @@
+// Package helm reads releases from the storage of Tiller (the server side of
+// Helm 2), which keeps a ConfigMap for every revision of every release.
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// StorageSelector is the label selector of the ConfigMaps Tiller stores
+// releases in.
+const StorageSelector = "OWNER=TILLER"
+
+// Release is a revision of a release, with the parts of hapi.release.Release
+// Supergiant needs.
+type Release struct {
+	Name         string
+	Namespace    string
+	Version      int
+	Status       string
+	Description  string
+	ChartName    string
+	ChartVersion string
+	AppVersion   string
+	// Values are the values the release was installed or upgraded with, as
+	// YAML (not including the chart's defaults).
+	Values        string
+	FirstDeployed time.Time
+	LastDeployed  time.Time
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// hapi.release.Status_Code
+var statusCodes = []string{
+	"UNKNOWN",
+	"DEPLOYED",
+	"DELETED",
+	"SUPERSEDED",
+	"FAILED",
+	"DELETING",
+	"PENDING_INSTALL",
+	"PENDING_UPGRADE",
+	"PENDING_ROLLBACK",
+}
+
+// DecodeRelease decodes the "release" data of a Tiller ConfigMap, which is a
+// base64 encoded, gzipped hapi.release.Release.
+func DecodeRelease(data string) (*Release, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	// Older versions of Tiller don't compress releases
+	if len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		if b, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	release := new(Release)
+	err = eachField(b, func(field uint64, value []byte, varint uint64) error {
+		switch field {
+		case 1:
+			release.Name = string(value)
+		case 2:
+			return decodeInfo(value, release)
+		case 3:
+			return decodeChart(value, release)
+		case 4:
+			// hapi.chart.Config
+			return eachField(value, func(field uint64, value []byte, _ uint64) error {
+				if field == 1 {
+					release.Values = string(value)
+				}
+				return nil
+			})
+		case 7:
+			release.Version = int(varint)
+		case 8:
+			release.Namespace = string(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode release: %s", err)
+	}
+	return release, nil
+}
+
+//------------------------------------------------------------------------------
+
+// hapi.release.Info
+func decodeInfo(b []byte, release *Release) error {
+	return eachField(b, func(field uint64, value []byte, _ uint64) (err error) {
+		switch field {
+		case 1:
+			// hapi.release.Status
+			return eachField(value, func(field uint64, _ []byte, varint uint64) error {
+				if field == 1 && int(varint) < len(statusCodes) {
+					release.Status = statusCodes[varint]
+				}
+				return nil
+			})
+		case 2:
+			release.FirstDeployed, err = decodeTimestamp(value)
+		case 3:
+			release.LastDeployed, err = decodeTimestamp(value)
+		case 5:
+			release.Description = string(value)
+		}
+		return err
+	})
+}
+
+// hapi.chart.Chart
+func decodeChart(b []byte, release *Release) error {
+	return eachField(b, func(field uint64, value []byte, _ uint64) error {
+		if field != 1 {
+			return nil
+		}
+		// hapi.chart.Metadata
+		return eachField(value, func(field uint64, value []byte, _ uint64) error {
+			switch field {
+			case 1:
+				release.ChartName = string(value)
+			case 4:
+				release.ChartVersion = string(value)
+			case 13:
+				release.AppVersion = string(value)
+			}
+			return nil
+		})
+	})
+}
+
+// google.protobuf.Timestamp
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := eachField(b, func(field uint64, _ []byte, varint uint64) error {
+		switch field {
+		case 1:
+			seconds = int64(varint)
+		case 2:
+			nanos = int64(varint)
+		}
+		return nil
+	})
+	return time.Unix(seconds, nanos).UTC(), err
+}
+
+// eachField calls fn with each field of the protobuf message, with either the
+// bytes of a length-delimited field, or the value of a varint.
+func eachField(b []byte, fn func(field uint64, value []byte, varint uint64) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+
+		var value []byte
+		var varint uint64
+		switch key & 7 {
+		case wireVarint:
+			if varint, n = binary.Uvarint(b); n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return errTruncated
+			}
+			value = b[n : n+int(length)]
+			b = b[n+int(length):]
+		case wireFixed64, wireFixed32:
+			size := 8
+			if key&7 == wireFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errTruncated
+			}
+			b = b[size:]
+		default:
+			return fmt.Errorf("unexpected wire type %d", key&7)
+		}
+
+		if err := fn(key>>3, value, varint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
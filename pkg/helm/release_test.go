@@ -0,0 +1,101 @@
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Protobuf encoding helpers, for building releases the way Tiller stores them
+func uvarint(x uint64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	return b[:binary.PutUvarint(b, x)]
+}
+
+func bytesField(field uint64, value []byte) []byte {
+	return message(uvarint(field<<3|wireBytes), uvarint(uint64(len(value))), value)
+}
+
+func varintField(field uint64, value uint64) []byte {
+	return message(uvarint(field<<3|wireVarint), uvarint(value))
+}
+
+func message(fields ...[]byte) []byte {
+	return bytes.Join(fields, nil)
+}
+
+func TestDecodeRelease(t *testing.T) {
+	Convey("DecodeRelease works correctly", t, func() {
+		deployed := time.Date(2018, 3, 1, 12, 0, 0, 500, time.UTC)
+		timestamp := message(varintField(1, uint64(deployed.Unix())), varintField(2, 500))
+
+		release := message(
+			bytesField(1, []byte("my-redis")),
+			bytesField(2, message(
+				bytesField(1, message(varintField(1, 3))),
+				bytesField(2, timestamp),
+				bytesField(3, timestamp),
+				bytesField(5, []byte("Upgrade complete")),
+			)),
+			bytesField(3, message(
+				bytesField(1, message(
+					bytesField(1, []byte("kube-state-metrics")),
+					bytesField(2, []byte("https://github.com/kubernetes/kube-state-metrics")),
+					bytesField(4, []byte("0.5.0")),
+					bytesField(13, []byte("1.2.0")),
+				)),
+				// A template, which is skipped
+				bytesField(2, message(bytesField(1, []byte("templates/deployment.yaml")))),
+			)),
+			bytesField(4, message(bytesField(1, []byte("replicas: 3\n")))),
+			bytesField(5, []byte("---\nkind: Deployment\n")),
+			varintField(7, 2),
+			bytesField(8, []byte("monitoring")),
+		)
+
+		expected := &Release{
+			Name:          "my-redis",
+			Namespace:     "monitoring",
+			Version:       2,
+			Status:        "SUPERSEDED",
+			Description:   "Upgrade complete",
+			ChartName:     "kube-state-metrics",
+			ChartVersion:  "0.5.0",
+			AppVersion:    "1.2.0",
+			Values:        "replicas: 3\n",
+			FirstDeployed: deployed,
+			LastDeployed:  deployed,
+		}
+
+		Convey("when gzipped", func() {
+			buf := new(bytes.Buffer)
+			w := gzip.NewWriter(buf)
+			w.Write(release)
+			w.Close()
+
+			decoded, err := DecodeRelease(base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, expected)
+		})
+
+		Convey("when not compressed", func() {
+			decoded, err := DecodeRelease(base64.StdEncoding.EncodeToString(release))
+
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, expected)
+		})
+
+		Convey("when truncated", func() {
+			_, err := DecodeRelease(base64.StdEncoding.EncodeToString(release[:20]))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Could not decode release: truncated message")
+		})
+	})
+}
@@ -3,15 +3,20 @@ package kubernetes
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/supergiant/supergiant/pkg/model"
 )
 
@@ -30,8 +35,18 @@ type ClientInterface interface {
 	ListPods(query string) ([]*Pod, error)
 	ListServices(query string) ([]*Service, error)
 	ListPersistentVolumes(query string) ([]*PersistentVolume, error)
+	ListConfigMaps(query string) ([]*ConfigMap, error)
 
 	GetPodLog(namespace, name string) (string, error)
+	// StreamPodLog opens the log of a Pod, which is streamed as it's written if
+	// the options follow it.
+	StreamPodLog(namespace, name string, opts *model.PodLogOptions) (io.ReadCloser, error)
+	// ExecPod runs a command in a container of a Pod, through a WebSocket
+	// speaking the channel.k8s.io protocol (as kubectl exec does).
+	ExecPod(namespace, name string, opts *model.PodExecOptions) (*websocket.Conn, error)
+	// RunPodCommand runs a command in a Pod until it exits, returning an error
+	// if it fails.
+	RunPodCommand(namespace, name string, cmd *PodCommand) error
 
 	ListNodeHeapsterStats(node string) ([]string, error)
 	ListPodHeapsterCPUUsageMetrics(namespace string, name string) ([]*HeapsterMetric, error)
@@ -140,6 +155,14 @@ func (k *Client) ListPersistentVolumes(query string) ([]*PersistentVolume, error
 	return list.Items, nil
 }
 
+func (k *Client) ListConfigMaps(query string) ([]*ConfigMap, error) {
+	list := new(ConfigMapList)
+	if err := k.requestInto("GET", "api/v1", "configmaps?"+query, nil, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
 func (k *Client) ListEvents(query string) ([]*Event, error) {
 	list := new(EventList)
 	if err := k.requestInto("GET", "api/v1", "events?"+query, nil, list); err != nil {
@@ -162,6 +185,162 @@ func (k *Client) GetPodLog(namespace, name string) (string, error) {
 	return string(body), nil
 }
 
+func (k *Client) StreamPodLog(namespace, name string, opts *model.PodLogOptions) (io.ReadCloser, error) {
+	query := make(url.Values)
+	if opts.Container != "" {
+		query.Set("container", opts.Container)
+	}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+	if opts.TailLines != nil {
+		query.Set("tailLines", strconv.FormatInt(*opts.TailLines, 10))
+	}
+	if opts.SinceSeconds != nil {
+		query.Set("sinceSeconds", strconv.FormatInt(*opts.SinceSeconds, 10))
+	}
+	path := fmt.Sprintf("namespaces/%s/pods/%s/log", namespace, name)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	// A followed log lasts as long as the container, so it can't time out.
+	streaming := *k.HTTPClient
+	streaming.Timeout = 0
+	resp, err := (&Client{k.Kube, &streaming}).request("application/json", "GET", "api/v1", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// execProtocols are the subprotocols of exec sessions, newest first.
+var execProtocols = []string{"v4.channel.k8s.io", "channel.k8s.io"}
+
+func (k *Client) ExecPod(namespace, name string, opts *model.PodExecOptions) (*websocket.Conn, error) {
+	query := make(url.Values)
+	for _, arg := range opts.Command {
+		query.Add("command", arg)
+	}
+	if opts.Container != "" {
+		query.Set("container", opts.Container)
+	}
+	query.Set("stdout", "true")
+	query.Set("stderr", strconv.FormatBool(!opts.TTY))
+	query.Set("stdin", strconv.FormatBool(opts.Stdin))
+	query.Set("tty", strconv.FormatBool(opts.TTY))
+	endpoint := fmt.Sprintf("wss://%s/api/v1/namespaces/%s/pods/%s/exec?%s", k.Kube.MasterPublicIP, namespace, name, query.Encode())
+
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: 30 * time.Second,
+		Subprotocols:     execProtocols,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true},
+	}
+	if transport, ok := k.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	header := make(http.Header)
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(k.Kube.Username+":"+k.Kube.Password)))
+
+	conn, resp, err := dialer.Dial(endpoint, header)
+	if err != nil {
+		if resp == nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("K8S %s error: %s", resp.Status, string(respBody))
+	}
+	return conn, nil
+}
+
+// PodCommand is a command run in a container of a Pod by RunPodCommand.
+type PodCommand struct {
+	// Container is that of the Pod to run in, which may be omitted if it has one.
+	Container string
+	Command   []string
+	// Stdin is sent to the command, which must only read as much of it as there
+	// is (such as with head -c), since it can't be closed.
+	Stdin []byte
+	// Output has the stdout and stderr of the command written to it as it runs.
+	Output io.Writer
+	// Timeout is how long the command may run for, if set.
+	Timeout time.Duration
+}
+
+// The streams of exec sessions, which prefix each message.
+const (
+	execStdin byte = iota
+	execStdout
+	execStderr
+	execStatus
+)
+
+func (k *Client) RunPodCommand(namespace, name string, cmd *PodCommand) error {
+	conn, err := k.ExecPod(namespace, name, &model.PodExecOptions{
+		Container: cmd.Container,
+		Command:   cmd.Command,
+		Stdin:     cmd.Stdin != nil,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if cmd.Timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(cmd.Timeout))
+	}
+	if cmd.Stdin != nil {
+		if err := conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStdin}, cmd.Stdin...)); err != nil {
+			return err
+		}
+	}
+	output := cmd.Output
+	if output == nil {
+		output = ioutil.Discard
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			// Before v4, sessions of commands which succeed end without a status
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) && conn.Subprotocol() != "v4.channel.k8s.io" {
+				return nil
+			}
+			return err
+		}
+		if len(message) < 1 {
+			continue
+		}
+		switch message[0] {
+		case execStdout, execStderr:
+			output.Write(message[1:])
+		case execStatus:
+			return execStatusError(conn.Subprotocol(), message[1:])
+		}
+	}
+}
+
+// execStatusError returns the error of the status a command exited with, or nil
+// if it succeeded.
+func execStatusError(protocol string, message []byte) error {
+	// Before v4, only errors are sent, as text
+	if protocol != "v4.channel.k8s.io" {
+		return errors.New(string(message))
+	}
+	status := new(struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	})
+	if err := json.Unmarshal(message, status); err != nil {
+		return errors.New(string(message))
+	}
+	if status.Status == "Success" {
+		return nil
+	}
+	return errors.New(status.Message)
+}
+
 func (k *Client) ListKubeHeapsterStats() ([]string, error) {
 	var metrics []string
 	err := k.requestInto("GET", "api/v1", "proxy/namespaces/kube-system/services/heapster/api/v1/model/metrics/", nil, &metrics)
@@ -290,37 +469,63 @@ func CoresFromCPUString(str string) (float64, error) {
 	return 0, fmt.Errorf("Could not parse cores value from %s", str)
 }
 
+// GiBFromMemString parses a Kubernetes quantity of bytes, with any of its
+// suffixes: binary (Ki, Mi, ...), decimal (k, M, ...) or an exponent (e9).
 func GiBFromMemString(memStr string) (float64, error) {
 	if memStr == "" {
 		return 0, nil
 	}
-
-	rxp := regexp.MustCompile(`^"?([0-9]+(?:\.[0-9]+)?)([KMG]i)?"?$`)
-
-	if !rxp.MatchString(memStr) {
-		return 0, fmt.Errorf(`Bytes value %s does not match regex ^"?([0-9]+(?:\.[0-9]+)?)([KMG]i)?"?$`, memStr)
+	bytes, err := parseQuantity(strings.Trim(memStr, `"`))
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse bytes value from %s", memStr)
 	}
+	return bytes / (1 << 30), nil
+}
 
-	match := rxp.FindStringSubmatch(memStr)
-
-	float, _ := strconv.ParseFloat(match[1], 64)
-
-	switch match[2] {
-	case "":
-		return float / 1073741824, nil
-	case "Ki":
-		return float / 1048576, nil
-	case "Mi":
-		return float / 1024, nil
-	case "Gi":
-		return float, nil
+var (
+	rxpQuantity = regexp.MustCompile(`^([+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+))((?:[eE][+-]?[0-9]+)|[KMGTPE]i|[numkMGTPE])?$`)
+
+	quantitySuffixes = map[string]float64{
+		"":   1,
+		"n":  1e-9,
+		"u":  1e-6,
+		"m":  1e-3,
+		"k":  1e3,
+		"M":  1e6,
+		"G":  1e9,
+		"T":  1e12,
+		"P":  1e15,
+		"E":  1e18,
+		"Ki": 1 << 10,
+		"Mi": 1 << 20,
+		"Gi": 1 << 30,
+		"Ti": 1 << 40,
+		"Pi": 1 << 50,
+		"Ei": 1 << 60,
 	}
+)
 
-	return 0, fmt.Errorf("Cannot parse RAM GiB from %s", memStr)
+// parseQuantity returns the value of a Kubernetes quantity, such as 1.5Gi,
+// 256M or 1e9.
+func parseQuantity(str string) (float64, error) {
+	match := rxpQuantity.FindStringSubmatch(str)
+	if match == nil {
+		return 0, fmt.Errorf("Invalid quantity %s", str)
+	}
+	if multiplier, ok := quantitySuffixes[match[2]]; ok {
+		num, err := strconv.ParseFloat(match[1], 64)
+		return num * multiplier, err
+	}
+	// An exponent
+	return strconv.ParseFloat(match[1]+match[2], 64)
 }
 
 //------------------------------------------------------------------------------
 
 func lowerPlural(str string) string {
+	// Ingress, StorageClass
+	if strings.HasSuffix(str, "ss") {
+		return strings.ToLower(str) + "es"
+	}
 	return strings.ToLower(str) + "s"
 }
@@ -6,10 +6,14 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/supergiant/supergiant/pkg/kubernetes"
 	"github.com/supergiant/supergiant/pkg/model"
 	"github.com/supergiant/supergiant/test/fake_http"
@@ -762,6 +766,73 @@ func TestKubernetesListEvents(t *testing.T) {
 
 //------------------------------------------------------------------------------
 
+func TestKubernetesStreamPodLog(t *testing.T) {
+	Convey("Kubernetes StreamPodLog works correctly", t, func() {
+		tail, since := int64(10), int64(300)
+
+		table := []struct {
+			// Input
+			opts *model.PodLogOptions
+			// Mocks
+			mockLogResponseCode int
+			mockLogResponseBody string
+			// Expectations
+			query string
+			log   string
+			err   error
+		}{
+			// A successful example
+			{
+				opts:                &model.PodLogOptions{Container: "nginx", Follow: true, TailLines: &tail, SinceSeconds: &since},
+				mockLogResponseCode: 200,
+				mockLogResponseBody: "line 1\n",
+				query:               "container=nginx&follow=true&sinceSeconds=300&tailLines=10",
+				log:                 "line 1\n",
+			},
+			// Without options
+			{
+				opts:                new(model.PodLogOptions),
+				mockLogResponseCode: 200,
+				mockLogResponseBody: "line 1\n",
+				log:                 "line 1\n",
+			},
+			// On error
+			{
+				opts:                new(model.PodLogOptions),
+				mockLogResponseCode: 500,
+				mockLogResponseBody: "something bad",
+				err:                 errors.New("K8S 500 error: something bad"),
+			},
+		}
+
+		for _, item := range table {
+			httpClient := &http.Client{Transport: &fake_http.RoundTripper{
+				RoundTripFn: func(r *http.Request) (resp *http.Response, err error) {
+					if r.Method == "GET" && r.URL.Path == "/api/v1/namespaces/test/pods/web/log" && r.URL.Query().Encode() == item.query {
+						resp = &http.Response{
+							Status:     strconv.Itoa(item.mockLogResponseCode),
+							StatusCode: item.mockLogResponseCode,
+							Body:       ioutil.NopCloser(bytes.NewBufferString(item.mockLogResponseBody)),
+						}
+					} else {
+						panic("Did not recognize request Method / URL Path: " + r.Method + " " + r.URL.Path + " " + r.URL.Query().Encode())
+					}
+					return
+				},
+			}}
+			kubernetes := &kubernetes.Client{Kube: &model.Kube{}, HTTPClient: httpClient}
+
+			body, err := kubernetes.StreamPodLog("test", "web", item.opts)
+
+			So(err, ShouldResemble, item.err)
+			if err == nil {
+				log, _ := ioutil.ReadAll(body)
+				So(string(log), ShouldEqual, item.log)
+			}
+		}
+	})
+}
+
 //------------------------------------------------------------------------------
 
 func TestKubernetesListPodHeapsterCPUUsageMetrics(t *testing.T) {
@@ -1029,10 +1100,45 @@ func TestKubernetesGiBFromMemString(t *testing.T) {
 				gib: 0,
 				err: nil,
 			},
+			{
+				str: `0.5Ti`,
+				gib: 512,
+				err: nil,
+			},
+			{
+				str: `2.5G`,
+				gib: 2.5e9 / 1073741824,
+				err: nil,
+			},
+			{
+				str: `256M`,
+				gib: 256e6 / 1073741824,
+				err: nil,
+			},
+			{
+				str: `1k`,
+				gib: 1e3 / 1073741824,
+				err: nil,
+			},
+			{
+				str: `1e9`,
+				gib: 1e9 / 1073741824,
+				err: nil,
+			},
+			{
+				str: `"2147483648e0"`,
+				gib: 2,
+				err: nil,
+			},
+			{
+				str: `2Gb`,
+				gib: 0,
+				err: errors.New("Could not parse bytes value from 2Gb"),
+			},
 			{
 				str: `butt`,
 				gib: 0,
-				err: errors.New(`Bytes value butt does not match regex ^"?([0-9]+(?:\.[0-9]+)?)([KMG]i)?"?$`),
+				err: errors.New("Could not parse bytes value from butt"),
 			},
 		}
 
@@ -1045,3 +1151,87 @@ func TestKubernetesGiBFromMemString(t *testing.T) {
 		}
 	})
 }
+
+func TestKubernetesRunPodCommand(t *testing.T) {
+	Convey("Kubernetes RunPodCommand works correctly", t, func() {
+		table := []struct {
+			// Input
+			stdin string
+			// Mocks
+			protocol string
+			status   string
+			// Expectations
+			output string
+			err    error
+		}{
+			// A successful example, which is sent stdin
+			{
+				stdin:    "values",
+				protocol: "v4.channel.k8s.io",
+				status:   `{"status":"Success"}`,
+				output:   "values\nwarning",
+			},
+			// Commands which fail
+			{
+				protocol: "v4.channel.k8s.io",
+				status:   `{"status":"Failure","message":"command terminated with non-zero exit code"}`,
+				output:   "\nwarning",
+				err:      errors.New("command terminated with non-zero exit code"),
+			},
+			// Before v4, there's only a status if it fails
+			{
+				protocol: "channel.k8s.io",
+				output:   "\nwarning",
+			},
+			{
+				protocol: "channel.k8s.io",
+				status:   "executable file not found",
+				output:   "\nwarning",
+				err:      errors.New("executable file not found"),
+			},
+		}
+
+		for _, item := range table {
+			var query url.Values
+			upgrader := websocket.Upgrader{Subprotocols: []string{item.protocol}}
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				stdin := []byte{0}
+				if r.URL.Query().Get("stdin") == "true" {
+					_, stdin, _ = conn.ReadMessage()
+				}
+				conn.WriteMessage(websocket.BinaryMessage, append([]byte{1}, append(stdin[1:], '\n')...))
+				conn.WriteMessage(websocket.BinaryMessage, append([]byte{2}, "warning"...))
+				if item.status != "" {
+					conn.WriteMessage(websocket.BinaryMessage, append([]byte{3}, item.status...))
+				}
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			}))
+
+			kube := &model.Kube{MasterPublicIP: strings.TrimPrefix(server.URL, "https://")}
+			k8s := &kubernetes.Client{Kube: kube, HTTPClient: server.Client()}
+			cmd := &kubernetes.PodCommand{
+				Command: []string{"/bin/sh", "-c", "head -c 6"},
+				Output:  new(bytes.Buffer),
+				Timeout: 5 * time.Second,
+			}
+			if item.stdin != "" {
+				cmd.Stdin = []byte(item.stdin)
+			}
+
+			err := k8s.RunPodCommand("default", "worker", cmd)
+
+			So(err, ShouldResemble, item.err)
+			So(cmd.Output.(*bytes.Buffer).String(), ShouldEqual, item.output)
+			So(query["command"], ShouldResemble, cmd.Command)
+			So(query.Get("stdin"), ShouldEqual, strconv.FormatBool(item.stdin != ""))
+
+			server.Close()
+		}
+	})
+}
@@ -19,10 +19,12 @@ type HeapsterMetrics struct {
 }
 
 type Metadata struct {
+	UID               string            `json:"uid,omitempty"`
 	Name              string            `json:"name,omitempty"`
 	GenerateName      string            `json:"generateName,omitempty"`
 	Namespace         string            `json:"namespace,omitempty"`
 	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
 	CreationTimestamp string            `json:"creationTimestamp,omitempty"`
 }
 
@@ -113,6 +115,11 @@ type Volume struct {
 	FlexVolume           *FlexVolume           `json:"flexVolume,omitempty"`
 	Cinder               *Cinder               `json:"cinder,omitempty"`
 	GcePersistentDisk    *GcePersistentDisk    `json:"gcePersistentDisk,omitempty"`
+	Secret               *SecretVolumeSource   `json:"secret,omitempty"`
+}
+
+type SecretVolumeSource struct {
+	SecretName string `json:"secretName"`
 }
 
 type VolumeMount struct {
@@ -242,10 +249,11 @@ type Service struct {
 }
 
 type ServiceSpec struct {
-	Type      string            `json:"type,omitempty"`
-	Selector  map[string]string `json:"selector"`
-	Ports     []ServicePort     `json:"ports"`
-	ClusterIP string            `json:"clusterIP,omitempty"`
+	Type                     string            `json:"type,omitempty"`
+	Selector                 map[string]string `json:"selector"`
+	Ports                    []ServicePort     `json:"ports"`
+	ClusterIP                string            `json:"clusterIP,omitempty"`
+	LoadBalancerSourceRanges []string          `json:"loadBalancerSourceRanges,omitempty"`
 }
 
 type ServicePort struct {
@@ -266,6 +274,66 @@ type LoadBalancerStatus struct {
 
 type LoadBalancerIngress struct {
 	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+}
+
+//------------------------------------------------------------------------------
+type Ingress struct {
+	Metadata Metadata      `json:"metadata"`
+	Spec     IngressSpec   `json:"spec"`
+	Status   IngressStatus `json:"status"`
+}
+
+type IngressSpec struct {
+	TLS   []IngressTLS  `json:"tls,omitempty"`
+	Rules []IngressRule `json:"rules"`
+}
+
+type IngressTLS struct {
+	Hosts      []string `json:"hosts"`
+	SecretName string   `json:"secretName"`
+}
+
+type IngressRule struct {
+	Host string           `json:"host,omitempty"`
+	HTTP IngressRuleValue `json:"http"`
+}
+
+type IngressRuleValue struct {
+	Paths []IngressPath `json:"paths"`
+}
+
+type IngressPath struct {
+	Path    string         `json:"path,omitempty"`
+	Backend IngressBackend `json:"backend"`
+}
+
+type IngressBackend struct {
+	ServiceName string `json:"serviceName"`
+	ServicePort int    `json:"servicePort"`
+}
+
+type IngressStatus struct {
+	LoadBalancer LoadBalancerStatus `json:"loadBalancer"`
+}
+
+//------------------------------------------------------------------------------
+
+type Secret struct {
+	Metadata Metadata `json:"metadata"`
+	Type     string   `json:"type,omitempty"`
+	// Data values are base64 encoded by encoding/json.
+	Data map[string][]byte `json:"data"`
+}
+
+//------------------------------------------------------------------------------
+type ConfigMapList struct {
+	Items []*ConfigMap `json:"items"`
+}
+
+type ConfigMap struct {
+	Metadata Metadata          `json:"metadata"`
+	Data     map[string]string `json:"data"`
 }
 
 //------------------------------------------------------------------------------
@@ -0,0 +1,369 @@
+// Package metrics is a small, dependency-free implementation of labelled
+// counters, gauges and histograms that renders the Prometheus text exposition
+// format (version 0.0.4).
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Content-Type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets (in seconds), suited to
+// measuring the latency of network calls.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// LongBuckets are histogram buckets (in seconds) suited to measuring slow
+// operations such as provisioning steps and Helm jobs.
+var LongBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds a set of metric vectors and renders them on request.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	hooks      []func()
+}
+
+// NewRegistry returns an empty *Registry.
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+// OnCollect registers a function that runs before every render. It is useful
+// for gauges whose values are derived from state held elsewhere (e.g. a DB).
+func (r *Registry) OnCollect(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, fn)
+}
+
+// NewCounterVec registers and returns a new *CounterVec.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	v := &CounterVec{newVec(name, help, "counter", labelNames)}
+	r.register(v)
+	return v
+}
+
+// NewGaugeVec registers and returns a new *GaugeVec.
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	v := &GaugeVec{newVec(name, help, "gauge", labelNames)}
+	r.register(v)
+	return v
+}
+
+// NewHistogramVec registers and returns a new *HistogramVec. Buckets must be
+// sorted in ascending order; DefBuckets is used if none are provided.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	v := &HistogramVec{
+		name:       name,
+		help:       help,
+		buckets:    buckets,
+		labelNames: labelNames,
+		series:     make(map[string]*histogram),
+	}
+	r.register(v)
+	return v
+}
+
+// Write runs every OnCollect hook and writes all registered metrics to w.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	hooks := append([]func(){}, r.hooks...)
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
+	buf := new(bytes.Buffer)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if err := r.Write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+//------------------------------------------------------------------------------
+
+// vec is the shared implementation of counters and gauges, which are both a
+// single float value per label set.
+type vec struct {
+	mu         sync.Mutex
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+	values     map[string]float64
+	labels     map[string][]string
+}
+
+func newVec(name, help, typ string, labelNames []string) *vec {
+	return &vec{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+}
+
+func (v *vec) add(delta float64, labelValues []string) {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.labels[key] = labelValues
+	v.values[key] += delta
+}
+
+func (v *vec) set(value float64, labelValues []string) {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.labels[key] = labelValues
+	v.values[key] = value
+}
+
+func (v *vec) get(labelValues []string) float64 {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[key]
+}
+
+func (v *vec) reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values = make(map[string]float64)
+	v.labels = make(map[string][]string)
+}
+
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	writeHeader(w, v.name, v.help, v.typ)
+	for _, key := range sortedKeys(v.values) {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labelNames, v.labels[key], "", ""), formatFloat(v.values[key]))
+	}
+}
+
+// CounterVec is a set of monotonically increasing values partitioned by label.
+type CounterVec struct {
+	*vec
+}
+
+// Inc increments the counter for the given label values by 1.
+func (v *CounterVec) Inc(labelValues ...string) {
+	v.add(1, labelValues)
+}
+
+// Add increments the counter for the given label values by delta, which must
+// not be negative.
+func (v *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("counter cannot decrease in value")
+	}
+	v.add(delta, labelValues)
+}
+
+// Get returns the current value for the given label values.
+func (v *CounterVec) Get(labelValues ...string) float64 {
+	return v.get(labelValues)
+}
+
+// GaugeVec is a set of arbitrary values partitioned by label.
+type GaugeVec struct {
+	*vec
+}
+
+// Set sets the gauge for the given label values.
+func (v *GaugeVec) Set(value float64, labelValues ...string) {
+	v.set(value, labelValues)
+}
+
+// Inc increments the gauge for the given label values by 1.
+func (v *GaugeVec) Inc(labelValues ...string) {
+	v.add(1, labelValues)
+}
+
+// Dec decrements the gauge for the given label values by 1.
+func (v *GaugeVec) Dec(labelValues ...string) {
+	v.add(-1, labelValues)
+}
+
+// Get returns the current value for the given label values.
+func (v *GaugeVec) Get(labelValues ...string) float64 {
+	return v.get(labelValues)
+}
+
+// Reset removes every label set from the gauge. It is used by OnCollect hooks
+// that rebuild a gauge from scratch on each render.
+func (v *GaugeVec) Reset() {
+	v.reset()
+}
+
+//------------------------------------------------------------------------------
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64 // non-cumulative, one per bucket
+	count       uint64
+	sum         float64
+}
+
+// HistogramVec is a set of histograms partitioned by label.
+type HistogramVec struct {
+	mu         sync.Mutex
+	name       string
+	help       string
+	buckets    []float64
+	labelNames []string
+	series     map[string]*histogram
+}
+
+// Observe adds a single observation to the histogram for the given label
+// values.
+func (v *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.series[key]
+	if !ok {
+		h = &histogram{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(v.buckets)),
+		}
+		v.series[key] = h
+	}
+	for i, upper := range v.buckets {
+		if value <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Count returns the number of observations for the given label values.
+func (v *HistogramVec) Count(labelValues ...string) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok := v.series[strings.Join(labelValues, "\xff")]; ok {
+		return h.count
+	}
+	return 0
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	writeHeader(w, v.name, v.help, "histogram")
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		h := v.series[key]
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labelNames, h.labelValues, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labelNames, h.labelValues, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labelNames, h.labelValues, "", ""), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labelNames, h.labelValues, "", ""), h.count)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,120 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/supergiant/supergiant/pkg/metrics"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	Convey("Registry Write renders the Prometheus text format", t, func() {
+		table := []struct {
+			// Input
+			record func(*metrics.Registry)
+			// Expectations
+			output string
+		}{
+			// Counter with labels
+			{
+				record: func(r *metrics.Registry) {
+					c := r.NewCounterVec("sg_things_total", "Things done.", "kind")
+					c.Inc("b")
+					c.Add(2, "a")
+					c.Inc("b")
+				},
+				output: `# HELP sg_things_total Things done.
+# TYPE sg_things_total counter
+sg_things_total{kind="a"} 2
+sg_things_total{kind="b"} 2
+`,
+			},
+
+			// Gauge without labels, with escaped help
+			{
+				record: func(r *metrics.Registry) {
+					g := r.NewGaugeVec("sg_level", "Level\nof \\ things.")
+					g.Set(3.5)
+					g.Dec()
+				},
+				output: `# HELP sg_level Level\nof \\ things.
+# TYPE sg_level gauge
+sg_level 2.5
+`,
+			},
+
+			// Escaped label values
+			{
+				record: func(r *metrics.Registry) {
+					r.NewCounterVec("sg_errors_total", "Errors.", "tag").Inc(`say "hi"`)
+				},
+				output: `# HELP sg_errors_total Errors.
+# TYPE sg_errors_total counter
+sg_errors_total{tag="say \"hi\""} 1
+`,
+			},
+
+			// Histogram
+			{
+				record: func(r *metrics.Registry) {
+					h := r.NewHistogramVec("sg_duration_seconds", "Durations.", []float64{1, 5}, "op")
+					h.Observe(0.5, "get")
+					h.Observe(3, "get")
+					h.Observe(10, "get")
+				},
+				output: `# HELP sg_duration_seconds Durations.
+# TYPE sg_duration_seconds histogram
+sg_duration_seconds_bucket{op="get",le="1"} 1
+sg_duration_seconds_bucket{op="get",le="5"} 2
+sg_duration_seconds_bucket{op="get",le="+Inf"} 3
+sg_duration_seconds_sum{op="get"} 13.5
+sg_duration_seconds_count{op="get"} 3
+`,
+			},
+
+			// OnCollect hooks run before rendering
+			{
+				record: func(r *metrics.Registry) {
+					g := r.NewGaugeVec("sg_kubes", "Kubes.", "ready")
+					g.Set(10, "stale")
+					r.OnCollect(func() {
+						g.Reset()
+						g.Set(1, "true")
+					})
+				},
+				output: `# HELP sg_kubes Kubes.
+# TYPE sg_kubes gauge
+sg_kubes{ready="true"} 1
+`,
+			},
+		}
+
+		for _, item := range table {
+			registry := metrics.NewRegistry()
+			item.record(registry)
+
+			buf := new(bytes.Buffer)
+			err := registry.Write(buf)
+
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldEqual, item.output)
+		}
+	})
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	Convey("Registry ServeHTTP sets the exposition Content-Type", t, func() {
+		registry := metrics.NewRegistry()
+		registry.NewCounterVec("sg_requests_total", "Requests.").Inc()
+
+		rec := httptest.NewRecorder()
+		registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		So(rec.Code, ShouldEqual, 200)
+		So(rec.Header().Get("Content-Type"), ShouldEqual, metrics.ContentType)
+		So(rec.Body.String(), ShouldContainSubstring, "sg_requests_total 1\n")
+	})
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Budget is composed into models which can be given a monthly spending limit.
+type Budget struct {
+	// MonthlyBudget is disabled when 0.
+	MonthlyBudget float64 `json:"monthly_budget"`
+	// BudgetAlertThresholds are percentages of MonthlyBudget at which an alert
+	// is sent. They default to 50, 80 and 100.
+	BudgetAlertThresholds     []float64 `json:"budget_alert_thresholds" gorm:"-" sg:"store_as_json_in=BudgetAlertThresholdsJSON"`
+	BudgetAlertThresholdsJSON []byte    `json:"-"`
+	// BudgetHardStop prevents Nodes from being created once MonthlyBudget has
+	// been spent.
+	BudgetHardStop bool `json:"budget_hard_stop"`
+
+	// BudgetSpend is the spend of the current month, as of BudgetCheckedAt.
+	BudgetSpend     float64   `json:"budget_spend" sg:"readonly"`
+	BudgetCheckedAt time.Time `json:"budget_checked_at" sg:"readonly"`
+	// BudgetAlertMonth and BudgetAlertPercent record the highest threshold
+	// alerted on, so that each alert is only sent once a month.
+	BudgetAlertMonth   string  `json:"budget_alert_month" sg:"readonly"`
+	BudgetAlertPercent float64 `json:"budget_alert_percent" sg:"readonly"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "encoding/json"
+
+// Bulk is a batch of operations across resources, which are run in order.
+// Each has a result, at the same index as the operation.
+type Bulk struct {
+	// Atomic validates every operation before running any, and runs none of
+	// them if any is invalid.
+	Atomic     bool             `json:"atomic,omitempty"`
+	Operations []*BulkOperation `json:"operations" validate:"nonzero"`
+
+	Results []*BulkResult `json:"results,omitempty" sg:"readonly"`
+}
+
+// BulkOperation is a create, update or delete of a resource, or an action of
+// one (such as start), as with the API route of each.
+type BulkOperation struct {
+	// Op is create, update, delete or action.
+	Op string `json:"op"`
+	// Resource is the name of the resource in the API, such as kube_resources.
+	Resource string `json:"resource"`
+	// ID is of the resource to update, delete or act on.
+	ID *int64 `json:"id,omitempty"`
+	// Action is that of an action operation, such as start.
+	Action string `json:"action,omitempty"`
+	// Item is the body of the request, if any.
+	Item json.RawMessage `json:"item,omitempty"`
+}
+
+// BulkResult is the response to a BulkOperation.
+type BulkResult struct {
+	Status int             `json:"status"`
+	Item   json.RawMessage `json:"item,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+	// Action is the handle of the Action the operation started, if any.
+	Action *BulkAction `json:"action,omitempty"`
+}
+
+// BulkAction is an Action started by an operation, such as deleting a Kube.
+// Its status is rendered on the item at Path until it's done.
+type BulkAction struct {
+	Path   string        `json:"path"`
+	Status *ActionStatus `json:"status,omitempty"`
+}
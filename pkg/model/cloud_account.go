@@ -18,4 +18,6 @@ type CloudAccount struct {
 	// NOTE this is loose map to allow for multiple clouds (eventually)
 	Credentials     map[string]string `json:"credentials,omitempty" validate:"nonzero" gorm:"-" sg:"store_as_json_in=CredentialsJSON,private,immutable"`
 	CredentialsJSON []byte            `json:"-" gorm:"not null"`
+
+	Budget
 }
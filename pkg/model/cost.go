@@ -0,0 +1,99 @@
+package model
+
+import "time"
+
+const (
+	CostAllocationKindNamespace   = "namespace"
+	CostAllocationKindHelmRelease = "helm_release"
+
+	// UnallocatedCostName is the name under which the share of a Kube's spend
+	// not claimed by any pod resource request is recorded.
+	UnallocatedCostName = "(unallocated)"
+)
+
+// NodeUsage records the uptime of a Node (or of a Kube's masters) which no
+// longer exists, so that its spend can still be reported after deletion.
+type NodeUsage struct {
+	BaseModel
+
+	CloudAccountName string `json:"cloud_account_name" gorm:"not null;index"`
+	KubeName         string `json:"kube_name" gorm:"not null;index"`
+	NodeName         string `json:"node_name"`
+	Size             string `json:"size"`
+	Master           bool   `json:"master"`
+
+	HourlyPrice float64   `json:"hourly_price"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at" gorm:"index"`
+}
+
+// CostAllocation is the spend accrued to a single namespace or HelmRelease of
+// a Kube in a calendar month (formatted as 2006-01).
+type CostAllocation struct {
+	BaseModel
+
+	KubeName string  `json:"kube_name" gorm:"not null;index"`
+	Month    string  `json:"month" gorm:"not null;index"`
+	Kind     string  `json:"kind" gorm:"not null"`
+	Name     string  `json:"name" gorm:"not null"`
+	Amount   float64 `json:"amount"`
+}
+
+// CostAccrual is when the spend of a Kube was last accrued to its
+// CostAllocations, which the next accrual carries on from (such as after a
+// restart).
+type CostAccrual struct {
+	BaseModel
+
+	KubeName  string    `json:"kube_name" gorm:"not null;unique_index"`
+	AccruedAt time.Time `json:"accrued_at"`
+}
+
+//------------------------------------------------------------------------------
+
+// KubeCosts is the cost report of a Kube for a calendar month. It is not
+// persisted.
+type KubeCosts struct {
+	KubeName string `json:"kube_name"`
+	Month    string `json:"month"`
+
+	// HourlyRate is the current price per hour of all running Nodes and masters.
+	HourlyRate float64 `json:"hourly_rate"`
+	Total      float64 `json:"total"`
+
+	Nodes        []*NodeCost `json:"nodes"`
+	Namespaces   []*CostItem `json:"namespaces"`
+	HelmReleases []*CostItem `json:"helm_releases"`
+}
+
+// NodeCost is the spend of a single Node (or a Kube's masters) within the
+// reported month.
+type NodeCost struct {
+	Name        string    `json:"name"`
+	Size        string    `json:"size"`
+	Master      bool      `json:"master"`
+	HourlyPrice float64   `json:"hourly_price"`
+	StartedAt   time.Time `json:"started_at"`
+	// EndedAt is nil while the Node is still running.
+	EndedAt *time.Time `json:"ended_at"`
+	Hours   float64    `json:"hours"`
+	Cost    float64    `json:"cost"`
+}
+
+type CostItem struct {
+	Name string  `json:"name"`
+	Cost float64 `json:"cost"`
+}
+
+// Chargeback is the spend of every namespace across all Kubes for a calendar
+// month. It is not persisted.
+type Chargeback struct {
+	Month string            `json:"month"`
+	Items []*ChargebackItem `json:"items"`
+}
+
+type ChargebackItem struct {
+	KubeName  string  `json:"kube_name"`
+	Namespace string  `json:"namespace"`
+	Cost      float64 `json:"cost"`
+}
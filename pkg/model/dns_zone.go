@@ -0,0 +1,34 @@
+package model
+
+type DNSZoneList struct {
+	BaseList
+	Items []*DNSZone `json:"items"`
+}
+
+// DNSZone is a domain whose records Supergiant manages for the hosts of
+// Ingresses, and for the challenges of the certificates issued for them.
+type DNSZone struct {
+	BaseModel
+
+	// belongs_to CloudAccount, for the route53 and digitalocean providers
+	CloudAccount     *CloudAccount `json:"cloud_account,omitempty" gorm:"ForeignKey:CloudAccountName;AssociationForeignKey:Name"`
+	CloudAccountName string        `json:"cloud_account_name" sg:"immutable"`
+
+	// Name is the domain of the zone, such as example.com.
+	Name string `json:"name" validate:"nonzero,max=253,regexp=^([a-z0-9]([-a-z0-9]*[a-z0-9])?\\.)+[a-z]([-a-z0-9]*[a-z0-9])?$" gorm:"not null;unique_index" sg:"immutable"`
+
+	Provider string `json:"provider" validate:"regexp=^(route53|digitalocean|rfc2136)$" gorm:"not null" sg:"immutable"`
+
+	TTL int `json:"ttl" validate:"min=0" sg:"default=300"`
+
+	// Route53HostedZoneID is looked up by Name if not set.
+	Route53HostedZoneID string `json:"route53_hosted_zone_id"`
+
+	// RFC2136Server is the host:port of the primary name server accepting
+	// dynamic updates. Updates are signed with TSIG when a key is set.
+	RFC2136Server        string `json:"rfc2136_server"`
+	RFC2136TSIGKeyName   string `json:"rfc2136_tsig_key_name"`
+	RFC2136TSIGAlgorithm string `json:"rfc2136_tsig_algorithm" validate:"regexp=^(hmac-md5|hmac-sha1|hmac-sha256|hmac-sha512)?$" sg:"default=hmac-sha256"`
+	// RFC2136TSIGSecret is base64 encoded, as in BIND key files.
+	RFC2136TSIGSecret string `json:"rfc2136_tsig_secret,omitempty" sg:"private"`
+}
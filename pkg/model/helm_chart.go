@@ -16,6 +16,47 @@ type HelmChart struct {
 	Version     string `json:"version" validate:"nonzero"`
 	Description string `json:"description" validate:"nonzero"`
 
+	// From the Chart.yaml of the version, as listed in the index of the repo.
+	Home            string                 `json:"home,omitempty"`
+	Icon            string                 `json:"icon,omitempty"`
+	Keywords        []string               `json:"keywords,omitempty" gorm:"-" sg:"store_as_json_in=KeywordsJSON"`
+	KeywordsJSON    []byte                 `json:"-"`
+	Maintainers     []*HelmChartMaintainer `json:"maintainers,omitempty" gorm:"-" sg:"store_as_json_in=MaintainersJSON"`
+	MaintainersJSON []byte                 `json:"-"`
+	// Deprecated charts have a description starting with "DEPRECATED", as is
+	// the convention of the stable repo. They can still be installed.
+	Deprecated bool `json:"deprecated"`
+
 	DefaultConfig     map[string]interface{} `json:"default_config" gorm:"-" sg:"store_as_json_in=DefaultConfigJSON,immutable"`
 	DefaultConfigJSON []byte                 `json:"-"`
+
+	// ValuesSchema is the values.schema.json of the chart, if it has one.
+	ValuesSchema     map[string]interface{} `json:"values_schema,omitempty" gorm:"-" sg:"store_as_json_in=ValuesSchemaJSON,immutable"`
+	ValuesSchemaJSON []byte                 `json:"-"`
+}
+
+type HelmChartMaintainer struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// HelmChartSearch is a search of the chart catalog.
+type HelmChartSearch struct {
+	// Query is words that each must be in the name, keywords or description of
+	// a chart. Charts are ranked by where they are found, name first.
+	Query    string
+	RepoName string
+	// KubeName limits the results to charts HelmChartPolicies allow on the Kube.
+	KubeName string
+	// Latest limits the results to the latest version of each chart.
+	Latest bool
+	// Deprecated, if set, limits the results to charts which are deprecated, or
+	// which aren't.
+	Deprecated *bool
+}
+
+// HelmChartUpload is the body of a chart upload request.
+type HelmChartUpload struct {
+	// Archive is the packaged chart (.tgz), base64 encoded in JSON.
+	Archive []byte `json:"archive"`
 }
@@ -0,0 +1,31 @@
+package model
+
+const (
+	HelmChartPolicyAllow = "allow"
+	HelmChartPolicyDeny  = "deny"
+)
+
+type HelmChartPolicyList struct {
+	BaseList
+	Items []*HelmChartPolicy `json:"items"`
+}
+
+// HelmChartPolicy allows or denies installing versions of charts on Kubes.
+// A release is denied if any deny policy for its Kube matches it. If there
+// are allow policies for its Kube, one of them must match it.
+type HelmChartPolicy struct {
+	BaseModel
+
+	Effect string `json:"effect" validate:"regexp=^(allow|deny)$" gorm:"not null"`
+
+	// Patterns as for path.Match, such as "prod-*". Empty matches anything.
+	KubeName  string `json:"kube_name"`
+	RepoName  string `json:"repo_name"`
+	ChartName string `json:"chart_name"`
+
+	// Versions is a semver constraint, such as ">= 1.2, < 2". Empty matches
+	// every version, and versions which aren't semver match no constraint.
+	Versions string `json:"versions"`
+
+	Description string `json:"description"`
+}
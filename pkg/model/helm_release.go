@@ -1,10 +1,26 @@
 package model
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 type HelmReleaseList struct {
 	BaseList
 	Items []*HelmRelease `json:"items"`
+
+	// Outdated, if set, lists only releases which are outdated, or which
+	// aren't.
+	Outdated *bool `json:"-"`
+}
+
+func (l HelmReleaseList) QueryValues() map[string][]string {
+	qv := l.BaseList.QueryValues()
+	if l.Outdated != nil {
+		qv["outdated"] = []string{strconv.FormatBool(*l.Outdated)}
+	}
+	return qv
 }
 
 type HelmRelease struct {
@@ -16,9 +32,19 @@ type HelmRelease struct {
 
 	// NOTE this is just a "soft" belongs_to.
 	// We don't do relation since there's no real need, and it complicates things.
-	RepoName     string `json:"repo_name" gorm:"not null;index" validate:"nonzero" sg:"immutable"`
-	ChartName    string `json:"chart_name" gorm:"not null;index" validate:"nonzero" sg:"immutable"`
-	ChartVersion string `json:"chart_version" validate:"nonzero" sg:"immutable"`
+	RepoName  string `json:"repo_name" gorm:"not null;index" validate:"nonzero" sg:"immutable"`
+	ChartName string `json:"chart_name" gorm:"not null;index" validate:"nonzero" sg:"immutable"`
+	// ChartVersion and Config can be updated, which upgrades the release.
+	ChartVersion string `json:"chart_version" validate:"nonzero"`
+	// AppVersion is that of the chart, as synced from Tiller.
+	AppVersion string `json:"app_version"`
+
+	// LatestChartVersion is the newest version of the chart in its HelmRepo.
+	// If it is newer than ChartVersion, the release is Outdated and
+	// ChartUpdate is whether it is a "major", "minor" or "patch" update.
+	LatestChartVersion string `json:"latest_chart_version,omitempty" sg:"readonly"`
+	ChartUpdate        string `json:"chart_update,omitempty" sg:"readonly"`
+	Outdated           bool   `json:"outdated" gorm:"index" sg:"readonly"`
 
 	Name      string `json:"name" validate:"regexp=^[\\w-\\.]*$" gorm:"index" sg:"immutable"`
 	Namespace string `json:"namespace" validate:"regexp=^[\\w-\\.]*$" gorm:"index" sg:"immutable"`
@@ -28,11 +54,52 @@ type HelmRelease struct {
 	StatusValue  string `json:"status_value"`
 	UpdatedValue string `json:"updated_value"`
 
-	Config     map[string]interface{} `json:"config" gorm:"-" sg:"store_as_json_in=ConfigJSON,immutable"`
+	// Values are YAML documents of values, each overriding those before it,
+	// such as defaults, then those of an environment. Config overrides them all.
+	Values     []string `json:"values,omitempty" gorm:"-" sg:"store_as_json_in=ValuesJSON"`
+	ValuesJSON []byte   `json:"-"`
+
+	Config     map[string]interface{} `json:"config" gorm:"-" sg:"store_as_json_in=ConfigJSON"`
 	ConfigJSON []byte                 `json:"-"`
+
+	// Options of the install or upgrade started by a create or update; they
+	// are not stored. ReuseValues merges Config into the values of the current
+	// revision, rather than replacing them. Wait waits until the release's
+	// resources are ready, for up to Timeout seconds.
+	ReuseValues bool `json:"reuse_values,omitempty" gorm:"-"`
+	Wait        bool `json:"wait,omitempty" gorm:"-"`
+	Timeout     int  `json:"timeout,omitempty" gorm:"-" validate:"min=0"`
 }
 
 func (m *HelmRelease) SetPassiveStatus() {
 	m.PassiveStatus = strings.ToLower(m.StatusValue)
 	m.PassiveStatusOkay = m.StatusValue == "DEPLOYED"
+	if m.Outdated {
+		m.PassiveStatus += ", outdated"
+	}
+}
+
+// HelmReleaseRevision is a revision in the history of a HelmRelease, as kept
+// by Tiller.
+type HelmReleaseRevision struct {
+	Revision     int                    `json:"revision"`
+	ChartName    string                 `json:"chart_name"`
+	ChartVersion string                 `json:"chart_version"`
+	AppVersion   string                 `json:"app_version"`
+	Config       map[string]interface{} `json:"config"`
+	Status       string                 `json:"status"`
+	Description  string                 `json:"description"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// HelmReleaseHistory lists the revisions of a HelmRelease, latest first.
+type HelmReleaseHistory struct {
+	Items []*HelmReleaseRevision `json:"items"`
+}
+
+// HelmReleaseRollback is the body of a rollback request.
+type HelmReleaseRollback struct {
+	Revision int  `json:"revision"`
+	Wait     bool `json:"wait,omitempty"`
+	Timeout  int  `json:"timeout,omitempty"`
 }
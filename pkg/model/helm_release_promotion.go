@@ -0,0 +1,82 @@
+package model
+
+const (
+	HelmReleasePromotionPending  = "pending_approval"
+	HelmReleasePromotionPromoted = "promoted"
+	HelmReleasePromotionRejected = "rejected"
+)
+
+type HelmReleasePromotionList struct {
+	BaseList
+	Items []*HelmReleasePromotion `json:"items"`
+}
+
+// HelmReleasePromotion installs or upgrades a HelmRelease on another Kube with
+// the chart, version and values of the release, such as from staging to
+// production. Promotions to a Kube with PromotionApproval wait for an admin to
+// approve them.
+type HelmReleasePromotion struct {
+	BaseModel
+
+	// The promoted HelmRelease
+	HelmReleaseID *int64 `json:"helm_release_id" gorm:"not null;index" sg:"readonly"`
+
+	// belongs_to Kube, the one promoted to
+	Kube      *Kube  `json:"kube,omitempty" gorm:"ForeignKey:KubeName;AssociationForeignKey:Name"`
+	KubeName  string `json:"kube_name" validate:"nonzero" gorm:"not null;index" sg:"immutable"`
+	Namespace string `json:"namespace" validate:"regexp=^[\\w-\\.]*$" sg:"immutable"`
+
+	// What is promoted, as of the request, so an approval promotes what was
+	// asked for even if the release changed since.
+	ReleaseName  string `json:"release_name" sg:"readonly"`
+	RepoName     string `json:"repo_name" sg:"readonly"`
+	ChartName    string `json:"chart_name" sg:"readonly"`
+	ChartVersion string `json:"chart_version" sg:"readonly"`
+	// SourceValues are the Values of the release, then its Config.
+	SourceValues     []string `json:"source_values" gorm:"-" sg:"store_as_json_in=SourceValuesJSON,readonly"`
+	SourceValuesJSON []byte   `json:"-"`
+
+	// Values and Config override those of the release on the Kube promoted to,
+	// such as the hostnames of the environment.
+	Values     []string               `json:"values,omitempty" gorm:"-" sg:"store_as_json_in=ValuesJSON,immutable"`
+	ValuesJSON []byte                 `json:"-"`
+	Config     map[string]interface{} `json:"config,omitempty" gorm:"-" sg:"store_as_json_in=ConfigJSON,immutable"`
+	ConfigJSON []byte                 `json:"-"`
+
+	// Options of the install or upgrade, as for HelmReleases.
+	Wait    bool `json:"wait,omitempty"`
+	Timeout int  `json:"timeout,omitempty" validate:"min=0"`
+
+	State string `json:"state" sg:"readonly"`
+	// The HelmRelease on the Kube promoted to, once promoted.
+	TargetReleaseID *int64 `json:"target_release_id,omitempty" sg:"readonly"`
+	RequestedBy     string `json:"requested_by" sg:"readonly"`
+	ReviewedBy      string `json:"reviewed_by,omitempty" sg:"readonly"`
+}
+
+func (m *HelmReleasePromotion) SetPassiveStatus() {
+	m.PassiveStatus = m.State
+	m.PassiveStatusOkay = m.State == HelmReleasePromotionPromoted
+}
+
+// HelmReleaseDrift lists the releases of the same name and chart as a
+// HelmRelease on every Kube, with how their versions compare to it.
+type HelmReleaseDrift struct {
+	Items []*HelmReleaseDriftItem `json:"items"`
+}
+
+type HelmReleaseDriftItem struct {
+	ID           *int64 `json:"id"`
+	KubeName     string `json:"kube_name"`
+	Namespace    string `json:"namespace"`
+	ChartVersion string `json:"chart_version"`
+	AppVersion   string `json:"app_version"`
+	Revision     string `json:"revision"`
+	StatusValue  string `json:"status_value"`
+	// Drift is "same", "behind" or "ahead" of the chart version of the release
+	// asked about, or "unknown" when a version isn't semver.
+	Drift string `json:"drift"`
+	// ValuesDiffer is true if the release has other values, which is expected
+	// for overrides of an environment.
+	ValuesDiffer bool `json:"values_differ"`
+}
@@ -11,6 +11,12 @@ type HelmRepo struct {
 	Name string `json:"name" validate:"nonzero" gorm:"not null;unique_index" sg:"immutable"`
 	URL  string `json:"url" validate:"nonzero" gorm:"not null" sg:"immutable"`
 
+	// Credentials of a private repo, any of "username" and "password", "token"
+	// (sent as a bearer token), "ca_bundle" (PEM certificates the repo is
+	// trusted with), and "client_cert" and "client_key" (PEM).
+	Credentials     map[string]string `json:"credentials,omitempty" gorm:"-" sg:"store_as_json_in=CredentialsJSON,private"`
+	CredentialsJSON []byte            `json:"-"`
+
 	// has_many Charts
 	Charts []*HelmChart `json:"charts,omitempty" gorm:"ForeignKey:RepoName;AssociationForeignKey:Name"`
 }
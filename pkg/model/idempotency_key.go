@@ -0,0 +1,22 @@
+package model
+
+// IdempotencyKey is the response to a request made with an Idempotency-Key
+// header, which is replayed to requests made again with the same key (such as
+// retries after a timeout), instead of them creating or acting again.
+type IdempotencyKey struct {
+	BaseModel
+
+	// Keys are unique to each User.
+	UserID *int64 `json:"user_id" gorm:"not null;unique_index:idx_idempotency_keys_user_id_key"`
+	Key    string `json:"key" gorm:"not null;unique_index:idx_idempotency_keys_user_id_key"`
+
+	// The request made with the key, which requests made again must match.
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	RequestHash string `json:"request_hash"`
+
+	// ResponseStatus is 0 while the request is still being made.
+	ResponseStatus int    `json:"response_status"`
+	ResponseETag   string `json:"response_etag" gorm:"column:response_etag"`
+	ResponseBody   string `json:"response_body" gorm:"type:text"`
+}
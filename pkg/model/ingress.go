@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type IngressList struct {
+	BaseList
+	Items []*Ingress `json:"items"`
+}
+
+// Ingress routes HTTP(S) traffic for hosts and paths to Services of a Kube.
+type Ingress struct {
+	BaseModel
+
+	// belongs_to Kube
+	Kube     *Kube  `json:"kube,omitempty" gorm:"ForeignKey:KubeName;AssociationForeignKey:Name"`
+	KubeName string `json:"kube_name" gorm:"not null;unique_index:ingress_name_within_kube" validate:"nonzero" sg:"immutable"`
+
+	Name string `json:"name" validate:"nonzero,max=63,regexp=^[a-z]([-a-z0-9]*[a-z0-9])?$" gorm:"not null;unique_index:ingress_name_within_kube" sg:"immutable"`
+
+	Namespace string `json:"namespace" validate:"nonzero" sg:"immutable"`
+
+	// IngressClass selects the ingress controller serving the Ingress.
+	IngressClass string `json:"ingress_class" sg:"default=nginx"`
+
+	Rules     []*IngressRule `json:"rules" gorm:"-" validate:"min=1" sg:"store_as_json_in=RulesJSON"`
+	RulesJSON []byte         `json:"-"`
+
+	// TLS serves every host of Rules over HTTPS. Unless TLSSecretName is set,
+	// a certificate is issued (and renewed) through ACME, which requires every
+	// host to be within a DNSZone.
+	TLS           bool   `json:"tls"`
+	TLSSecretName string `json:"tls_secret_name"`
+
+	// Address is the hostname or IP of the ingress controller's load balancer.
+	// Hosts within a DNSZone are pointed at it.
+	Address string `json:"address" sg:"readonly"`
+
+	CertificateExpiresAt *time.Time `json:"certificate_expires_at" sg:"readonly"`
+}
+
+type IngressRule struct {
+	Host  string         `json:"host"`
+	Paths []*IngressPath `json:"paths"`
+}
+
+type IngressPath struct {
+	// Path defaults to all paths.
+	Path        string `json:"path"`
+	ServiceName string `json:"service_name"`
+	ServicePort int    `json:"service_port"`
+}
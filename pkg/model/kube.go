@@ -25,6 +25,9 @@ type Kube struct {
 	// has_many HelmReleases
 	HelmReleases     []*HelmRelease `json:"helm_releases,omitempty" gorm:"ForeignKey:KubeName;AssociationForeignKey:Name" sg:"store_as_json_in=HelmReleasesJSON"`
 	HelmReleasesJSON []byte         `json:"-"`
+	// has_many Ingresses
+	Ingresses     []*Ingress `json:"ingresses,omitempty" gorm:"ForeignKey:KubeName;AssociationForeignKey:Name" sg:"store_as_json_in=IngressesJSON"`
+	IngressesJSON []byte     `json:"-"`
 
 	Name string `json:"name" validate:"nonzero,max=12,regexp=^[a-z]([-a-z0-9]*[a-z0-9])?$" gorm:"not null;unique_index" sg:"immutable"`
 	// Kubernetes
@@ -53,6 +56,18 @@ type Kube struct {
 
 	RBACEnabled bool `json:"rbac_enabled"`
 
+	// IngressController installs the nginx ingress controller (through a
+	// HelmRelease) when the first Ingress of the Kube is provisioned.
+	IngressController bool `json:"ingress_controller"`
+
+	// PromotionApproval makes HelmReleasePromotions to the Kube wait for an
+	// admin to approve them. Only admins can set it, or change the HelmReleases
+	// of the Kube directly.
+	PromotionApproval bool `json:"promotion_approval"`
+
+	// An optional budget of the Kube alone, on top of that of its CloudAccount.
+	Budget
+
 	HeapsterVersion          string `json:"heapster_version" validate:"nonzero" sg:"default=v1.4.0,immutable"`
 	HeapsterMetricResolution string `json:"heapster_metric_resolution" validate:"regexp=^([0-9]+[smhd])+$" sg:"default=20s,immutable"`
 
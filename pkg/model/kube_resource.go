@@ -50,3 +50,26 @@ func (m *KubeResource) SetPassiveStatus() {
 	}
 	m.PassiveStatus = "stopped"
 }
+
+// PodLogOptions are of the log of a Pod KubeResource.
+type PodLogOptions struct {
+	// Container is that of the Pod to log, which may be omitted if it has one.
+	Container string `json:"container,omitempty"`
+	// Follow streams the log until the container stops, or the request ends.
+	Follow bool `json:"follow,omitempty"`
+	// TailLines is how many lines from the end of the log to start from.
+	TailLines *int64 `json:"tail,omitempty"`
+	// SinceSeconds is how far back the log starts from.
+	SinceSeconds *int64 `json:"since,omitempty"`
+}
+
+// PodExecOptions are of a command run in a container of a Pod KubeResource.
+type PodExecOptions struct {
+	// Container is that of the Pod to run in, which may be omitted if it has one.
+	Container string   `json:"container,omitempty"`
+	Command   []string `json:"command"`
+	// Stdin sends the input of the session to the command.
+	Stdin bool `json:"stdin,omitempty"`
+	// TTY allocates a terminal for the command.
+	TTY bool `json:"tty,omitempty"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type List interface {
 	QueryValues() map[string][]string
@@ -21,10 +24,30 @@ type BaseList struct {
 	// The above translates to "(name=this OR name=that) AND (other_fied=thingy)".
 	Filters map[string][]string `json:"filters"`
 
+	// Query holds filter expressions, all of which must match, such as
+	// "name!=test", "kube.cloud_account_name in (aws,gce)", "name like redis%"
+	// or "config.image.tag=4.0" (on a field stored as JSON).
+	Query []string `json:"-"`
+
+	// Sort lists the fields to sort by, descending when prefixed with "-", such
+	// as "-created_at".
+	Sort []string `json:"-"`
+
+	// Fields, when given, are the only fields of the items listed (along with
+	// their ID). Exclude leaves fields out, such as "extra_data".
+	Fields  []string `json:"-"`
+	Exclude []string `json:"-"`
+
 	// Pagination
 	Offset int64 `json:"offset"`
 	Limit  int64 `json:"limit"`
 	Total  int64 `json:"total"`
+
+	// Cursor pages by cursor instead of Offset when it's set: empty for the
+	// first page, then the NextCursor of the page before. NextCursor is empty
+	// on the last page.
+	Cursor     *string `json:"-"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
 
 func (l BaseList) QueryValues() map[string][]string {
@@ -35,5 +58,20 @@ func (l BaseList) QueryValues() map[string][]string {
 	for key, values := range l.Filters {
 		qv["filter."+key] = values
 	}
+	if len(l.Query) > 0 {
+		qv["filter"] = l.Query
+	}
+	if len(l.Sort) > 0 {
+		qv["sort"] = []string{strings.Join(l.Sort, ",")}
+	}
+	if len(l.Fields) > 0 {
+		qv["fields"] = []string{strings.Join(l.Fields, ",")}
+	}
+	if len(l.Exclude) > 0 {
+		qv["exclude"] = []string{strings.Join(l.Exclude, ",")}
+	}
+	if l.Cursor != nil {
+		qv["cursor"] = []string{*l.Cursor}
+	}
 	return qv
 }
@@ -19,8 +19,51 @@ type LoadBalancer struct {
 	Selector     map[string]string `json:"selector,omitempty" gorm:"-" sg:"store_as_json_in=SelectorJSON"`
 	SelectorJSON []byte            `json:"-"`
 
+	// Ports maps load balancer ports to target ports, using Protocol.
 	Ports     map[int]int `json:"ports,omitempty" gorm:"-" sg:"store_as_json_in=PortsJSON"`
 	PortsJSON []byte      `json:"-"`
 
+	// Protocol of Ports, TCP (the default) or UDP.
+	Protocol string `json:"protocol" validate:"regexp=^(TCP|UDP)?$" sg:"default=TCP"`
+
+	// NamedPorts are served alongside Ports, for ports which need a name or
+	// their own protocol.
+	NamedPorts     []*LoadBalancerPort `json:"named_ports,omitempty" gorm:"-" sg:"store_as_json_in=NamedPortsJSON"`
+	NamedPortsJSON []byte              `json:"-"`
+
+	// TLSCertificate is terminated by the load balancer on TLSPorts (443 by
+	// default). On AWS it is the ARN of an ACM or IAM certificate, and on
+	// DigitalOcean a certificate ID.
+	TLSCertificate string `json:"tls_certificate"`
+	TLSPorts       []int  `json:"tls_ports,omitempty" gorm:"-" sg:"store_as_json_in=TLSPortsJSON"`
+	TLSPortsJSON   []byte `json:"-"`
+
+	HealthCheckPath string `json:"health_check_path"`
+	// HealthCheckInterval is in seconds.
+	HealthCheckInterval int `json:"health_check_interval"`
+
+	// SourceRanges are the CIDRs allowed to connect. Everyone is allowed if
+	// empty.
+	SourceRanges     []string `json:"source_ranges,omitempty" gorm:"-" sg:"store_as_json_in=SourceRangesJSON"`
+	SourceRangesJSON []byte   `json:"-"`
+
+	// Internal load balancers are only reachable from within the network of
+	// the Kube. Providers can't switch an existing load balancer, so this can't
+	// be changed.
+	Internal bool `json:"internal" sg:"immutable"`
+
+	// Annotations are added to the Kubernetes Service, and override those set
+	// by Supergiant for the fields above.
+	Annotations     map[string]string `json:"annotations,omitempty" gorm:"-" sg:"store_as_json_in=AnnotationsJSON"`
+	AnnotationsJSON []byte            `json:"-"`
+
 	Address string `json:"address" sg:"readonly"`
 }
+
+type LoadBalancerPort struct {
+	Name       string `json:"name"`
+	Port       int    `json:"port"`
+	TargetPort int    `json:"target_port"`
+	// Protocol defaults to that of the LoadBalancer.
+	Protocol string `json:"protocol"`
+}
@@ -31,6 +31,16 @@ type BaseModel struct {
 
 	PassiveStatus     string `gorm:"-" json:"passive_status,omitempty"`
 	PassiveStatusOkay bool   `gorm:"-" json:"passive_status_okay,omitempty"`
+
+	// ResourceVersion is incremented each time the model is saved. Updates
+	// are made from a version, and conflict if the model has changed since.
+	ResourceVersion int64 `json:"resource_version" gorm:"not null;default:1"`
+}
+
+// Versioned is a Model with a ResourceVersion.
+type Versioned interface {
+	GetResourceVersion() int64
+	SetResourceVersion(int64)
 }
 
 // ActionStatus holds all the information pertaining to any running or failed
@@ -43,6 +53,9 @@ type ActionStatus struct {
 	Cancelled      bool   `json:"cancelled,omitempty"`
 	TotalSteps     int    `json:"total_steps,omitempty"`
 	StepsCompleted int    `json:"steps_completed,omitempty"`
+	// Output is the end of the output of the command the Action is running, for
+	// those that run one (such as helm).
+	Output string `json:"output,omitempty"`
 }
 
 // GetID returns the model ID.
@@ -67,6 +80,16 @@ func (m *BaseModel) SetActionStatus(status *ActionStatus) {
 	m.Status = status
 }
 
+// GetResourceVersion returns the model ResourceVersion.
+func (m *BaseModel) GetResourceVersion() int64 {
+	return m.ResourceVersion
+}
+
+// SetResourceVersion sets the model ResourceVersion.
+func (m *BaseModel) SetResourceVersion(version int64) {
+	m.ResourceVersion = version
+}
+
 // SetPassiveStatus implements the Model interface, but does nothing. It can be
 // used by models to set render the PassiveStatus and PassiveStatusOkay fields.
 func (m *BaseModel) SetPassiveStatus() {
@@ -11,10 +11,18 @@ type Session struct {
 	ID        string    `json:"id"`
 	UserID    *int64    `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the Session stops authenticating its User, who then
+	// logs in again.
+	ExpiresAt time.Time `json:"expires_at"`
 
 	User *User `json:"user"`
 }
 
+// Expired returns whether the Session expired.
+func (m *Session) Expired() bool {
+	return !m.ExpiresAt.IsZero() && time.Now().After(m.ExpiresAt)
+}
+
 func (m *Session) Description() string {
 	return "Session " + m.ID
 }
@@ -0,0 +1,267 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/restxml"
+	"github.com/aws/aws-sdk-go/private/signer/v4"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/pkg/util"
+)
+
+// NOTE the vendored aws-sdk-go predates the route53 package we'd otherwise
+// use, so the few operations needed are defined here, the way the SDK
+// generates them.
+
+// Route53 manages the records of DNSZones in Route 53 hosted zones.
+type Route53 struct {
+	client *client.Client
+}
+
+// NewRoute53 returns a Route53 DNSProvider using the credentials of an AWS
+// CloudAccount.
+func NewRoute53(creds map[string]string) core.DNSProvider {
+	awsCreds := credentials.NewStaticCredentials(creds["access_key"], creds["secret_key"], "")
+	// Route 53 is global, the region is only needed to resolve its endpoint
+	c := globalAWSSession.ClientConfig("route53", aws.NewConfig().WithRegion("us-east-1").WithCredentials(awsCreds))
+
+	svc := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   "route53",
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    "2013-04-01",
+		},
+		c.Handlers,
+	)
+	svc.Handlers.Sign.PushBack(v4.Sign)
+	svc.Handlers.Build.PushBackNamed(restxml.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(restxml.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(restxml.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(restxml.UnmarshalErrorHandler)
+
+	return &Route53{svc}
+}
+
+// UpsertRecord implements core.DNSProvider.
+func (r *Route53) UpsertRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	hostedZoneID, err := r.hostedZoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	value := record.Value
+	if record.Type == "TXT" {
+		value = `"` + value + `"`
+	}
+	change := &route53Change{
+		Action: aws.String("UPSERT"),
+		ResourceRecordSet: &route53ResourceRecordSet{
+			Name: aws.String(record.Name),
+			Type: aws.String(record.Type),
+			TTL:  aws.Int64(int64(record.TTL)),
+			ResourceRecords: []*route53ResourceRecord{
+				{Value: aws.String(value)},
+			},
+		},
+	}
+	return r.change(hostedZoneID, change)
+}
+
+// DeleteRecord implements core.DNSProvider.
+func (r *Route53) DeleteRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	hostedZoneID, err := r.hostedZoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	// Deletes must match the existing record set exactly
+	input := &route53ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(hostedZoneID),
+		StartRecordName: aws.String(record.Name),
+		StartRecordType: aws.String(record.Type),
+		MaxItems:        aws.String("1"),
+	}
+	output := new(route53ListResourceRecordSetsOutput)
+	op := &request.Operation{
+		Name:       "ListResourceRecordSets",
+		HTTPMethod: "GET",
+		HTTPPath:   "/2013-04-01/hostedzone/{Id}/rrset",
+	}
+	if err := r.client.NewRequest(op, input, output).Send(); err != nil {
+		return err
+	}
+	for _, set := range output.ResourceRecordSets {
+		if strings.TrimSuffix(aws.StringValue(set.Name), ".") == record.Name && aws.StringValue(set.Type) == record.Type {
+			return r.change(hostedZoneID, &route53Change{
+				Action:            aws.String("DELETE"),
+				ResourceRecordSet: set,
+			})
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// change applies the change and waits for it to reach all name servers.
+func (r *Route53) change(hostedZoneID string, change *route53Change) error {
+	input := &route53ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneID),
+		ChangeBatch: &route53ChangeBatch{
+			Changes: []*route53Change{change},
+		},
+	}
+	output := new(route53ChangeInfoOutput)
+	op := &request.Operation{
+		Name:       "ChangeResourceRecordSets",
+		HTTPMethod: "POST",
+		HTTPPath:   "/2013-04-01/hostedzone/{Id}/rrset/",
+	}
+	if err := r.client.NewRequest(op, input, output).Send(); err != nil {
+		return err
+	}
+
+	changeID := strings.TrimPrefix(aws.StringValue(output.ChangeInfo.Id), "/change/")
+	desc := fmt.Sprintf("Route 53 change %s", changeID)
+	return util.WaitFor(desc, 5*time.Minute, 5*time.Second, func() (bool, error) {
+		input := &route53GetChangeInput{Id: aws.String(changeID)}
+		output := new(route53ChangeInfoOutput)
+		op := &request.Operation{
+			Name:       "GetChange",
+			HTTPMethod: "GET",
+			HTTPPath:   "/2013-04-01/change/{Id}",
+		}
+		if err := r.client.NewRequest(op, input, output).Send(); err != nil {
+			return false, err
+		}
+		return aws.StringValue(output.ChangeInfo.Status) == "INSYNC", nil
+	})
+}
+
+// hostedZoneID returns the ID set on the DNSZone, or looks it up by name.
+func (r *Route53) hostedZoneID(zone *model.DNSZone) (string, error) {
+	if zone.Route53HostedZoneID != "" {
+		return strings.TrimPrefix(zone.Route53HostedZoneID, "/hostedzone/"), nil
+	}
+
+	input := &route53ListHostedZonesByNameInput{
+		DNSName:  aws.String(zone.Name),
+		MaxItems: aws.String("1"),
+	}
+	output := new(route53ListHostedZonesByNameOutput)
+	op := &request.Operation{
+		Name:       "ListHostedZonesByName",
+		HTTPMethod: "GET",
+		HTTPPath:   "/2013-04-01/hostedzonesbyname",
+	}
+	if err := r.client.NewRequest(op, input, output).Send(); err != nil {
+		return "", err
+	}
+	for _, hostedZone := range output.HostedZones {
+		if strings.TrimSuffix(aws.StringValue(hostedZone.Name), ".") == zone.Name {
+			return strings.TrimPrefix(aws.StringValue(hostedZone.Id), "/hostedzone/"), nil
+		}
+	}
+	return "", fmt.Errorf("No Route 53 hosted zone named %s", zone.Name)
+}
+
+//------------------------------------------------------------------------------
+
+type route53ListHostedZonesByNameInput struct {
+	_ struct{} `type:"structure"`
+
+	DNSName  *string `location:"querystring" locationName:"dnsname" type:"string"`
+	MaxItems *string `location:"querystring" locationName:"maxitems" type:"string"`
+}
+
+type route53ListHostedZonesByNameOutput struct {
+	_ struct{} `type:"structure"`
+
+	HostedZones []*route53HostedZone `locationNameList:"HostedZone" type:"list"`
+}
+
+type route53HostedZone struct {
+	_ struct{} `type:"structure"`
+
+	Id   *string `type:"string"`
+	Name *string `type:"string"`
+}
+
+type route53ListResourceRecordSetsInput struct {
+	_ struct{} `type:"structure"`
+
+	HostedZoneId    *string `location:"uri" locationName:"Id" type:"string"`
+	StartRecordName *string `location:"querystring" locationName:"name" type:"string"`
+	StartRecordType *string `location:"querystring" locationName:"type" type:"string"`
+	MaxItems        *string `location:"querystring" locationName:"maxitems" type:"string"`
+}
+
+type route53ListResourceRecordSetsOutput struct {
+	_ struct{} `type:"structure"`
+
+	ResourceRecordSets []*route53ResourceRecordSet `locationNameList:"ResourceRecordSet" type:"list"`
+}
+
+type route53ChangeResourceRecordSetsInput struct {
+	_ struct{} `locationName:"ChangeResourceRecordSetsRequest" type:"structure" xmlURI:"https://route53.amazonaws.com/doc/2013-04-01/"`
+
+	ChangeBatch  *route53ChangeBatch `type:"structure"`
+	HostedZoneId *string             `location:"uri" locationName:"Id" type:"string"`
+}
+
+type route53ChangeBatch struct {
+	_ struct{} `type:"structure"`
+
+	Changes []*route53Change `locationNameList:"Change" type:"list"`
+}
+
+type route53Change struct {
+	_ struct{} `type:"structure"`
+
+	Action            *string                   `type:"string"`
+	ResourceRecordSet *route53ResourceRecordSet `type:"structure"`
+}
+
+type route53ResourceRecordSet struct {
+	_ struct{} `type:"structure"`
+
+	Name            *string                  `type:"string"`
+	Type            *string                  `type:"string"`
+	TTL             *int64                   `type:"long"`
+	ResourceRecords []*route53ResourceRecord `locationNameList:"ResourceRecord" type:"list"`
+}
+
+type route53ResourceRecord struct {
+	_ struct{} `type:"structure"`
+
+	Value *string `type:"string"`
+}
+
+type route53GetChangeInput struct {
+	_ struct{} `type:"structure"`
+
+	Id *string `location:"uri" locationName:"Id" type:"string"`
+}
+
+type route53ChangeInfoOutput struct {
+	_ struct{} `type:"structure"`
+
+	ChangeInfo *route53ChangeInfo `type:"structure"`
+}
+
+type route53ChangeInfo struct {
+	_ struct{} `type:"structure"`
+
+	Id     *string `type:"string"`
+	Status *string `type:"string"`
+}
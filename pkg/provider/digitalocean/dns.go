@@ -0,0 +1,90 @@
+package digitalocean
+
+import (
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+// DNS manages the records of DNSZones in DigitalOcean domains.
+type DNS struct {
+	Client *godo.Client
+}
+
+// NewDNS returns a DNS DNSProvider using the credentials of a DigitalOcean
+// CloudAccount.
+func NewDNS(creds map[string]string) core.DNSProvider {
+	account := &model.CloudAccount{Credentials: creds}
+	return &DNS{Client(&model.Kube{CloudAccount: account})}
+}
+
+// UpsertRecord implements core.DNSProvider. NOTE DigitalOcean sets the TTL of
+// records itself.
+func (d *DNS) UpsertRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	req := &godo.DomainRecordEditRequest{
+		Type: record.Type,
+		Name: relativeRecordName(zone, record.Name),
+		Data: record.Value,
+	}
+	// Hostnames must be fully qualified
+	if record.Type == "CNAME" {
+		req.Data += "."
+	}
+
+	existing, err := d.records(zone, req.Name, req.Type)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		_, _, err = d.Client.Domains.CreateRecord(zone.Name, req)
+		return err
+	}
+	_, _, err = d.Client.Domains.EditRecord(zone.Name, existing[0].ID, req)
+	return err
+}
+
+// DeleteRecord implements core.DNSProvider.
+func (d *DNS) DeleteRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	existing, err := d.records(zone, relativeRecordName(zone, record.Name), record.Type)
+	if err != nil {
+		return err
+	}
+	for _, domainRecord := range existing {
+		if _, err := d.Client.Domains.DeleteRecord(zone.Name, domainRecord.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// records returns the records of the domain with the name and type.
+func (d *DNS) records(zone *model.DNSZone, name string, recordType string) ([]godo.DomainRecord, error) {
+	var matches []godo.DomainRecord
+	opts := &godo.ListOptions{Page: 1, PerPage: 200}
+	for {
+		records, resp, err := d.Client.Domains.Records(zone.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.Name == name && record.Type == recordType {
+				matches = append(matches, record)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return matches, nil
+		}
+		opts.Page++
+	}
+}
+
+// relativeRecordName returns the name relative to the domain, which is how
+// DigitalOcean names records ("@" being the domain itself).
+func relativeRecordName(zone *model.DNSZone, name string) string {
+	if name == zone.Name {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zone.Name)
+}
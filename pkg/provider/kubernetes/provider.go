@@ -1,7 +1,10 @@
 package kubernetes
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,14 +44,17 @@ func (p *Provider) DeleteNode(m *model.Node, action *core.Action) error {
 }
 
 func (p *Provider) CreateLoadBalancer(m *model.LoadBalancer, action *core.Action) error {
-	service := loadBalancerAsKubernetesService(m)
+	service, err := loadBalancerAsKubernetesService(m)
+	if err != nil {
+		return err
+	}
 
 	if err := p.Core.K8S(m.Kube).CreateResource("api/v1", "Service", m.Namespace, service, service); err != nil {
 		return err
 	}
 
 	waitDesc := fmt.Sprintf("LoadBalancer %s address", m.Name)
-	err := util.WaitFor(waitDesc, 5*time.Minute, 4*time.Second, func() (bool, error) {
+	err = util.WaitFor(waitDesc, 5*time.Minute, 4*time.Second, func() (bool, error) {
 		if getErr := p.Core.K8S(m.Kube).GetResource("api/v1", "Service", m.Namespace, m.Name, service); getErr != nil {
 			return false, getErr
 		}
@@ -58,12 +64,31 @@ func (p *Provider) CreateLoadBalancer(m *model.LoadBalancer, action *core.Action
 		return err
 	}
 
-	return p.Core.DB.Model(m).Update("address", service.Status.LoadBalancer.Ingress[0].Hostname)
+	// AWS gives load balancers a hostname, most other providers an IP
+	ingress := service.Status.LoadBalancer.Ingress[0]
+	address := ingress.Hostname
+	if address == "" {
+		address = ingress.IP
+	}
+	return p.Core.DB.Model(m).Update("address", address)
 }
 
+// UpdateLoadBalancer reconciles the Service with the LoadBalancer. Fields are
+// patched onto the existing Service so that its cluster IP and node ports are
+// kept, and anything Supergiant set before but is no longer wanted is removed.
 func (p *Provider) UpdateLoadBalancer(m *model.LoadBalancer, action *core.Action) error {
-	service := loadBalancerAsKubernetesService(m)
-	return p.Core.K8S(m.Kube).UpdateResource("api/v1", "Service", m.Namespace, m.Name, service, service)
+	desired, err := loadBalancerAsKubernetesService(m)
+	if err != nil {
+		return err
+	}
+
+	existing := new(kubernetes.Service)
+	if err := p.Core.K8S(m.Kube).GetResource("api/v1", "Service", m.Namespace, m.Name, existing); err != nil {
+		return err
+	}
+
+	patch := loadBalancerServicePatch(existing, desired)
+	return p.Core.K8S(m.Kube).UpdateResource("api/v1", "Service", m.Namespace, m.Name, patch, existing)
 }
 
 func (p *Provider) DeleteLoadBalancer(m *model.LoadBalancer, action *core.Action) error {
@@ -76,24 +101,204 @@ func (p *Provider) DeleteLoadBalancer(m *model.LoadBalancer, action *core.Action
 
 // Private
 
-func loadBalancerAsKubernetesService(m *model.LoadBalancer) *kubernetes.Service {
+// managedAnnotationsKey is the Service annotation listing the annotations
+// set by Supergiant, so that they can be removed on update when no longer
+// wanted without touching any set by others.
+const managedAnnotationsKey = "supergiant.io/managed-annotations"
+
+func loadBalancerAsKubernetesService(m *model.LoadBalancer) (*kubernetes.Service, error) {
+	protocol := m.Protocol
+	if protocol == "" {
+		protocol = "TCP"
+	}
+
+	// Sorted, so that updates are stable
+	var lbPorts []int
+	for lbPort := range m.Ports {
+		lbPorts = append(lbPorts, lbPort)
+	}
+	sort.Ints(lbPorts)
+
 	var ports []kubernetes.ServicePort
-	for lbPort, targetPort := range m.Ports {
-		port := kubernetes.ServicePort{
+	for _, lbPort := range lbPorts {
+		ports = append(ports, kubernetes.ServicePort{
 			Port:       lbPort,
-			TargetPort: targetPort,
+			TargetPort: m.Ports[lbPort],
+			Protocol:   protocol,
+		})
+	}
+	for _, namedPort := range m.NamedPorts {
+		port := kubernetes.ServicePort{
+			Name:       namedPort.Name,
+			Port:       namedPort.Port,
+			TargetPort: namedPort.TargetPort,
+			Protocol:   namedPort.Protocol,
+		}
+		if port.Protocol == "" {
+			port.Protocol = protocol
 		}
 		ports = append(ports, port)
 	}
 
+	// Kubernetes requires every port to be named when there are several
+	if len(ports) > 1 {
+		for i := range ports {
+			if ports[i].Name == "" {
+				ports[i].Name = fmt.Sprintf("%s-%d", strings.ToLower(ports[i].Protocol), ports[i].Port)
+			}
+		}
+	}
+
+	annotations, err := loadBalancerAnnotations(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var managed []string
+	for key := range annotations {
+		managed = append(managed, key)
+	}
+	sort.Strings(managed)
+	annotations[managedAnnotationsKey] = strings.Join(managed, ",")
+
 	return &kubernetes.Service{
 		Metadata: kubernetes.Metadata{
-			Name: m.Name,
+			Name:        m.Name,
+			Annotations: annotations,
 		},
 		Spec: kubernetes.ServiceSpec{
-			Type:     "LoadBalancer",
-			Selector: m.Selector,
-			Ports:    ports,
+			Type:                     "LoadBalancer",
+			Selector:                 m.Selector,
+			Ports:                    ports,
+			LoadBalancerSourceRanges: m.SourceRanges,
+		},
+	}, nil
+}
+
+// loadBalancerAnnotations translates TLS, health check and internal settings
+// into the Service annotations understood by the provider of the Kube.
+func loadBalancerAnnotations(m *model.LoadBalancer) (map[string]string, error) {
+	var provider string
+	if m.Kube != nil && m.Kube.CloudAccount != nil {
+		provider = m.Kube.CloudAccount.Provider
+	}
+
+	tlsPorts := "443"
+	if len(m.TLSPorts) > 0 {
+		var strs []string
+		for _, port := range m.TLSPorts {
+			strs = append(strs, strconv.Itoa(port))
+		}
+		tlsPorts = strings.Join(strs, ",")
+	}
+
+	annotations := make(map[string]string)
+
+	switch provider {
+	case "aws":
+		if m.TLSCertificate != "" {
+			annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-cert"] = m.TLSCertificate
+			annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-ports"] = tlsPorts
+		}
+		if m.HealthCheckPath != "" {
+			annotations["service.beta.kubernetes.io/aws-load-balancer-healthcheck-path"] = m.HealthCheckPath
+		}
+		if m.HealthCheckInterval > 0 {
+			annotations["service.beta.kubernetes.io/aws-load-balancer-healthcheck-interval"] = strconv.Itoa(m.HealthCheckInterval)
+		}
+		if m.Internal {
+			annotations["service.beta.kubernetes.io/aws-load-balancer-internal"] = "true"
+		}
+
+	case "digitalocean":
+		if m.TLSCertificate != "" {
+			annotations["service.beta.kubernetes.io/do-loadbalancer-protocol"] = "https"
+			annotations["service.beta.kubernetes.io/do-loadbalancer-certificate-id"] = m.TLSCertificate
+			annotations["service.beta.kubernetes.io/do-loadbalancer-tls-ports"] = tlsPorts
+		}
+		if m.HealthCheckPath != "" {
+			annotations["service.beta.kubernetes.io/do-loadbalancer-healthcheck-protocol"] = "http"
+			annotations["service.beta.kubernetes.io/do-loadbalancer-healthcheck-path"] = m.HealthCheckPath
+		}
+		if m.HealthCheckInterval > 0 {
+			annotations["service.beta.kubernetes.io/do-loadbalancer-healthcheck-check-interval-seconds"] = strconv.Itoa(m.HealthCheckInterval)
+		}
+		if m.Internal {
+			return nil, errors.New("Internal LoadBalancers are not supported on digitalocean")
+		}
+
+	default:
+		if m.TLSCertificate != "" {
+			return nil, fmt.Errorf("TLS termination of LoadBalancers is not supported on %s", provider)
+		}
+		if m.Internal {
+			switch provider {
+			case "gce":
+				annotations["cloud.google.com/load-balancer-type"] = "Internal"
+			case "openstack":
+				annotations["service.beta.kubernetes.io/openstack-internal-load-balancer"] = "true"
+			default:
+				return nil, fmt.Errorf("Internal LoadBalancers are not supported on %s", provider)
+			}
+		}
+	}
+
+	for key, value := range m.Annotations {
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
+// loadBalancerServicePatch returns a JSON merge patch which turns existing into
+// desired. Map entries which should go away have to be nulled explicitly.
+func loadBalancerServicePatch(existing *kubernetes.Service, desired *kubernetes.Service) map[string]interface{} {
+	annotations := make(map[string]interface{})
+	for key, value := range desired.Metadata.Annotations {
+		annotations[key] = value
+	}
+	for _, key := range strings.Split(existing.Metadata.Annotations[managedAnnotationsKey], ",") {
+		if _, ok := desired.Metadata.Annotations[key]; !ok && key != "" {
+			annotations[key] = nil
+		}
+	}
+
+	selector := make(map[string]interface{})
+	for key, value := range desired.Spec.Selector {
+		selector[key] = value
+	}
+	for key := range existing.Spec.Selector {
+		if _, ok := desired.Spec.Selector[key]; !ok {
+			selector[key] = nil
+		}
+	}
+
+	// Keep the node ports already allocated, so traffic isn't interrupted
+	ports := append([]kubernetes.ServicePort{}, desired.Spec.Ports...)
+	for i := range ports {
+		for _, existingPort := range existing.Spec.Ports {
+			existingProtocol := existingPort.Protocol
+			if existingProtocol == "" {
+				existingProtocol = "TCP"
+			}
+			if existingPort.Port == ports[i].Port && existingProtocol == ports[i].Protocol {
+				ports[i].NodePort = existingPort.NodePort
+			}
+		}
+	}
+
+	var sourceRanges interface{} // null removes them
+	if len(desired.Spec.LoadBalancerSourceRanges) > 0 {
+		sourceRanges = desired.Spec.LoadBalancerSourceRanges
+	}
+
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+		"spec": map[string]interface{}{
+			"selector":                 selector,
+			"ports":                    ports,
+			"loadBalancerSourceRanges": sourceRanges,
 		},
 	}
 }
@@ -0,0 +1,245 @@
+package kubernetes_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+	kubernetesprovider "github.com/supergiant/supergiant/pkg/provider/kubernetes"
+	"github.com/supergiant/supergiant/test/fake_core"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKubernetesProviderCreateLoadBalancer(t *testing.T) {
+	Convey("Kubernetes Provider CreateLoadBalancer works correctly", t, func() {
+		table := []struct {
+			// Input
+			provider     string
+			loadBalancer *model.LoadBalancer
+			// Mocks
+			ingress kubernetes.LoadBalancerIngress
+			// Expectations
+			service *kubernetes.Service
+			address string
+			err     error
+		}{
+			// Ports are sorted and named, TLS and health check annotations on AWS
+			{
+				provider: "aws",
+				loadBalancer: &model.LoadBalancer{
+					Name:     "web",
+					Selector: map[string]string{"app": "web"},
+					Ports:    map[int]int{443: 8080, 80: 8080},
+					NamedPorts: []*model.LoadBalancerPort{
+						{Name: "dns", Port: 53, TargetPort: 5353, Protocol: "UDP"},
+					},
+					TLSCertificate:      "arn:aws:acm:cert",
+					HealthCheckPath:     "/healthz",
+					HealthCheckInterval: 10,
+					SourceRanges:        []string{"10.0.0.0/8"},
+					Annotations: map[string]string{
+						"service.beta.kubernetes.io/aws-load-balancer-ssl-ports": "8443",
+					},
+				},
+				ingress: kubernetes.LoadBalancerIngress{Hostname: "elb.amazonaws.com"},
+				service: &kubernetes.Service{
+					Metadata: kubernetes.Metadata{
+						Name: "web",
+						Annotations: map[string]string{
+							"service.beta.kubernetes.io/aws-load-balancer-ssl-cert":             "arn:aws:acm:cert",
+							"service.beta.kubernetes.io/aws-load-balancer-ssl-ports":            "8443",
+							"service.beta.kubernetes.io/aws-load-balancer-healthcheck-path":     "/healthz",
+							"service.beta.kubernetes.io/aws-load-balancer-healthcheck-interval": "10",
+							"supergiant.io/managed-annotations":                                 "service.beta.kubernetes.io/aws-load-balancer-healthcheck-interval,service.beta.kubernetes.io/aws-load-balancer-healthcheck-path,service.beta.kubernetes.io/aws-load-balancer-ssl-cert,service.beta.kubernetes.io/aws-load-balancer-ssl-ports",
+						},
+					},
+					Spec: kubernetes.ServiceSpec{
+						Type:     "LoadBalancer",
+						Selector: map[string]string{"app": "web"},
+						Ports: []kubernetes.ServicePort{
+							{Name: "tcp-80", Port: 80, TargetPort: 8080, Protocol: "TCP"},
+							{Name: "tcp-443", Port: 443, TargetPort: 8080, Protocol: "TCP"},
+							{Name: "dns", Port: 53, TargetPort: 5353, Protocol: "UDP"},
+						},
+						LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+					},
+				},
+				address: "elb.amazonaws.com",
+			},
+
+			// Internal load balancer on GCE, addressed by IP
+			{
+				provider: "gce",
+				loadBalancer: &model.LoadBalancer{
+					Name:     "web",
+					Ports:    map[int]int{80: 8080},
+					Protocol: "UDP",
+					Internal: true,
+				},
+				ingress: kubernetes.LoadBalancerIngress{IP: "10.0.0.5"},
+				service: &kubernetes.Service{
+					Metadata: kubernetes.Metadata{
+						Name: "web",
+						Annotations: map[string]string{
+							"cloud.google.com/load-balancer-type": "Internal",
+							"supergiant.io/managed-annotations":   "cloud.google.com/load-balancer-type",
+						},
+					},
+					Spec: kubernetes.ServiceSpec{
+						Type: "LoadBalancer",
+						Ports: []kubernetes.ServicePort{
+							{Port: 80, TargetPort: 8080, Protocol: "UDP"},
+						},
+					},
+				},
+				address: "10.0.0.5",
+			},
+
+			// Internal load balancers are not supported on DigitalOcean
+			{
+				provider: "digitalocean",
+				loadBalancer: &model.LoadBalancer{
+					Name:     "web",
+					Ports:    map[int]int{80: 8080},
+					Internal: true,
+				},
+				err: errors.New("Internal LoadBalancers are not supported on digitalocean"),
+			},
+
+			// TLS termination is not supported on GCE
+			{
+				provider: "gce",
+				loadBalancer: &model.LoadBalancer{
+					Name:           "web",
+					Ports:          map[int]int{443: 8080},
+					TLSCertificate: "cert",
+				},
+				err: errors.New("TLS termination of LoadBalancers is not supported on gce"),
+			},
+		}
+
+		for _, item := range table {
+			var created *kubernetes.Service
+			var address string
+
+			item.loadBalancer.Kube = &model.Kube{
+				CloudAccount: &model.CloudAccount{Provider: item.provider},
+			}
+
+			c := &core.Core{
+				Log: logrus.New(),
+				DB: &fake_core.DB{
+					ModelFn: func(value interface{}) core.DBInterface {
+						return &fake_core.DB{
+							UpdateFn: func(attrs ...interface{}) error {
+								address = attrs[1].(string)
+								return nil
+							},
+						}
+					},
+				},
+				K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+					return &fake_core.KubernetesClient{
+						CreateResourceFn: func(apiVersion, kind, namespace string, in, out interface{}) error {
+							created = in.(*kubernetes.Service)
+							copied := *created
+							copied.Status.LoadBalancer.Ingress = []kubernetes.LoadBalancerIngress{item.ingress}
+							*out.(*kubernetes.Service) = copied
+							return nil
+						},
+						GetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
+							out.(*kubernetes.Service).Status.LoadBalancer.Ingress = []kubernetes.LoadBalancerIngress{item.ingress}
+							return nil
+						},
+					}
+				},
+			}
+
+			provider := &kubernetesprovider.Provider{Core: c}
+			err := provider.CreateLoadBalancer(item.loadBalancer, nil)
+
+			So(err, ShouldResemble, item.err)
+			if item.err == nil {
+				So(created.Metadata, ShouldResemble, item.service.Metadata)
+				So(created.Spec, ShouldResemble, item.service.Spec)
+				So(address, ShouldEqual, item.address)
+			}
+		}
+	})
+}
+
+func TestKubernetesProviderUpdateLoadBalancer(t *testing.T) {
+	Convey("Kubernetes Provider UpdateLoadBalancer patches the existing Service", t, func() {
+		table := []struct {
+			// Input
+			loadBalancer *model.LoadBalancer
+			// Mocks
+			existing *kubernetes.Service
+			// Expectations
+			patch string
+		}{
+			// Node ports are kept, removed annotations, selectors and source ranges are nulled
+			{
+				loadBalancer: &model.LoadBalancer{
+					Name:     "web",
+					Selector: map[string]string{"app": "web"},
+					Ports:    map[int]int{80: 8081},
+				},
+				existing: &kubernetes.Service{
+					Metadata: kubernetes.Metadata{
+						Name: "web",
+						Annotations: map[string]string{
+							"service.beta.kubernetes.io/aws-load-balancer-internal": "true",
+							"supergiant.io/managed-annotations":                     "service.beta.kubernetes.io/aws-load-balancer-internal",
+							"set-by-someone-else":                                   "true",
+						},
+					},
+					Spec: kubernetes.ServiceSpec{
+						Selector: map[string]string{"app": "web", "tier": "front"},
+						Ports: []kubernetes.ServicePort{
+							{Port: 80, TargetPort: 8080, NodePort: 30080},
+						},
+						LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+					},
+				},
+				patch: `{"metadata":{"annotations":{"service.beta.kubernetes.io/aws-load-balancer-internal":null,"supergiant.io/managed-annotations":""}},"spec":{"loadBalancerSourceRanges":null,"ports":[{"name":"","port":80,"protocol":"TCP","nodePort":30080,"targetPort":8081}],"selector":{"app":"web","tier":null}}}`,
+			},
+		}
+
+		for _, item := range table {
+			var patch []byte
+
+			item.loadBalancer.Kube = &model.Kube{
+				CloudAccount: &model.CloudAccount{Provider: "aws"},
+			}
+
+			c := &core.Core{
+				Log: logrus.New(),
+				DB:  new(fake_core.DB),
+				K8S: func(kube *model.Kube) kubernetes.ClientInterface {
+					return &fake_core.KubernetesClient{
+						GetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
+							*out.(*kubernetes.Service) = *item.existing
+							return nil
+						},
+						UpdateResourceFn: func(apiVersion, kind, namespace, name string, in interface{}, out interface{}) error {
+							patch, _ = json.Marshal(in)
+							return nil
+						},
+					}
+				},
+			}
+
+			provider := &kubernetesprovider.Provider{Core: c}
+			err := provider.UpdateLoadBalancer(item.loadBalancer, nil)
+
+			So(err, ShouldBeNil)
+			So(string(patch), ShouldEqual, item.patch)
+		}
+	})
+}
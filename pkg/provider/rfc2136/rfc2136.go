@@ -0,0 +1,286 @@
+// Package rfc2136 manages the records of DNSZones through dynamic updates
+// (RFC 2136) to their primary name server, signed with TSIG (RFC 2845).
+package rfc2136
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+const (
+	opcodeUpdate = 5
+
+	classIN  = 1
+	classANY = 255
+
+	typeA     = 1
+	typeCNAME = 5
+	typeSOA   = 6
+	typeTXT   = 16
+	typeAAAA  = 28
+	typeTSIG  = 250
+
+	tsigFudge = 300
+)
+
+var recordTypes = map[string]uint16{
+	"A":     typeA,
+	"CNAME": typeCNAME,
+	"TXT":   typeTXT,
+	"AAAA":  typeAAAA,
+}
+
+var tsigAlgorithms = map[string]struct {
+	name string
+	hash func() hash.Hash
+}{
+	"hmac-md5":    {"hmac-md5.sig-alg.reg.int.", md5.New},
+	"hmac-sha1":   {"hmac-sha1.", sha1.New},
+	"hmac-sha256": {"hmac-sha256.", sha256.New},
+	"hmac-sha512": {"hmac-sha512.", sha512.New},
+}
+
+var rcodes = map[int]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// Provider sends the updates to the RFC2136Server of each DNSZone.
+type Provider struct {
+	// Timeout of each update, 30 seconds if not set.
+	Timeout time.Duration
+}
+
+// UpsertRecord implements core.DNSProvider, replacing the RRset of the name and
+// type in a single update.
+func (p *Provider) UpsertRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	rdata, err := encodeRData(record)
+	if err != nil {
+		return err
+	}
+	msg := newUpdate(zone)
+	msg.deleteRRset(record)
+	msg.addRR(record, rdata)
+	return p.send(zone, msg)
+}
+
+// DeleteRecord implements core.DNSProvider.
+func (p *Provider) DeleteRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	if _, ok := recordTypes[record.Type]; !ok {
+		return fmt.Errorf("Unsupported record type %s", record.Type)
+	}
+	msg := newUpdate(zone)
+	msg.deleteRRset(record)
+	return p.send(zone, msg)
+}
+
+//------------------------------------------------------------------------------
+
+// update is an UPDATE message being built.
+type update struct {
+	id      uint16
+	zone    string
+	updates [][]byte
+}
+
+func newUpdate(zone *model.DNSZone) *update {
+	var id [2]byte
+	rand.Read(id[:])
+	return &update{id: binary.BigEndian.Uint16(id[:]), zone: zone.Name}
+}
+
+// deleteRRset adds the deletion of all records of the name and type.
+func (u *update) deleteRRset(record *core.DNSRecord) {
+	u.updates = append(u.updates, encodeRR(record.Name, recordTypes[record.Type], classANY, 0, nil))
+}
+
+func (u *update) addRR(record *core.DNSRecord, rdata []byte) {
+	u.updates = append(u.updates, encodeRR(record.Name, recordTypes[record.Type], classIN, uint32(record.TTL), rdata))
+}
+
+// bytes returns the message, with arcount additional records to follow.
+func (u *update) bytes(arcount uint16) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[0:], u.id)
+	binary.BigEndian.PutUint16(msg[2:], opcodeUpdate<<11)
+	binary.BigEndian.PutUint16(msg[4:], 1) // ZOCOUNT
+	binary.BigEndian.PutUint16(msg[8:], uint16(len(u.updates)))
+	binary.BigEndian.PutUint16(msg[10:], arcount)
+
+	msg = append(msg, encodeName(u.zone)...)
+	msg = appendUint16(msg, typeSOA)
+	msg = appendUint16(msg, classIN)
+	for _, rr := range u.updates {
+		msg = append(msg, rr...)
+	}
+	return msg
+}
+
+// sign returns the message with a TSIG record appended.
+func (u *update) sign(keyName string, algorithm string, secret string, now time.Time) ([]byte, error) {
+	alg, ok := tsigAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported TSIG algorithm %s", algorithm)
+	}
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode TSIG secret: %s", err)
+	}
+
+	timeSigned := make([]byte, 6)
+	signed := uint64(now.Unix())
+	binary.BigEndian.PutUint16(timeSigned[0:], uint16(signed>>32))
+	binary.BigEndian.PutUint32(timeSigned[2:], uint32(signed))
+
+	// The MAC covers the unsigned message and the TSIG variables (RFC 2845 3.4)
+	vars := encodeName(keyName)
+	vars = appendUint16(vars, classANY)
+	vars = append(vars, 0, 0, 0, 0) // TTL
+	vars = append(vars, encodeName(alg.name)...)
+	vars = append(vars, timeSigned...)
+	vars = appendUint16(vars, tsigFudge)
+	vars = append(vars, 0, 0, 0, 0) // Error, Other Len
+
+	mac := hmac.New(alg.hash, key)
+	mac.Write(u.bytes(0))
+	mac.Write(vars)
+	sum := mac.Sum(nil)
+
+	rdata := encodeName(alg.name)
+	rdata = append(rdata, timeSigned...)
+	rdata = appendUint16(rdata, tsigFudge)
+	rdata = appendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = appendUint16(rdata, u.id)
+	rdata = append(rdata, 0, 0, 0, 0) // Error, Other Len
+
+	return append(u.bytes(1), encodeRR(keyName, typeTSIG, classANY, 0, rdata)...), nil
+}
+
+// send sends the update over TCP, and checks the response code.
+func (p *Provider) send(zone *model.DNSZone, u *update) error {
+	msg := u.bytes(0)
+	if zone.RFC2136TSIGKeyName != "" {
+		var err error
+		if msg, err = u.sign(zone.RFC2136TSIGKeyName, zone.RFC2136TSIGAlgorithm, zone.RFC2136TSIGSecret, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", zone.RFC2136Server, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// Messages over TCP are prefixed with their length
+	if _, err := conn.Write(append(appendUint16(nil, uint16(len(msg))), msg...)); err != nil {
+		return err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp[0:]) != u.id {
+		return fmt.Errorf("Invalid response to update of %s from %s", zone.Name, zone.RFC2136Server)
+	}
+
+	if rcode := int(resp[3] & 0x0f); rcode != 0 {
+		name, ok := rcodes[rcode]
+		if !ok {
+			name = fmt.Sprintf("RCODE %d", rcode)
+		}
+		return fmt.Errorf("Update of %s refused by %s: %s", zone.Name, zone.RFC2136Server, name)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func encodeRR(name string, rrType uint16, class uint16, ttl uint32, rdata []byte) []byte {
+	rr := encodeName(name)
+	rr = appendUint16(rr, rrType)
+	rr = appendUint16(rr, class)
+	rr = append(rr, byte(ttl>>24), byte(ttl>>16), byte(ttl>>8), byte(ttl))
+	rr = appendUint16(rr, uint16(len(rdata)))
+	return append(rr, rdata...)
+}
+
+func encodeRData(record *core.DNSRecord) ([]byte, error) {
+	switch record.Type {
+	case "A", "AAAA":
+		ip := net.ParseIP(record.Value)
+		if ip == nil {
+			return nil, fmt.Errorf("Invalid IP address %s", record.Value)
+		}
+		if record.Type == "A" {
+			if ip = ip.To4(); ip == nil {
+				return nil, fmt.Errorf("%s is not an IPv4 address", record.Value)
+			}
+			return ip, nil
+		}
+		return ip.To16(), nil
+	case "CNAME":
+		return encodeName(record.Value), nil
+	case "TXT":
+		// Character strings are at most 255 bytes
+		var rdata []byte
+		value := record.Value
+		for len(value) > 255 {
+			rdata = append(append(rdata, 255), value[:255]...)
+			value = value[255:]
+		}
+		return append(append(rdata, byte(len(value))), value...), nil
+	default:
+		return nil, fmt.Errorf("Unsupported record type %s", record.Type)
+	}
+}
+
+// encodeName encodes the domain name in lowercase (the canonical form TSIG
+// requires), without compression.
+func encodeName(name string) []byte {
+	var encoded []byte
+	for _, label := range strings.Split(strings.TrimSuffix(strings.ToLower(name), "."), ".") {
+		if label == "" {
+			continue
+		}
+		encoded = append(append(encoded, byte(len(label))), label...)
+	}
+	return append(encoded, 0)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
@@ -0,0 +1,169 @@
+package rfc2136
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeServer accepts a single update, and responds with the rcode.
+func fakeServer(rcode byte) (addr string, received chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	received = make(chan []byte, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var length [2]byte
+		io.ReadFull(conn, length[:])
+		msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+		io.ReadFull(conn, msg)
+		received <- msg
+
+		resp := append([]byte{}, msg[:12]...)
+		resp[2] |= 0x80 // QR
+		resp[3] = rcode
+		conn.Write(append(appendUint16(nil, uint16(len(resp))), resp...))
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestProviderUpsertRecord(t *testing.T) {
+	Convey("RFC2136 Provider UpsertRecord works correctly", t, func() {
+		table := []struct {
+			// Input
+			zone   *model.DNSZone
+			record *core.DNSRecord
+			// Mocks
+			rcode byte
+			// Expectations
+			updates []byte
+			signed  bool
+			err     error
+		}{
+			// Unsigned A record, replacing the RRset
+			{
+				zone:   &model.DNSZone{Name: "example.com"},
+				record: &core.DNSRecord{Name: "www.example.com", Type: "A", Value: "10.0.0.1", TTL: 300},
+				updates: concat(
+					[]byte("\x03www\x07example\x03com\x00\x00\x01\x00\xff\x00\x00\x00\x00\x00\x00"),
+					[]byte("\x03www\x07example\x03com\x00\x00\x01\x00\x01\x00\x00\x01\x2c\x00\x04\x0a\x00\x00\x01"),
+				),
+			},
+			// TSIG signed TXT record
+			{
+				zone: &model.DNSZone{
+					Name:                 "example.com",
+					RFC2136TSIGKeyName:   "sg-key",
+					RFC2136TSIGAlgorithm: "hmac-sha256",
+					RFC2136TSIGSecret:    "c2VjcmV0",
+				},
+				record: &core.DNSRecord{Name: "_acme-challenge.example.com", Type: "TXT", Value: "abc", TTL: 60},
+				updates: concat(
+					[]byte("\x0f_acme-challenge\x07example\x03com\x00\x00\x10\x00\xff\x00\x00\x00\x00\x00\x00"),
+					[]byte("\x0f_acme-challenge\x07example\x03com\x00\x00\x10\x00\x01\x00\x00\x00\x3c\x00\x04\x03abc"),
+				),
+				signed: true,
+			},
+			// Refused by the server
+			{
+				zone:   &model.DNSZone{Name: "example.com"},
+				record: &core.DNSRecord{Name: "www.example.com", Type: "CNAME", Value: "elb.amazonaws.com", TTL: 300},
+				rcode:  5,
+				updates: concat(
+					[]byte("\x03www\x07example\x03com\x00\x00\x05\x00\xff\x00\x00\x00\x00\x00\x00"),
+					[]byte("\x03www\x07example\x03com\x00\x00\x05\x00\x01\x00\x00\x01\x2c\x00\x13\x03elb\x09amazonaws\x03com\x00"),
+				),
+				err: errors.New("Update of example.com refused by {addr}: REFUSED"),
+			},
+		}
+
+		for _, item := range table {
+			addr, received := fakeServer(item.rcode)
+			item.zone.RFC2136Server = addr
+
+			err := (&Provider{Timeout: 5 * time.Second}).UpsertRecord(item.zone, item.record)
+
+			if item.err == nil {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, strings.Replace(item.err.Error(), "{addr}", addr, 1))
+			}
+
+			msg := <-received
+			So(msg[2]>>3, ShouldEqual, opcodeUpdate)
+			So(binary.BigEndian.Uint16(msg[4:]), ShouldEqual, 1)
+			So(binary.BigEndian.Uint16(msg[8:]), ShouldEqual, 2)
+
+			zone := []byte("\x07example\x03com\x00\x00\x06\x00\x01")
+			So(msg[12:12+len(zone)], ShouldResemble, zone)
+			body := msg[12+len(zone):]
+
+			if !item.signed {
+				So(binary.BigEndian.Uint16(msg[10:]), ShouldEqual, 0)
+				So(body, ShouldResemble, item.updates)
+				continue
+			}
+
+			So(binary.BigEndian.Uint16(msg[10:]), ShouldEqual, 1)
+			So(body[:len(item.updates)], ShouldResemble, item.updates)
+
+			// Recompute the MAC over the unsigned message and TSIG variables
+			tsig := body[len(item.updates):]
+			owner := []byte("\x06sg-key\x00")
+			algorithm := []byte("\x0bhmac-sha256\x00")
+			So(tsig[:len(owner)], ShouldResemble, owner)
+			So(binary.BigEndian.Uint16(tsig[len(owner):]), ShouldEqual, typeTSIG)
+			rdata := tsig[len(owner)+10:]
+			So(rdata[:len(algorithm)], ShouldResemble, algorithm)
+			timeAndFudge := rdata[len(algorithm) : len(algorithm)+8]
+			mac := rdata[len(algorithm)+10 : len(algorithm)+10+sha256.Size]
+
+			unsigned := append([]byte{}, msg[:len(msg)-len(tsig)]...)
+			binary.BigEndian.PutUint16(unsigned[10:], 0)
+			expected := hmac.New(sha256.New, []byte("secret"))
+			expected.Write(unsigned)
+			expected.Write(concat(owner, []byte("\x00\xff\x00\x00\x00\x00"), algorithm, timeAndFudge, []byte("\x00\x00\x00\x00")))
+			So(mac, ShouldResemble, expected.Sum(nil))
+		}
+	})
+}
+
+func TestProviderDeleteRecord(t *testing.T) {
+	Convey("RFC2136 Provider DeleteRecord works correctly", t, func() {
+		addr, received := fakeServer(0)
+		zone := &model.DNSZone{Name: "example.com", RFC2136Server: addr}
+
+		err := (&Provider{Timeout: 5 * time.Second}).DeleteRecord(zone, &core.DNSRecord{Name: "www.example.com", Type: "AAAA"})
+
+		So(err, ShouldBeNil)
+		msg := <-received
+		So(binary.BigEndian.Uint16(msg[8:]), ShouldEqual, 1)
+		So(msg[len(msg)-27:], ShouldResemble, []byte("\x03www\x07example\x03com\x00\x00\x1c\x00\xff\x00\x00\x00\x00\x00\x00"))
+	})
+}
+
+func concat(parts ...[]byte) (b []byte) {
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
@@ -121,7 +121,8 @@ func EditKubeResource(sg *client.Client, w http.ResponseWriter, r *http.Request)
 		"title":      "Kube Resources",
 		"formAction": fmt.Sprintf("/ui/kube_resources/%d", *id),
 		"model": map[string]interface{}{
-			"resource": item.Resource,
+			"resource":         item.Resource,
+			"resource_version": item.ResourceVersion,
 		},
 	})
 }
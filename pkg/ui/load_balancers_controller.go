@@ -110,8 +110,9 @@ func EditLoadBalancer(sg *client.Client, w http.ResponseWriter, r *http.Request)
 		"title":      "Load Balancers",
 		"formAction": fmt.Sprintf("/ui/load_balancers/%d", *id),
 		"model": map[string]interface{}{
-			"selector": item.Selector,
-			"ports":    item.Ports,
+			"selector":         item.Selector,
+			"ports":            item.Ports,
+			"resource_version": item.ResourceVersion,
 		},
 	})
 }
@@ -101,8 +101,9 @@ func EditUser(sg *client.Client, w http.ResponseWriter, r *http.Request) error {
 		"title":      "Users",
 		"formAction": fmt.Sprintf("/ui/users/%d", *id),
 		"model": map[string]interface{}{
-			"password": "",
-			"role":     item.Role,
+			"password":         "",
+			"role":             item.Role,
+			"resource_version": item.ResourceVersion,
 		},
 	})
 }
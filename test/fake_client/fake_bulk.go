@@ -0,0 +1,14 @@
+package fake_client
+
+import "github.com/supergiant/supergiant/pkg/model"
+
+type Bulk struct {
+	RunFn func(*model.Bulk) error
+}
+
+func (c *Bulk) Run(m *model.Bulk) error {
+	if c.RunFn == nil {
+		return nil
+	}
+	return c.RunFn(m)
+}
@@ -2,4 +2,12 @@ package fake_client
 
 type CloudAccounts struct {
 	Collection
+	SchemaFn func(*map[string]interface{}) error
+}
+
+func (c *CloudAccounts) Schema(schema *map[string]interface{}) error {
+	if c.SchemaFn == nil {
+		return nil
+	}
+	return c.SchemaFn(schema)
 }
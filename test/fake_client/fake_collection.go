@@ -1,6 +1,9 @@
 package fake_client
 
-import "github.com/supergiant/supergiant/pkg/model"
+import (
+	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type Collection struct {
 	ListFn            func(model.List) error
@@ -18,6 +21,11 @@ func (c *Collection) List(list model.List) error {
 	return c.ListFn(list)
 }
 
+// Iterate walks the pages ListFn lists.
+func (c *Collection) Iterate(list model.List) *client.ListIterator {
+	return client.NewListIterator(c, list)
+}
+
 func (c *Collection) Create(m model.Model) error {
 	if c.CreateFn == nil {
 		return nil
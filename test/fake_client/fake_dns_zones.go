@@ -0,0 +1,5 @@
+package fake_client
+
+type DNSZones struct {
+	Collection
+}
@@ -1,5 +1,23 @@
 package fake_client
 
+import "github.com/supergiant/supergiant/pkg/model"
+
 type HelmCharts struct {
 	Collection
+	UploadFn func(*model.HelmChartUpload, *model.HelmChart) error
+	SearchFn func(*model.HelmChartSearch, *model.HelmChartList) error
+}
+
+func (c *HelmCharts) Upload(upload *model.HelmChartUpload, m *model.HelmChart) error {
+	if c.UploadFn == nil {
+		return nil
+	}
+	return c.UploadFn(upload, m)
+}
+
+func (c *HelmCharts) Search(search *model.HelmChartSearch, list *model.HelmChartList) error {
+	if c.SearchFn == nil {
+		return nil
+	}
+	return c.SearchFn(search, list)
 }
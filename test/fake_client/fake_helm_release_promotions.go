@@ -0,0 +1,23 @@
+package fake_client
+
+import "github.com/supergiant/supergiant/pkg/model"
+
+type HelmReleasePromotions struct {
+	Collection
+	ApproveFn func(*int64, *model.HelmReleasePromotion) error
+	RejectFn  func(*int64, *model.HelmReleasePromotion) error
+}
+
+func (c *HelmReleasePromotions) Approve(id *int64, m *model.HelmReleasePromotion) error {
+	if c.ApproveFn == nil {
+		return nil
+	}
+	return c.ApproveFn(id, m)
+}
+
+func (c *HelmReleasePromotions) Reject(id *int64, m *model.HelmReleasePromotion) error {
+	if c.RejectFn == nil {
+		return nil
+	}
+	return c.RejectFn(id, m)
+}
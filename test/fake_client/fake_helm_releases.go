@@ -1,5 +1,39 @@
 package fake_client
 
+import "github.com/supergiant/supergiant/pkg/model"
+
 type HelmReleases struct {
 	Collection
+	HistoryFn  func(*int64, *model.HelmReleaseHistory) error
+	RollbackFn func(*int64, *model.HelmReleaseRollback, *model.HelmRelease) error
+	PromoteFn  func(*int64, *model.HelmReleasePromotion) error
+	DriftFn    func(*int64, *model.HelmReleaseDrift) error
+}
+
+func (c *HelmReleases) History(id *int64, history *model.HelmReleaseHistory) error {
+	if c.HistoryFn == nil {
+		return nil
+	}
+	return c.HistoryFn(id, history)
+}
+
+func (c *HelmReleases) Rollback(id *int64, rollback *model.HelmReleaseRollback, m *model.HelmRelease) error {
+	if c.RollbackFn == nil {
+		return nil
+	}
+	return c.RollbackFn(id, rollback, m)
+}
+
+func (c *HelmReleases) Promote(id *int64, promotion *model.HelmReleasePromotion) error {
+	if c.PromoteFn == nil {
+		return nil
+	}
+	return c.PromoteFn(id, promotion)
+}
+
+func (c *HelmReleases) Drift(id *int64, drift *model.HelmReleaseDrift) error {
+	if c.DriftFn == nil {
+		return nil
+	}
+	return c.DriftFn(id, drift)
 }
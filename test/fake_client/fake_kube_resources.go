@@ -1,11 +1,18 @@
 package fake_client
 
-import "github.com/supergiant/supergiant/pkg/model"
+import (
+	"io"
+
+	"github.com/gorilla/websocket"
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type KubeResources struct {
 	Collection
 	StartFn func(*int64, *model.KubeResource) error
 	StopFn  func(*int64, *model.KubeResource) error
+	LogsFn  func(*int64, *model.PodLogOptions, io.Writer) error
+	ExecFn  func(*int64, *model.PodExecOptions) (*websocket.Conn, error)
 }
 
 func (c *KubeResources) Start(id *int64, m *model.KubeResource) error {
@@ -21,3 +28,17 @@ func (c *KubeResources) Stop(id *int64, m *model.KubeResource) error {
 	}
 	return c.StopFn(id, m)
 }
+
+func (c *KubeResources) Logs(id *int64, opts *model.PodLogOptions, w io.Writer) error {
+	if c.LogsFn == nil {
+		return nil
+	}
+	return c.LogsFn(id, opts, w)
+}
+
+func (c *KubeResources) Exec(id *int64, opts *model.PodExecOptions) (*websocket.Conn, error) {
+	if c.ExecFn == nil {
+		return nil, nil
+	}
+	return c.ExecFn(id, opts)
+}
@@ -4,7 +4,9 @@ import "github.com/supergiant/supergiant/pkg/model"
 
 type Kubes struct {
 	Collection
-	ProvisionFn func(*int64, *model.Kube) error
+	ProvisionFn  func(*int64, *model.Kube) error
+	CostsFn      func(*int64, string, *model.KubeCosts) error
+	ChargebackFn func(string, *model.Chargeback) error
 }
 
 func (c *Kubes) Provision(id *int64, m *model.Kube) error {
@@ -13,3 +15,17 @@ func (c *Kubes) Provision(id *int64, m *model.Kube) error {
 	}
 	return c.ProvisionFn(id, m)
 }
+
+func (c *Kubes) Costs(id *int64, month string, m *model.KubeCosts) error {
+	if c.CostsFn == nil {
+		return nil
+	}
+	return c.CostsFn(id, month, m)
+}
+
+func (c *Kubes) Chargeback(month string, m *model.Chargeback) error {
+	if c.ChargebackFn == nil {
+		return nil
+	}
+	return c.ChargebackFn(month, m)
+}
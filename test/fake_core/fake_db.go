@@ -6,18 +6,23 @@ import (
 )
 
 type DB struct {
-	CreateFn  func(model.Model) error
-	SaveFn    func(model.Model) error
-	FindFn    func(out interface{}, where ...interface{}) error
-	FirstFn   func(out interface{}, where ...interface{}) error
-	DeleteFn  func(m model.Model) error
-	PreloadFn func(column string, conditions ...interface{}) core.DBInterface
-	WhereFn   func(query interface{}, args ...interface{}) core.DBInterface
-	LimitFn   func(limit interface{}) core.DBInterface
-	OffsetFn  func(offset interface{}) core.DBInterface
-	ModelFn   func(value interface{}) core.DBInterface
-	UpdateFn  func(attrs ...interface{}) error
-	CountFn   func(interface{}) error
+	CreateFn      func(model.Model) error
+	ValidateFn    func(model.Model) error
+	SaveFn        func(model.Model) error
+	SaveVersionFn func(m model.Model, version int64) error
+	FindFn        func(out interface{}, where ...interface{}) error
+	FirstFn       func(out interface{}, where ...interface{}) error
+	DeleteFn      func(m model.Model) error
+	PreloadFn     func(column string, conditions ...interface{}) core.DBInterface
+	WhereFn       func(query interface{}, args ...interface{}) core.DBInterface
+	LimitFn       func(limit interface{}) core.DBInterface
+	OffsetFn      func(offset interface{}) core.DBInterface
+	OrderFn       func(value interface{}) core.DBInterface
+	SelectFn      func(query interface{}, args ...interface{}) core.DBInterface
+	ModelFn       func(value interface{}) core.DBInterface
+	UpdateFn      func(attrs ...interface{}) error
+	CountFn       func(interface{}) error
+	TransactionFn func(fn func(tx core.DBInterface) error) error
 }
 
 func (db *DB) Create(m model.Model) error {
@@ -27,6 +32,13 @@ func (db *DB) Create(m model.Model) error {
 	return db.CreateFn(m)
 }
 
+func (db *DB) Validate(m model.Model) error {
+	if db.ValidateFn == nil {
+		return nil
+	}
+	return db.ValidateFn(m)
+}
+
 func (db *DB) Save(m model.Model) error {
 	if db.SaveFn == nil {
 		return nil
@@ -34,6 +46,13 @@ func (db *DB) Save(m model.Model) error {
 	return db.SaveFn(m)
 }
 
+func (db *DB) SaveVersion(m model.Model, version int64) error {
+	if db.SaveVersionFn == nil {
+		return nil
+	}
+	return db.SaveVersionFn(m, version)
+}
+
 func (db *DB) Find(out interface{}, where ...interface{}) error {
 	if db.FindFn == nil {
 		return nil
@@ -83,6 +102,20 @@ func (db *DB) Offset(offset interface{}) core.DBInterface {
 	return db.OffsetFn(offset)
 }
 
+func (db *DB) Order(value interface{}) core.DBInterface {
+	if db.OrderFn == nil {
+		return db // return db instead of nil, since these are chainable
+	}
+	return db.OrderFn(value)
+}
+
+func (db *DB) Select(query interface{}, args ...interface{}) core.DBInterface {
+	if db.SelectFn == nil {
+		return db // return db instead of nil, since these are chainable
+	}
+	return db.SelectFn(query, args...)
+}
+
 func (db *DB) Model(value interface{}) core.DBInterface {
 	if db.ModelFn == nil {
 		return db // return db instead of nil, since these are chainable
@@ -103,3 +136,10 @@ func (db *DB) Count(value interface{}) error {
 	}
 	return db.CountFn(value)
 }
+
+func (db *DB) Transaction(fn func(tx core.DBInterface) error) error {
+	if db.TransactionFn == nil {
+		return fn(db) // run it on the same DB, as if it's committed
+	}
+	return db.TransactionFn(fn)
+}
@@ -0,0 +1,25 @@
+package fake_core
+
+import (
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+)
+
+type DNSProvider struct {
+	UpsertRecordFn func(*model.DNSZone, *core.DNSRecord) error
+	DeleteRecordFn func(*model.DNSZone, *core.DNSRecord) error
+}
+
+func (p *DNSProvider) UpsertRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	if p.UpsertRecordFn == nil {
+		return nil
+	}
+	return p.UpsertRecordFn(zone, record)
+}
+
+func (p *DNSProvider) DeleteRecord(zone *model.DNSZone, record *core.DNSRecord) error {
+	if p.DeleteRecordFn == nil {
+		return nil
+	}
+	return p.DeleteRecordFn(zone, record)
+}
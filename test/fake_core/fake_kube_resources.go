@@ -1,6 +1,9 @@
 package fake_core
 
 import (
+	"io"
+
+	"github.com/gorilla/websocket"
 	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
 )
@@ -15,6 +18,8 @@ type KubeResources struct {
 	StartFn           func(*int64, *model.KubeResource) core.ActionInterface
 	StopFn            func(*int64, *model.KubeResource) core.ActionInterface
 	RefreshFn         func(*model.KubeResource) error
+	LogsFn            func(*int64, *model.KubeResource, *model.PodLogOptions) (io.ReadCloser, error)
+	ExecFn            func(*int64, *model.KubeResource, *model.PodExecOptions) (*websocket.Conn, error)
 }
 
 func (c *KubeResources) Populate() error {
@@ -52,3 +57,11 @@ func (c *KubeResources) Stop(id *int64, m *model.KubeResource) core.ActionInterf
 func (c *KubeResources) Refresh(m *model.KubeResource) error {
 	return c.RefreshFn(m)
 }
+
+func (c *KubeResources) Logs(id *int64, m *model.KubeResource, opts *model.PodLogOptions) (io.ReadCloser, error) {
+	return c.LogsFn(id, m, opts)
+}
+
+func (c *KubeResources) Exec(id *int64, m *model.KubeResource, opts *model.PodExecOptions) (*websocket.Conn, error) {
+	return c.ExecFn(id, m, opts)
+}
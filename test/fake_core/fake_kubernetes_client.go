@@ -1,6 +1,14 @@
 package fake_core
 
-import "github.com/supergiant/supergiant/pkg/kubernetes"
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+)
 
 type KubernetesClient struct {
 	EnsureNamespaceFn                func(name string) error
@@ -14,10 +22,14 @@ type KubernetesClient struct {
 	ListPodsFn                       func(query string) ([]*kubernetes.Pod, error)
 	ListServicesFn                   func(query string) ([]*kubernetes.Service, error)
 	ListPersistentVolumesFn          func(query string) ([]*kubernetes.PersistentVolume, error)
+	ListConfigMapsFn                 func(query string) ([]*kubernetes.ConfigMap, error)
 	ListNodeHeapsterStatsFn          func(node string) ([]string, error)
 	ListPodHeapsterCPUUsageMetricsFn func(namespace, name string) ([]*kubernetes.HeapsterMetric, error)
 	ListPodHeapsterRAMUsageMetricsFn func(namespace, name string) ([]*kubernetes.HeapsterMetric, error)
 	GetPodLogFn                      func(namespace, name string) (string, error)
+	StreamPodLogFn                   func(namespace, name string, opts *model.PodLogOptions) (io.ReadCloser, error)
+	ExecPodFn                        func(namespace, name string, opts *model.PodExecOptions) (*websocket.Conn, error)
+	RunPodCommandFn                  func(namespace, name string, cmd *kubernetes.PodCommand) error
 	GetKubeHeapsterStatsfn           func(metricPath string) (kubernetes.HeapsterMetrics, error)
 	GetNodeHeapsterStatsfn           func(node string, metricPath string) (kubernetes.HeapsterMetrics, error)
 	ListKubeHeapsterStatsfn          func() ([]string, error)
@@ -100,6 +112,13 @@ func (k *KubernetesClient) ListPersistentVolumes(query string) ([]*kubernetes.Pe
 	return k.ListPersistentVolumesFn(query)
 }
 
+func (k *KubernetesClient) ListConfigMaps(query string) ([]*kubernetes.ConfigMap, error) {
+	if k.ListConfigMapsFn == nil {
+		return nil, nil
+	}
+	return k.ListConfigMapsFn(query)
+}
+
 func (k *KubernetesClient) ListNodeHeapsterStats(node string) ([]string, error) {
 	if k.ListNodeHeapsterStatsFn == nil {
 		return []string{}, nil
@@ -128,6 +147,27 @@ func (k *KubernetesClient) GetPodLog(namespace string, name string) (string, err
 	return k.GetPodLogFn(namespace, name)
 }
 
+func (k *KubernetesClient) StreamPodLog(namespace string, name string, opts *model.PodLogOptions) (io.ReadCloser, error) {
+	if k.StreamPodLogFn == nil {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return k.StreamPodLogFn(namespace, name, opts)
+}
+
+func (k *KubernetesClient) ExecPod(namespace string, name string, opts *model.PodExecOptions) (*websocket.Conn, error) {
+	if k.ExecPodFn == nil {
+		return nil, nil
+	}
+	return k.ExecPodFn(namespace, name, opts)
+}
+
+func (k *KubernetesClient) RunPodCommand(namespace string, name string, cmd *kubernetes.PodCommand) error {
+	if k.RunPodCommandFn == nil {
+		return nil
+	}
+	return k.RunPodCommandFn(namespace, name, cmd)
+}
+
 func (k *KubernetesClient) GetKubeHeapsterStats(metricPath string) (kubernetes.HeapsterMetrics, error) {
 	if k.GetKubeHeapsterStatsfn == nil {
 		return kubernetes.HeapsterMetrics{}, nil
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// missingID is of no resource
+var missingID int64 = 1000
+
+func TestBulk(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	Convey("Bulk works correctly", t, func() {
+		table := []struct {
+			// Input
+			atomic     bool
+			operations []*model.BulkOperation
+			// Expectations
+			statuses  []int
+			errors    []string
+			usernames []string
+		}{
+			// Operations run in order, and fail on their own
+			{
+				operations: []*model.BulkOperation{
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk1","password":"password"}`)},
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk 2","password":"password"}`)},
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk3","password":"password"}`)},
+					{Op: "update", Resource: "users", ID: requestor.ID, Item: json.RawMessage(`{"password":"password2"}`)},
+					{Op: "action", Resource: "users", ID: requestor.ID, Action: "regenerate_api_token"},
+					{Op: "delete", Resource: "users", ID: &missingID},
+				},
+				statuses:  []int{201, 422, 201, 202, 202, 404},
+				errors:    []string{"", "Validation failed: Username: regular expression mismatch", "", "", "", "record not found"},
+				usernames: []string{"bossman", "bulk1", "bulk3"},
+			},
+			// An atomic Bulk runs nothing if any operation is invalid
+			{
+				atomic: true,
+				operations: []*model.BulkOperation{
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk1","password":"password"}`)},
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk2","password":"short"}`)},
+					{Op: "update", Resource: "users", ID: requestor.ID, Item: json.RawMessage(`{"username":"changed","password":"password2"}`)},
+					{Op: "delete", Resource: "users", ID: &missingID},
+				},
+				statuses:  []int{424, 422, 424, 404},
+				errors:    []string{"Not run, since operation 1 is invalid", "Validation failed: Password: less than min", "Not run, since operation 1 is invalid", "record not found"},
+				usernames: []string{"bossman"},
+			},
+			// An atomic Bulk which is valid runs
+			{
+				atomic: true,
+				operations: []*model.BulkOperation{
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk1","password":"password"}`)},
+					{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk2","password":"password"}`)},
+				},
+				statuses:  []int{201, 201},
+				errors:    []string{"", ""},
+				usernames: []string{"bossman", "bulk1", "bulk2"},
+			},
+		}
+
+		for _, item := range table {
+			// Updates are made from the resource version of the requestor as
+			// of the rows before
+			for _, op := range item.operations {
+				if op.Op == "update" {
+					op.Item = json.RawMessage(strings.Replace(string(op.Item), "{", fmt.Sprintf(`{"resource_version":%d,`, requestor.ResourceVersion), 1))
+				}
+			}
+			bulk := &model.Bulk{Atomic: item.atomic, Operations: item.operations}
+			err := sg.Bulk.Run(bulk)
+			So(err, ShouldBeNil)
+
+			var statuses []int
+			var errors []string
+			for _, result := range bulk.Results {
+				statuses = append(statuses, result.Status)
+				if result.Error != nil {
+					errors = append(errors, result.Error.Message)
+				} else {
+					errors = append(errors, "")
+				}
+			}
+			So(statuses, ShouldResemble, item.statuses)
+			So(errors, ShouldResemble, item.errors)
+
+			users := new(model.UserList)
+			srv.Core.DB.Order("username").Find(&users.Items)
+			var usernames []string
+			for _, user := range users.Items {
+				usernames = append(usernames, user.Username)
+			}
+			So(usernames, ShouldResemble, item.usernames)
+
+			srv.Core.DB.Where("username <> ?", "bossman").Delete(new(model.User))
+			// The admin regenerated their API token
+			srv.Core.DB.First(requestor, *requestor.ID)
+			sg = srv.Core.APIClient("token", requestor.APIToken)
+		}
+	})
+
+	Convey("Bulk responds with the item of each operation", t, func() {
+		bulk := &model.Bulk{Operations: []*model.BulkOperation{
+			{Op: "create", Resource: "users", Item: json.RawMessage(`{"username":"bulk1","password":"password"}`)},
+		}}
+		err := sg.Bulk.Run(bulk)
+
+		So(err, ShouldBeNil)
+		user := new(model.User)
+		So(json.Unmarshal(bulk.Results[0].Item, user), ShouldBeNil)
+		So(user.Username, ShouldEqual, "bulk1")
+		So(user.ID, ShouldNotBeNil)
+		So(bulk.Results[0].Action, ShouldBeNil)
+
+		srv.Core.DB.Where("username <> ?", "bossman").Delete(new(model.User))
+	})
+
+	Convey("Bulk responds with the handles of the Actions operations start", t, func() {
+		deleting := make(chan struct{})
+		srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+			return &fake_core.Provider{
+				DeleteKubeFn: func(_ *model.Kube, _ *core.Action) error {
+					<-deleting
+					return nil
+				},
+			}
+		}
+		kube := createKube(sg)
+		defer close(deleting)
+
+		bulk := &model.Bulk{Operations: []*model.BulkOperation{
+			{Op: "delete", Resource: "kubes", ID: kube.ID},
+		}}
+		err := sg.Bulk.Run(bulk)
+
+		So(err, ShouldBeNil)
+		So(bulk.Results[0].Status, ShouldEqual, 202)
+		So(bulk.Results[0].Action.Path, ShouldEqual, "/api/v1/kubes/"+strconv.FormatInt(*kube.ID, 10))
+		So(bulk.Results[0].Action.Status.Description, ShouldEqual, "deleting")
+	})
+
+	Convey("An atomic Bulk runs nothing if the user may not make any operation", t, func() {
+		user := createUser(srv.Core)
+		userSG := srv.Core.APIClient("token", user.APIToken)
+
+		bulk := &model.Bulk{Atomic: true, Operations: []*model.BulkOperation{
+			{Op: "create", Resource: "helm_chart_policies", Item: json.RawMessage(`{"name":"policy"}`)},
+			{Op: "action", Resource: "users", ID: user.ID, Action: "regenerate_api_token"},
+			{Op: "delete", Resource: "users", ID: requestor.ID},
+		}}
+		err := userSG.Bulk.Run(bulk)
+
+		So(err, ShouldBeNil)
+		forbidden := fmt.Sprintf("User %d cannot perform this operation", *user.ID)
+		So(bulk.Results[0].Error.Message, ShouldEqual, forbidden)
+		So(bulk.Results[0].Status, ShouldEqual, 403)
+		So(bulk.Results[1].Status, ShouldEqual, 424)
+		So(bulk.Results[2].Error.Message, ShouldEqual, forbidden)
+		So(bulk.Results[2].Status, ShouldEqual, 403)
+
+		reloaded := new(model.User)
+		srv.Core.DB.First(reloaded, *user.ID)
+		So(reloaded.APIToken, ShouldEqual, user.APIToken)
+		var policies []*model.HelmChartPolicy
+		srv.Core.DB.Find(&policies)
+		So(policies, ShouldBeEmpty)
+
+		srv.Core.DB.Where("username <> ?", "bossman").Delete(new(model.User))
+	})
+
+	Convey("Invalid Bulks respond 400", t, func() {
+		table := []struct {
+			operations []*model.BulkOperation
+			err        string
+		}{
+			{
+				operations: nil,
+				err:        "Invalid bulk request: no operations",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "launch", Resource: "kubes"}},
+				err:        "Invalid bulk request: operation 0: op must be create, update, delete or action, not 'launch'",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "create", Resource: "users"}, {Op: "create", Resource: "planets"}},
+				err:        "Invalid bulk request: operation 1: can't create planets",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "delete", Resource: "kubes"}},
+				err:        "Invalid bulk request: operation 0: id required to delete kubes",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "action", Resource: "kube_resources", ID: &missingID, Action: "launch"}},
+				err:        "Invalid bulk request: operation 0: kube_resources have no action 'launch'",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "action", Resource: "kubes", ID: &missingID, Action: "costs"}},
+				err:        "Invalid bulk request: operation 0: kubes have no action 'costs'",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "delete", Resource: "sessions", ID: &missingID}},
+				err:        "Invalid bulk request: operation 0: sessions can't be changed in bulk",
+			},
+			{
+				operations: []*model.BulkOperation{{Op: "create", Resource: "bulk"}},
+				err:        "Invalid bulk request: operation 0: bulk can't be changed in bulk",
+			},
+		}
+
+		for _, item := range table {
+			err := sg.Bulk.Run(&model.Bulk{Operations: item.operations})
+			So(err, ShouldResemble, &model.Error{Status: 400, Message: item.err})
+		}
+	})
+}
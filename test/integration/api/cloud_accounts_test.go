@@ -285,6 +285,7 @@ func TestCloudAccountsUpdate(t *testing.T) {
 
 			srv.Core.CloudAccounts.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.CloudAccounts.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
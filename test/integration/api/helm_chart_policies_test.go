@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHelmChartPoliciesCreate(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	Convey("HelmChartPolicies Create works correctly", t, func() {
+
+		table := []struct {
+			// Input
+			model *model.HelmChartPolicy
+			// Expectations
+			err *model.Error
+		}{
+			// A successful example
+			{
+				model: &model.HelmChartPolicy{
+					Effect:    "allow",
+					KubeName:  "prod-*",
+					RepoName:  "stable",
+					ChartName: "redis",
+					Versions:  ">= 1.2, < 2",
+				},
+			},
+
+			// Effect must be allow or deny
+			{
+				model: &model.HelmChartPolicy{Effect: "ignore"},
+				err:   &model.Error{Status: 422, Message: "Validation failed: Effect: regular expression mismatch"},
+			},
+
+			// Invalid pattern
+			{
+				model: &model.HelmChartPolicy{Effect: "deny", ChartName: "redis["},
+				err:   &model.Error{Status: 422, Message: "Validation failed: chart_name: invalid pattern 'redis['"},
+			},
+
+			// Invalid version constraint
+			{
+				model: &model.HelmChartPolicy{Effect: "deny", Versions: "newest"},
+				err:   &model.Error{Status: 422, Message: "Validation failed: versions: improper constraint: newest"},
+			},
+		}
+
+		for _, item := range table {
+
+			wipeAndInitialize(srv.Core)
+
+			requestor := createAdmin(srv.Core)
+			sg := srv.Core.APIClient("token", requestor.APIToken)
+
+			err := sg.HelmChartPolicies.Create(item.model)
+
+			if item.err == nil {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldResemble, item.err)
+			}
+		}
+
+		Convey("Only admins can create policies", func() {
+			requestor := createUser(srv.Core)
+			sg := srv.Core.APIClient("token", requestor.APIToken)
+
+			err := sg.HelmChartPolicies.Create(&model.HelmChartPolicy{Effect: "deny"})
+			So(err.(*model.Error).Status, ShouldEqual, 403)
+		})
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func TestHelmChartPoliciesEnforced(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+
+	srv.Core.HelmJobStartTimeout = time.Nanosecond
+	srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+		return &fake_core.KubernetesClient{
+			CreateResourceFn: func(apiVersion, kind, namespace string, in, out interface{}) error {
+				return errors.New("stop here")
+			},
+		}
+	}
+
+	allow := &model.HelmChartPolicy{Effect: "allow", KubeName: kube.Name, RepoName: "stable", Versions: ">= 0.2"}
+	deny := &model.HelmChartPolicy{Effect: "deny", ChartName: "redis", Versions: "0.3.0"}
+	// Policies of other Kubes don't apply
+	other := &model.HelmChartPolicy{Effect: "allow", KubeName: "other", ChartName: "mysql"}
+	for _, policy := range []*model.HelmChartPolicy{allow, deny, other} {
+		if err := sg.HelmChartPolicies.Create(policy); err != nil {
+			panic(err)
+		}
+	}
+
+	Convey("HelmChartPolicies are enforced on HelmReleases", t, func() {
+
+		table := []struct {
+			// Input
+			repoName     string
+			chartName    string
+			chartVersion string
+			// Expectations
+			err *model.Error
+		}{
+			{"stable", "redis", "0.2.0", nil},
+			{"stable", "mysql", "1.0.0", nil},
+			{"stable", "redis", "0.1.0", &model.Error{Status: 422, Message: "Validation failed: stable/redis 0.1.0 can't be installed on Kube test, no HelmChartPolicy allows it"}},
+			{"incubator", "redis", "0.2.0", &model.Error{Status: 422, Message: "Validation failed: incubator/redis 0.2.0 can't be installed on Kube test, no HelmChartPolicy allows it"}},
+			{"stable", "redis", "0.3.0", &model.Error{Status: 422, Message: fmt.Sprintf("Validation failed: stable/redis 0.3.0 can't be installed on Kube test, denied by HelmChartPolicy %d", *deny.ID)}},
+		}
+
+		for _, item := range table {
+			release := &model.HelmRelease{
+				KubeName:     kube.Name,
+				Name:         "test",
+				RepoName:     item.repoName,
+				ChartName:    item.chartName,
+				ChartVersion: item.chartVersion,
+			}
+			err := sg.HelmReleases.Create(release)
+
+			if item.err == nil {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldResemble, item.err)
+			}
+
+			// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
+			srv.Core.DB.Delete(&model.HelmRelease{})
+		}
+
+		Convey("including upgrades", func() {
+			release := &model.HelmRelease{
+				KubeName:     kube.Name,
+				Name:         "test",
+				RepoName:     "stable",
+				ChartName:    "redis",
+				ChartVersion: "0.2.0",
+			}
+			srv.Core.DB.Create(release)
+
+			update := &model.HelmRelease{ChartVersion: "0.3.0"}
+			update.ResourceVersion = release.ResourceVersion
+			err := sg.HelmReleases.Update(release.ID, update)
+			So(err, ShouldResemble, &model.Error{Status: 422, Message: fmt.Sprintf("Validation failed: stable/redis 0.3.0 can't be installed on Kube test, denied by HelmChartPolicy %d", *deny.ID)})
+
+			srv.Core.DB.Delete(&model.HelmRelease{})
+		})
+	})
+}
@@ -1,8 +1,15 @@
 package api
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/url"
+	"os"
 	"testing"
 
+	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -184,6 +191,248 @@ func TestHelmChartsCreate(t *testing.T) {
 	})
 }
 
+// testChartArchive packages a chart the way helm package does.
+func testChartArchive(chartYAML string, valuesYAML string) []byte {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	files := [][2]string{{"mychart/Chart.yaml", chartYAML}, {"mychart/values.yaml", valuesYAML}}
+	for _, file := range files {
+		tw.WriteHeader(&tar.Header{Name: file[0], Mode: 0644, Size: int64(len(file[1]))})
+		tw.Write([]byte(file[1]))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestHelmChartsUpload(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	Convey("HelmCharts Upload works correctly", t, func() {
+
+		table := []struct {
+			// Input
+			existingArchives [][]byte
+			archive          []byte
+			// Expectations
+			chart  *model.HelmChart
+			charts int
+			err    *model.Error
+		}{
+			// A successful example
+			{
+				archive: testChartArchive("name: mychart\nversion: 0.1.0\ndescription: A chart\nkeywords: [cache]\nicon: https://example.com/icon.png\nmaintainers:\n- name: jane\n  email: jane@example.com\n", "replicas: 1\n"),
+				chart: &model.HelmChart{
+					RepoName:      "local",
+					Name:          "mychart",
+					Version:       "0.1.0",
+					Description:   "A chart",
+					Icon:          "https://example.com/icon.png",
+					Keywords:      []string{"cache"},
+					Maintainers:   []*model.HelmChartMaintainer{{Name: "jane", Email: "jane@example.com"}},
+					DefaultConfig: map[string]interface{}{"replicas": float64(1)},
+				},
+				charts: 1,
+			},
+
+			// A deprecated chart
+			{
+				archive: testChartArchive("name: mychart\nversion: 0.1.0\ndescription: DEPRECATED A chart\n", ""),
+				chart: &model.HelmChart{
+					RepoName:    "local",
+					Name:        "mychart",
+					Version:     "0.1.0",
+					Description: "DEPRECATED A chart",
+					Deprecated:  true,
+				},
+				charts: 1,
+			},
+
+			// Uploading a version again replaces it
+			{
+				existingArchives: [][]byte{
+					testChartArchive("name: mychart\nversion: 0.1.0\ndescription: A chart\n", "replicas: 1\n"),
+				},
+				archive: testChartArchive("name: mychart\nversion: 0.1.0\ndescription: A changed chart\n", "replicas: 2\n"),
+				chart: &model.HelmChart{
+					RepoName:      "local",
+					Name:          "mychart",
+					Version:       "0.1.0",
+					Description:   "A changed chart",
+					DefaultConfig: map[string]interface{}{"replicas": float64(2)},
+				},
+				charts: 1,
+			},
+
+			// Versions uploaded before are kept
+			{
+				existingArchives: [][]byte{
+					testChartArchive("name: mychart\nversion: 0.1.0\ndescription: A chart\n", "replicas: 1\n"),
+				},
+				archive: testChartArchive("name: mychart\nversion: 0.2.0\ndescription: A chart\n", ""),
+				chart: &model.HelmChart{
+					RepoName:    "local",
+					Name:        "mychart",
+					Version:     "0.2.0",
+					Description: "A chart",
+				},
+				charts: 2,
+			},
+
+			// Not an archive
+			{
+				archive: []byte("not a chart archive"),
+				err:     &model.Error{Status: 422, Message: "Validation failed: archive: gzip: invalid header"},
+			},
+
+			// No description
+			{
+				archive: testChartArchive("name: mychart\nversion: 0.1.0\n", ""),
+				err:     &model.Error{Status: 422, Message: "Validation failed: archive: Chart.yaml must have a name, version and description"},
+			},
+		}
+
+		for _, item := range table {
+
+			wipeAndInitialize(srv.Core)
+
+			requestor := createAdmin(srv.Core)
+			sg := srv.Core.APIClient("token", requestor.APIToken)
+
+			dir, _ := ioutil.TempDir("", "charts")
+			defer os.RemoveAll(dir)
+			srv.Core.HelmLocalRepoDir = dir
+
+			for _, archive := range item.existingArchives {
+				So(sg.HelmCharts.Upload(&model.HelmChartUpload{Archive: archive}, new(model.HelmChart)), ShouldBeNil)
+			}
+
+			chart := new(model.HelmChart)
+			err := sg.HelmCharts.Upload(&model.HelmChartUpload{Archive: item.archive}, chart)
+
+			if item.err == nil {
+				So(err, ShouldBeNil)
+				So(chart.RepoName, ShouldEqual, item.chart.RepoName)
+				So(chart.Name, ShouldEqual, item.chart.Name)
+				So(chart.Version, ShouldEqual, item.chart.Version)
+				So(chart.Description, ShouldEqual, item.chart.Description)
+				So(chart.DefaultConfig, ShouldResemble, item.chart.DefaultConfig)
+				So(chart.Icon, ShouldEqual, item.chart.Icon)
+				So(chart.Keywords, ShouldResemble, item.chart.Keywords)
+				So(chart.Maintainers, ShouldResemble, item.chart.Maintainers)
+				So(chart.Deprecated, ShouldEqual, item.chart.Deprecated)
+
+				repo := new(model.HelmRepo)
+				So(srv.Core.DB.Where("name = ?", "local").First(repo), ShouldBeNil)
+				So(repo.URL, ShouldEqual, "file://"+dir)
+			} else {
+				So(err, ShouldResemble, item.err)
+			}
+
+			list := new(model.HelmChartList)
+			So(sg.HelmCharts.List(list), ShouldBeNil)
+			So(len(list.Items), ShouldEqual, item.charts)
+		}
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func TestHelmChartsSearch(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	wipeAndInitialize(srv.Core)
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "test", URL: "www.test.com"})
+	srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "other", URL: "www.other.com"})
+
+	charts := []*model.HelmChart{
+		{RepoName: "test", Name: "redis", Version: "0.9.0", Description: "Open source, in-memory store"},
+		{RepoName: "test", Name: "redis", Version: "0.10.0", Description: "Open source, in-memory store"},
+		{RepoName: "test", Name: "redis-ha", Version: "1.0.0", Description: "Highly available Redis"},
+		{RepoName: "test", Name: "memcached", Version: "1.0.0", Description: "Free and open source cache", Keywords: []string{"in-memory"}},
+		{RepoName: "other", Name: "keydb", Version: "0.1.0", Description: "DEPRECATED A fork of Redis", Deprecated: true},
+	}
+	for _, chart := range charts {
+		srv.Core.HelmCharts.Create(chart)
+	}
+	srv.Core.HelmChartPolicies.Create(&model.HelmChartPolicy{Effect: "deny", KubeName: "prod*", Versions: "< 1"})
+
+	Convey("HelmCharts Search works correctly", t, func() {
+
+		deprecated := false
+
+		table := []struct {
+			// Input
+			search *model.HelmChartSearch
+			// Expectations
+			charts []string
+			err    *model.Error
+		}{
+			// Everything, newest version first
+			{
+				search: &model.HelmChartSearch{},
+				charts: []string{"other/keydb 0.1.0", "test/memcached 1.0.0", "test/redis 0.10.0", "test/redis 0.9.0", "test/redis-ha 1.0.0"},
+			},
+			// The name first, then the description
+			{
+				search: &model.HelmChartSearch{Query: "Redis", Latest: true},
+				charts: []string{"test/redis 0.10.0", "test/redis-ha 1.0.0", "other/keydb 0.1.0"},
+			},
+			// Keywords before the description
+			{
+				search: &model.HelmChartSearch{Query: "in-memory", Latest: true},
+				charts: []string{"test/memcached 1.0.0", "test/redis 0.10.0"},
+			},
+			// Every word must match
+			{
+				search: &model.HelmChartSearch{Query: "open cache"},
+				charts: []string{"test/memcached 1.0.0"},
+			},
+			{
+				search: &model.HelmChartSearch{Query: "redis", Deprecated: &deprecated},
+				charts: []string{"test/redis 0.10.0", "test/redis 0.9.0", "test/redis-ha 1.0.0"},
+			},
+			{
+				search: &model.HelmChartSearch{RepoName: "other"},
+				charts: []string{"other/keydb 0.1.0"},
+			},
+			// Only what policies allow on the Kube
+			{
+				search: &model.HelmChartSearch{KubeName: "production"},
+				charts: []string{"test/memcached 1.0.0", "test/redis-ha 1.0.0"},
+			},
+		}
+
+		for _, item := range table {
+			list := new(model.HelmChartList)
+			err := sg.HelmCharts.Search(item.search, list)
+
+			So(err, ShouldBeNil)
+
+			var found []string
+			for _, chart := range list.Items {
+				found = append(found, chart.RepoName+"/"+chart.Name+" "+chart.Version)
+			}
+			So(found, ShouldResemble, item.charts)
+			So(list.Total, ShouldEqual, len(item.charts))
+		}
+
+		Convey("Flags of the search must be booleans", func() {
+			_, err := core.ParseHelmChartSearch(url.Values{"latest": {"yes"}})
+			So(err.Error(), ShouldEqual, "Validation failed: latest must be true or false, got 'yes'")
+		})
+	})
+}
+
 //------------------------------------------------------------------------------
 
 func TestHelmChartsGet(t *testing.T) {
@@ -307,6 +556,7 @@ func TestHelmChartsUpdate(t *testing.T) {
 
 			srv.Core.HelmCharts.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.HelmCharts.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHelmReleasesPromote(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	user, admin := createUserAndAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", admin.APIToken)
+	sgUser := srv.Core.APIClient("token", user.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+	production := &model.Kube{
+		CloudAccountName:  kube.CloudAccountName,
+		Name:              "production",
+		MasterNodeSize:    "m4.large",
+		NodeSizes:         []string{"m4.large"},
+		AWSConfig:         &model.AWSKubeConfig{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+		PromotionApproval: true,
+	}
+	sg.Kubes.Create(production)
+	staging := &model.Kube{
+		CloudAccountName: kube.CloudAccountName,
+		Name:             "staging",
+		MasterNodeSize:   "m4.large",
+		NodeSizes:        []string{"m4.large"},
+		AWSConfig:        &model.AWSKubeConfig{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+	}
+	sg.Kubes.Create(staging)
+
+	srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "stable", URL: "www.stable.com"})
+
+	worker := newFakeHelmWorker(errors.New("stop here"))
+
+	srv.Core.HelmJobStartTimeout = time.Nanosecond
+	srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+		return worker.k8s()
+	}
+
+	Convey("HelmReleases Promote works correctly", t, func() {
+		// Created directly, so no install is run
+		source := &model.HelmRelease{
+			KubeName:     kube.Name,
+			Name:         "test",
+			RepoName:     "stable",
+			ChartName:    "redis",
+			ChartVersion: "0.2.0",
+			Values:       []string{"replicas: 1\n"},
+			Config:       map[string]interface{}{"persistence": false},
+		}
+		srv.Core.DB.Create(source)
+
+		Convey("When the Kube doesn't need approval, the release is installed there", func() {
+			promotion := &model.HelmReleasePromotion{
+				KubeName: staging.Name,
+				Values:   []string{"ingress:\n  host: staging.example.com\n"},
+				Config:   map[string]interface{}{"replicas": 3},
+			}
+			err := sg.HelmReleases.Promote(source.ID, promotion)
+
+			So(err, ShouldBeNil)
+			So(promotion.State, ShouldEqual, model.HelmReleasePromotionPromoted)
+			So(promotion.RequestedBy, ShouldEqual, admin.Username)
+			So(promotion.ChartVersion, ShouldEqual, "0.2.0")
+			So(promotion.SourceValues, ShouldResemble, []string{"replicas: 1\n", "persistence: false\n"})
+			So(<-worker.commands, ShouldEqual, `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm install stable/redis -f /supergiant/values.yaml --version 0.2.0 --name test`)
+			So(worker.files["values.yaml"], ShouldEqual, "ingress:\n  host: staging.example.com\npersistence: false\nreplicas: 3\n")
+
+			target := new(model.HelmRelease)
+			So(sg.HelmReleases.Get(promotion.TargetReleaseID, target), ShouldBeNil)
+			So(target.KubeName, ShouldEqual, staging.Name)
+
+			Convey("and promoting again upgrades it, keeping the overrides of the environment", func() {
+				srv.Core.DB.Model(source).Update("chart_version", "0.3.0")
+
+				promotion := &model.HelmReleasePromotion{KubeName: staging.Name}
+				err := sg.HelmReleases.Promote(source.ID, promotion)
+
+				So(err, ShouldBeNil)
+				So(promotion.TargetReleaseID, ShouldResemble, target.ID)
+				So(<-worker.commands, ShouldEqual, `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm upgrade test stable/redis --version 0.3.0 -f /supergiant/values.yaml`)
+				So(worker.files["values.yaml"], ShouldEqual, "persistence: false\nreplicas: 3\n")
+			})
+		})
+
+		Convey("When the Kube needs approval, the promotion waits for an admin", func() {
+			promotion := &model.HelmReleasePromotion{KubeName: production.Name}
+			err := sgUser.HelmReleases.Promote(source.ID, promotion)
+
+			So(err, ShouldBeNil)
+			So(promotion.State, ShouldEqual, model.HelmReleasePromotionPending)
+			So(promotion.TargetReleaseID, ShouldBeNil)
+
+			err = sgUser.HelmReleasePromotions.Approve(promotion.ID, promotion)
+			So(err.(*model.Error).Status, ShouldEqual, 403)
+
+			Convey("who approves it", func() {
+				err := sg.HelmReleasePromotions.Approve(promotion.ID, promotion)
+
+				So(err, ShouldBeNil)
+				So(promotion.State, ShouldEqual, model.HelmReleasePromotionPromoted)
+				So(promotion.ReviewedBy, ShouldEqual, admin.Username)
+				So(<-worker.commands, ShouldEqual, `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm install stable/redis -f /supergiant/values.yaml --version 0.2.0 --name test`)
+
+				err = sg.HelmReleasePromotions.Approve(promotion.ID, promotion)
+				So(err, ShouldResemble, &model.Error{Status: 422, Message: "Validation failed: HelmReleasePromotion is promoted, not pending_approval"})
+			})
+
+			Convey("or rejects it", func() {
+				err := sg.HelmReleasePromotions.Reject(promotion.ID, promotion)
+
+				So(err, ShouldBeNil)
+				So(promotion.State, ShouldEqual, model.HelmReleasePromotionRejected)
+				So(promotion.ReviewedBy, ShouldEqual, admin.Username)
+
+				list := new(model.HelmReleaseList)
+				sg.HelmReleases.List(list)
+				So(len(list.Items), ShouldEqual, 1)
+			})
+		})
+
+		Convey("HelmReleases of a Kube which needs approval can't be changed directly by users", func() {
+			message := "HelmReleases of Kube production can only be changed by an admin, or by promoting them to it"
+
+			err := sgUser.HelmReleases.Create(&model.HelmRelease{
+				KubeName:     production.Name,
+				Name:         "direct",
+				RepoName:     "stable",
+				ChartName:    "redis",
+				ChartVersion: "0.2.0",
+			})
+			So(err, ShouldResemble, &model.Error{Status: 403, Message: message})
+
+			release := &model.HelmRelease{
+				KubeName:     production.Name,
+				Name:         "test",
+				RepoName:     "stable",
+				ChartName:    "redis",
+				ChartVersion: "0.2.0",
+			}
+			srv.Core.DB.Create(release)
+
+			err = sgUser.HelmReleases.Update(release.ID, &model.HelmRelease{ChartVersion: "0.3.0", ResourceVersion: release.ResourceVersion})
+			So(err, ShouldResemble, &model.Error{Status: 403, Message: message})
+
+			err = sgUser.HelmReleases.Rollback(release.ID, &model.HelmReleaseRollback{Revision: 1}, release)
+			So(err, ShouldResemble, &model.Error{Status: 403, Message: message})
+
+			err = sgUser.HelmReleases.Delete(release.ID, release)
+			So(err, ShouldResemble, &model.Error{Status: 403, Message: message})
+		})
+
+		Convey("Only admins can set promotion_approval on a Kube", func() {
+			err := sgUser.Kubes.Update(staging.ID, &model.Kube{PromotionApproval: true, ResourceVersion: staging.ResourceVersion})
+			So(err.(*model.Error).Status, ShouldEqual, 403)
+
+			err = sgUser.Kubes.Create(&model.Kube{
+				CloudAccountName:  kube.CloudAccountName,
+				Name:              "qa",
+				MasterNodeSize:    "m4.large",
+				NodeSizes:         []string{"m4.large"},
+				AWSConfig:         &model.AWSKubeConfig{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+				PromotionApproval: true,
+			})
+			So(err.(*model.Error).Status, ShouldEqual, 403)
+
+			// Left as it is
+			current := new(model.Kube)
+			sg.Kubes.Get(production.ID, current)
+			err = sgUser.Kubes.Update(production.ID, &model.Kube{PromotionApproval: true, ResourceVersion: current.ResourceVersion})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("A release can't be promoted to its own Kube", func() {
+			err := sg.HelmReleases.Promote(source.ID, &model.HelmReleasePromotion{KubeName: kube.Name})
+			So(err, ShouldResemble, &model.Error{Status: 422, Message: "Validation failed: kube_name: HelmRelease test is on Kube test already"})
+		})
+
+		Convey("A release can't be promoted to a Kube which doesn't exist", func() {
+			err := sg.HelmReleases.Promote(source.ID, &model.HelmReleasePromotion{KubeName: "crub"})
+			So(err, ShouldResemble, &model.Error{Status: 422, Message: "Parent does not exist, foreign key 'KubeName' on HelmReleasePromotion"})
+		})
+
+		// NOTE we have to clean up manually since we do not wipe DB each time
+		srv.Core.DB.Delete(&model.HelmReleasePromotion{})
+		srv.Core.DB.Delete(&model.HelmRelease{})
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func TestHelmReleasesDrift(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+	for _, name := range []string{"production", "staging", "qa"} {
+		sg.Kubes.Create(&model.Kube{
+			CloudAccountName: kube.CloudAccountName,
+			Name:             name,
+			MasterNodeSize:   "m4.large",
+			NodeSizes:        []string{"m4.large"},
+			AWSConfig:        &model.AWSKubeConfig{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+		})
+	}
+
+	Convey("HelmReleases Drift compares the release across Kubes", t, func() {
+		releases := []*model.HelmRelease{
+			{KubeName: "production", ChartVersion: "0.1.0", Config: map[string]interface{}{"replicas": 3}},
+			{KubeName: "staging", ChartVersion: "0.3.0"},
+			{KubeName: kube.Name, ChartVersion: "0.2.0"},
+			{KubeName: "qa", ChartVersion: "latest"},
+		}
+		for _, release := range releases {
+			release.Name = "test"
+			release.RepoName = "stable"
+			release.ChartName = "redis"
+			srv.Core.DB.Create(release)
+		}
+		// Another chart of the same name isn't compared
+		srv.Core.DB.Create(&model.HelmRelease{KubeName: "staging", Name: "test", RepoName: "stable", ChartName: "mysql", ChartVersion: "0.1.0"})
+
+		drift := new(model.HelmReleaseDrift)
+		err := sg.HelmReleases.Drift(releases[2].ID, drift)
+
+		So(err, ShouldBeNil)
+		So(len(drift.Items), ShouldEqual, 4)
+
+		table := []struct {
+			kubeName     string
+			drift        string
+			valuesDiffer bool
+		}{
+			{"production", "behind", true},
+			{"qa", "unknown", false},
+			{"staging", "ahead", false},
+			{kube.Name, "same", false},
+		}
+		for i, item := range table {
+			So(drift.Items[i].KubeName, ShouldEqual, item.kubeName)
+			So(drift.Items[i].Drift, ShouldEqual, item.drift)
+			So(drift.Items[i].ValuesDiffer, ShouldEqual, item.valuesDiffer)
+		}
+
+		// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
+		srv.Core.DB.Delete(&model.HelmRelease{})
+	})
+}
@@ -1,7 +1,13 @@
 package api
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -94,13 +100,16 @@ func TestHelmReleasesCreate(t *testing.T) {
 
 		table := []struct {
 			// Input
-			repos []*model.HelmRepo
-			model *model.HelmRelease
+			repos  []*model.HelmRepo
+			charts []*model.HelmChart
+			model  *model.HelmRelease
 			// Mocks
 			mockKubeCreateResourceError error
 			mockKubeGetResourceFn       func(apiVersion, kind, namespace, name string, out interface{}) error
+			mockHelmCmdError            error
 			// Expectations
 			fullCommand string
+			values      string
 			err         *model.Error
 			asyncErr    string // because Create has a sync and async phase
 		}{
@@ -119,18 +128,38 @@ func TestHelmReleasesCreate(t *testing.T) {
 							"key": "value",
 						},
 						"not-nested": 6,
+						"hosts":      []interface{}{"a.example.com", "b,c"},
+						"quoted":     `say "hi"`,
 					},
 				},
-				mockKubeCreateResourceError: nil,
-				mockKubeGetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
-					return errors.New("404") // means job finishes successfully
-				},
-				fullCommand: `/helm init --client-only && /helm install stable/redis --set nested.key="value",not-nested=6 --version 0.1.0 --name test`,
+				fullCommand: `/helm init --client-only --skip-refresh && /helm install stable/redis -f /supergiant/values.yaml --version 0.1.0 --name test`,
+				values:      "hosts:\n- a.example.com\n- b,c\nnested:\n  key: value\nnot-nested: 6\nquoted: say \"hi\"\n",
 				err:         nil,
 				asyncErr:    "",
 			},
 
-			// A successful example (with Repos)
+			// Layered values, overridden by Config
+			{
+				model: &model.HelmRelease{
+					BaseModel:    model.BaseModel{ID: &helmReleaseID},
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "stable",
+					ChartName:    "redis",
+					ChartVersion: "0.1.0",
+					Values: []string{
+						"replicas: 1\nimage:\n  repository: redis\n  tag: \"4.0\"\n",
+						"image:\n  tag: \"4.0.8\"\n",
+					},
+					Config: map[string]interface{}{
+						"replicas": 3,
+					},
+				},
+				fullCommand: `/helm init --client-only --skip-refresh && /helm install stable/redis -f /supergiant/values.yaml --version 0.1.0 --name test`,
+				values:      "image:\n  repository: redis\n  tag: 4.0.8\nreplicas: 3\n",
+			},
+
+			// Values of the wrong type for the chart's defaults
 			{
 				repos: []*model.HelmRepo{
 					{
@@ -138,6 +167,15 @@ func TestHelmReleasesCreate(t *testing.T) {
 						URL:  "www.website.com",
 					},
 				},
+				charts: []*model.HelmChart{
+					{
+						RepoName:      "supergiant",
+						Name:          "elasticsearch",
+						Version:       "0.7.1",
+						Description:   "A chart",
+						DefaultConfig: map[string]interface{}{"cluster": map[string]interface{}{"replicas": 3}},
+					},
+				},
 				model: &model.HelmRelease{
 					BaseModel:    model.BaseModel{ID: &helmReleaseID},
 					KubeName:     kube.Name,
@@ -145,19 +183,37 @@ func TestHelmReleasesCreate(t *testing.T) {
 					RepoName:     "supergiant",
 					ChartName:    "elasticsearch",
 					ChartVersion: "0.7.1",
+					Values:       []string{"cluster:\n  replicas: three\n"},
 				},
-				mockKubeCreateResourceError: nil,
-				mockKubeGetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
-					return errors.New("404") // means job finishes successfully
-				},
-				fullCommand: `/helm init --client-only && /helm repo add supergiant www.website.com && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
-				err:         nil,
-				asyncErr:    "",
+				err: &model.Error{Status: 422, Message: "Validation failed: values: cluster.replicas: expected number, got string"},
 			},
 
-			// A successful example (when catching Pod phase succeeded)
+			// Values not allowed by the chart's schema
 			{
-				repos: nil,
+				repos: []*model.HelmRepo{
+					{
+						Name: "supergiant",
+						URL:  "www.website.com",
+					},
+				},
+				charts: []*model.HelmChart{
+					{
+						RepoName:      "supergiant",
+						Name:          "elasticsearch",
+						Version:       "0.7.1",
+						Description:   "A chart",
+						DefaultConfig: map[string]interface{}{"cluster": map[string]interface{}{"replicas": 3}},
+						ValuesSchema: map[string]interface{}{
+							"properties": map[string]interface{}{
+								"cluster": map[string]interface{}{
+									"properties": map[string]interface{}{
+										"replicas": map[string]interface{}{"type": "integer", "minimum": 1},
+									},
+								},
+							},
+						},
+					},
+				},
 				model: &model.HelmRelease{
 					BaseModel:    model.BaseModel{ID: &helmReleaseID},
 					KubeName:     kube.Name,
@@ -165,19 +221,62 @@ func TestHelmReleasesCreate(t *testing.T) {
 					RepoName:     "supergiant",
 					ChartName:    "elasticsearch",
 					ChartVersion: "0.7.1",
+					Config:       map[string]interface{}{"cluster": map[string]interface{}{"replicas": 0}},
 				},
-				mockKubeCreateResourceError: nil,
-				mockKubeGetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
-					pod := out.(*kubernetes.Pod)
-					pod.Status.Phase = "Succeeded"
-					return nil
+				err: &model.Error{Status: 422, Message: "Validation failed: values: cluster.replicas: must be at least 1"},
+			},
+
+			// Invalid values YAML
+			{
+				model: &model.HelmRelease{
+					BaseModel:    model.BaseModel{ID: &helmReleaseID},
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "stable",
+					ChartName:    "redis",
+					ChartVersion: "0.1.0",
+					Values:       []string{"replicas: 1\n", "- not a map\n"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: values[1]: error unmarshaling JSON: json: cannot unmarshal array into Go value of type map[string]interface {}"},
+			},
+
+			// A successful example (with Repos)
+			{
+				repos: []*model.HelmRepo{
+					{
+						Name: "supergiant",
+						URL:  "www.website.com",
+					},
 				},
-				fullCommand: `/helm init --client-only && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
+				model: &model.HelmRelease{
+					BaseModel:    model.BaseModel{ID: &helmReleaseID},
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "supergiant",
+					ChartName:    "elasticsearch",
+					ChartVersion: "0.7.1",
+				},
+				fullCommand: `/helm init --client-only --skip-refresh && /helm repo add supergiant www.website.com && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
 				err:         nil,
 				asyncErr:    "",
 			},
 
-			// Timeout (Pod never starts)
+			// The command fails
+			{
+				model: &model.HelmRelease{
+					BaseModel:    model.BaseModel{ID: &helmReleaseID},
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "supergiant",
+					ChartName:    "elasticsearch",
+					ChartVersion: "0.7.1",
+				},
+				mockHelmCmdError: errors.New("command terminated with non-zero exit code"),
+				fullCommand:      `/helm init --client-only --skip-refresh && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
+				asyncErr:         "Helm cmd failed: install supergiant/elasticsearch --version 0.7.1 --name test (command terminated with non-zero exit code)\n\n",
+			},
+
+			// Timeout (the worker pod never starts)
 			{
 				model: &model.HelmRelease{
 					BaseModel:    model.BaseModel{ID: &helmReleaseID},
@@ -192,11 +291,11 @@ func TestHelmReleasesCreate(t *testing.T) {
 					pod.Status.Phase = "Pending"
 					return nil
 				},
-				fullCommand: `/helm init --client-only && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
-				asyncErr:    "Timed out waiting for Helm cmd 'install supergiant/elasticsearch --version 0.7.1 --name test'",
+				asyncErr: "Timed out waiting for Helm cmd 'install supergiant/elasticsearch --version 0.7.1 --name test'",
 			},
 
-			// Unexpected error on Kubernetes CreateResource
+			// Unexpected error on Kubernetes CreateResource (of the worker pod, when
+			// there's none)
 			{
 				model: &model.HelmRelease{
 					BaseModel:    model.BaseModel{ID: &helmReleaseID},
@@ -207,8 +306,10 @@ func TestHelmReleasesCreate(t *testing.T) {
 					ChartVersion: "0.7.1",
 				},
 				mockKubeCreateResourceError: errors.New("something unexpected"),
-				fullCommand:                 `/helm init --client-only && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
-				asyncErr:                    "Error creating Pod: something unexpected",
+				mockKubeGetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
+					return errors.New("K8S 404 error: not found")
+				},
+				asyncErr: "Error creating Pod: something unexpected",
 			},
 
 			// Unexpected error on Kubernetes GetResource
@@ -224,8 +325,7 @@ func TestHelmReleasesCreate(t *testing.T) {
 				mockKubeGetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
 					return errors.New("something unexpected")
 				},
-				fullCommand: `/helm init --client-only && /helm install supergiant/elasticsearch --version 0.7.1 --name test`,
-				asyncErr:    "Error GETting Pod: something unexpected",
+				asyncErr: "Error GETting Pod: something unexpected",
 			},
 
 			// No KubeName
@@ -289,24 +389,27 @@ func TestHelmReleasesCreate(t *testing.T) {
 
 			// wipeAndInitialize(srv.Core)
 
-			var fullCommand string
+			worker := newFakeHelmWorker(item.mockHelmCmdError)
 
 			srv.Core.HelmJobStartTimeout = time.Nanosecond
 
 			srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
-				return &fake_core.KubernetesClient{
-					CreateResourceFn: func(apiVersion, kind, namespace string, in, out interface{}) error {
-						pod := in.(*kubernetes.Pod)
-						fullCommand = pod.Spec.Containers[0].Args[0]
-						return item.mockKubeCreateResourceError
-					},
-					GetResourceFn: item.mockKubeGetResourceFn,
+				k8s := worker.k8s()
+				k8s.CreateResourceFn = func(apiVersion, kind, namespace string, in, out interface{}) error {
+					return item.mockKubeCreateResourceError
+				}
+				if item.mockKubeGetResourceFn != nil {
+					k8s.GetResourceFn = item.mockKubeGetResourceFn
 				}
+				return k8s
 			}
 
 			for _, repo := range item.repos {
 				srv.Core.HelmRepos.Create(repo)
 			}
+			for _, chart := range item.charts {
+				srv.Core.HelmCharts.Create(chart)
+			}
 
 			err := sg.HelmReleases.Create(item.model)
 
@@ -327,17 +430,86 @@ func TestHelmReleasesCreate(t *testing.T) {
 				So(freshModel.Status.Error, ShouldEqual, item.asyncErr)
 			}
 
-			So(fullCommand, ShouldEqual, item.fullCommand)
+			if item.fullCommand != "" {
+				So(<-worker.commands, ShouldEqual, item.fullCommand)
+			}
+			So(worker.files["values.yaml"], ShouldEqual, item.values)
 
 			// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
 			srv.Core.DB.Delete(&model.HelmRelease{})
-			srv.Core.DB.Delete(&model.HelmRepo{})
+			for _, chart := range item.charts {
+				srv.Core.DB.Delete(chart)
+			}
+			// Through the collection, so the cached repos are forgotten
+			for _, repo := range item.repos {
+				srv.Core.HelmRepos.Delete(repo.ID, repo).Now()
+			}
 		}
 	})
 }
 
 //------------------------------------------------------------------------------
 
+func TestHelmReleasesCreateFromPrivateRepo(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+
+	Convey("HelmReleases Create installs charts of private repos from their archive", t, func() {
+		repoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if username, password, _ := r.BasicAuth(); username != "sg" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.URL.Path {
+			case "/index.yaml":
+				w.Write([]byte("apiVersion: v1\nentries:\n  api:\n  - name: api\n    version: 1.0.0\n    urls:\n    - api-1.0.0.tgz\n"))
+			case "/api-1.0.0.tgz":
+				w.Write([]byte("archive"))
+			}
+		}))
+		defer repoServer.Close()
+
+		srv.Core.HelmRepos.Create(&model.HelmRepo{
+			Name:        "internal",
+			URL:         repoServer.URL,
+			Credentials: map[string]string{"username": "sg", "password": "secret"},
+		})
+
+		worker := newFakeHelmWorker(errors.New("stop here"))
+		srv.Core.HelmJobStartTimeout = time.Nanosecond
+		srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+			return worker.k8s()
+		}
+
+		release := &model.HelmRelease{
+			KubeName:     kube.Name,
+			Name:         "test",
+			RepoName:     "internal",
+			ChartName:    "api",
+			ChartVersion: "1.0.0",
+		}
+		err := srv.Core.HelmReleases.Create(release)
+		So(err, ShouldBeNil)
+
+		So(<-worker.commands, ShouldEqual, "/helm init --client-only --skip-refresh && /helm install /supergiant/chart.tgz --version 1.0.0 --name test")
+		So(worker.files, ShouldResemble, map[string]string{"chart.tgz": "archive"})
+
+		// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
+		srv.Core.DB.Delete(&model.HelmRelease{})
+	})
+}
+
+//------------------------------------------------------------------------------
+
 func TestHelmReleasesGet(t *testing.T) {
 	srv := newTestServer()
 	go srv.Start()
@@ -475,7 +647,7 @@ func TestHelmReleasesUpdate(t *testing.T) {
 				err: &model.Error{Status: 422, Message: "ChartName cannot be changed"},
 			},
 
-			// Can't update ChartVersion
+			// Can update ChartVersion, which upgrades the release
 			{
 				existingModel: &model.HelmRelease{
 					KubeName:     kube.Name,
@@ -487,14 +659,20 @@ func TestHelmReleasesUpdate(t *testing.T) {
 				modelUpdate: &model.HelmRelease{
 					ChartVersion: "7.7.7",
 				},
-				err: &model.Error{Status: 422, Message: "ChartVersion cannot be changed"},
+				err: nil,
 			},
 		}
 
 		for _, item := range table {
 
+			srv.Core.HelmJobStartTimeout = time.Nanosecond
+			srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+				return new(fake_core.KubernetesClient)
+			}
+
 			srv.Core.HelmReleases.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.HelmReleases.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
@@ -512,6 +690,413 @@ func TestHelmReleasesUpdate(t *testing.T) {
 
 //------------------------------------------------------------------------------
 
+func TestHelmReleasesUpgrade(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+
+	srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "stable", URL: "www.stable.com"})
+
+	Convey("HelmReleases Update upgrades the release", t, func() {
+
+		table := []struct {
+			// Input
+			existingModel *model.HelmRelease
+			modelUpdate   *model.HelmRelease
+			// Expectations
+			fullCommand string
+			values      string
+			config      map[string]interface{}
+		}{
+			// New version, keeping the stored values
+			{
+				existingModel: &model.HelmRelease{
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "stable",
+					ChartName:    "redis",
+					ChartVersion: "0.1.0",
+					Config:       map[string]interface{}{"persistence": false},
+				},
+				modelUpdate: &model.HelmRelease{
+					ChartVersion: "0.2.0",
+				},
+				fullCommand: `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm upgrade test stable/redis --version 0.2.0 -f /supergiant/values.yaml`,
+				values:      "persistence: false\n",
+				config:      map[string]interface{}{"persistence": false},
+			},
+
+			// New values replace the old ones, and options are passed
+			{
+				existingModel: &model.HelmRelease{
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "stable",
+					ChartName:    "redis",
+					ChartVersion: "0.1.0",
+					Config:       map[string]interface{}{"persistence": false},
+				},
+				modelUpdate: &model.HelmRelease{
+					Config:  map[string]interface{}{"replicas": float64(3)},
+					Wait:    true,
+					Timeout: 600,
+				},
+				fullCommand: `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm upgrade test stable/redis --version 0.1.0 -f /supergiant/values.yaml --wait --timeout 600`,
+				values:      "replicas: 3\n",
+				config:      map[string]interface{}{"replicas": float64(3)},
+			},
+
+			// Reusing values merges them
+			{
+				existingModel: &model.HelmRelease{
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "stable",
+					ChartName:    "redis",
+					ChartVersion: "0.1.0",
+					Config:       map[string]interface{}{"persistence": false},
+				},
+				modelUpdate: &model.HelmRelease{
+					Config:      map[string]interface{}{"persistence": true},
+					ReuseValues: true,
+				},
+				fullCommand: `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm upgrade test stable/redis --version 0.1.0 --reuse-values -f /supergiant/values.yaml`,
+				values:      "persistence: true\n",
+				config:      map[string]interface{}{"persistence": true},
+			},
+
+			// New values documents replace the old ones, and the stored Config
+			// still overrides them
+			{
+				existingModel: &model.HelmRelease{
+					KubeName:     kube.Name,
+					Name:         "test",
+					RepoName:     "stable",
+					ChartName:    "redis",
+					ChartVersion: "0.1.0",
+					Values:       []string{"replicas: 1\n"},
+					Config:       map[string]interface{}{"persistence": false},
+				},
+				modelUpdate: &model.HelmRelease{
+					Values: []string{"replicas: 2\n", "persistence: true\n"},
+				},
+				fullCommand: `/helm init --client-only --skip-refresh && /helm repo add stable www.stable.com && /helm upgrade test stable/redis --version 0.1.0 -f /supergiant/values.yaml`,
+				values:      "persistence: false\nreplicas: 2\n",
+				config:      map[string]interface{}{"persistence": false},
+			},
+		}
+
+		for _, item := range table {
+
+			worker := newFakeHelmWorker(errors.New("stop here"))
+
+			srv.Core.HelmJobStartTimeout = time.Nanosecond
+			srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+				return worker.k8s()
+			}
+
+			// Created directly, so no install is run
+			srv.Core.DB.Create(item.existingModel)
+
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
+			err := sg.HelmReleases.Update(item.existingModel.ID, item.modelUpdate)
+			So(err, ShouldBeNil)
+
+			So(<-worker.commands, ShouldEqual, item.fullCommand)
+			So(worker.files["values.yaml"], ShouldEqual, item.values)
+
+			freshModel := new(model.HelmRelease)
+			sg.HelmReleases.Get(item.existingModel.ID, freshModel)
+			So(freshModel.Config, ShouldResemble, item.config)
+
+			// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
+			srv.Core.DB.Delete(&model.HelmRelease{})
+		}
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func TestHelmReleasesOutdated(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+
+	srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "stable", URL: "www.stable.com"})
+	for _, version := range []string{"0.1.0", "0.2.0"} {
+		srv.Core.HelmCharts.Create(&model.HelmChart{RepoName: "stable", Name: "redis", Version: version, Description: "A chart"})
+	}
+
+	Convey("HelmReleases with newer chart versions are flagged as outdated", t, func() {
+		outdated := &model.HelmRelease{KubeName: kube.Name, Name: "old", RepoName: "stable", ChartName: "redis", ChartVersion: "0.1.0", StatusValue: "DEPLOYED"}
+		current := &model.HelmRelease{KubeName: kube.Name, Name: "new", RepoName: "stable", ChartName: "redis", ChartVersion: "0.2.0", StatusValue: "DEPLOYED"}
+		srv.Core.DB.Create(outdated)
+		srv.Core.DB.Create(current)
+
+		So((&core.HelmReleaseUpdateChecker{Core: srv.Core}).Perform(), ShouldBeNil)
+
+		release := new(model.HelmRelease)
+		So(sg.HelmReleases.Get(outdated.ID, release), ShouldBeNil)
+		So(release.LatestChartVersion, ShouldEqual, "0.2.0")
+		So(release.ChartUpdate, ShouldEqual, "minor")
+		So(release.PassiveStatus, ShouldEqual, "deployed, outdated")
+
+		yes, no := true, false
+
+		list := &model.HelmReleaseList{Outdated: &yes}
+		So(sg.HelmReleases.List(list), ShouldBeNil)
+		So(len(list.Items), ShouldEqual, 1)
+		So(list.Items[0].Name, ShouldEqual, "old")
+
+		list = &model.HelmReleaseList{Outdated: &no}
+		So(sg.HelmReleases.List(list), ShouldBeNil)
+		So(len(list.Items), ShouldEqual, 1)
+		So(list.Items[0].Name, ShouldEqual, "new")
+
+		// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
+		srv.Core.DB.Delete(&model.HelmRelease{})
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// tillerConfigMap returns a ConfigMap as Tiller stores revisions of releases
+// in, with the protobuf encoded fields Supergiant reads.
+func tillerConfigMap(name string, revision int, status int, chartName string, chartVersion string, values string) *kubernetes.ConfigMap {
+	uvarint := func(x uint64) []byte {
+		b := make([]byte, binary.MaxVarintLen64)
+		return b[:binary.PutUvarint(b, x)]
+	}
+	field := func(field uint64, value []byte) []byte {
+		return bytes.Join([][]byte{uvarint(field<<3 | 2), uvarint(uint64(len(value))), value}, nil)
+	}
+	varint := func(field uint64, value uint64) []byte {
+		return append(uvarint(field<<3), uvarint(value)...)
+	}
+
+	release := bytes.Join([][]byte{
+		field(1, []byte(name)),
+		field(2, bytes.Join([][]byte{
+			field(1, varint(1, uint64(status))),
+			field(3, varint(1, uint64(1500000000+revision))),
+		}, nil)),
+		field(3, field(1, bytes.Join([][]byte{field(1, []byte(chartName)), field(4, []byte(chartVersion))}, nil))),
+		field(4, field(1, []byte(values))),
+		varint(7, uint64(revision)),
+		field(8, []byte("default")),
+	}, nil)
+
+	return &kubernetes.ConfigMap{
+		Metadata: kubernetes.Metadata{Name: fmt.Sprintf("%s.v%d", name, revision)},
+		Data:     map[string]string{"release": base64.StdEncoding.EncodeToString(release)},
+	}
+}
+
+func TestHelmReleasesHistory(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+
+	Convey("HelmReleases History lists the revisions kept by Tiller", t, func() {
+		var query string
+		srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+			return &fake_core.KubernetesClient{
+				ListConfigMapsFn: func(q string) ([]*kubernetes.ConfigMap, error) {
+					query = q
+					return []*kubernetes.ConfigMap{
+						tillerConfigMap("test", 1, 3, "redis", "0.1.0", ""),
+						tillerConfigMap("test", 2, 1, "redis", "0.2.0", "persistence:\n  enabled: false\n"),
+					}, nil
+				},
+			}
+		}
+
+		release := &model.HelmRelease{
+			KubeName:     kube.Name,
+			Name:         "test",
+			RepoName:     "stable",
+			ChartName:    "redis",
+			ChartVersion: "0.2.0",
+		}
+		srv.Core.DB.Create(release)
+
+		history := new(model.HelmReleaseHistory)
+		err := sg.HelmReleases.History(release.ID, history)
+
+		So(err, ShouldBeNil)
+		So(query, ShouldEqual, "labelSelector=OWNER%3DTILLER%2CNAME%3Dtest")
+		So(history.Items, ShouldResemble, []*model.HelmReleaseRevision{
+			{
+				Revision:     2,
+				ChartName:    "redis",
+				ChartVersion: "0.2.0",
+				Config:       map[string]interface{}{"persistence": map[string]interface{}{"enabled": false}},
+				Status:       "DEPLOYED",
+				UpdatedAt:    time.Unix(1500000002, 0).UTC(),
+			},
+			{
+				Revision:     1,
+				ChartName:    "redis",
+				ChartVersion: "0.1.0",
+				Config:       map[string]interface{}{},
+				Status:       "SUPERSEDED",
+				UpdatedAt:    time.Unix(1500000001, 0).UTC(),
+			},
+		})
+
+		Convey("and rolls back to one of them", func() {
+			worker := newFakeHelmWorker(errors.New("stop here"))
+			srv.Core.HelmJobStartTimeout = time.Nanosecond
+			srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+				k8s := worker.k8s()
+				k8s.ListConfigMapsFn = func(q string) ([]*kubernetes.ConfigMap, error) {
+					return []*kubernetes.ConfigMap{tillerConfigMap("test", 1, 3, "redis", "0.1.0", "")}, nil
+				}
+				return k8s
+			}
+
+			err := sg.HelmReleases.Rollback(release.ID, &model.HelmReleaseRollback{Revision: 1, Wait: true}, release)
+			So(err, ShouldBeNil)
+			So(<-worker.commands, ShouldEqual, "/helm init --client-only --skip-refresh && /helm rollback test 1 --wait")
+
+			err = sg.HelmReleases.Rollback(release.ID, &model.HelmReleaseRollback{Revision: 5}, release)
+			So(err, ShouldResemble, &model.Error{Status: 422, Message: "Validation failed: HelmRelease test has no revision 5"})
+		})
+
+		// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
+		srv.Core.DB.Delete(&model.HelmRelease{})
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func TestHelmReleasesPopulate(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+	srv.Core.DB.Model(kube).Update("ready", true)
+
+	Convey("HelmReleases Populate syncs the latest revision of each release from Tiller", t, func() {
+		srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "stable", URL: "www.stable.com"})
+		srv.Core.HelmRepos.Create(&model.HelmRepo{Name: "incubator", URL: "www.incubator.com"})
+		for _, chart := range []*model.HelmChart{
+			{RepoName: "stable", Name: "kube-state-metrics", Version: "0.5.0", Description: "A chart"},
+			{RepoName: "stable", Name: "redis", Version: "0.2.0", Description: "A chart"},
+			{RepoName: "incubator", Name: "redis", Version: "0.3.0", Description: "A chart"},
+		} {
+			srv.Core.HelmCharts.Create(chart)
+		}
+
+		existing := &model.HelmRelease{
+			KubeName:     kube.Name,
+			Name:         "cache",
+			RepoName:     "stable",
+			ChartName:    "redis",
+			ChartVersion: "0.1.0",
+			Config:       map[string]interface{}{"persistence": false},
+		}
+		srv.Core.DB.Create(existing)
+		removed := &model.HelmRelease{
+			KubeName:     kube.Name,
+			Name:         "removed",
+			RepoName:     "stable",
+			ChartName:    "redis",
+			ChartVersion: "0.1.0",
+		}
+		srv.Core.DB.Create(removed)
+
+		var query string
+		srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+			return &fake_core.KubernetesClient{
+				ListConfigMapsFn: func(q string) ([]*kubernetes.ConfigMap, error) {
+					query = q
+					return []*kubernetes.ConfigMap{
+						// Upgraded outside of Supergiant
+						tillerConfigMap("cache", 1, 3, "redis", "0.1.0", "persistence: false\n"),
+						tillerConfigMap("cache", 2, 1, "redis", "0.2.0", "persistence: false\n"),
+						// Installed outside of Supergiant, from a chart with hyphens
+						tillerConfigMap("metrics", 1, 1, "kube-state-metrics", "0.5.0", "replicas: 2\n"),
+						// From a chart of no known repo
+						tillerConfigMap("unknown", 1, 1, "nginx", "1.0.0", ""),
+						// Deleted, but not purged
+						tillerConfigMap("removed", 1, 2, "redis", "0.1.0", ""),
+					}, nil
+				},
+			}
+		}
+
+		err := srv.Core.HelmReleases.Populate()
+
+		So(err, ShouldBeNil)
+		So(query, ShouldEqual, "labelSelector=OWNER%3DTILLER")
+
+		var releases []*model.HelmRelease
+		srv.Core.DB.Find(&releases)
+		So(len(releases), ShouldEqual, 2)
+
+		for _, release := range releases {
+			switch release.Name {
+			case "cache":
+				So(*release.ID, ShouldEqual, *existing.ID)
+				So(release.RepoName, ShouldEqual, "stable")
+				So(release.ChartVersion, ShouldEqual, "0.2.0")
+				So(release.Namespace, ShouldEqual, "default")
+				So(release.Revision, ShouldEqual, "2")
+				So(release.StatusValue, ShouldEqual, "DEPLOYED")
+				So(release.UpdatedValue, ShouldEqual, time.Unix(1500000002, 0).UTC().Format(time.ANSIC))
+				So(release.Config, ShouldResemble, map[string]interface{}{"persistence": false})
+			case "metrics":
+				So(release.RepoName, ShouldEqual, "stable")
+				So(release.ChartName, ShouldEqual, "kube-state-metrics")
+				So(release.ChartVersion, ShouldEqual, "0.5.0")
+				So(release.Revision, ShouldEqual, "1")
+				So(release.Config, ShouldResemble, map[string]interface{}{"replicas": float64(2)})
+			default:
+				t.Errorf("Unexpected HelmRelease %s", release.Name)
+			}
+		}
+
+		// NOTE we have to clean up manually since we do not wipe DB each time
+		srv.Core.DB.Delete(&model.HelmRelease{})
+		srv.Core.DB.Delete(&model.HelmChart{})
+		srv.Core.DB.Delete(&model.HelmRepo{})
+	})
+}
+
+//------------------------------------------------------------------------------
+
 func TestHelmReleasesDelete(t *testing.T) {
 	srv := newTestServer()
 	go srv.Start()
@@ -530,9 +1115,6 @@ func TestHelmReleasesDelete(t *testing.T) {
 		table := []struct {
 			// Input
 			existingModel *model.HelmRelease
-			// Mocks
-			mockKubeCreateResourceError error
-			mockKubeGetResourceFn       func(apiVersion, kind, namespace, name string, out interface{}) error
 			// Expectations
 			fullCommand string
 			asyncErr    string
@@ -547,11 +1129,7 @@ func TestHelmReleasesDelete(t *testing.T) {
 					ChartName:    "redis",
 					ChartVersion: "0.1.0",
 				},
-				mockKubeCreateResourceError: nil,
-				mockKubeGetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
-					return errors.New("404") // means job finishes successfully
-				},
-				fullCommand: `/helm init --client-only && /helm delete test --purge`,
+				fullCommand: `/helm init --client-only --skip-refresh && /helm delete test --purge`,
 				asyncErr:    "",
 			},
 
@@ -562,22 +1140,16 @@ func TestHelmReleasesDelete(t *testing.T) {
 
 		for _, item := range table {
 
-			var fullCommand string
+			worker := newFakeHelmWorker(nil)
 
 			srv.Core.HelmJobStartTimeout = time.Nanosecond
 
 			srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
-				return &fake_core.KubernetesClient{
-					CreateResourceFn: func(apiVersion, kind, namespace string, in, out interface{}) error {
-						pod := in.(*kubernetes.Pod)
-						fullCommand = pod.Spec.Containers[0].Args[0]
-						return item.mockKubeCreateResourceError
-					},
-					GetResourceFn: item.mockKubeGetResourceFn,
-				}
+				return worker.k8s()
 			}
 
 			srv.Core.HelmReleases.Create(item.existingModel)
+			<-worker.commands // The install
 
 			// NOTE no need to test this error here
 			_ = sg.HelmReleases.Delete(item.existingModel.ID, item.existingModel)
@@ -595,7 +1167,7 @@ func TestHelmReleasesDelete(t *testing.T) {
 				So(freshModel.Status.Error, ShouldEqual, item.asyncErr)
 			}
 
-			So(fullCommand, ShouldEqual, item.fullCommand)
+			So(<-worker.commands, ShouldEqual, item.fullCommand)
 
 			// NOTE we have to clean up HelmReleases manually since we do not wipe DB each time
 			srv.Core.DB.Delete(&model.HelmRelease{})
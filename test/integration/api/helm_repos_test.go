@@ -97,6 +97,74 @@ func TestHelmReposCreate(t *testing.T) {
 				},
 				err: &model.Error{Status: 422, Message: "Validation failed: URL: zero value"},
 			},
+
+			// With credentials
+			{
+				model: &model.HelmRepo{
+					Name:        "test",
+					URL:         "https://charts.example.com",
+					Credentials: map[string]string{"username": "sg", "password": "secret"},
+				},
+				err: nil,
+			},
+
+			// Unknown credential
+			{
+				model: &model.HelmRepo{
+					Name:        "test",
+					URL:         "https://charts.example.com",
+					Credentials: map[string]string{"api_key": "secret"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: credentials: unknown key 'api_key', expected one of username, password, token, ca_bundle, client_cert, client_key"},
+			},
+
+			// Username without password
+			{
+				model: &model.HelmRepo{
+					Name:        "test",
+					URL:         "https://charts.example.com",
+					Credentials: map[string]string{"username": "sg"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: credentials: username and password must be given together"},
+			},
+
+			// Basic auth and a token
+			{
+				model: &model.HelmRepo{
+					Name:        "test",
+					URL:         "https://charts.example.com",
+					Credentials: map[string]string{"username": "sg", "password": "secret", "token": "abc"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: credentials: use either username and password, or token"},
+			},
+
+			// CA bundle which isn't PEM
+			{
+				model: &model.HelmRepo{
+					Name:        "test",
+					URL:         "https://charts.example.com",
+					Credentials: map[string]string{"ca_bundle": "not a certificate"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: credentials: ca_bundle has no PEM certificates"},
+			},
+
+			// The name of the repo of uploaded charts
+			{
+				model: &model.HelmRepo{
+					Name: "local",
+					URL:  "www.website.com",
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: name: 'local' is reserved for uploaded charts"},
+			},
+
+			// A local directory
+			{
+				model: &model.HelmRepo{
+					Name: "test",
+					URL:  "file:///etc",
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: url: file URLs are only used by the 'local' repo, upload charts to it instead"},
+			},
 		}
 
 		for _, item := range table {
@@ -201,6 +269,53 @@ func TestHelmReposUpdate(t *testing.T) {
 				},
 				err: &model.Error{Status: 422, Message: "URL cannot be changed"},
 			},
+
+			// Credentials are replaced, so they're given in full
+			{
+				existingModel: &model.HelmRepo{
+					Name:        "test",
+					URL:         "www.website.com",
+					Credentials: map[string]string{"username": "sg", "password": "secret"},
+				},
+				modelUpdate: &model.HelmRepo{
+					Credentials: map[string]string{"username": "sg", "password": "rotated"},
+				},
+				err: nil,
+			},
+			{
+				existingModel: &model.HelmRepo{
+					Name:        "test",
+					URL:         "www.website.com",
+					Credentials: map[string]string{"username": "sg", "password": "secret"},
+				},
+				modelUpdate: &model.HelmRepo{
+					Credentials: map[string]string{"password": "rotated"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: credentials: username and password must be given together"},
+			},
+			{
+				existingModel: &model.HelmRepo{
+					Name:        "test",
+					URL:         "www.website.com",
+					Credentials: map[string]string{"username": "sg", "password": "secret"},
+				},
+				modelUpdate: &model.HelmRepo{
+					Credentials: map[string]string{"token": "abc123"},
+				},
+				err: nil,
+			},
+
+			// Credentials are validated
+			{
+				existingModel: &model.HelmRepo{
+					Name: "test",
+					URL:  "www.website.com",
+				},
+				modelUpdate: &model.HelmRepo{
+					Credentials: map[string]string{"client_cert": "not a certificate"},
+				},
+				err: &model.Error{Status: 422, Message: "Validation failed: credentials: invalid client_cert or client_key: tls: failed to find any PEM data in certificate input"},
+			},
 		}
 
 		for _, item := range table {
@@ -212,14 +327,42 @@ func TestHelmReposUpdate(t *testing.T) {
 
 			srv.Core.HelmRepos.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.HelmRepos.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
 				So(err, ShouldBeNil)
 			} else {
 				So(err, ShouldResemble, item.err)
+				continue
 			}
+
+			stored := new(model.HelmRepo)
+			srv.Core.DB.First(stored, *item.existingModel.ID)
+			So(stored.Credentials, ShouldResemble, item.modelUpdate.Credentials)
 		}
+
+		Convey("Credentials are kept by updates without them, and removed by empty ones", func() {
+			wipeAndInitialize(srv.Core)
+			existing := &model.HelmRepo{
+				Name:        "test",
+				URL:         "www.website.com",
+				Credentials: map[string]string{"token": "abc123"},
+			}
+			srv.Core.HelmRepos.Create(existing)
+
+			err := srv.Core.HelmRepos.Update(existing.ID, new(model.HelmRepo), &model.HelmRepo{BaseModel: model.BaseModel{ResourceVersion: existing.ResourceVersion}})
+			So(err, ShouldBeNil)
+			stored := new(model.HelmRepo)
+			srv.Core.DB.First(stored, *existing.ID)
+			So(stored.Credentials, ShouldResemble, map[string]string{"token": "abc123"})
+
+			err = srv.Core.HelmRepos.Update(existing.ID, new(model.HelmRepo), &model.HelmRepo{BaseModel: model.BaseModel{ResourceVersion: stored.ResourceVersion}, Credentials: map[string]string{}})
+			So(err, ShouldBeNil)
+			stored = new(model.HelmRepo)
+			srv.Core.DB.First(stored, *existing.ID)
+			So(stored.Credentials, ShouldBeEmpty)
+		})
 	})
 }
 
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	user, admin := createUserAndAdmin(srv.Core)
+
+	// The headers of the last response
+	var header http.Header
+
+	send := func(token string, path string, key string, body string) (*http.Response, error) {
+		req, _ := http.NewRequest("POST", "http://localhost:9999"+path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", `SGAPI token="`+token+`"`)
+		req.Header.Set("Idempotency-Key", key)
+		return http.DefaultClient.Do(req)
+	}
+
+	post := func(token string, path string, key string, body string) (int, []byte) {
+		resp, err := send(token, path, key, body)
+		So(err, ShouldBeNil)
+		defer resp.Body.Close()
+		header = resp.Header
+		out, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, out
+	}
+
+	countUsers := func() (n int) {
+		srv.Core.DB.Model(new(model.User)).Count(&n)
+		return n
+	}
+
+	Convey("Creates made again with the same Idempotency-Key respond as the first did", t, func() {
+		status, first := post(admin.APIToken, "/api/v1/users", "create-1", `{"username":"idempotent","password":"password"}`)
+		So(status, ShouldEqual, 201)
+		So(countUsers(), ShouldEqual, 3)
+		etag := header.Get("ETag")
+		So(etag, ShouldNotBeEmpty)
+
+		// The key is of the request under any version of the API
+		status, again := post(admin.APIToken, "/api/v0/users", "create-1", `{"username":"idempotent","password":"password"}`)
+		So(status, ShouldEqual, 201)
+		So(string(again), ShouldEqual, string(first))
+		So(header.Get("ETag"), ShouldEqual, etag)
+		So(header.Get("Content-Type"), ShouldEqual, "application/json")
+		So(countUsers(), ShouldEqual, 3)
+
+		Convey("and so do errors, but server errors, which can be retried", func() {
+			status, _ := post(admin.APIToken, "/api/v1/users", "create-2", `{"username":"idempotent","password":"password"}`)
+			So(status, ShouldEqual, 500)
+			So(srv.Core.DB.Where("key = ?", "create-2").First(new(model.IdempotencyKey)), ShouldNotBeNil)
+
+			status, _ = post(admin.APIToken, "/api/v1/users", "create-3", `{"username":"idempotent 2","password":"password"}`)
+			So(status, ShouldEqual, 422)
+			So(srv.Core.DB.Where("key = ?", "create-3").First(new(model.IdempotencyKey)), ShouldBeNil)
+		})
+
+		Convey("but not those of another request", func() {
+			status, body := post(admin.APIToken, "/api/v1/users", "create-1", `{"username":"another","password":"password"}`)
+			So(status, ShouldEqual, 422)
+			So(string(body), ShouldContainSubstring, "Idempotency-Key 'create-1' was used for another request, each request must have its own")
+		})
+
+		Convey("nor those of another User", func() {
+			status, _ := post(user.APIToken, "/api/v1/users", "create-1", `{"username":"idempotent","password":"password"}`)
+			So(status, ShouldEqual, 403)
+		})
+
+		Convey("nor those which have expired", func() {
+			srv.Core.DB.Model(new(model.IdempotencyKey)).Where("key = ?", "create-1").Update("created_at", time.Now().Add(-25*time.Hour))
+			srv.Core.DB.Where("username = ?", "idempotent").Delete(new(model.User))
+
+			status, _ := post(admin.APIToken, "/api/v1/users", "create-1", `{"username":"idempotent","password":"password"}`)
+			So(status, ShouldEqual, 201)
+			So(countUsers(), ShouldEqual, 3)
+		})
+
+		Reset(func() {
+			srv.Core.DB.Where("username = ?", "idempotent").Delete(new(model.User))
+			srv.Core.DB.Delete(new(model.IdempotencyKey))
+		})
+	})
+
+	Convey("Keys of requests which panic are released, for them to be made again", t, func() {
+		srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+			return &fake_core.Provider{
+				ValidateAccountFn: func(*model.CloudAccount) error {
+					panic("provider error")
+				},
+			}
+		}
+		body := `{"name":"idempotent","provider":"aws","credentials":{"access_key":"key","secret_key":"secret"}}`
+
+		_, err := send(admin.APIToken, "/api/v1/cloud_accounts", "panic-1", body)
+		So(err, ShouldNotBeNil)
+		So(srv.Core.DB.Where("key = ?", "panic-1").First(new(model.IdempotencyKey)), ShouldNotBeNil)
+
+		srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+			return new(fake_core.Provider)
+		}
+		status, _ := post(admin.APIToken, "/api/v1/cloud_accounts", "panic-1", body)
+		So(status, ShouldEqual, 201)
+	})
+
+	Convey("Actions made again with the same Idempotency-Key are made once", t, func() {
+		path := "/api/v1/users/" + strconv.FormatInt(*admin.ID, 10) + "/regenerate_api_token"
+		token := admin.APIToken
+
+		status, first := post(token, path, "regenerate-1", "")
+		So(status, ShouldEqual, 202)
+		regenerated := new(model.User)
+		So(json.Unmarshal(first, regenerated), ShouldBeNil)
+		So(regenerated.APIToken, ShouldNotEqual, token)
+
+		status, again := post(regenerated.APIToken, path, "regenerate-1", "")
+		So(status, ShouldEqual, 202)
+		So(string(again), ShouldEqual, string(first))
+
+		srv.Core.DB.First(admin, *admin.ID)
+		So(admin.APIToken, ShouldEqual, regenerated.APIToken)
+	})
+
+	Convey("The client makes creates with an Idempotency-Key, which it retries with", t, func() {
+		var keys []string
+		fails := 1
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			if fails > 0 {
+				fails--
+				// Drop the connection, as a timeout would
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		}))
+		defer server.Close()
+
+		sg := client.New(server.URL, "token", admin.APIToken, "")
+		err := sg.Users.Create(&model.User{Username: "idempotent"})
+
+		So(err, ShouldBeNil)
+		So(keys, ShouldHaveLength, 2)
+		So(keys[0], ShouldNotBeEmpty)
+		So(keys[1], ShouldEqual, keys[0])
+	})
+}
@@ -1,10 +1,19 @@
 package api
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/supergiant/supergiant/pkg/client"
 	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/kubernetes"
 	"github.com/supergiant/supergiant/pkg/model"
@@ -289,3 +298,252 @@ func TestKubeResourcesStart(t *testing.T) {
 		}
 	})
 }
+
+//------------------------------------------------------------------------------
+
+// createPodAndService creates a Pod and a Service KubeResource of the Kube,
+// without starting them.
+func createPodAndService(c *core.Core, kube *model.Kube) (*model.KubeResource, *model.KubeResource) {
+	pod := &model.KubeResource{KubeName: kube.Name, Namespace: "test", Name: "web", Kind: "Pod", Resource: newRawMessage(`{}`)}
+	service := &model.KubeResource{KubeName: kube.Name, Namespace: "test", Name: "web", Kind: "Service", Resource: newRawMessage(`{}`)}
+	c.DB.Create(pod)
+	c.DB.Create(service)
+	return pod, service
+}
+
+func TestKubeResourcesLogs(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+	pod, service := createPodAndService(srv.Core, kube)
+
+	tail, since := int64(10), int64(300)
+
+	Convey("KubeResources Logs responds with the log of Pods", t, func() {
+		table := []struct {
+			// Input
+			id   *int64
+			opts *model.PodLogOptions
+			// Mocks
+			mockLogErr error
+			// Expectations
+			log        string
+			loggedPod  string
+			loggedOpts *model.PodLogOptions
+			err        *model.Error
+		}{
+			// A successful example
+			{
+				id:         pod.ID,
+				opts:       &model.PodLogOptions{Container: "nginx", TailLines: &tail, SinceSeconds: &since},
+				log:        "line 1\nline 2\n",
+				loggedPod:  "test/web",
+				loggedOpts: &model.PodLogOptions{Container: "nginx", TailLines: &tail, SinceSeconds: &since},
+			},
+			// Only Pods have logs
+			{
+				id:   service.ID,
+				opts: new(model.PodLogOptions),
+				err:  &model.Error{Status: 422, Message: fmt.Sprintf("KubeResource %d is a Service, only Pods have logs and exec sessions", *service.ID)},
+			},
+			// Errors of Kubernetes
+			{
+				id:         pod.ID,
+				opts:       &model.PodLogOptions{Container: "missing"},
+				mockLogErr: errors.New(`K8S 400 Bad Request error: container missing is not valid for pod web`),
+				loggedPod:  "test/web",
+				loggedOpts: &model.PodLogOptions{Container: "missing"},
+				err:        &model.Error{Status: 500, Message: `K8S 400 Bad Request error: container missing is not valid for pod web`},
+			},
+		}
+
+		for _, item := range table {
+			var loggedPod string
+			var loggedOpts *model.PodLogOptions
+			srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+				return &fake_core.KubernetesClient{
+					StreamPodLogFn: func(namespace, name string, opts *model.PodLogOptions) (io.ReadCloser, error) {
+						loggedPod, loggedOpts = namespace+"/"+name, opts
+						if item.mockLogErr != nil {
+							return nil, item.mockLogErr
+						}
+						return ioutil.NopCloser(strings.NewReader("line 1\nline 2\n")), nil
+					},
+				}
+			}
+
+			log := new(bytes.Buffer)
+			err := sg.KubeResources.Logs(item.id, item.opts, log)
+
+			if item.err == nil {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldResemble, item.err)
+			}
+			So(log.String(), ShouldEqual, item.log)
+			So(loggedPod, ShouldEqual, item.loggedPod)
+			So(loggedOpts, ShouldResemble, item.loggedOpts)
+		}
+	})
+
+	Convey("KubeResources Logs streams followed logs as they're written", t, func() {
+		logR, logW := io.Pipe()
+		srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+			return &fake_core.KubernetesClient{
+				StreamPodLogFn: func(_, _ string, _ *model.PodLogOptions) (io.ReadCloser, error) {
+					return logR, nil
+				},
+			}
+		}
+
+		outR, outW := io.Pipe()
+		done := make(chan error)
+		go func() {
+			done <- sg.KubeResources.Logs(pod.ID, &model.PodLogOptions{Follow: true}, outW)
+		}()
+
+		logW.Write([]byte("line 1\n"))
+		line := make([]byte, 7)
+		_, err := io.ReadFull(outR, line)
+		So(err, ShouldBeNil)
+		So(string(line), ShouldEqual, "line 1\n")
+
+		logW.Close()
+		So(<-done, ShouldBeNil)
+	})
+
+	Convey("KubeResources Logs rejects invalid options", t, func() {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("http://localhost:9999/api/v1/kube_resources/%d/logs?since=yesterday", *pod.ID), nil)
+		req.Header.Set("Authorization", `SGAPI token="`+requestor.APIToken+`"`)
+		resp, err := http.DefaultClient.Do(req)
+
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, 400)
+		So(string(body), ShouldContainSubstring, "Invalid since parameter: must be a duration such as 10m, or a number of seconds, got 'yesterday'")
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func TestKubeResourcesExec(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+	pod, service := createPodAndService(srv.Core, kube)
+
+	// Kubernetes echoes stdin to stdout, until stdin has "exit"
+	kubeUpgrader := websocket.Upgrader{Subprotocols: []string{"v4.channel.k8s.io"}}
+	fakeKube := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := kubeUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(message[1:]) == "exit" {
+				conn.WriteMessage(websocket.BinaryMessage, []byte("\x03"+`{"status":"Success"}`))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			conn.WriteMessage(websocket.BinaryMessage, append([]byte{1}, message[1:]...))
+		}
+	}))
+	defer fakeKube.Close()
+
+	var execPod string
+	var execOpts *model.PodExecOptions
+	srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+		return &fake_core.KubernetesClient{
+			ExecPodFn: func(namespace, name string, opts *model.PodExecOptions) (*websocket.Conn, error) {
+				execPod, execOpts = namespace+"/"+name, opts
+				dialer := &websocket.Dialer{Subprotocols: []string{"v4.channel.k8s.io", "channel.k8s.io"}}
+				conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(fakeKube.URL, "http"), nil)
+				return conn, err
+			},
+		}
+	}
+
+	Convey("KubeResources Exec proxies a session with Kubernetes", t, func() {
+		conn, err := sg.KubeResources.Exec(pod.ID, &model.PodExecOptions{Container: "nginx", Command: []string{"sh", "-c", "cat"}, Stdin: true})
+
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		So(conn.Subprotocol(), ShouldEqual, "v4.channel.k8s.io")
+		So(execPod, ShouldEqual, "test/web")
+		So(execOpts, ShouldResemble, &model.PodExecOptions{Container: "nginx", Command: []string{"sh", "-c", "cat"}, Stdin: true})
+
+		So(conn.WriteMessage(websocket.BinaryMessage, []byte("\x00hello")), ShouldBeNil)
+		_, message, err := conn.ReadMessage()
+		So(err, ShouldBeNil)
+		So(string(message), ShouldEqual, "\x01hello")
+
+		So(conn.WriteMessage(websocket.BinaryMessage, []byte("\x00exit")), ShouldBeNil)
+		_, message, err = conn.ReadMessage()
+		So(err, ShouldBeNil)
+		So(string(message), ShouldEqual, "\x03"+`{"status":"Success"}`)
+		_, _, err = conn.ReadMessage()
+		So(websocket.IsCloseError(err, websocket.CloseNormalClosure), ShouldBeTrue)
+	})
+
+	Convey("KubeResources Exec responds with errors before opening a session", t, func() {
+		table := []struct {
+			// Input
+			sg   *client.Client
+			id   *int64
+			opts *model.PodExecOptions
+			// Expectations
+			err *model.Error
+		}{
+			// Only Pods have exec sessions
+			{
+				sg:   sg,
+				id:   service.ID,
+				opts: &model.PodExecOptions{Command: []string{"sh"}},
+				err:  &model.Error{Status: 422, Message: fmt.Sprintf("KubeResource %d is a Service, only Pods have logs and exec sessions", *service.ID)},
+			},
+			// A command is required
+			{
+				sg:   sg,
+				id:   pod.ID,
+				opts: new(model.PodExecOptions),
+				err:  &model.Error{Status: 400, Message: "Invalid command parameter: required"},
+			},
+			// As the requests of any route, they're authenticated
+			{
+				sg:   srv.Core.APIClient("token", strings.Repeat("x", 32)),
+				id:   pod.ID,
+				opts: &model.PodExecOptions{Command: []string{"sh"}},
+				err:  &model.Error{Status: 401, Message: "Unauthorized"},
+			},
+		}
+
+		for _, item := range table {
+			conn, err := item.sg.KubeResources.Exec(item.id, item.opts)
+
+			So(conn, ShouldBeNil)
+			So(err, ShouldResemble, item.err)
+		}
+	})
+}
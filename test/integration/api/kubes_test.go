@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
 	"github.com/supergiant/supergiant/pkg/model"
 	"github.com/supergiant/supergiant/test/fake_core"
 
@@ -658,6 +659,7 @@ func TestKubesUpdate(t *testing.T) {
 
 			srv.Core.Kubes.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.Kubes.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
@@ -843,3 +845,69 @@ func TestKubeDelete(t *testing.T) {
 		}
 	})
 }
+
+func TestKubesDeleteIngresses(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	srv.Core.K8S = func(_ *model.Kube) kubernetes.ClientInterface {
+		return new(fake_core.KubernetesClient)
+	}
+	var recordsDeleted []string
+	srv.Core.RFC2136Provider = &fake_core.DNSProvider{
+		DeleteRecordFn: func(_ *model.DNSZone, record *core.DNSRecord) error {
+			recordsDeleted = append(recordsDeleted, record.Type+" "+record.Name)
+			return nil
+		},
+	}
+	srv.Core.DB.Create(&model.DNSZone{Name: "example.com", Provider: "rfc2136"})
+
+	Convey("Kubes Delete deletes the Ingresses of the Kube, and their DNS records", t, func() {
+		kube := createKube(sg)
+		ingress := &model.Ingress{
+			KubeName:  kube.Name,
+			Name:      "web",
+			Namespace: "default",
+			Rules: []*model.IngressRule{
+				{Host: "www.example.com", Paths: []*model.IngressPath{{Path: "/", ServiceName: "web", ServicePort: 80}}},
+			},
+		}
+		So(srv.Core.DB.Create(ingress), ShouldBeNil)
+
+		So(sg.Kubes.Delete(kube.ID, kube), ShouldBeNil)
+		// TODO (cuz Async delete is too slow for following DB lookups)
+		time.Sleep(time.Second)
+
+		var remaining []*model.Ingress
+		srv.Core.DB.Find(&remaining)
+		So(remaining, ShouldBeEmpty)
+		So(recordsDeleted, ShouldResemble, []string{"CNAME www.example.com", "A www.example.com", "AAAA www.example.com"})
+	})
+
+	Convey("Certificates of Ingresses without a Kube aren't renewed", t, func() {
+		kube := createKube(sg)
+		expiresAt := time.Now().Add(time.Hour)
+		ingress := &model.Ingress{
+			KubeName:  kube.Name,
+			Name:      "orphan",
+			Namespace: "default",
+			TLS:       true,
+			Rules: []*model.IngressRule{
+				{Host: "www.example.com", Paths: []*model.IngressPath{{Path: "/", ServiceName: "web", ServicePort: 80}}},
+			},
+			CertificateExpiresAt: &expiresAt,
+		}
+		So(srv.Core.DB.Create(ingress), ShouldBeNil)
+		srv.Core.DB.Delete(kube)
+
+		renewer := &core.CertificateRenewer{Core: srv.Core, RenewBefore: 24 * time.Hour}
+		So(renewer.Perform(), ShouldBeNil)
+	})
+}
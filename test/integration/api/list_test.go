@@ -3,7 +3,9 @@ package api
 import (
 	"testing"
 
+	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
+	"github.com/supergiant/supergiant/test/fake_core"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -194,3 +196,287 @@ func TestListPagination(t *testing.T) {
 		}
 	})
 }
+
+func TestListQuery(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+		return new(fake_core.Provider)
+	}
+	kube := createKube(sg)
+	sg.CloudAccounts.Create(&model.CloudAccount{Name: "other", Provider: "aws", Credentials: map[string]string{"thanks": "for being great"}})
+	sg.Kubes.Create(&model.Kube{
+		CloudAccountName: "other",
+		Name:             "production",
+		MasterNodeSize:   "m4.large",
+		NodeSizes:        []string{"m4.large"},
+		AWSConfig:        &model.AWSKubeConfig{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+	})
+
+	releases := []*model.HelmRelease{
+		{KubeName: kube.Name, Name: "redis", ChartName: "redis", ChartVersion: "0.2.0", Config: map[string]interface{}{"image": map[string]interface{}{"tag": "4.0"}, "persistence": map[string]interface{}{"enabled": true}}},
+		{KubeName: kube.Name, Name: "mysql", ChartName: "mysql", ChartVersion: "0.3.0", Config: map[string]interface{}{"image": map[string]interface{}{"tag": "5.7"}}},
+		{KubeName: "production", Name: "cache", ChartName: "redis", ChartVersion: "0.1.0"},
+	}
+	for _, release := range releases {
+		release.RepoName = "stable"
+		if err := srv.Core.DB.Create(release); err != nil {
+			panic(err)
+		}
+	}
+
+	Convey("Filter expressions and sorting on API list operations work correctly", t, func() {
+		table := []struct {
+			// Input
+			filters map[string][]string
+			query   []string
+			sort    []string
+			// Expectations
+			names []string
+			err   *model.Error
+		}{
+			// Empty (sorted by id unless sort is given)
+			{names: []string{"redis", "mysql", "cache"}},
+			// Comparisons
+			{query: []string{"name!=redis"}, names: []string{"mysql", "cache"}},
+			{query: []string{"chart_version>0.1.0"}, names: []string{"redis", "mysql"}},
+			{query: []string{"chart_version <= 0.2.0", "chart_name==redis"}, names: []string{"redis", "cache"}},
+			{query: []string{"name in (redis, 'cache')"}, names: []string{"redis", "cache"}},
+			{query: []string{"name notin (redis)"}, names: []string{"mysql", "cache"}},
+			{query: []string{"name like %s%"}, names: []string{"redis", "mysql"}},
+			// Fields of belongs_to associations
+			{query: []string{"kube.cloud_account_name=other"}, names: []string{"cache"}},
+			{query: []string{"kube.cloud_account.name in (test)"}, names: []string{"redis", "mysql"}},
+			{filters: map[string][]string{"kube.cloud_account_name": []string{"other"}}, names: []string{"cache"}},
+			// Fields stored as JSON
+			{query: []string{"config.image.tag=4.0"}, names: []string{"redis"}},
+			{query: []string{"config.image.tag!=4.0"}, names: []string{"mysql", "cache"}},
+			{query: []string{"config.image.tag>5"}, names: []string{"mysql"}},
+			{query: []string{"config.persistence.enabled"}, names: []string{"redis"}},
+			{query: []string{"!config.image"}, names: []string{"cache"}},
+			// Sorting
+			{sort: []string{"name"}, names: []string{"cache", "mysql", "redis"}},
+			{sort: []string{"chart_name", "-chart_version"}, names: []string{"mysql", "redis", "cache"}},
+			// Values are never interpolated
+			{query: []string{"name=redis' OR '1'='1"}},
+			// Errors
+			{query: []string{"password=secret"}, err: &model.Error{Status: 400, Message: "Invalid list query: can't filter on 'password'"}},
+			{query: []string{"name ~ redis"}, err: &model.Error{Status: 400, Message: "Invalid list query: can't parse filter 'name ~ redis'"}},
+			{query: []string{"id>one"}, err: &model.Error{Status: 400, Message: "Invalid list query: id: invalid value 'one'"}},
+			{query: []string{"name in redis"}, err: &model.Error{Status: 400, Message: "Invalid list query: values of 'name in redis' must be in parentheses, such as (this,that)"}},
+			{query: []string{"kube.config=x"}, err: &model.Error{Status: 400, Message: "Invalid list query: can't filter on 'kube.config'"}},
+			{sort: []string{"config"}, err: &model.Error{Status: 400, Message: "Invalid list query: can't sort on 'config'"}},
+		}
+
+		for _, item := range table {
+			if item.sort == nil {
+				item.sort = []string{"id"}
+			}
+			list := &model.HelmReleaseList{
+				BaseList: model.BaseList{
+					Filters: item.filters,
+					Query:   item.query,
+					Sort:    item.sort,
+				},
+			}
+			err := sg.HelmReleases.List(list)
+
+			if item.err != nil {
+				So(err, ShouldResemble, item.err)
+				continue
+			}
+			So(err, ShouldBeNil)
+
+			var names []string
+			for _, release := range list.Items {
+				names = append(names, release.Name)
+			}
+			So(names, ShouldResemble, item.names)
+		}
+
+		Convey("Matches on fields stored as JSON are paginated", func() {
+			list := &model.HelmReleaseList{
+				BaseList: model.BaseList{
+					Query:  []string{"config.image"},
+					Sort:   []string{"id"},
+					Limit:  1,
+					Offset: 1,
+				},
+			}
+			err := sg.HelmReleases.List(list)
+
+			So(err, ShouldBeNil)
+			So(list.Total, ShouldEqual, 2)
+			So(len(list.Items), ShouldEqual, 1)
+			So(list.Items[0].Name, ShouldEqual, "mysql")
+
+			list.Offset = 5
+			err = sg.HelmReleases.List(list)
+
+			So(err, ShouldBeNil)
+			So(list.Total, ShouldEqual, 2)
+			So(list.Items, ShouldBeEmpty)
+		})
+
+		Convey("Fields stored as JSON which are excluded aren't loaded, unless filtered on", func() {
+			list := &model.HelmReleaseList{
+				BaseList: model.BaseList{
+					Query:   []string{"config.image.tag=4.0"},
+					Exclude: []string{"config", "values"},
+				},
+			}
+			err := sg.HelmReleases.List(list)
+
+			So(err, ShouldBeNil)
+			So(len(list.Items), ShouldEqual, 1)
+			So(list.Items[0].Name, ShouldEqual, "redis")
+			So(list.Items[0].Config, ShouldBeNil)
+		})
+	})
+}
+
+func TestListCursorPagination(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	for _, username := range []string{"user1", "user2", "user3", "user4"} {
+		sg.Users.Create(&model.User{Username: username, Password: "password"})
+	}
+
+	usernames := func(list *model.UserList) (names []string) {
+		for _, user := range list.Items {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+
+	Convey("Cursor pagination on API list operations works correctly", t, func() {
+		start := ""
+		list := &model.UserList{
+			BaseList: model.BaseList{
+				Sort:   []string{"username"},
+				Limit:  2,
+				Cursor: &start,
+			},
+		}
+		err := sg.Users.List(list)
+
+		So(err, ShouldBeNil)
+		So(usernames(list), ShouldResemble, []string{"bossman", "user1"})
+		So(list.Total, ShouldEqual, 5)
+		So(list.NextCursor, ShouldNotBeEmpty)
+
+		Convey("Items created before the cursor don't shift the next page", func() {
+			user := &model.User{Username: "user0", Password: "password"}
+			sg.Users.Create(user)
+			defer sg.Users.Delete(user.ID, user)
+
+			next := list.NextCursor
+			list.Cursor = &next
+			list.NextCursor = ""
+			list.Items = nil
+			err := sg.Users.List(list)
+
+			So(err, ShouldBeNil)
+			So(usernames(list), ShouldResemble, []string{"user2", "user3"})
+
+			next = list.NextCursor
+			list.Items = nil
+			list.NextCursor = ""
+			err = sg.Users.List(list)
+
+			So(err, ShouldBeNil)
+			So(usernames(list), ShouldResemble, []string{"user4"})
+			So(list.NextCursor, ShouldBeEmpty)
+		})
+
+		Convey("A cursor is only valid for the same sort", func() {
+			next := list.NextCursor
+			err := sg.Users.List(&model.UserList{BaseList: model.BaseList{Cursor: &next}})
+			So(err, ShouldResemble, &model.Error{Status: 400, Message: "Invalid list query: the cursor is of a list sorted by 'username', not ''"})
+
+			bad := "nope"
+			err = sg.Users.List(&model.UserList{BaseList: model.BaseList{Cursor: &bad}})
+			So(err, ShouldResemble, &model.Error{Status: 400, Message: "Invalid list query: invalid cursor"})
+		})
+
+		Convey("Lists sorted on fields which may be empty can't be paged by cursor", func() {
+			err := sg.Ingresses.List(&model.IngressList{BaseList: model.BaseList{Sort: []string{"certificate_expires_at"}, Cursor: &start}})
+			So(err, ShouldResemble, &model.Error{Status: 400, Message: "Invalid list query: can't page by cursor when sorted on 'certificate_expires_at', which may be empty; page by offset instead"})
+
+			err = sg.Ingresses.List(&model.IngressList{BaseList: model.BaseList{Sort: []string{"certificate_expires_at"}}})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("The client iterates every page", func() {
+			iter := sg.Users.Iterate(&model.UserList{BaseList: model.BaseList{Limit: 2, Query: []string{"username!=user3"}}})
+			var names []string
+			for iter.Next() {
+				names = append(names, iter.Item().(*model.User).Username)
+			}
+
+			So(iter.Err(), ShouldBeNil)
+			So(names, ShouldResemble, []string{"bossman", "user1", "user2", "user4"})
+		})
+	})
+}
+
+func TestListFields(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	requestor := createAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", requestor.APIToken)
+
+	Convey("Selecting the fields of API list operations works correctly", t, func() {
+		table := []struct {
+			// Input
+			fields  []string
+			exclude []string
+			// Expectations
+			user *model.User
+			err  *model.Error
+		}{
+			{
+				fields: []string{"username"},
+				user:   &model.User{BaseModel: model.BaseModel{ID: requestor.ID}, Username: requestor.Username},
+			},
+			{
+				fields:  []string{"username", "role", "api_token"},
+				exclude: []string{"api_token"},
+				user:    &model.User{BaseModel: model.BaseModel{ID: requestor.ID}, Username: requestor.Username, Role: "admin"},
+			},
+			{
+				fields: []string{"nope"},
+				err:    &model.Error{Status: 400, Message: "Invalid list query: no field 'nope'"},
+			},
+		}
+
+		for _, item := range table {
+			list := &model.UserList{
+				BaseList: model.BaseList{
+					Fields:  item.fields,
+					Exclude: item.exclude,
+				},
+			}
+			err := sg.Users.List(list)
+
+			if item.err != nil {
+				So(err, ShouldResemble, item.err)
+				continue
+			}
+			So(err, ShouldBeNil)
+			So(list.Items, ShouldResemble, []*model.User{item.user})
+		}
+	})
+}
@@ -354,6 +354,7 @@ func TestLoadBalancersUpdate(t *testing.T) {
 
 			srv.Core.LoadBalancers.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.LoadBalancers.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
@@ -103,6 +104,7 @@ func TestNodesCreate(t *testing.T) {
 			// Input
 			parentCloudAccount *model.CloudAccount
 			parentKube         *model.Kube
+			existingNodeUsages []*model.NodeUsage
 			model              *model.Node
 			// Mocks
 			mockCreateNodeError error
@@ -207,6 +209,45 @@ func TestNodesCreate(t *testing.T) {
 				err:                 nil,
 				statusError:         "error creating Node",
 			},
+
+			// CloudAccount budget spent, with hard stop
+			{
+				parentCloudAccount: &model.CloudAccount{
+					Name:        "test",
+					Provider:    "aws",
+					Credentials: map[string]string{"test": "test"},
+					Budget: model.Budget{
+						MonthlyBudget:  0.5,
+						BudgetHardStop: true,
+					},
+				},
+				parentKube: &model.Kube{
+					CloudAccountName: "test",
+					Name:             "test",
+					MasterNodeSize:   "t2.micro",
+					NodeSizes:        []string{"t2.micro"},
+					AWSConfig: &model.AWSKubeConfig{
+						Region:           "us-east-1",
+						AvailabilityZone: "us-east-1a",
+					},
+				},
+				existingNodeUsages: []*model.NodeUsage{
+					{
+						CloudAccountName: "test",
+						KubeName:         "deleted",
+						Size:             "t2.micro",
+						HourlyPrice:      6,
+						StartedAt:        time.Now().UTC().Add(-20 * time.Minute),
+						EndedAt:          time.Now().UTC().Add(-10 * time.Minute),
+					},
+				},
+				model: &model.Node{
+					KubeName: "test",
+					Size:     "t2.micro",
+				},
+				mockCreateNodeError: nil,
+				err:                 &model.Error{Status: 422, Message: "Monthly budget of 0.50 for CloudAccount 'test' is exceeded (1.00 spent), so no Nodes can be created"},
+			},
 		}
 
 		for _, item := range table {
@@ -230,6 +271,9 @@ func TestNodesCreate(t *testing.T) {
 			if item.parentKube != nil {
 				srv.Core.Kubes.Create(item.parentKube)
 			}
+			for _, usage := range item.existingNodeUsages {
+				srv.Core.DB.Create(usage)
+			}
 
 			err := sg.Nodes.Create(item.model)
 
@@ -414,6 +458,7 @@ func TestNodesUpdate(t *testing.T) {
 
 			srv.Core.Nodes.Create(item.existingModel)
 
+			item.modelUpdate.ResourceVersion = item.existingModel.ResourceVersion
 			err := sg.Nodes.Update(item.existingModel.ID, item.modelUpdate)
 
 			if item.err == nil {
@@ -533,5 +578,32 @@ func TestNodesDelete(t *testing.T) {
 
 			So(item.existingModel.Status.Error, ShouldEqual, item.statusError)
 		}
+
+		Convey("The uptime of a deleted Node is recorded once, even when deleting it is retried", func() {
+			wipeAndInitialize(srv.Core)
+			srv.Core.AWSProvider = func(_ map[string]string) core.Provider {
+				return new(fake_core.Provider)
+			}
+			srv.Core.CloudAccounts.Create(&model.CloudAccount{Name: "test", Provider: "aws", Credentials: map[string]string{"test": "test"}})
+			srv.Core.Kubes.Create(&model.Kube{
+				CloudAccountName: "test",
+				Name:             "test",
+				MasterNodeSize:   "t2.micro",
+				NodeSizes:        []string{"t2.micro"},
+				AWSConfig:        &model.AWSKubeConfig{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+			})
+			startedAt := time.Now().Add(-time.Hour)
+
+			// A retry finds the Node again if deleting its row failed
+			for i := 0; i < 2; i++ {
+				node := &model.Node{KubeName: "test", Size: "t2.micro", Name: "test.host", ProviderID: "prov-ID", ProviderCreationTimestamp: startedAt}
+				So(srv.Core.DB.Create(node), ShouldBeNil)
+				So(srv.Core.Nodes.Delete(node.ID, node).Now(), ShouldBeNil)
+			}
+
+			var usages []*model.NodeUsage
+			srv.Core.DB.Where("node_name = ?", "test.host").Find(&usages)
+			So(usages, ShouldHaveLength, 1)
+		})
 	})
 }
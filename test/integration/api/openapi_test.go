@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/model"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                                       `json:"openapi"`
+	Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func getOpenAPIDocument() (*openAPIDocument, error) {
+	resp, err := http.Get("http://localhost:9999/api/v1/openapi.json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	doc := new(openAPIDocument)
+	return doc, json.NewDecoder(resp.Body).Decode(doc)
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	Convey("The OpenAPI document describes /api/v1, without logging in", t, func() {
+		doc, err := getOpenAPIDocument()
+
+		So(err, ShouldBeNil)
+		So(doc.OpenAPI, ShouldEqual, "3.0.0")
+		So(doc.Paths["/kubes"]["post"]["operationId"], ShouldEqual, "CreateKube")
+		So(doc.Paths["/kubes/{id}"]["patch"]["operationId"], ShouldEqual, "UpdateKube")
+		So(doc.Paths["/kubes/{id}"]["put"]["operationId"], ShouldEqual, "UpdateKubePut")
+		So(doc.Paths["/sessions"]["post"]["security"], ShouldResemble, []interface{}{})
+
+		Convey("with schemas built from the tags of the models", func() {
+			user := doc.Components.Schemas["User"].Properties
+			So(user["username"], ShouldResemble, map[string]interface{}{"type": "string", "minLength": float64(1), "maxLength": float64(24), "pattern": "^[A-Za-z0-9_-]+$"})
+			So(user["password"], ShouldResemble, map[string]interface{}{"type": "string", "minLength": float64(8), "maxLength": float64(32), "writeOnly": true})
+			So(user["role"], ShouldResemble, map[string]interface{}{"type": "string", "minLength": float64(1), "default": "user"})
+			So(user["id"], ShouldResemble, map[string]interface{}{"type": "integer", "format": "int64", "readOnly": true})
+			So(user["created_at"], ShouldResemble, map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true})
+
+			kube := doc.Components.Schemas["Kube"].Properties
+			So(kube["heapster_version"]["default"], ShouldEqual, "v1.4.0")
+			So(kube["heapster_version"]["x-immutable"], ShouldBeTrue)
+			So(kube["node_sizes"]["items"], ShouldResemble, map[string]interface{}{"type": "string"})
+			So(kube["cloud_account"]["$ref"], ShouldEqual, "#/components/schemas/CloudAccount")
+			// Fields of embedded structs are flattened
+			So(kube["monthly_budget"]["type"], ShouldEqual, "number")
+		})
+	})
+
+	Convey("The API v0 is the same as v1", t, func() {
+		requestor := createAdmin(srv.Core)
+		req, _ := http.NewRequest("GET", "http://localhost:9999/api/v0/users", nil)
+		req.Header.Set("Authorization", `SGAPI token="`+requestor.APIToken+`"`)
+		resp, err := http.DefaultClient.Do(req)
+
+		So(err, ShouldBeNil)
+		So(resp.StatusCode, ShouldEqual, 200)
+		resp.Body.Close()
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// The requests of the client which the API doesn't serve
+var unsupportedClientRequests = map[string]bool{
+	// Sessions and HelmReleasePromotions are made through other routes, but
+	// have every method of a Collection.
+	"Sessions.Update":              true,
+	"HelmReleasePromotions.Create": true,
+	"HelmReleasePromotions.Update": true,
+	"HelmReleasePromotions.Delete": true,
+	// Exec sessions are WebSockets, which OpenAPI can't describe
+	"KubeResources.Exec": true,
+}
+
+func TestClientMatchesOpenAPIDocument(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	Convey("Every request the client makes is in the OpenAPI document", t, func() {
+		doc, err := getOpenAPIDocument()
+		So(err, ShouldBeNil)
+
+		type request struct{ method, path string }
+		var requests []request
+		recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, request{r.Method, r.URL.Path})
+			w.Write([]byte("{}"))
+		}))
+		defer recorder.Close()
+
+		sg := client.New(recorder.URL, "token", "token", "")
+		idRxp := regexp.MustCompile(`/1(/|$)`)
+		id := int64(1)
+
+		collections := reflect.ValueOf(sg).Elem()
+		for i := 0; i < collections.NumField(); i++ {
+			collection := collections.Field(i)
+			if collection.Kind() != reflect.Interface || collection.IsNil() {
+				continue
+			}
+			collectionName := collections.Type().Field(i).Name
+
+			for j := 0; j < collection.NumMethod(); j++ {
+				name := collectionName + "." + collection.Type().Method(j).Name
+				method := collection.Method(j)
+				// Iterate requests through List
+				if strings.HasSuffix(name, ".Iterate") || unsupportedClientRequests[name] {
+					continue
+				}
+
+				var args []reflect.Value
+				for k := 0; k < method.Type().NumIn(); k++ {
+					in := method.Type().In(k)
+					switch {
+					case in == reflect.TypeOf(&id) || in.Kind() == reflect.Interface && in.NumMethod() == 0:
+						args = append(args, reflect.ValueOf(&id))
+					case in == reflect.TypeOf((*model.Model)(nil)).Elem():
+						args = append(args, reflect.ValueOf(new(model.User)))
+					case in == reflect.TypeOf((*model.List)(nil)).Elem():
+						args = append(args, reflect.ValueOf(new(model.UserList)))
+					case in.Kind() == reflect.Ptr:
+						args = append(args, reflect.New(in.Elem()))
+					default:
+						args = append(args, reflect.Zero(in))
+					}
+				}
+
+				requests = nil
+				method.Call(args)
+
+				So(len(requests), ShouldEqual, 1)
+				path := idRxp.ReplaceAllString(strings.TrimPrefix(requests[0].path, "/api/v1"), "/{id}$1")
+				So(doc.Paths[path][strings.ToLower(requests[0].method)], ShouldNotBeNil)
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/supergiant/supergiant/pkg/client"
+	"github.com/supergiant/supergiant/pkg/model"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResourceVersions(t *testing.T) {
+	srv := newTestServer()
+	go srv.Start()
+	defer srv.Stop()
+
+	user, admin := createUserAndAdmin(srv.Core)
+	sg := srv.Core.APIClient("token", admin.APIToken)
+
+	Convey("Updates are made from the resource version of the item", t, func() {
+		srv.Core.Users.Get(user.ID, user)
+		version := user.ResourceVersion
+
+		table := []struct {
+			// Input
+			resourceVersion int64
+			// Expectations
+			err           *model.Error
+			storedVersion int64
+		}{
+			// Updates from the current version increment it
+			{
+				resourceVersion: version,
+				storedVersion:   version + 1,
+			},
+			// Updates from an older version conflict
+			{
+				resourceVersion: version,
+				err:             &model.Error{Status: 409, Message: "User " + strconv.FormatInt(*user.ID, 10) + " has changed since resource version " + strconv.FormatInt(version, 10) + ", get it again and retry"},
+				storedVersion:   version + 1,
+			},
+			// Updates require a version
+			{
+				resourceVersion: 0,
+				err:             &model.Error{Status: 428, Message: "resource_version (or If-Match) required, of the version the update was made from"},
+				storedVersion:   version + 1,
+			},
+		}
+
+		for _, item := range table {
+			update := &model.User{Password: "new-password"}
+			update.ResourceVersion = item.resourceVersion
+			err := sg.Users.Update(user.ID, update)
+
+			if item.err == nil {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldResemble, item.err)
+				So(client.IsConflict(err), ShouldEqual, item.err.Status == 409)
+			}
+
+			reloaded := new(model.User)
+			srv.Core.Users.Get(user.ID, reloaded)
+			So(reloaded.ResourceVersion, ShouldEqual, item.storedVersion)
+		}
+	})
+
+	Convey("Items have their resource version as ETag, which updates can give as If-Match", t, func() {
+		srv.Core.Users.Get(user.ID, user)
+		path := "http://localhost:9999/api/v1/users/" + strconv.FormatInt(*user.ID, 10)
+
+		do := func(method string, ifMatch string, body string) *http.Response {
+			req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+			req.Header.Set("Authorization", `SGAPI token="`+admin.APIToken+`"`)
+			if ifMatch != "" {
+				req.Header.Set("If-Match", ifMatch)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			return resp
+		}
+
+		etag := strconv.Quote(strconv.FormatInt(user.ResourceVersion, 10))
+		So(do("GET", "", "").Header.Get("ETag"), ShouldEqual, etag)
+
+		resp := do("PATCH", etag, `{"password":"new-password"}`)
+		So(resp.StatusCode, ShouldEqual, 202)
+		So(resp.Header.Get("ETag"), ShouldEqual, strconv.Quote(strconv.FormatInt(user.ResourceVersion+1, 10)))
+
+		// If-Match takes precedence over resource_version
+		So(do("PATCH", etag, `{"password":"new-password","resource_version":`+strconv.FormatInt(user.ResourceVersion+1, 10)+`}`).StatusCode, ShouldEqual, 409)
+		So(do("PATCH", "W/"+resp.Header.Get("ETag"), `{"password":"new-password"}`).StatusCode, ShouldEqual, 202)
+		So(do("PATCH", "*", `{"password":"new-password"}`).StatusCode, ShouldEqual, 400)
+	})
+
+	Convey("Saves made meanwhile, such as by populating, conflict with updates", t, func() {
+		srv.Core.Users.Get(user.ID, user)
+		version := user.ResourceVersion
+
+		user.Password = "password"
+		So(srv.Core.DB.Save(user), ShouldBeNil)
+		So(user.ResourceVersion, ShouldEqual, version+1)
+
+		update := &model.User{Password: "new-password"}
+		update.ResourceVersion = version
+		err := sg.Users.Update(user.ID, update)
+
+		So(client.IsConflict(err), ShouldBeTrue)
+	})
+
+	Convey("Saves of items read before an update don't reuse its version", t, func() {
+		stale := new(model.User)
+		srv.Core.Users.Get(user.ID, stale)
+		version := stale.ResourceVersion
+
+		update := &model.User{Password: "newer-password"}
+		update.ResourceVersion = version
+		So(sg.Users.Update(user.ID, update), ShouldBeNil)
+		So(update.ResourceVersion, ShouldEqual, version+1)
+
+		stale.Password = "password"
+		So(srv.Core.DB.Save(stale), ShouldBeNil)
+		So(stale.ResourceVersion, ShouldEqual, version+2)
+
+		update = &model.User{Password: "newest-password"}
+		update.ResourceVersion = version + 1
+		err := sg.Users.Update(user.ID, update)
+
+		So(client.IsConflict(err), ShouldBeTrue)
+	})
+}
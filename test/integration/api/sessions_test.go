@@ -2,7 +2,9 @@ package api
 
 import (
 	"testing"
+	"time"
 
+	"github.com/supergiant/supergiant/pkg/client"
 	"github.com/supergiant/supergiant/pkg/core"
 	"github.com/supergiant/supergiant/pkg/model"
 
@@ -121,6 +123,19 @@ func TestSessionsCreate(t *testing.T) {
 				list := new(model.NodeList)
 				authErr := userSG.Nodes.List(list)
 				So(authErr, ShouldBeNil)
+				So(session.ExpiresAt.Sub(session.CreatedAt), ShouldEqual, 3*time.Hour)
+			})
+		})
+
+		Convey("When a Session expired", func() {
+			session := createUserSession(srv.Core)
+			session.ExpiresAt = time.Now().Add(-time.Minute)
+
+			userSG := srv.Core.APIClient("session", session.ID)
+			err := userSG.Nodes.List(new(model.NodeList))
+
+			Convey("It should no longer allow for API authentication", func() {
+				So(client.IsUnauthorized(err), ShouldBeTrue)
 			})
 		})
 	})
@@ -2,11 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"path"
 
 	"github.com/supergiant/supergiant/pkg/client"
 	"github.com/supergiant/supergiant/pkg/core"
+	"github.com/supergiant/supergiant/pkg/kubernetes"
 	"github.com/supergiant/supergiant/pkg/model"
 	"github.com/supergiant/supergiant/pkg/server"
+	"github.com/supergiant/supergiant/pkg/util"
+	"github.com/supergiant/supergiant/test/fake_core"
 )
 
 func newTestServer() *server.Server {
@@ -40,9 +44,16 @@ func wipeDatabase(c *core.Core) {
 	c.DB.Delete(&model.CloudAccount{})
 	c.DB.Delete(&model.Node{})
 	c.DB.Delete(&model.LoadBalancer{})
+	c.DB.Delete(&model.DNSZone{})
+	c.DB.Delete(&model.Ingress{})
 	c.DB.Delete(&model.HelmRepo{})
 	c.DB.Delete(&model.HelmChart{})
+	c.DB.Delete(&model.HelmChartPolicy{})
 	c.DB.Delete(&model.HelmRelease{})
+	c.DB.Delete(&model.HelmReleasePromotion{})
+	c.DB.Delete(&model.NodeUsage{})
+	c.DB.Delete(&model.CostAllocation{})
+	c.DB.Delete(&model.IdempotencyKey{})
 }
 
 func wipeAndInitialize(c *core.Core) {
@@ -103,3 +114,45 @@ func createKube(sg *client.Client) *model.Kube {
 
 	return kube
 }
+
+// fakeHelmWorker is the worker pod helm commands run in. The shell command of
+// each helm command is sent to commands, and fails with err (if set). Files
+// copied to the pod are kept in files, before the command is sent.
+type fakeHelmWorker struct {
+	commands chan string
+	files    map[string]string
+	err      error
+}
+
+func newFakeHelmWorker(err error) *fakeHelmWorker {
+	return &fakeHelmWorker{
+		commands: make(chan string, 2),
+		files:    make(map[string]string),
+		err:      err,
+	}
+}
+
+// k8s returns a fake of Kubernetes which runs the worker as a new pod, so helm
+// is set up in it again.
+func (w *fakeHelmWorker) k8s() *fake_core.KubernetesClient {
+	uid := util.RandomString(8)
+	return &fake_core.KubernetesClient{
+		GetResourceFn: func(apiVersion, kind, namespace, name string, out interface{}) error {
+			pod := out.(*kubernetes.Pod)
+			pod.Metadata.UID = uid
+			pod.Status.Phase = "Running"
+			return nil
+		},
+		RunPodCommandFn: func(namespace, name string, cmd *kubernetes.PodCommand) error {
+			switch {
+			case cmd.Command[0] != "/bin/sh":
+				return nil // removing the files
+			case cmd.Stdin != nil:
+				w.files[path.Base(cmd.Command[2])] = string(cmd.Stdin)
+				return nil
+			}
+			w.commands <- cmd.Command[2]
+			return w.err
+		},
+	}
+}
@@ -136,10 +136,13 @@ func TestUsersUpdate(t *testing.T) {
 	origAdminPass := string(admin.EncryptedPassword)
 
 	Convey("Given a user and an admin", t, func() {
+		// As of the last update, which is made from their resource version
+		srv.Core.Users.Get(user.ID, user)
+		srv.Core.Users.Get(admin.ID, admin)
 
 		Convey("When the user Updates another User", func() {
 			sg := srv.Core.APIClient("token", user.APIToken)
-			err := sg.Users.Update(admin.ID, &model.User{Password: "new-password"})
+			err := sg.Users.Update(admin.ID, newPasswordUpdate(admin))
 
 			Convey("They should receive a 403 Forbidden error", func() {
 				So(err.(*model.Error).Status, ShouldEqual, 403)
@@ -148,7 +151,7 @@ func TestUsersUpdate(t *testing.T) {
 
 		Convey("When the user Updates themself", func() {
 			sg := srv.Core.APIClient("token", user.APIToken)
-			err := sg.Users.Update(user.ID, &model.User{Password: "new-password"})
+			err := sg.Users.Update(user.ID, newPasswordUpdate(user))
 
 			reloadedUser := new(model.User)
 			srv.Core.Users.Get(user.ID, reloadedUser)
@@ -176,7 +179,7 @@ func TestUsersUpdate(t *testing.T) {
 
 		Convey("When the admin Updates another User", func() {
 			sg := srv.Core.APIClient("token", admin.APIToken)
-			err := sg.Users.Update(user.ID, &model.User{Password: "new-password"})
+			err := sg.Users.Update(user.ID, newPasswordUpdate(user))
 
 			reloadedUser := new(model.User)
 			srv.Core.Users.Get(user.ID, reloadedUser)
@@ -191,7 +194,7 @@ func TestUsersUpdate(t *testing.T) {
 
 		Convey("When the admin Updates themself", func() {
 			sg := srv.Core.APIClient("token", admin.APIToken)
-			err := sg.Users.Update(admin.ID, &model.User{Password: "new-password"})
+			err := sg.Users.Update(admin.ID, newPasswordUpdate(admin))
 
 			reloadedAdmin := new(model.User)
 			srv.Core.Users.Get(admin.ID, reloadedAdmin)
@@ -333,3 +336,11 @@ func TestUsersDelete(t *testing.T) {
 		})
 	})
 }
+
+// newPasswordUpdate is an update of the password of a User, made from its
+// resource version.
+func newPasswordUpdate(user *model.User) *model.User {
+	update := &model.User{Password: "new-password"}
+	update.ResourceVersion = user.ResourceVersion
+	return update
+}